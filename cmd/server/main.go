@@ -19,9 +19,11 @@ import (
 	"github.com/joinself/restful-client/internal/app"
 	"github.com/joinself/restful-client/internal/attestation"
 	"github.com/joinself/restful-client/internal/auth"
+	"github.com/joinself/restful-client/internal/broadcast"
 	"github.com/joinself/restful-client/internal/clean"
 	"github.com/joinself/restful-client/internal/config"
 	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/conversation"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/internal/fact"
 	"github.com/joinself/restful-client/internal/healthcheck"
@@ -29,14 +31,18 @@ import (
 	"github.com/joinself/restful-client/internal/metric"
 	"github.com/joinself/restful-client/internal/notification"
 	"github.com/joinself/restful-client/internal/object"
+	"github.com/joinself/restful-client/internal/onboarding"
 	"github.com/joinself/restful-client/internal/request"
+	"github.com/joinself/restful-client/internal/rule"
 	"github.com/joinself/restful-client/internal/self"
 	"github.com/joinself/restful-client/internal/signature"
+	"github.com/joinself/restful-client/internal/template"
 	"github.com/joinself/restful-client/internal/voice"
 	"github.com/joinself/restful-client/pkg/acl"
 	"github.com/joinself/restful-client/pkg/dbcontext"
 	"github.com/joinself/restful-client/pkg/filter"
 	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/worker"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/maragudk/goqite"
@@ -87,18 +93,19 @@ func main() {
 	}()
 
 	// setup queue system
-	q := setupQueueSystem(logger, db.DB())
+	q := setupQueueSystem(logger, db.DB(), "jobs")
+	sq := setupQueueSystem(logger, db.DB(), "scheduled")
 
 	// build HTTP server
-	buildHandler(logger, dbcontext.New(db), cfg, q)
+	buildHandler(logger, dbcontext.New(db), cfg, q, sq)
 }
 
 // setup queue system
-func setupQueueSystem(logger log.Logger, db *sql.DB) *goqite.Queue {
+func setupQueueSystem(logger log.Logger, db *sql.DB, name string) *goqite.Queue {
 	q := goqite.New(goqite.NewOpts{
 		DB:         db,
-		Name:       "jobs",
-		MaxReceive: 100,
+		Name:       name,
+		MaxReceive: worker.MaxReceive,
 	})
 
 	return q
@@ -124,7 +131,7 @@ func setupQueueSystem(logger log.Logger, db *sql.DB) *goqite.Queue {
 // @host		localhost:8080
 // @BasePath	/v1/
 // @schemes		http https
-func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, q *goqite.Queue) http.Handler {
+func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, q, sq *goqite.Queue) http.Handler {
 	e := echo.New()
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
@@ -143,6 +150,7 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, q *go
 	connectionRepo := connection.NewRepository(db, logger)
 	messageRepo := message.NewRepository(db, logger)
 	factRepo := fact.NewRepository(db, logger)
+	issuedFactRepo := fact.NewIssuedRepository(db, logger)
 	requestRepo := request.NewRepository(db, logger)
 	attestationRepo := attestation.NewRepository(db, logger)
 	accountRepo := account.NewRepository(db, logger)
@@ -151,29 +159,55 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, q *go
 	metricRepo := metric.NewRepository(db, tokenChecker, logger)
 	voiceRepo := voice.NewRepository(db, logger)
 	signatureRepo := signature.NewRepository(db, logger)
+	broadcastRepo := broadcast.NewRepository(db, logger)
+	templateRepo := template.NewRepository(db, logger)
+	conversationRepo := conversation.NewRepository(db, logger)
+	ruleRepo := rule.NewRepository(db, logger)
+	onboardingRepo := onboarding.NewRepository(db, logger)
+
+	attachmentsDir := ""
+	if cfg.DownloadAttachments == "true" {
+		attachmentsDir = filepath.Join(cfg.StorageDir, "attachments")
+	}
 
 	// Services
-	rService := request.NewService(requestRepo, factRepo, attestationRepo, logger)
+	rService := request.NewService(requestRepo, factRepo, attestationRepo, appRepo, logger)
 	runner := self.NewRunner(self.RunnerConfig{
-		ConnectionRepo: connectionRepo,
-		FactRepo:       factRepo,
-		MessageRepo:    messageRepo,
-		RequestRepo:    requestRepo,
-		RequestService: rService,
-		AppRepo:        appRepo,
-		MetricRepo:     metricRepo,
-		VoiceRepo:      voiceRepo,
-		SignatureRepo:  signatureRepo,
-		Logger:         logger,
-		StorageKey:     cfg.StorageKey,
-		StorageDir:     cfg.StorageDir,
-		Queue:          q,
+		ConnectionRepo:   connectionRepo,
+		FactRepo:         factRepo,
+		IssuedFactRepo:   issuedFactRepo,
+		MessageRepo:      messageRepo,
+		ConversationRepo: conversationRepo,
+		RequestRepo:      requestRepo,
+		RequestService:   rService,
+		AppRepo:          appRepo,
+		MetricRepo:       metricRepo,
+		VoiceRepo:        voiceRepo,
+		SignatureRepo:    signatureRepo,
+		Logger:           logger,
+		StorageKey:       cfg.StorageKey,
+		StorageDir:       cfg.StorageDir,
+		AttachmentsDir:   attachmentsDir,
+		Queue:            q,
 	})
 	rService.SetRunner(runner)
-	cService := connection.NewService(connectionRepo, runner, logger)
 	aService := app.NewService(appRepo, runner, logger)
 	vService := voice.NewService(voiceRepo, runner, logger)
 	sService := signature.NewService(signatureRepo, runner, logger)
+	scheduler := worker.NewScheduler(sq, logger)
+	tService := template.NewService(templateRepo, connectionRepo, logger)
+	mService := message.NewService(messageRepo, runner, scheduler, tService, logger)
+	scheduler.Register(message.TASK_SEND_MESSAGE, mService.Dispatch)
+	bService := broadcast.NewService(broadcastRepo, mService, runner, scheduler, logger)
+	scheduler.Register(broadcast.TASK_SEND_BROADCAST, bService.Dispatch)
+	ruleService := rule.NewService(ruleRepo, mService, rService, connectionRepo, logger)
+	runner.SetRules(ruleService)
+	oService := onboarding.NewService(onboardingRepo, mService, rService, connectionRepo, logger)
+	runner.SetOnboarding(oService)
+	cService := connection.NewService(connectionRepo, runner, oService, logger)
+	sweeper := request.NewSweeper(requestRepo, runner, logger)
+	scheduler.Start()
+	sweeper.Start()
 
 	// TODO: preload all deleted pi keys
 	apikeyRepo.PreloadDeleted(context.Background())
@@ -194,12 +228,32 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, q *go
 		logger,
 	)
 	message.RegisterHandlers(appsGroup,
-		message.NewService(messageRepo, runner, logger),
+		mService,
 		cService,
 		logger,
 	)
+	broadcast.RegisterHandlers(appsGroup,
+		bService,
+		logger,
+	)
+	template.RegisterHandlers(appsGroup,
+		tService,
+		logger,
+	)
+	onboarding.RegisterHandlers(appsGroup,
+		oService,
+		logger,
+	)
+	rule.RegisterHandlers(appsGroup,
+		ruleService,
+		logger,
+	)
+	conversation.RegisterHandlers(appsGroup,
+		conversation.NewService(conversationRepo, accountRepo, runner, logger),
+		logger,
+	)
 	fact.RegisterHandlers(appsGroup,
-		fact.NewService(factRepo, attestationRepo, runner, logger),
+		fact.NewService(factRepo, issuedFactRepo, attestationRepo, requestRepo, appRepo, runner, logger),
 		cService,
 		logger,
 	)
@@ -271,7 +325,7 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, q *go
 		Service: clean.NewService(clean.Config{
 			DB:     db,
 			Period: cfg.CleanupPeriod,
-			Tables: []string{"fact", "message", "request", "attestation", "call"},
+			Tables: []string{"fact", "message", "message_revision", "request", "attestation", "call"},
 			Logger: logger,
 		}),
 	})
@@ -296,6 +350,8 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, q *go
 	defer cancel()
 
 	runner.StopAll()
+	scheduler.Stop()
+	sweeper.Stop()
 
 	if err := e.Shutdown(ctx); err != nil {
 		e.Logger.Fatal(err)
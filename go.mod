@@ -16,6 +16,7 @@ require (
 	github.com/joinself/self-go-sdk v0.0.0-20240716150319-2384e918fde1
 	github.com/labstack/echo-jwt/v4 v4.1.0
 	github.com/labstack/echo/v4 v4.9.0
+	github.com/labstack/gommon v0.4.0
 	github.com/lib/pq v1.2.0
 	github.com/maragudk/goqite v0.2.3
 	github.com/mattn/go-sqlite3 v1.14.19
@@ -42,7 +43,6 @@ require (
 	github.com/gorilla/websocket v1.4.1 // indirect
 	github.com/joinself/self-crypto-go v0.0.0-20240228130735-0f203e289d87 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
-	github.com/labstack/gommon v0.4.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.0.3 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
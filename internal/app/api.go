@@ -3,6 +3,7 @@ package app
 import (
 	"net/http"
 
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/acl"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/pagination"
@@ -141,10 +142,11 @@ func (r resource) update(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, ExtApp{
-		ID:     a.ID,
-		Name:   a.Name,
-		Status: a.Status,
-		Env:    a.Env,
+		ID:              a.ID,
+		Name:            a.Name,
+		Status:          a.Status,
+		Env:             a.Env,
+		OutOfBandConfig: outOfBandConfig(a.OutOfBandConfig),
 	})
 }
 
@@ -169,10 +171,20 @@ func (r resource) get(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, ExtApp{
-		ID:       a.ID,
-		Name:     a.Name,
-		Status:   a.Status,
-		Env:      a.Env,
-		Callback: a.Callback,
+		ID:              a.ID,
+		Name:            a.Name,
+		Status:          a.Status,
+		Env:             a.Env,
+		Callback:        a.Callback,
+		OutOfBandConfig: outOfBandConfig(a.OutOfBandConfig),
 	})
 }
+
+// outOfBandConfig returns the out of band settings of an app, or nil when
+// they are not set.
+func outOfBandConfig(c entity.OutOfBandConfig) *entity.OutOfBandConfig {
+	if c == (entity.OutOfBandConfig{}) {
+		return nil
+	}
+	return &c
+}
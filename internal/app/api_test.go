@@ -292,6 +292,24 @@ func TestUpdateAppAPIEndpointAsAdmin(t *testing.T) {
 			WantStatus:   http.StatusBadRequest,
 			WantResponse: `{"details":"The provided body is not valid", "error":"Invalid input", "status":400}`,
 		},
+		{
+			Name:         "out of band config",
+			Method:       "PUT",
+			URL:          "/apps/app",
+			Body:         `{"out_of_band_config":{"dl_code":"code","qr_size":300,"qr_foreground_color":"#0E1C42"}}`,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: ``,
+		},
+		{
+			Name:         "invalid out of band config",
+			Method:       "PUT",
+			URL:          "/apps/app",
+			Body:         `{"out_of_band_config":{"qr_size":10,"qr_foreground_color":"blue"}}`,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"out_of_band_config: (qr_foreground_color: must be in a valid format; qr_size: must be no less than 100.)."}`,
+		},
 		{
 			Name:         "error updating",
 			Method:       "PUT",
@@ -57,10 +57,13 @@ func TestRepository(t *testing.T) {
 
 	// update
 	app.Name = "app1 updated"
+	app.OutOfBandConfig = entity.OutOfBandConfig{DLCode: "dl_code", QRSize: 300}
 	err = repo.Update(ctx, app)
 	assert.Nil(t, err)
 	app, _ = repo.Get(ctx, appID)
 	assert.Equal(t, "app1 updated", app.Name)
+	assert.Equal(t, "dl_code", app.DLCode)
+	assert.Equal(t, 300, app.QRSize)
 
 	// delete
 	err = repo.Delete(ctx, app.ID)
@@ -127,6 +127,9 @@ func (s service) Update(ctx context.Context, id string, req UpdateAppRequest) (A
 	if len(req.CallbackSecret) > 0 {
 		existing.CallbackSecret = req.CallbackSecret
 	}
+	if req.OutOfBandConfig != nil {
+		existing.OutOfBandConfig = *req.OutOfBandConfig
+	}
 	err = s.repo.Update(ctx, existing)
 	if err != nil {
 		s.logger.With(ctx).Infof("there is a problem updating the app %v", err)
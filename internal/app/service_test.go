@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/mock"
 	"github.com/stretchr/testify/assert"
@@ -42,6 +43,17 @@ func Test_service_CRUD(t *testing.T) {
 	app, err = s.Get(ctx, id)
 	assert.Nil(t, err)
 
+	// update
+	_, err = s.Update(ctx, "none", UpdateAppRequest{})
+	assert.NotNil(t, err)
+	app, err = s.Update(ctx, id, UpdateAppRequest{
+		OutOfBandConfig: &entity.OutOfBandConfig{DLCode: "dl_code", QRForegroundColor: "#112233"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, callback, app.Callback)
+	assert.Equal(t, "dl_code", app.DLCode)
+	assert.Equal(t, "#112233", app.QRForegroundColor)
+
 	// delete
 	_, err = s.Delete(ctx, "none")
 	assert.NotNil(t, err)
@@ -7,15 +7,18 @@ import (
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/request"
 	"github.com/joinself/restful-client/pkg/response"
 )
 
 type ExtApp struct {
-	ID       string `json:"id"`
-	Name     string `json:"name,omitempty"`
-	Env      string `json:"env,omitempty"`
-	Status   string `json:"status,omitempty"`
-	Callback string `json:"callback,,omitempty"`
+	ID              string                  `json:"id"`
+	Name            string                  `json:"name,omitempty"`
+	Env             string                  `json:"env,omitempty"`
+	Status          string                  `json:"status,omitempty"`
+	Callback        string                  `json:"callback,,omitempty"`
+	OutOfBandConfig *entity.OutOfBandConfig `json:"out_of_band_config,omitempty"`
 }
 
 type ExtListResponse struct {
@@ -83,9 +86,27 @@ func (m CreateAppRequest) deviceKeyValidator() func(value interface{}) error {
 type UpdateAppRequest struct {
 	Callback       string `json:"callback"`
 	CallbackSecret string `json:"callback_secret"`
+	// OutOfBandConfig replaces the settings used to build the QR codes and
+	// deep links of the app out of band requests.
+	OutOfBandConfig *entity.OutOfBandConfig `json:"out_of_band_config,omitempty"`
 }
 
 // Validate validates the CreateAppRequest fields.
 func (m UpdateAppRequest) Validate() *response.Error {
-	return nil
+	if m.OutOfBandConfig == nil {
+		return nil
+	}
+
+	err := validation.Errors{
+		"out_of_band_config": request.ValidateOutOfBandConfig(*m.OutOfBandConfig),
+	}.Filter()
+	if err == nil {
+		return nil
+	}
+
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: err.Error(),
+	}
 }
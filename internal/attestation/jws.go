@@ -0,0 +1,89 @@
+package attestation
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/jws"
+)
+
+var (
+	// ErrInvalidBody is returned when the attestation body is not a signed JWS.
+	ErrInvalidBody = jws.ErrInvalidJWS
+	// ErrNoKeyHistory is returned when the issuer public keys were not cached
+	// when the attestation was received.
+	ErrNoKeyHistory = jws.ErrNoKeyHistory
+	// ErrInvalidKey is returned when the signing key was not valid at the
+	// time the attestation was issued.
+	ErrInvalidKey = jws.ErrInvalidKey
+	// ErrInvalidSignature is returned when the attestation signature does not
+	// match the issuer public key.
+	ErrInvalidSignature = jws.ErrInvalidSignature
+)
+
+type payload struct {
+	Issuer    string `json:"iss"`
+	Source    string `json:"source"`
+	IssuedAt  string `json:"iat"`
+	ExpiresAt string `json:"exp"`
+}
+
+// Details fills the issuer, source, signing key and validity period of the
+// attestation from its signed body.
+func Details(a *entity.Attestation) error {
+	body, err := jws.Parse([]byte(a.Body))
+	if err != nil {
+		return ErrInvalidBody
+	}
+
+	hdr, err := body.Header()
+	if err != nil {
+		return err
+	}
+
+	var p payload
+	err = body.DecodePayload(&p)
+	if err != nil {
+		return err
+	}
+
+	a.KID = hdr.KID
+	a.Issuer = p.Issuer
+	a.Source = p.Source
+	a.IssuedAt = parseTime(p.IssuedAt)
+	a.ExpiresAt = parseTime(p.ExpiresAt)
+
+	return nil
+}
+
+// Verify checks the attestation signature against the issuer public keys
+// cached when the attestation was received.
+func Verify(a entity.Attestation) error {
+	// The key and issue time are read back from the signed body rather than
+	// trusting the stored columns.
+	err := Details(&a)
+	if err != nil {
+		return err
+	}
+
+	var history []json.RawMessage
+	if len(a.History) > 0 && json.Unmarshal(a.History, &history) != nil {
+		return ErrNoKeyHistory
+	}
+
+	if a.IssuedAt == nil {
+		return ErrInvalidKey
+	}
+
+	body, _ := jws.Parse([]byte(a.Body))
+	return body.Verify(history, *a.IssuedAt)
+}
+
+func parseTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,118 @@
+package attestation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var enc = base64.RawURLEncoding
+
+// sign builds a json serialized jws for the given payload.
+func sign(t *testing.T, sk ed25519.PrivateKey, kid string, payload interface{}) string {
+	hdr, err := json.Marshal(map[string]string{"alg": "EdDSA", "kid": kid})
+	require.Nil(t, err)
+	body, err := json.Marshal(payload)
+	require.Nil(t, err)
+
+	protected := enc.EncodeToString(hdr)
+	p := enc.EncodeToString(body)
+	sig := ed25519.Sign(sk, []byte(protected+"."+p))
+
+	jws, err := json.Marshal(map[string]string{
+		"protected": protected,
+		"payload":   p,
+		"signature": enc.EncodeToString(sig),
+	})
+	require.Nil(t, err)
+	return string(jws)
+}
+
+// keyHistory builds the public key history of an identity with a single
+// device key created at the given time.
+func keyHistory(t *testing.T, sk ed25519.PrivateKey, kid string, createdAt time.Time) []byte {
+	rpk, _, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	op := sign(t, sk, kid, map[string]interface{}{
+		"sequence":  0,
+		"version":   "1.0.0",
+		"timestamp": createdAt.Unix(),
+		"actions": []map[string]interface{}{
+			{
+				"kid":    kid,
+				"did":    "device",
+				"type":   "device.key",
+				"action": "key.add",
+				"from":   createdAt.Unix(),
+				"key":    enc.EncodeToString(sk.Public().(ed25519.PublicKey)),
+			},
+			{
+				"kid":    "recovery",
+				"type":   "recovery.key",
+				"action": "key.add",
+				"from":   createdAt.Unix(),
+				"key":    enc.EncodeToString(rpk),
+			},
+		},
+	})
+
+	history, err := json.Marshal([]json.RawMessage{json.RawMessage(op)})
+	require.Nil(t, err)
+	return history
+}
+
+func TestDetailsAndVerify(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	_, otherSK, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	iat := time.Now().Truncate(time.Second).UTC()
+	payload := map[string]string{
+		"iss":         "issuer",
+		"sub":         "subject",
+		"source":      "passport",
+		"iat":         iat.Format(time.RFC3339),
+		"exp":         iat.Add(time.Hour).Format(time.RFC3339),
+		"nationality": "GB",
+	}
+	history := keyHistory(t, sk, "1", iat.Add(-time.Hour))
+
+	a := entity.Attestation{Body: sign(t, sk, "1", payload), History: history}
+	err = Details(&a)
+	assert.Nil(t, err)
+	assert.Equal(t, "issuer", a.Issuer)
+	assert.Equal(t, "passport", a.Source)
+	assert.Equal(t, "1", a.KID)
+	assert.Equal(t, iat, *a.IssuedAt)
+	assert.Equal(t, iat.Add(time.Hour), *a.ExpiresAt)
+	assert.Nil(t, Verify(a))
+
+	tests := []struct {
+		name string
+		a    entity.Attestation
+		want error
+	}{
+		{"invalid body", entity.Attestation{Body: "TODO", History: history}, ErrInvalidBody},
+		{"no history", entity.Attestation{Body: a.Body}, ErrNoKeyHistory},
+		{"unknown key", entity.Attestation{Body: sign(t, sk, "2", payload), History: history}, ErrInvalidKey},
+		{"issued before the key", entity.Attestation{
+			Body:    a.Body,
+			History: keyHistory(t, sk, "1", iat.Add(time.Hour)),
+		}, ErrInvalidKey},
+		{"invalid signature", entity.Attestation{Body: sign(t, otherSK, "1", payload), History: history}, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Verify(tt.a))
+		})
+	}
+}
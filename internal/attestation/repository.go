@@ -15,6 +15,8 @@ type Repository interface {
 	Get(ctx context.Context, id string) (entity.Attestation, error)
 	// Query returns the list of attestations with the given offset and limit.
 	Query(ctx context.Context, factID string, offset, limit int) ([]entity.Attestation, error)
+	// QueryByFacts returns the attestations of the given facts.
+	QueryByFacts(ctx context.Context, factIDs []string) ([]entity.Attestation, error)
 	// Create saves a new attestation in the storage.
 	Create(ctx context.Context, attestation entity.Attestation) error
 }
@@ -55,3 +57,23 @@ func (r repository) Query(ctx context.Context, factID string, offset, limit int)
 		All(&attestations)
 	return attestations, err
 }
+
+// QueryByFacts retrieves the attestation records of the given facts at once.
+func (r repository) QueryByFacts(ctx context.Context, factIDs []string) ([]entity.Attestation, error) {
+	attestations := []entity.Attestation{}
+	if len(factIDs) == 0 {
+		return attestations, nil
+	}
+
+	ids := make([]interface{}, len(factIDs))
+	for i, id := range factIDs {
+		ids[i] = id
+	}
+
+	err := r.db.With(ctx).
+		Select().
+		OrderBy("id").
+		Where(dbx.In("fact_id", ids...)).
+		All(&attestations)
+	return attestations, err
+}
@@ -0,0 +1,201 @@
+package broadcast
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/pagination"
+	"github.com/joinself/restful-client/pkg/response"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *echo.Group, service Service, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.GET("/:app_id/broadcasts", res.query)
+	r.GET("/:app_id/broadcasts/:id", res.get)
+	r.GET("/:app_id/broadcasts/:id/recipients", res.recipients)
+	r.POST("/:app_id/broadcasts", res.create)
+	r.POST("/:app_id/broadcasts/:id/cancel", res.cancel)
+}
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+// GetBroadcast godoc
+// @Summary         Retrieve broadcast details
+// @Description     Get a broadcast and the progress of its delivery to the targeted connections.
+// @Tags            broadcasts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Param           id      path      string  true  "Broadcast ID"
+// @Success         200     {object}  Broadcast         "Successful Response"
+// @Failure         404     {object}  response.Error    "Broadcast Not Found"
+// @Router          /apps/{app_id}/broadcasts/{id} [get]
+func (r resource) get(c echo.Context) error {
+	broadcast, err := r.service.Get(c.Request().Context(), c.Param("app_id"), c.Param("id"))
+	if err != nil {
+		r.logger.With(c.Request().Context()).Warnf("error retrieving broadcast: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, broadcast)
+}
+
+// ListBroadcasts godoc
+// @Summary         List broadcasts
+// @Description     Retrieves the broadcasts of an app, newest first.
+// @Tags            broadcasts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path   string  true  "Application ID"
+// @Param           page query int false "Page number for results pagination"
+// @Param           per_page query int false "Number of results per page for pagination"
+// @Success         200  {object}  ExtListResponse "Successfully retrieved the broadcasts"
+// @Failure         500  {object}  response.Error "Internal server error"
+// @Router          /apps/{app_id}/broadcasts [get]
+func (r resource) query(c echo.Context) error {
+	ctx := c.Request().Context()
+	count, err := r.service.Count(ctx, c.Param("app_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting broadcasts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	broadcasts, err := r.service.Query(ctx, c.Param("app_id"), pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving broadcasts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = broadcasts
+	return c.JSON(http.StatusOK, pages)
+}
+
+// ListBroadcastRecipients godoc
+// @Summary         List broadcast recipients
+// @Description     Retrieves the connections targeted by a broadcast and the delivery status for each of them.
+// @Tags            broadcasts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path   string  true  "Application ID"
+// @Param           id       path   string  true  "Broadcast ID"
+// @Param           status query string false "Only list the recipients with the given status" Enums(pending, sending, sent, failed, cancelled)
+// @Param           page query int false "Page number for results pagination"
+// @Param           per_page query int false "Number of results per page for pagination"
+// @Success         200  {object}  ExtRecipientsResponse "Successfully retrieved the recipients"
+// @Failure         400  {object}  response.Error "Invalid input"
+// @Failure         404  {object}  response.Error "Broadcast not found or unauthorized access"
+// @Failure         500  {object}  response.Error "Internal server error"
+// @Router          /apps/{app_id}/broadcasts/{id}/recipients [get]
+func (r resource) recipients(c echo.Context) error {
+	ctx := c.Request().Context()
+	broadcast, err := r.service.Get(ctx, c.Param("app_id"), c.Param("id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving broadcast: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	status := c.QueryParam("status")
+	if err := validateRecipientStatus(status); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	count, err := r.service.CountRecipients(ctx, broadcast.ID, status)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting broadcast recipients: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	recipients, err := r.service.Recipients(ctx, broadcast.ID, status, pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving broadcast recipients: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = recipients
+	return c.JSON(http.StatusOK, pages)
+}
+
+// CreateBroadcast godoc
+// @Summary         Send a message to many connections
+// @Description     Sends a message to all the connections of an app, an explicit list of connections, or the connections with any of the given tags. Messages are sent in the background, the progress can be checked on the returned broadcast.
+// @Tags            broadcasts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path      string                  true  "Application ID"
+// @Param           request  body      CreateBroadcastRequest  true  "Broadcast details"
+// @Success         202      {object}  Broadcast               "Broadcast accepted"
+// @Failure         400      {object}  response.Error          "Invalid input or no matching connections"
+// @Failure         500      {object}  response.Error          "Internal Server Error"
+// @Router          /apps/{app_id}/broadcasts [post]
+func (r resource) create(c echo.Context) error {
+	ctx := c.Request().Context()
+	var input CreateBroadcastRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("error invalid input: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+
+	if err := input.Validate(); err != nil {
+		r.logger.With(ctx).Infof("error invalid input: %s", err.Error)
+		return c.JSON(err.Status, err)
+	}
+
+	broadcast, err := r.service.Create(ctx, c.Param("app_id"), input)
+	if errors.Is(err, ErrNoRecipients) {
+		return c.JSON(http.StatusBadRequest, &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		})
+	}
+	if err != nil {
+		r.logger.With(ctx).Warnf("error creating broadcast: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusAccepted, broadcast)
+}
+
+// CancelBroadcast godoc
+// @Summary         Cancel a broadcast
+// @Description     Stops sending a broadcast, the connections it was not sent to yet are marked as cancelled.
+// @Tags            broadcasts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Param           id      path      string  true  "Broadcast ID"
+// @Success         200     {object}  Broadcast         "Broadcast cancelled"
+// @Failure         404     {object}  response.Error    "Broadcast Not Found"
+// @Failure         400     {object}  response.Error    "Broadcast already finished"
+// @Router          /apps/{app_id}/broadcasts/{id}/cancel [post]
+func (r resource) cancel(c echo.Context) error {
+	ctx := c.Request().Context()
+	broadcast, err := r.service.Cancel(ctx, c.Param("app_id"), c.Param("id"))
+	if errors.Is(err, ErrBroadcastFinished) {
+		return c.JSON(http.StatusBadRequest, &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		})
+	}
+	if err != nil {
+		r.logger.With(ctx).Warnf("error cancelling broadcast: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, broadcast)
+}
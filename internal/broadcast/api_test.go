@@ -0,0 +1,155 @@
+package broadcast
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/acl"
+	"github.com/joinself/restful-client/pkg/filter"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+func TestBroadcastAPIEndpointsAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"ANY /apps/app_id/broadcasts", "ANY /apps/app_id/broadcasts/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/broadcasts/id",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"progress":{"total":2,"pending":1,"sending":0,"sent":1,"failed":0,"cancelled":0}*`,
+		},
+		{
+			Name:         "get not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/broadcasts/not_found_id",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "list",
+			Method:       "GET",
+			URL:          "/apps/app_id/broadcasts",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"total_count":1*`,
+		},
+		{
+			Name:         "recipients",
+			Method:       "GET",
+			URL:          "/apps/app_id/broadcasts/id/recipients?status=sent",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"selfid":"selfid","status":"sent","jti":"jti"*`,
+		},
+		{
+			Name:         "recipients invalid status",
+			Method:       "GET",
+			URL:          "/apps/app_id/broadcasts/id/recipients?status=unknown",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"status: must be a valid value."}`,
+		},
+		{
+			Name:       "recipients not found",
+			Method:     "GET",
+			URL:        "/apps/app_id/broadcasts/not_found_id/recipients",
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:       "recipients error",
+			Method:     "GET",
+			URL:        "/apps/app_id/broadcasts/recipients_error/recipients",
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:         "create",
+			Method:       "POST",
+			URL:          "/apps/app_id/broadcasts",
+			Body:         `{"body":"hello","target":"tags","tags":["vip"]}`,
+			WantStatus:   http.StatusAccepted,
+			WantResponse: `*"target":"tags","status":"pending"*`,
+		},
+		{
+			Name:         "create invalid input",
+			Method:       "POST",
+			URL:          "/apps/app_id/broadcasts",
+			Body:         `{"body":"hello","target":"connections"}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"connections: cannot be blank."}`,
+		},
+		{
+			Name:         "create without recipients",
+			Method:       "POST",
+			URL:          "/apps/app_id/broadcasts",
+			Body:         `{"body":"nobody","target":"all"}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"no connections match the broadcast target"}`,
+		},
+		{
+			Name:       "create error",
+			Method:     "POST",
+			URL:        "/apps/app_id/broadcasts",
+			Body:       `{"body":"error","target":"all"}`,
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:         "cancel",
+			Method:       "POST",
+			URL:          "/apps/app_id/broadcasts/id/cancel",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"status":"cancelled"*`,
+		},
+		{
+			Name:         "cancel finished",
+			Method:       "POST",
+			URL:          "/apps/app_id/broadcasts/completed/cancel",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"broadcast already finished"}`,
+		},
+		{
+			Name:       "cancel not found",
+			Method:     "POST",
+			URL:        "/apps/app_id/broadcasts/not_found_id/cancel",
+			WantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestBroadcastAPIEndpointsAsPlainWithoutPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/broadcasts/id",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:       "create",
+			Method:     "POST",
+			URL:        "/apps/app_id/broadcasts",
+			Body:       `{"body":"hello","target":"all"}`,
+			WantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
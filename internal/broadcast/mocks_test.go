@@ -0,0 +1,242 @@
+package broadcast
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/pkg/webhook"
+	"github.com/joinself/restful-client/pkg/worker"
+	selfsdk "github.com/joinself/self-go-sdk"
+)
+
+type mockService struct{}
+
+func (m mockService) Get(ctx context.Context, appID, id string) (Broadcast, error) {
+	if id == "not_found_id" {
+		return Broadcast{}, sql.ErrNoRows
+	}
+	return Broadcast{Broadcast: entity.Broadcast{ID: id, Body: "hello", Status: entity.BROADCAST_RUNNING_STATUS}, Progress: Progress{Total: 2, Sent: 1, Pending: 1}}, nil
+}
+
+func (m mockService) Query(ctx context.Context, appID string, offset, limit int) ([]Broadcast, error) {
+	if appID == "query_error" {
+		return nil, errors.New("error!")
+	}
+	return []Broadcast{{Broadcast: entity.Broadcast{ID: "id", Body: "hello", Status: entity.BROADCAST_COMPLETED_STATUS}, Progress: Progress{Total: 1, Sent: 1}}}, nil
+}
+
+func (m mockService) Count(ctx context.Context, appID string) (int, error) {
+	if appID == "count_error" {
+		return 0, errors.New("error!")
+	}
+	return 1, nil
+}
+
+func (m mockService) Create(ctx context.Context, appID string, input CreateBroadcastRequest) (Broadcast, error) {
+	switch input.Body {
+	case "error":
+		return Broadcast{}, errors.New("error!")
+	case "nobody":
+		return Broadcast{}, ErrNoRecipients
+	}
+	return Broadcast{Broadcast: entity.Broadcast{ID: "id", Body: input.Body, Target: input.Target, Status: entity.BROADCAST_PENDING_STATUS}, Progress: Progress{Total: 1, Pending: 1}}, nil
+}
+
+func (m mockService) Cancel(ctx context.Context, appID, id string) (Broadcast, error) {
+	switch id {
+	case "not_found_id":
+		return Broadcast{}, sql.ErrNoRows
+	case "completed":
+		return Broadcast{}, ErrBroadcastFinished
+	}
+	return Broadcast{Broadcast: entity.Broadcast{ID: id, Body: "hello", Status: entity.BROADCAST_CANCELLED_STATUS}, Progress: Progress{Total: 2, Sent: 1, Cancelled: 1}}, nil
+}
+
+func (m mockService) Recipients(ctx context.Context, id, status string, offset, limit int) ([]entity.BroadcastRecipient, error) {
+	if id == "recipients_error" {
+		return nil, errors.New("error!")
+	}
+	return []entity.BroadcastRecipient{{BroadcastID: id, SelfID: "selfid", Status: entity.RECIPIENT_SENT_STATUS, JTI: "jti"}}, nil
+}
+
+func (m mockService) CountRecipients(ctx context.Context, id, status string) (int, error) {
+	return 1, nil
+}
+
+func (m mockService) Dispatch(ctx context.Context, task worker.ScheduledTask) error {
+	return nil
+}
+
+type mockRepository struct {
+	connections []entity.Connection
+	broadcasts  []entity.Broadcast
+	recipients  []entity.BroadcastRecipient
+	// onClaim is called once a recipient is claimed.
+	onClaim func(id int)
+}
+
+func (m *mockRepository) Get(ctx context.Context, appID, id string) (entity.Broadcast, error) {
+	for _, b := range m.broadcasts {
+		if b.AppID == appID && b.ID == id {
+			return b, nil
+		}
+	}
+	return entity.Broadcast{}, sql.ErrNoRows
+}
+
+func (m *mockRepository) Count(ctx context.Context, appID string) (int, error) {
+	return len(m.broadcasts), nil
+}
+
+func (m *mockRepository) Query(ctx context.Context, appID string, offset, limit int) ([]entity.Broadcast, error) {
+	return m.broadcasts, nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, broadcast entity.Broadcast, recipients []entity.BroadcastRecipient) error {
+	m.broadcasts = append(m.broadcasts, broadcast)
+	for _, r := range recipients {
+		r.ID = len(m.recipients) + 1
+		r.BroadcastID = broadcast.ID
+		m.recipients = append(m.recipients, r)
+	}
+	return nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, broadcast entity.Broadcast) error {
+	for i, b := range m.broadcasts {
+		if b.ID == broadcast.ID {
+			m.broadcasts[i] = broadcast
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) Targets(ctx context.Context, appID string, selfIDs, tags []string) ([]entity.Connection, error) {
+	result := []entity.Connection{}
+	for _, c := range m.connections {
+		if c.AppID != appID {
+			continue
+		}
+		if len(selfIDs) > 0 && !contains(selfIDs, c.SelfID) {
+			continue
+		}
+		if len(tags) > 0 && !containsAny(c.Tags, tags) {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (m *mockRepository) Progress(ctx context.Context, id string) (map[string]int, error) {
+	progress := map[string]int{}
+	for _, r := range m.recipients {
+		if r.BroadcastID == id {
+			progress[r.Status]++
+		}
+	}
+	return progress, nil
+}
+
+func (m *mockRepository) CountRecipients(ctx context.Context, id, status string) (int, error) {
+	recipients, _ := m.Recipients(ctx, id, status, 0, len(m.recipients))
+	return len(recipients), nil
+}
+
+func (m *mockRepository) Recipients(ctx context.Context, id, status string, offset, limit int) ([]entity.BroadcastRecipient, error) {
+	result := []entity.BroadcastRecipient{}
+	for _, r := range m.recipients {
+		if r.BroadcastID == id && (status == "" || r.Status == status) && len(result) < limit {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepository) ClaimRecipient(ctx context.Context, id int) (bool, error) {
+	for i, r := range m.recipients {
+		if r.ID == id && r.Status == entity.RECIPIENT_PENDING_STATUS {
+			m.recipients[i].Status = entity.RECIPIENT_SENDING_STATUS
+			if m.onClaim != nil {
+				m.onClaim(id)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) UpdateRecipient(ctx context.Context, recipient entity.BroadcastRecipient) error {
+	if recipient.SelfID == "update_error" {
+		return errors.New("error!")
+	}
+	for i, r := range m.recipients {
+		if r.ID == recipient.ID {
+			m.recipients[i] = recipient
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) CancelRecipients(ctx context.Context, id string) error {
+	for i, r := range m.recipients {
+		if r.BroadcastID == id && r.Status == entity.RECIPIENT_PENDING_STATUS {
+			m.recipients[i].Status = entity.RECIPIENT_CANCELLED_STATUS
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) FailSendingRecipients(ctx context.Context, id, reason string) error {
+	for i, r := range m.recipients {
+		if r.BroadcastID == id && r.Status == entity.RECIPIENT_SENDING_STATUS {
+			m.recipients[i].Status = entity.RECIPIENT_FAILED_STATUS
+			m.recipients[i].Error = reason
+		}
+	}
+	return nil
+}
+
+type mockSender struct {
+	sent []string
+}
+
+func (m *mockSender) Create(ctx context.Context, appID, connectionID string, connection int, input message.CreateMessageRequest) (message.Message, error) {
+	if connectionID == "failing" {
+		return message.Message{}, errors.New("error!")
+	}
+	m.sent = append(m.sent, connectionID)
+	return message.Message{ID: "jti_" + connectionID, Body: input.Body}, nil
+}
+
+// runningRunner reports every app as running.
+type runningRunner struct{}
+
+func (r runningRunner) Get(id string) (*selfsdk.Client, bool) {
+	return &selfsdk.Client{}, true
+}
+
+func (r runningRunner) Poster(id string) (webhook.Poster, bool) {
+	return nil, false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, search []string) bool {
+	for _, s := range search {
+		if contains(values, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package broadcast
+
+import (
+	"context"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/dbcontext"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// Repository encapsulates the logic to access broadcasts from the data source.
+type Repository interface {
+	// Get returns the broadcast with the specified ID.
+	Get(ctx context.Context, appID, id string) (entity.Broadcast, error)
+	// Count returns the number of broadcasts of an app.
+	Count(ctx context.Context, appID string) (int, error)
+	// Query returns the list of broadcasts of an app with the given offset and limit.
+	Query(ctx context.Context, appID string, offset, limit int) ([]entity.Broadcast, error)
+	// Create saves a new broadcast and its recipients in the storage.
+	Create(ctx context.Context, broadcast entity.Broadcast, recipients []entity.BroadcastRecipient) error
+	// Update updates the broadcast with given ID in the storage.
+	Update(ctx context.Context, broadcast entity.Broadcast) error
+	// Targets returns the connections of an app matching the given selfIDs
+	// or tags, all connections are returned when both are empty.
+	Targets(ctx context.Context, appID string, selfIDs, tags []string) ([]entity.Connection, error)
+	// Progress returns the number of recipients of a broadcast by status.
+	Progress(ctx context.Context, id string) (map[string]int, error)
+	// CountRecipients returns the number of recipients of a broadcast.
+	CountRecipients(ctx context.Context, id, status string) (int, error)
+	// Recipients returns the recipients of a broadcast with the given status.
+	Recipients(ctx context.Context, id, status string, offset, limit int) ([]entity.BroadcastRecipient, error)
+	// ClaimRecipient marks a pending recipient as being sent to. It returns
+	// false when the recipient is no longer pending.
+	ClaimRecipient(ctx context.Context, id int) (bool, error)
+	// UpdateRecipient updates the given broadcast recipient in the storage.
+	UpdateRecipient(ctx context.Context, recipient entity.BroadcastRecipient) error
+	// CancelRecipients marks the pending recipients of a broadcast as cancelled.
+	CancelRecipients(ctx context.Context, id string) error
+	// FailSendingRecipients marks the recipients of a broadcast left claimed
+	// by an interrupted batch as failed.
+	FailSendingRecipients(ctx context.Context, id, reason string) error
+}
+
+// repository persists broadcasts in database
+type repository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+// NewRepository creates a new broadcast repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+// Get reads the broadcast with the specified ID from the database.
+func (r repository) Get(ctx context.Context, appID, id string) (entity.Broadcast, error) {
+	var broadcast entity.Broadcast
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"id": id, "app_id": appID}).
+		One(&broadcast)
+	return broadcast, err
+}
+
+// Count returns the number of broadcasts of an app in the database.
+func (r repository) Count(ctx context.Context, appID string) (int, error) {
+	var count int
+	err := r.db.With(ctx).
+		Select("COUNT(*)").
+		From("broadcast").
+		Where(dbx.HashExp{"app_id": appID}).
+		Row(&count)
+	return count, err
+}
+
+// Query retrieves the broadcasts of an app newest first.
+func (r repository) Query(ctx context.Context, appID string, offset, limit int) ([]entity.Broadcast, error) {
+	var broadcasts []entity.Broadcast
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"app_id": appID}).
+		OrderBy("created_at DESC").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&broadcasts)
+	return broadcasts, err
+}
+
+// Create saves a new broadcast with its recipients in the database.
+func (r repository) Create(ctx context.Context, broadcast entity.Broadcast, recipients []entity.BroadcastRecipient) error {
+	return r.db.Transactional(ctx, func(ctx context.Context) error {
+		err := r.db.With(ctx).Model(&broadcast).Insert()
+		if err != nil {
+			return err
+		}
+
+		for i := range recipients {
+			recipients[i].BroadcastID = broadcast.ID
+			err = r.db.With(ctx).Model(&recipients[i]).Insert()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Update saves the changes to a broadcast in the database.
+func (r repository) Update(ctx context.Context, broadcast entity.Broadcast) error {
+	return r.db.With(ctx).Model(&broadcast).Update()
+}
+
+// Targets retrieves the connections of an app a broadcast is sent to.
+func (r repository) Targets(ctx context.Context, appID string, selfIDs, tags []string) ([]entity.Connection, error) {
+	var connections []entity.Connection
+
+	q := r.db.With(ctx).
+		Select("connection.*").
+		Distinct(true).
+		From("connection").
+		Where(dbx.HashExp{"connection.appid": appID}).
+		OrderBy("connection.id")
+
+	if len(selfIDs) > 0 {
+		q = q.AndWhere(dbx.In("connection.selfid", toInterfaces(selfIDs)...))
+	}
+	if len(tags) > 0 {
+		q = q.InnerJoin("connection_tag", dbx.NewExp("connection_tag.connection_id = connection.id")).
+			AndWhere(dbx.In("connection_tag.tag", toInterfaces(tags)...))
+	}
+
+	err := q.All(&connections)
+	return connections, err
+}
+
+// Progress counts the recipients of a broadcast grouped by status.
+func (r repository) Progress(ctx context.Context, id string) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Total  int    `db:"total"`
+	}
+	err := r.db.With(ctx).
+		Select("status", "COUNT(*) AS total").
+		From("broadcast_recipient").
+		Where(dbx.HashExp{"broadcast_id": id}).
+		GroupBy("status").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := map[string]int{}
+	for _, row := range rows {
+		progress[row.Status] = row.Total
+	}
+	return progress, nil
+}
+
+// CountRecipients returns the number of recipients of a broadcast, optionally
+// filtered by status.
+func (r repository) CountRecipients(ctx context.Context, id, status string) (int, error) {
+	var count int
+	err := r.db.With(ctx).
+		Select("COUNT(*)").
+		From("broadcast_recipient").
+		Where(recipientsExp(id, status)).
+		Row(&count)
+	return count, err
+}
+
+// Recipients retrieves the recipients of a broadcast in the order they are
+// sent, optionally filtered by status.
+func (r repository) Recipients(ctx context.Context, id, status string, offset, limit int) ([]entity.BroadcastRecipient, error) {
+	var recipients []entity.BroadcastRecipient
+	err := r.db.With(ctx).
+		Select().
+		Where(recipientsExp(id, status)).
+		OrderBy("id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&recipients)
+	return recipients, err
+}
+
+// ClaimRecipient marks a pending recipient as being sent to, so it's sent
+// only once and can't be cancelled while it's being sent.
+func (r repository) ClaimRecipient(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.With(ctx).Update("broadcast_recipient", dbx.Params{
+		"status":     entity.RECIPIENT_SENDING_STATUS,
+		"updated_at": time.Now(),
+	}, dbx.HashExp{"id": id, "status": entity.RECIPIENT_PENDING_STATUS}).Execute()
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := result.RowsAffected()
+	return claimed == 1, err
+}
+
+// UpdateRecipient saves the changes to a broadcast recipient in the database.
+func (r repository) UpdateRecipient(ctx context.Context, recipient entity.BroadcastRecipient) error {
+	return r.db.With(ctx).Model(&recipient).Update()
+}
+
+// CancelRecipients marks the pending recipients of a broadcast as cancelled.
+func (r repository) CancelRecipients(ctx context.Context, id string) error {
+	_, err := r.db.With(ctx).Update("broadcast_recipient", dbx.Params{
+		"status":     entity.RECIPIENT_CANCELLED_STATUS,
+		"updated_at": time.Now(),
+	}, recipientsExp(id, entity.RECIPIENT_PENDING_STATUS)).Execute()
+	return err
+}
+
+// FailSendingRecipients marks the recipients of a broadcast which are still
+// being sent to as failed, as it's unknown whether they were sent the
+// broadcast.
+func (r repository) FailSendingRecipients(ctx context.Context, id, reason string) error {
+	_, err := r.db.With(ctx).Update("broadcast_recipient", dbx.Params{
+		"status":     entity.RECIPIENT_FAILED_STATUS,
+		"error":      reason,
+		"updated_at": time.Now(),
+	}, recipientsExp(id, entity.RECIPIENT_SENDING_STATUS)).Execute()
+	return err
+}
+
+func recipientsExp(id, status string) dbx.Expression {
+	exp := dbx.HashExp{"broadcast_id": id}
+	if status != "" {
+		exp["status"] = status
+	}
+	return exp
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
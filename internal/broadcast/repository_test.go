@@ -0,0 +1,135 @@
+package broadcast
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "broadcast_recipient", "broadcast")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	connection := rand.Intn(99999999)
+	err := test.CreateConnection(ctx, db, connection)
+	assert.Nil(t, err)
+	appID := "app_" + strconv.Itoa(connection)
+	selfID := "connection_" + strconv.Itoa(connection)
+
+	// targets
+	targets, err := repo.Targets(ctx, appID, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(targets))
+	targets, err = repo.Targets(ctx, appID, []string{selfID}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(targets))
+	targets, err = repo.Targets(ctx, appID, []string{"unknown"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(targets))
+	targets, err = repo.Targets(ctx, appID, nil, []string{"vip"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(targets))
+	_, err = db.With(ctx).Insert("connection_tag", map[string]interface{}{"connection_id": connection, "tag": "vip"}).Execute()
+	assert.Nil(t, err)
+	targets, err = repo.Targets(ctx, appID, nil, []string{"vip", "beta"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(targets))
+	assert.Equal(t, selfID, targets[0].SelfID)
+
+	// create
+	now := time.Now()
+	broadcast := entity.Broadcast{
+		ID:        uuid.New().String(),
+		AppID:     appID,
+		Body:      "hello",
+		Target:    entity.BROADCAST_TARGET_ALL,
+		Status:    entity.BROADCAST_PENDING_STATUS,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	recipients := []entity.BroadcastRecipient{}
+	for i := 0; i < 3; i++ {
+		recipients = append(recipients, entity.BroadcastRecipient{
+			ConnectionID: connection,
+			SelfID:       selfID,
+			Status:       entity.RECIPIENT_PENDING_STATUS,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		})
+	}
+	err = repo.Create(ctx, broadcast, recipients)
+	assert.Nil(t, err)
+
+	// get
+	b, err := repo.Get(ctx, appID, broadcast.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", b.Body)
+	_, err = repo.Get(ctx, "other", broadcast.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// update
+	b.Status = entity.BROADCAST_RUNNING_STATUS
+	err = repo.Update(ctx, b)
+	assert.Nil(t, err)
+	b, _ = repo.Get(ctx, appID, broadcast.ID)
+	assert.Equal(t, entity.BROADCAST_RUNNING_STATUS, b.Status)
+
+	// query
+	count, err := repo.Count(ctx, appID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	broadcasts, err := repo.Query(ctx, appID, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(broadcasts))
+
+	// recipients
+	pending, err := repo.Recipients(ctx, broadcast.ID, entity.RECIPIENT_PENDING_STATUS, 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(pending))
+	pending[0].Status = entity.RECIPIENT_SENT_STATUS
+	pending[0].JTI = "jti"
+	err = repo.UpdateRecipient(ctx, pending[0])
+	assert.Nil(t, err)
+	count, err = repo.CountRecipients(ctx, broadcast.ID, entity.RECIPIENT_SENT_STATUS)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// claim
+	claimed, err := repo.ClaimRecipient(ctx, pending[1].ID)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.ClaimRecipient(ctx, pending[1].ID)
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+
+	// cancel
+	err = repo.CancelRecipients(ctx, broadcast.ID)
+	assert.Nil(t, err)
+	progress, err := repo.Progress(ctx, broadcast.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{entity.RECIPIENT_SENT_STATUS: 1, entity.RECIPIENT_SENDING_STATUS: 1, entity.RECIPIENT_CANCELLED_STATUS: 1}, progress)
+
+	// interrupted claims
+	err = repo.FailSendingRecipients(ctx, broadcast.ID, "interrupted")
+	assert.Nil(t, err)
+	failed, err := repo.Recipients(ctx, broadcast.ID, entity.RECIPIENT_FAILED_STATUS, 0, 10)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(failed))
+	assert.Equal(t, "interrupted", failed[0].Error)
+	count, err = repo.CountRecipients(ctx, broadcast.ID, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+}
@@ -0,0 +1,338 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/support"
+	"github.com/joinself/restful-client/pkg/worker"
+)
+
+// TASK_SEND_BROADCAST is the type of the scheduled tasks sending broadcasts.
+const TASK_SEND_BROADCAST = "send_broadcast"
+
+const (
+	// batchSize is the number of recipients a broadcast is sent to at once.
+	batchSize = 20
+	// batchInterval is the time to wait between batches.
+	batchInterval = 2 * time.Second
+)
+
+// ErrNoRecipients is returned when a broadcast does not match any connection.
+var ErrNoRecipients = errors.New("no connections match the broadcast target")
+
+// ErrBroadcastFinished is returned when cancelling a finished broadcast.
+var ErrBroadcastFinished = errors.New("broadcast already finished")
+
+// errInterrupted is recorded for the recipients whose sending was interrupted
+// before its result was saved.
+var errInterrupted = errors.New("sending interrupted, the broadcast may have been delivered")
+
+// Service encapsulates usecase logic for broadcasts.
+type Service interface {
+	Get(ctx context.Context, appID, id string) (Broadcast, error)
+	Query(ctx context.Context, appID string, offset, limit int) ([]Broadcast, error)
+	Count(ctx context.Context, appID string) (int, error)
+	Create(ctx context.Context, appID string, input CreateBroadcastRequest) (Broadcast, error)
+	Cancel(ctx context.Context, appID, id string) (Broadcast, error)
+	Recipients(ctx context.Context, id, status string, offset, limit int) ([]entity.BroadcastRecipient, error)
+	CountRecipients(ctx context.Context, id, status string) (int, error)
+	Dispatch(ctx context.Context, task worker.ScheduledTask) error
+}
+
+// Scheduler queues tasks to be run in the background.
+type Scheduler interface {
+	Schedule(task worker.ScheduledTask, at time.Time) error
+}
+
+// MessageSender sends a message to a connection.
+type MessageSender interface {
+	Create(ctx context.Context, appID, connectionID string, connection int, input message.CreateMessageRequest) (message.Message, error)
+}
+
+// Broadcast represents the data about a broadcast.
+type Broadcast struct {
+	entity.Broadcast
+	Progress Progress `json:"progress"`
+}
+
+// Progress represents the number of recipients of a broadcast by status.
+type Progress struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Sending   int `json:"sending"`
+	Sent      int `json:"sent"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+type scheduledBroadcast struct {
+	ID string `json:"id"`
+}
+
+type service struct {
+	repo      Repository
+	messages  MessageSender
+	runner    support.SelfClientGetter
+	scheduler Scheduler
+	logger    log.Logger
+}
+
+// NewService creates a new broadcast service.
+func NewService(repo Repository, messages MessageSender, runner support.SelfClientGetter, scheduler Scheduler, logger log.Logger) Service {
+	return service{repo, messages, runner, scheduler, logger}
+}
+
+// Get returns the broadcast with the specified ID and its progress.
+func (s service) Get(ctx context.Context, appID, id string) (Broadcast, error) {
+	broadcast, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return Broadcast{}, err
+	}
+	return s.withProgress(ctx, broadcast)
+}
+
+// Query returns the broadcasts of an app with the specified offset and limit.
+func (s service) Query(ctx context.Context, appID string, offset, limit int) ([]Broadcast, error) {
+	items, err := s.repo.Query(ctx, appID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Broadcast{}
+	for _, item := range items {
+		b, err := s.withProgress(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+// Count returns the number of broadcasts of an app.
+func (s service) Count(ctx context.Context, appID string) (int, error) {
+	return s.repo.Count(ctx, appID)
+}
+
+// Create stores a new broadcast for the connections matching its target and
+// schedules it to be sent in the background.
+func (s service) Create(ctx context.Context, appID string, req CreateBroadcastRequest) (Broadcast, error) {
+	var selfIDs, tags []string
+	switch req.Target {
+	case entity.BROADCAST_TARGET_CONNECTIONS:
+		selfIDs = req.Connections
+	case entity.BROADCAST_TARGET_TAGS:
+		tags = req.Tags
+	}
+
+	connections, err := s.repo.Targets(ctx, appID, selfIDs, tags)
+	if err != nil {
+		return Broadcast{}, err
+	}
+	if len(connections) == 0 {
+		return Broadcast{}, ErrNoRecipients
+	}
+
+	now := time.Now()
+	broadcast := entity.Broadcast{
+		ID:        uuid.New().String(),
+		AppID:     appID,
+		Body:      req.Body,
+		Target:    req.Target,
+		Status:    entity.BROADCAST_PENDING_STATUS,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	recipients := make([]entity.BroadcastRecipient, len(connections))
+	for i, c := range connections {
+		recipients[i] = entity.BroadcastRecipient{
+			ConnectionID: c.ID,
+			SelfID:       c.SelfID,
+			Status:       entity.RECIPIENT_PENDING_STATUS,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+	}
+
+	err = s.repo.Create(ctx, broadcast, recipients)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	err = s.schedule(appID, broadcast.ID, now)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	return s.Get(ctx, appID, broadcast.ID)
+}
+
+// Cancel stops sending the broadcast to the recipients it has not been sent
+// to yet.
+func (s service) Cancel(ctx context.Context, appID, id string) (Broadcast, error) {
+	broadcast, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	if broadcast.Status == entity.BROADCAST_COMPLETED_STATUS || broadcast.Status == entity.BROADCAST_CANCELLED_STATUS {
+		return Broadcast{}, ErrBroadcastFinished
+	}
+
+	broadcast.Status = entity.BROADCAST_CANCELLED_STATUS
+	broadcast.UpdatedAt = time.Now()
+	err = s.repo.Update(ctx, broadcast)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	err = s.repo.CancelRecipients(ctx, id)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	return s.withProgress(ctx, broadcast)
+}
+
+// Recipients returns the recipients of a broadcast.
+func (s service) Recipients(ctx context.Context, id, status string, offset, limit int) ([]entity.BroadcastRecipient, error) {
+	return s.repo.Recipients(ctx, id, status, offset, limit)
+}
+
+// CountRecipients returns the number of recipients of a broadcast.
+func (s service) CountRecipients(ctx context.Context, id, status string) (int, error) {
+	return s.repo.CountRecipients(ctx, id, status)
+}
+
+// Dispatch sends the broadcast to the next batch of pending recipients, and
+// schedules the following batch until all recipients are processed.
+func (s service) Dispatch(ctx context.Context, task worker.ScheduledTask) error {
+	var sb scheduledBroadcast
+	if err := json.Unmarshal(task.Payload, &sb); err != nil {
+		s.logger.With(ctx).Warnf("invalid scheduled broadcast: %v", err)
+		return nil
+	}
+
+	broadcast, err := s.repo.Get(ctx, task.AppID, sb.ID)
+	if err != nil {
+		s.logger.With(ctx).Warnf("scheduled broadcast not found: %v", err)
+		return nil
+	}
+	if broadcast.Status == entity.BROADCAST_COMPLETED_STATUS || broadcast.Status == entity.BROADCAST_CANCELLED_STATUS {
+		return nil
+	}
+
+	if _, ok := s.runner.Get(task.AppID); !ok {
+		return errors.New("app not running")
+	}
+
+	if broadcast.Status == entity.BROADCAST_PENDING_STATUS {
+		broadcast.Status = entity.BROADCAST_RUNNING_STATUS
+		broadcast.UpdatedAt = time.Now()
+		if err := s.repo.Update(ctx, broadcast); err != nil {
+			return err
+		}
+	}
+
+	// The tasks of a broadcast run one at a time, so the recipients still
+	// being sent to were left claimed by a batch which failed to record them.
+	err = s.repo.FailSendingRecipients(ctx, broadcast.ID, errInterrupted.Error())
+	if err != nil {
+		return err
+	}
+
+	recipients, err := s.repo.Recipients(ctx, broadcast.ID, entity.RECIPIENT_PENDING_STATUS, 0, batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		// The broadcast may be cancelled while the batch is being sent.
+		broadcast, err = s.repo.Get(ctx, task.AppID, broadcast.ID)
+		if err != nil {
+			return err
+		}
+		if broadcast.Status == entity.BROADCAST_CANCELLED_STATUS {
+			return nil
+		}
+
+		claimed, err := s.repo.ClaimRecipient(ctx, recipient.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		m, err := s.messages.Create(ctx, task.AppID, recipient.SelfID, recipient.ConnectionID, message.CreateMessageRequest{
+			Body: broadcast.Body,
+		})
+		if err != nil {
+			recipient.Status = entity.RECIPIENT_FAILED_STATUS
+			recipient.Error = err.Error()
+		} else {
+			recipient.Status = entity.RECIPIENT_SENT_STATUS
+			recipient.JTI = m.ID
+		}
+		recipient.UpdatedAt = time.Now()
+
+		// The recipient stays claimed if it can't be updated, so it's not
+		// sent the broadcast again, and it's failed when the task is retried.
+		if err := s.repo.UpdateRecipient(ctx, recipient); err != nil {
+			return err
+		}
+	}
+
+	if len(recipients) == batchSize {
+		return s.schedule(task.AppID, broadcast.ID, time.Now().Add(batchInterval))
+	}
+
+	// The broadcast may have been cancelled while sending the last batch.
+	broadcast, err = s.repo.Get(ctx, task.AppID, broadcast.ID)
+	if err != nil || broadcast.Status == entity.BROADCAST_CANCELLED_STATUS {
+		return err
+	}
+
+	broadcast.Status = entity.BROADCAST_COMPLETED_STATUS
+	broadcast.UpdatedAt = time.Now()
+	return s.repo.Update(ctx, broadcast)
+}
+
+func (s service) schedule(appID, id string, at time.Time) error {
+	payload, err := json.Marshal(scheduledBroadcast{ID: id})
+	if err != nil {
+		return err
+	}
+
+	return s.scheduler.Schedule(worker.ScheduledTask{
+		Type:    TASK_SEND_BROADCAST,
+		AppID:   appID,
+		Payload: payload,
+	}, at)
+}
+
+func (s service) withProgress(ctx context.Context, broadcast entity.Broadcast) (Broadcast, error) {
+	counts, err := s.repo.Progress(ctx, broadcast.ID)
+	if err != nil {
+		return Broadcast{}, err
+	}
+
+	progress := Progress{
+		Pending:   counts[entity.RECIPIENT_PENDING_STATUS],
+		Sending:   counts[entity.RECIPIENT_SENDING_STATUS],
+		Sent:      counts[entity.RECIPIENT_SENT_STATUS],
+		Failed:    counts[entity.RECIPIENT_FAILED_STATUS],
+		Cancelled: counts[entity.RECIPIENT_CANCELLED_STATUS],
+	}
+	progress.Total = progress.Pending + progress.Sending + progress.Sent + progress.Failed + progress.Cancelled
+
+	return Broadcast{broadcast, progress}, nil
+}
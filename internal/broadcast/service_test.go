@@ -0,0 +1,220 @@
+package broadcast
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/mock"
+	"github.com/joinself/restful-client/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateBroadcastRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     CreateBroadcastRequest
+		wantError bool
+	}{
+		{"all", CreateBroadcastRequest{Body: "hello", Target: "all"}, false},
+		{"connections", CreateBroadcastRequest{Body: "hello", Target: "connections", Connections: []string{"selfid"}}, false},
+		{"tags", CreateBroadcastRequest{Body: "hello", Target: "tags", Tags: []string{"vip"}}, false},
+		{"body required", CreateBroadcastRequest{Target: "all"}, true},
+		{"body too long", CreateBroadcastRequest{Body: strings.Repeat("a", message.MAX_BODY_LENGTH+1), Target: "all"}, true},
+		{"target required", CreateBroadcastRequest{Body: "hello"}, true},
+		{"invalid target", CreateBroadcastRequest{Body: "hello", Target: "everyone"}, true},
+		{"connections required", CreateBroadcastRequest{Body: "hello", Target: "connections"}, true},
+		{"tags required", CreateBroadcastRequest{Body: "hello", Target: "tags"}, true},
+		{"empty tag", CreateBroadcastRequest{Body: "hello", Target: "tags", Tags: []string{""}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.Validate()
+			assert.Equal(t, tt.wantError, err != nil)
+		})
+	}
+}
+
+func newTestRepository(n int) *mockRepository {
+	repo := &mockRepository{}
+	for i := 0; i < n; i++ {
+		c := entity.Connection{ID: i + 1, AppID: "app", SelfID: "selfid" + strconv.Itoa(i)}
+		if i%2 == 0 {
+			c.Tags = []string{"vip"}
+		}
+		repo.connections = append(repo.connections, c)
+	}
+	return repo
+}
+
+func Test_service_Create(t *testing.T) {
+	logger, _ := log.NewForTest()
+	scheduler := &mock.SchedulerMock{}
+	repo := newTestRepository(4)
+	s := NewService(repo, &mockSender{}, runningRunner{}, scheduler, logger)
+	ctx := context.Background()
+
+	// all connections
+	b, err := s.Create(ctx, "app", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_ALL})
+	assert.Nil(t, err)
+	assert.Equal(t, entity.BROADCAST_PENDING_STATUS, b.Status)
+	assert.Equal(t, Progress{Total: 4, Pending: 4}, b.Progress)
+	assert.Equal(t, 1, len(scheduler.Tasks))
+	assert.Equal(t, TASK_SEND_BROADCAST, scheduler.Tasks[0].Type)
+	assert.Equal(t, "app", scheduler.Tasks[0].AppID)
+
+	// explicit connections
+	b, err = s.Create(ctx, "app", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_CONNECTIONS, Connections: []string{"selfid1", "unknown"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, b.Progress.Total)
+
+	// tags
+	b, err = s.Create(ctx, "app", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_TAGS, Tags: []string{"vip"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, b.Progress.Total)
+
+	// no recipients
+	_, err = s.Create(ctx, "other", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_ALL})
+	assert.Equal(t, ErrNoRecipients, err)
+	assert.Equal(t, 3, len(scheduler.Tasks))
+
+	// query
+	count, err := s.Count(ctx, "app")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	broadcasts, err := s.Query(ctx, "app", 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(broadcasts))
+}
+
+func Test_service_Dispatch(t *testing.T) {
+	logger, _ := log.NewForTest()
+	scheduler := &mock.SchedulerMock{}
+	repo := newTestRepository(batchSize + 2)
+	repo.connections[1].SelfID = "failing"
+	sender := &mockSender{}
+	s := NewService(repo, sender, runningRunner{}, scheduler, logger)
+	ctx := context.Background()
+
+	b, err := s.Create(ctx, "app", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_ALL})
+	assert.Nil(t, err)
+	task := scheduler.Tasks[0]
+
+	// app not running
+	stopped := NewService(repo, sender, mock.NewRunnerMock(), scheduler, logger)
+	err = stopped.Dispatch(ctx, task)
+	assert.NotNil(t, err)
+
+	// first batch
+	err = s.Dispatch(ctx, task)
+	assert.Nil(t, err)
+	b, _ = s.Get(ctx, "app", b.ID)
+	assert.Equal(t, entity.BROADCAST_RUNNING_STATUS, b.Status)
+	assert.Equal(t, Progress{Total: batchSize + 2, Sent: batchSize - 1, Failed: 1, Pending: 2}, b.Progress)
+	assert.Equal(t, 2, len(scheduler.Tasks))
+	assert.True(t, scheduler.Times[1].After(scheduler.Times[0]))
+
+	recipients, _ := s.Recipients(ctx, b.ID, entity.RECIPIENT_FAILED_STATUS, 0, 10)
+	assert.Equal(t, 1, len(recipients))
+	assert.Equal(t, "error!", recipients[0].Error)
+	recipients, _ = s.Recipients(ctx, b.ID, entity.RECIPIENT_SENT_STATUS, 0, 1)
+	assert.Equal(t, "jti_selfid0", recipients[0].JTI)
+
+	// last batch
+	err = s.Dispatch(ctx, scheduler.Tasks[1])
+	assert.Nil(t, err)
+	b, _ = s.Get(ctx, "app", b.ID)
+	assert.Equal(t, entity.BROADCAST_COMPLETED_STATUS, b.Status)
+	assert.Equal(t, 0, b.Progress.Pending)
+	assert.Equal(t, batchSize+1, len(sender.sent))
+	assert.Equal(t, 2, len(scheduler.Tasks))
+
+	// completed broadcasts are not sent again
+	err = s.Dispatch(ctx, task)
+	assert.Nil(t, err)
+	assert.Equal(t, batchSize+1, len(sender.sent))
+
+	// invalid payload
+	err = s.Dispatch(ctx, worker.ScheduledTask{Type: TASK_SEND_BROADCAST, AppID: "app", Payload: []byte("{")})
+	assert.Nil(t, err)
+}
+
+func Test_service_DispatchUpdateError(t *testing.T) {
+	logger, _ := log.NewForTest()
+	scheduler := &mock.SchedulerMock{}
+	repo := newTestRepository(3)
+	repo.connections[1].SelfID = "update_error"
+	sender := &mockSender{}
+	s := NewService(repo, sender, runningRunner{}, scheduler, logger)
+	ctx := context.Background()
+
+	b, err := s.Create(ctx, "app", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_ALL})
+	assert.Nil(t, err)
+
+	// the batch stops on storage errors
+	err = s.Dispatch(ctx, scheduler.Tasks[0])
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"selfid0", "update_error"}, sender.sent)
+
+	// the retry doesn't send the broadcast to the same recipient again, and
+	// fails the recipient left claimed
+	err = s.Dispatch(ctx, scheduler.Tasks[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"selfid0", "update_error", "selfid2"}, sender.sent)
+	b, _ = s.Get(ctx, "app", b.ID)
+	assert.Equal(t, Progress{Total: 3, Sent: 2, Failed: 1}, b.Progress)
+}
+
+func Test_service_Cancel(t *testing.T) {
+	logger, _ := log.NewForTest()
+	scheduler := &mock.SchedulerMock{}
+	repo := newTestRepository(batchSize + 2)
+	sender := &mockSender{}
+	s := NewService(repo, sender, runningRunner{}, scheduler, logger)
+	ctx := context.Background()
+
+	b, err := s.Create(ctx, "app", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_ALL})
+	assert.Nil(t, err)
+	err = s.Dispatch(ctx, scheduler.Tasks[0])
+	assert.Nil(t, err)
+
+	// cancel
+	b, err = s.Cancel(ctx, "app", b.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.BROADCAST_CANCELLED_STATUS, b.Status)
+	assert.Equal(t, Progress{Total: batchSize + 2, Sent: batchSize, Cancelled: 2}, b.Progress)
+
+	// the next batch is not sent
+	err = s.Dispatch(ctx, scheduler.Tasks[1])
+	assert.Nil(t, err)
+	assert.Equal(t, batchSize, len(sender.sent))
+
+	// finished broadcasts can't be cancelled
+	_, err = s.Cancel(ctx, "app", b.ID)
+	assert.Equal(t, ErrBroadcastFinished, err)
+
+	// cancelling while a batch is being sent
+	b, err = s.Create(ctx, "app", CreateBroadcastRequest{Body: "hello", Target: entity.BROADCAST_TARGET_ALL})
+	assert.Nil(t, err)
+	claims := 0
+	repo.onClaim = func(id int) {
+		claims++
+		if claims == 3 {
+			_, err := s.Cancel(ctx, "app", b.ID)
+			assert.Nil(t, err)
+		}
+	}
+	err = s.Dispatch(ctx, scheduler.Tasks[len(scheduler.Tasks)-1])
+	assert.Nil(t, err)
+	b, _ = s.Get(ctx, "app", b.ID)
+	assert.Equal(t, entity.BROADCAST_CANCELLED_STATUS, b.Status)
+	assert.Equal(t, Progress{Total: batchSize + 2, Sent: 3, Cancelled: batchSize - 1}, b.Progress)
+
+	// not found
+	_, err = s.Cancel(ctx, "app", "unknown")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package broadcast
+
+import (
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/pkg/response"
+)
+
+// ExtListResponse represents the json object returned when listing broadcasts.
+type ExtListResponse struct {
+	Page       int         `json:"page"`
+	PerPage    int         `json:"per_page"`
+	PageCount  int         `json:"page_count"`
+	TotalCount int         `json:"total_count"`
+	Items      []Broadcast `json:"items"`
+}
+
+// ExtRecipientsResponse represents the json object returned when listing the
+// recipients of a broadcast.
+type ExtRecipientsResponse struct {
+	Page       int                         `json:"page"`
+	PerPage    int                         `json:"per_page"`
+	PageCount  int                         `json:"page_count"`
+	TotalCount int                         `json:"total_count"`
+	Items      []entity.BroadcastRecipient `json:"items"`
+}
+
+// CreateBroadcastRequest represents a broadcast creation request.
+type CreateBroadcastRequest struct {
+	Body string `json:"body"`
+	// Target is one of all, connections or tags.
+	Target string `json:"target"`
+	// Connections are the selfIDs the broadcast is sent to when targeting
+	// connections.
+	Connections []string `json:"connections,omitempty"`
+	// Tags select the connections the broadcast is sent to when targeting
+	// tags, connections with any of the tags are selected.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Validate validates the CreateBroadcastRequest fields.
+func (m CreateBroadcastRequest) Validate() *response.Error {
+	err := validation.ValidateStruct(&m,
+		validation.Field(&m.Body, validation.Required, validation.Length(1, message.MAX_BODY_LENGTH)),
+		validation.Field(&m.Target, validation.Required, validation.In(
+			entity.BROADCAST_TARGET_ALL,
+			entity.BROADCAST_TARGET_CONNECTIONS,
+			entity.BROADCAST_TARGET_TAGS,
+		)),
+		validation.Field(&m.Connections,
+			validation.When(m.Target == entity.BROADCAST_TARGET_CONNECTIONS, validation.Required),
+			validation.Each(validation.Required, validation.Length(3, 128)),
+		),
+		validation.Field(&m.Tags,
+			validation.When(m.Target == entity.BROADCAST_TARGET_TAGS, validation.Required),
+			validation.Each(validation.Required, validation.Length(1, 64)),
+		),
+	)
+	if err == nil {
+		return nil
+	}
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: err.Error(),
+	}
+}
+
+func validateRecipientStatus(status string) *response.Error {
+	err := validation.Errors{
+		"status": validation.Validate(status, validation.In(
+			entity.RECIPIENT_PENDING_STATUS,
+			entity.RECIPIENT_SENDING_STATUS,
+			entity.RECIPIENT_SENT_STATUS,
+			entity.RECIPIENT_FAILED_STATUS,
+			entity.RECIPIENT_CANCELLED_STATUS,
+		)),
+	}.Filter()
+	if err != nil {
+		return &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
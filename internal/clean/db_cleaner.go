@@ -2,12 +2,22 @@ package clean
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 
+	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/joinself/restful-client/pkg/dbcontext"
 	"github.com/joinself/restful-client/pkg/log"
 )
 
+// conditions restricts the rows cleaned from the given tables, scheduled
+// messages are kept until they are sent.
+var conditions = map[string]string{
+	"message": "status != 'scheduled'",
+}
+
 type Service interface {
 	Clean()
 }
@@ -31,6 +41,11 @@ func NewService(c Config) Service {
 }
 
 func (s *service) Clean() {
+	err := s.cleanAttachments(s.period)
+	if err != nil {
+		s.logger.With(context.Background()).Info(err.Error())
+	}
+
 	for _, t := range s.tables {
 		err := s.cleanTable(t, s.period)
 		if err != nil {
@@ -40,9 +55,47 @@ func (s *service) Clean() {
 }
 
 func (s *service) cleanTable(table string, period int) error {
-	sql := `DELETE FROM %s WHERE created_at < datetime('now', '-%d days');`
+	sql := `DELETE FROM %s WHERE created_at < datetime('now', '-%d days')`
 	query := fmt.Sprintf(sql, table, period)
+	if c, ok := conditions[table]; ok {
+		query += " AND " + c
+	}
 	_, err := s.db.DB().NewQuery(query).Execute()
 
 	return err
 }
+
+// cleanAttachments removes the downloaded content of the expired message
+// attachments and then their rows, the rows whose content can't be removed
+// are kept for the next run. The attachments of the messages kept by the
+// cleaner are kept too.
+func (s *service) cleanAttachments(period int) error {
+	sql := `SELECT id, path FROM message_attachment WHERE created_at < datetime('now', '-%d days') AND message_id NOT IN (SELECT id FROM message WHERE status = 'scheduled');`
+	var attachments []struct {
+		ID   int    `db:"id"`
+		Path string `db:"path"`
+	}
+	err := s.db.DB().NewQuery(fmt.Sprintf(sql, period)).All(&attachments)
+	if err != nil {
+		return err
+	}
+
+	ids := []interface{}{}
+	for _, a := range attachments {
+		if a.Path != "" {
+			err = os.Remove(a.Path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				s.logger.With(context.Background()).Info(err.Error())
+				continue
+			}
+			_ = os.Remove(filepath.Dir(a.Path))
+		}
+		ids = append(ids, a.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err = s.db.DB().Delete("message_attachment", dbx.In("id", ids...)).Execute()
+	return err
+}
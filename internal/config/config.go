@@ -61,6 +61,8 @@ type Config struct {
 	DefaultAppEnv string `env:"APP_ENV"`
 	// CleanupPeriod the number of days the database temporary data will be removed.
 	CleanupPeriod int `env:"CLEANUP_PERIOD"`
+	// DownloadAttachments string _(true|false)_ defining if inbound message attachments should be downloaded to the storage dir.
+	DownloadAttachments string `env:"DOWNLOAD_ATTACHMENTS"`
 }
 
 // Validate validates the application configuration.
@@ -90,6 +92,7 @@ func Load(logger log.Logger, envPath string) (*Config, error) {
 		ServeDocs:                     "false",
 		ServerPort:                    defaultServerPort,
 		CleanupPeriod:                 defaultCleanupPeriod,
+		DownloadAttachments:           "false",
 	}
 
 	// load from environment variables prefixed with "APP_"
@@ -48,6 +48,7 @@ func (r resource) get(c echo.Context) error {
 		ID:        conn.SelfID,
 		AppID:     conn.AppID,
 		Name:      conn.Name,
+		Tags:      conn.Tags,
 		CreatedAt: conn.CreatedAt,
 		UpdatedAt: conn.UpdatedAt,
 	})
@@ -135,6 +136,7 @@ func (r resource) create(c echo.Context) error {
 		ID:        conn.SelfID,
 		AppID:     conn.AppID,
 		Name:      conn.Name,
+		Tags:      conn.Tags,
 		CreatedAt: conn.CreatedAt,
 		UpdatedAt: conn.UpdatedAt,
 	})
@@ -142,7 +144,7 @@ func (r resource) create(c echo.Context) error {
 
 // UpdateConnection godoc
 // @Summary         Update a Connection
-// @Description     Updates the properties of an existing connection, identified by the provided app_id and connection id. The updates are passed in the request body.
+// @Description     Updates the properties of an existing connection, identified by the provided app_id and connection id. The updates are passed in the request body. When tags are provided they replace the existing tags of the connection.
 // @Tags            Connections
 // @Accept          json
 // @Produce         json
@@ -177,6 +179,7 @@ func (r resource) update(c echo.Context) error {
 		ID:        conn.SelfID,
 		AppID:     conn.AppID,
 		Name:      conn.Name,
+		Tags:      conn.Tags,
 		CreatedAt: conn.CreatedAt,
 		UpdatedAt: conn.UpdatedAt,
 	})
@@ -205,6 +208,7 @@ func (r resource) delete(c echo.Context) error {
 		ID:        conn.SelfID,
 		AppID:     conn.AppID,
 		Name:      conn.Name,
+		Tags:      conn.Tags,
 		CreatedAt: conn.CreatedAt,
 		UpdatedAt: conn.UpdatedAt,
 	})
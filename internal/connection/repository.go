@@ -24,6 +24,8 @@ type Repository interface {
 	Update(ctx context.Context, connection entity.Connection) error
 	// Delete removes the connection with given ID from the storage.
 	Delete(ctx context.Context, id int) error
+	// SetTags replaces the tags of the connection with the given ID.
+	SetTags(ctx context.Context, id int, tags []string) error
 }
 
 // repository persists connections in database
@@ -51,6 +53,7 @@ func (r repository) Get(ctx context.Context, appID, selfID string) (entity.Conne
 		return entity.Connection{}, errors.New("sql: no rows in result set")
 	}
 
+	connections[0].Tags, err = r.tags(ctx, connections[0].ID)
 	return connections[0], err
 }
 
@@ -71,7 +74,19 @@ func (r repository) Delete(ctx context.Context, id int) error {
 	if err != nil {
 		return err
 	}
-	return r.db.With(ctx).Model(&connection).Delete()
+
+	return r.db.Transactional(ctx, func(ctx context.Context) error {
+		// The tags and the support inbox state of the conversation go with
+		// the connection.
+		for _, table := range []string{"connection_tag", "conversation_state", "conversation_note"} {
+			_, err := r.db.With(ctx).Delete(table, dbx.HashExp{"connection_id": id}).Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		return r.db.With(ctx).Model(&connection).Delete()
+	})
 }
 
 // Count returns the number of the connection records in the database.
@@ -95,7 +110,48 @@ func (r repository) Query(ctx context.Context, appid string, offset, limit int)
 		OrderBy("created_at DESC").
 		Limit(int64(limit)).
 		All(&connections)
-	return connections, err
+	if err != nil {
+		return connections, err
+	}
+
+	for i := range connections {
+		connections[i].Tags, err = r.tags(ctx, connections[i].ID)
+		if err != nil {
+			return connections, err
+		}
+	}
+	return connections, nil
+}
+
+// SetTags replaces the tags of the given connection.
+func (r repository) SetTags(ctx context.Context, id int, tags []string) error {
+	_, err := r.db.With(ctx).Delete("connection_tag", dbx.HashExp{"connection_id": id}).Execute()
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = r.db.With(ctx).Insert("connection_tag", dbx.Params{
+			"connection_id": id,
+			"tag":           tag,
+		}).Execute()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tags retrieves the tags of the given connection sorted alphabetically.
+func (r repository) tags(ctx context.Context, id int) ([]string, error) {
+	var tags []string
+	err := r.db.With(ctx).
+		Select("tag").
+		From("connection_tag").
+		Where(dbx.HashExp{"connection_id": id}).
+		OrderBy("tag").
+		Column(&tags)
+	return tags, err
 }
 
 func (r repository) getByID(ctx context.Context, id int) (entity.Connection, error) {
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/gofrs/uuid"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/internal/test"
@@ -16,7 +17,7 @@ import (
 func TestRepository(t *testing.T) {
 	logger, _ := log.NewForTest()
 	db := test.DB(t)
-	test.ResetTables(t, db, "fact", "request", "message")
+	test.ResetTables(t, db, "fact", "request", "message", "connection_tag", "conversation_state", "conversation_note")
 	test.ResetTables(t, db, "connection")
 	repo := NewRepository(db, logger)
 
@@ -59,16 +60,32 @@ func TestRepository(t *testing.T) {
 	connection, _ = repo.Get(ctx, appID, connectionID)
 	assert.Equal(t, "connection1 updated", connection.Name)
 
+	// tags
+	err = repo.SetTags(ctx, connection.ID, []string{"vip", "beta"})
+	assert.Nil(t, err)
+	connection, _ = repo.Get(ctx, appID, connectionID)
+	assert.Equal(t, []string{"beta", "vip"}, connection.Tags)
+
 	// query
 	connections, err := repo.Query(ctx, appID, 0, count2)
 	assert.Nil(t, err)
 	assert.Equal(t, count2, len(connections))
 
 	// delete
+	_, err = db.DB().Insert("conversation_state", dbx.Params{"connection_id": connection.ID, "created_at": time.Now(), "updated_at": time.Now()}).Execute()
+	assert.Nil(t, err)
+	_, err = db.DB().Insert("conversation_note", dbx.Params{"connection_id": connection.ID, "author": "agent", "body": "note", "created_at": time.Now()}).Execute()
+	assert.Nil(t, err)
 	err = repo.Delete(ctx, connection.ID)
 	assert.Nil(t, err)
 	_, err = repo.Get(ctx, appID, connectionID)
 	assert.Equal(t, sql.ErrNoRows, err)
+	for _, table := range []string{"conversation_state", "conversation_note"} {
+		var rows int
+		err = db.DB().Select("COUNT(*)").From(table).Where(dbx.HashExp{"connection_id": connection.ID}).Row(&rows)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, rows, table)
+	}
 	err = repo.Delete(ctx, 1)
 	assert.Equal(t, sql.ErrNoRows, err)
 }
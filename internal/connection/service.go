@@ -31,14 +31,16 @@ type Connection struct {
 }
 
 type service struct {
-	repo   Repository
-	runner support.SelfClientGetter
-	logger log.Logger
+	repo       Repository
+	runner     support.SelfClientGetter
+	onboarding support.Onboarder
+	logger     log.Logger
 }
 
-// NewService creates a new connection service.
-func NewService(repo Repository, runner support.SelfClientGetter, logger log.Logger) Service {
-	return service{repo, runner, logger}
+// NewService creates a new connection service, the new connections are
+// onboarded with the given onboarder when it's not nil.
+func NewService(repo Repository, runner support.SelfClientGetter, onboarding support.Onboarder, logger log.Logger) Service {
+	return service{repo, runner, onboarding, logger}
 }
 
 // Get returns the connection with the specified the connection ID.
@@ -71,9 +73,19 @@ func (s service) Create(ctx context.Context, appid string, req CreateConnectionR
 		return Connection{}, err
 	}
 
-	go s.requestPublicInfo(appid, selfid)
+	conn, err := s.Get(ctx, appid, selfid)
+	if err != nil {
+		return conn, err
+	}
 
-	return s.Get(ctx, appid, selfid)
+	// Apps without onboarding only request the connection display name.
+	if s.onboarding != nil {
+		go s.onboarding.Onboard(context.Background(), appid, conn.Connection)
+	} else {
+		go s.requestPublicInfo(appid, selfid)
+	}
+
+	return conn, nil
 }
 
 // Update updates the connection with the specified ID.
@@ -90,6 +102,14 @@ func (s service) Update(ctx context.Context, appid, selfid string, req UpdateCon
 		s.logger.With(ctx).Infof("problem updating the app %v", err)
 		return connection, err
 	}
+
+	if req.Tags != nil {
+		connection.Tags = uniqueTags(req.Tags)
+		if err := s.repo.SetTags(ctx, connection.ID, connection.Tags); err != nil {
+			s.logger.With(ctx).Infof("problem updating the connection tags %v", err)
+			return connection, err
+		}
+	}
 	return connection, nil
 }
 
@@ -145,3 +165,16 @@ func (s service) requestPublicInfo(appid, selfid string) {
 		return
 	}
 }
+
+// uniqueTags removes duplicated tags keeping the original order.
+func uniqueTags(tags []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
@@ -3,7 +3,9 @@ package connection
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/mock"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +37,8 @@ func TestUpdateConnectionRequest_Validate(t *testing.T) {
 	}{
 		{"success", UpdateConnectionRequest{Name: "test"}, false},
 		{"required", UpdateConnectionRequest{Name: ""}, true},
+		{"tags", UpdateConnectionRequest{Name: "test", Tags: []string{"vip"}}, false},
+		{"empty tag", UpdateConnectionRequest{Name: "test", Tags: []string{""}}, true},
 		{"too long", UpdateConnectionRequest{Name: "1234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890"}, true},
 	}
 	for _, tt := range tests {
@@ -48,7 +52,7 @@ func TestUpdateConnectionRequest_Validate(t *testing.T) {
 func Test_service_CRUD(t *testing.T) {
 	logger, _ := log.NewForTest()
 	runner := mock.NewRunnerMock()
-	s := NewService(&mock.ConnectionRepositoryMock{}, runner, logger)
+	s := NewService(&mock.ConnectionRepositoryMock{}, runner, nil, logger)
 
 	ctx := context.Background()
 	id := "selfid"
@@ -77,6 +81,11 @@ func Test_service_CRUD(t *testing.T) {
 	_, err = s.Update(ctx, appid, "none", UpdateConnectionRequest{Name: "test updated"})
 	assert.NotNil(t, err)
 
+	// update tags
+	connection, err = s.Update(ctx, appid, id, UpdateConnectionRequest{Name: "test updated", Tags: []string{"vip", "beta", "vip"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"vip", "beta"}, connection.Tags)
+
 	// get
 	_, err = s.Get(ctx, appid, "none")
 	assert.NotNil(t, err)
@@ -98,3 +107,37 @@ func Test_service_CRUD(t *testing.T) {
 	count, _ = s.Count(ctx, appid)
 	assert.Equal(t, 1, count)
 }
+
+type onboarderMock struct {
+	onboarded chan entity.Connection
+}
+
+func (m onboarderMock) Onboard(ctx context.Context, appID string, conn entity.Connection) bool {
+	m.onboarded <- conn
+	return true
+}
+
+func Test_service_CreateOnboarding(t *testing.T) {
+	logger, _ := log.NewForTest()
+	onboarder := onboarderMock{make(chan entity.Connection, 2)}
+	s := NewService(&mock.ConnectionRepositoryMock{}, mock.NewRunnerMock(), onboarder, logger)
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, "appid", CreateConnectionRequest{SelfID: "selfid"})
+	assert.Nil(t, err)
+	select {
+	case conn := <-onboarder.onboarded:
+		assert.Equal(t, "selfid", conn.SelfID)
+	case <-time.After(time.Second):
+		t.Error("connection was not onboarded")
+	}
+
+	// existing connections are not onboarded again
+	_, err = s.Create(ctx, "appid", CreateConnectionRequest{SelfID: "selfid"})
+	assert.Nil(t, err)
+	select {
+	case <-onboarder.onboarded:
+		t.Error("connection was onboarded again")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -22,6 +22,7 @@ type ExtConnection struct {
 	ID        string    `json:"id"`
 	AppID     string    `json:"app_id"`
 	Name      string    `json:"name"`
+	Tags      []string  `json:"tags,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -48,13 +49,15 @@ func (m CreateConnectionRequest) Validate() *response.Error {
 
 // UpdateConnectionRequest represents an connection update request.
 type UpdateConnectionRequest struct {
-	Name string `json:"name"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
 }
 
 // Validate validates the CreateConnectionRequest fields.
 func (m UpdateConnectionRequest) Validate() *response.Error {
 	err := validation.ValidateStruct(&m,
 		validation.Field(&m.Name, validation.Required, validation.Length(3, 128)),
+		validation.Field(&m.Tags, validation.Each(validation.Required, validation.Length(1, 64))),
 	)
 	if err == nil {
 		return nil
@@ -0,0 +1,230 @@
+package conversation
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/acl"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/pagination"
+	"github.com/joinself/restful-client/pkg/response"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *echo.Group, service Service, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.GET("/:app_id/conversations", res.query)
+	r.GET("/:app_id/conversations/:connection_id", res.get)
+	r.PUT("/:app_id/conversations/:connection_id", res.update)
+	r.GET("/:app_id/conversations/:connection_id/notes", res.notes)
+	r.POST("/:app_id/conversations/:connection_id/notes", res.createNote)
+}
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+// ListConversations godoc
+// @Summary         List conversations
+// @Description     Retrieves the conversations of an app, one per connection, with their last message, the number of unread inbound messages, the time of their last activity and their support status and assignee.
+// @Tags            conversations
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path   string  true  "Application ID"
+// @Param           sort query string false "Sort order, -last_activity (default) or last_activity"
+// @Param           status query string false "Only list conversations with the given status, open, pending or closed"
+// @Param           assignee query string false "Only list conversations assigned to the given account"
+// @Param           page query int false "Page number for results pagination"
+// @Param           per_page query int false "Number of results per page for pagination"
+// @Success         200  {object}  ExtListResponse "Successfully retrieved the conversations"
+// @Failure         400  {object}  response.Error "Invalid input"
+// @Failure         500  {object}  response.Error "Internal server error"
+// @Router          /apps/{app_id}/conversations [get]
+func (r resource) query(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	sort := c.QueryParam("sort")
+	filter := entity.ConversationFilter{
+		Status:   c.QueryParam("status"),
+		Assignee: c.QueryParam("assignee"),
+	}
+	if err := validateListParams(sort, filter); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	count, err := r.service.Count(ctx, c.Param("app_id"), filter)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting conversations: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	conversations, err := r.service.Query(ctx, c.Param("app_id"), filter, sort, pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving conversations: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = conversations
+	return c.JSON(http.StatusOK, pages)
+}
+
+// GetConversation godoc
+// @Summary         Retrieve a conversation
+// @Description     Get the conversation with a connection.
+// @Tags            conversations
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id         path  string  true  "Application ID"
+// @Param           connection_id  path  string  true  "Connection ID"
+// @Success         200  {object}  Conversation    "Successful Response"
+// @Failure         404  {object}  response.Error  "Conversation Not Found"
+// @Router          /apps/{app_id}/conversations/{connection_id} [get]
+func (r resource) get(c echo.Context) error {
+	conversation, err := r.service.Get(c.Request().Context(), c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		r.logger.With(c.Request().Context()).Warnf("error retrieving conversation: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, conversation)
+}
+
+// UpdateConversation godoc
+// @Summary         Update a conversation
+// @Description     Assigns a conversation to an account or changes its status. Only the provided fields are changed, and a conversation webhook is sent when any of them changes.
+// @Tags            conversations
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id         path  string  true  "Application ID"
+// @Param           connection_id  path  string  true  "Connection ID"
+// @Param           request body UpdateConversationRequest true "Changes to the conversation"
+// @Success         200  {object}  Conversation    "Successfully updated the conversation"
+// @Failure         400  {object}  response.Error  "Invalid input"
+// @Failure         404  {object}  response.Error  "Conversation Not Found"
+// @Failure         500  {object}  response.Error  "Internal server error"
+// @Router          /apps/{app_id}/conversations/{connection_id} [put]
+func (r resource) update(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var input UpdateConversationRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("invalid input for updating a conversation: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+	if err := input.Validate(); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	if _, err := r.service.Get(ctx, c.Param("app_id"), c.Param("connection_id")); err != nil {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	conversation, err := r.service.Update(ctx, c.Param("app_id"), c.Param("connection_id"), input)
+	if errors.Is(err, ErrAssigneeNotFound) {
+		return c.JSON(http.StatusBadRequest, &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		})
+	}
+	if err != nil {
+		r.logger.With(ctx).Warnf("error updating conversation: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, conversation)
+}
+
+// ListConversationNotes godoc
+// @Summary         List conversation notes
+// @Description     Retrieves the internal notes of a conversation, newest first. Notes are never sent to the connection.
+// @Tags            conversations
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id         path  string  true  "Application ID"
+// @Param           connection_id  path  string  true  "Connection ID"
+// @Param           page query int false "Page number for results pagination"
+// @Param           per_page query int false "Number of results per page for pagination"
+// @Success         200  {object}  ExtNotesResponse "Successfully retrieved the notes"
+// @Failure         404  {object}  response.Error   "Conversation Not Found"
+// @Failure         500  {object}  response.Error   "Internal server error"
+// @Router          /apps/{app_id}/conversations/{connection_id}/notes [get]
+func (r resource) notes(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	conversation, err := r.service.Get(ctx, c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	count, err := r.service.CountNotes(ctx, conversation)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting conversation notes: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	notes, err := r.service.Notes(ctx, conversation, pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving conversation notes: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = notes
+	return c.JSON(http.StatusOK, pages)
+}
+
+// CreateConversationNote godoc
+// @Summary         Add a note to a conversation
+// @Description     Adds an internal note to a conversation on behalf of the current account. Notes are never sent to the connection, a conversation_note webhook is sent instead.
+// @Tags            conversations
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id         path  string  true  "Application ID"
+// @Param           connection_id  path  string  true  "Connection ID"
+// @Param           request body CreateNoteRequest true "Note to add"
+// @Success         201  {object}  entity.ConversationNote "Successfully created the note"
+// @Failure         400  {object}  response.Error "Invalid input"
+// @Failure         404  {object}  response.Error "Conversation Not Found"
+// @Failure         500  {object}  response.Error "Internal server error"
+// @Router          /apps/{app_id}/conversations/{connection_id}/notes [post]
+func (r resource) createNote(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var input CreateNoteRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("invalid input for creating a note: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+	if err := input.Validate(); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	conversation, err := r.service.Get(ctx, c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	author := ""
+	if user := acl.CurrentUser(c); user != nil {
+		author = user.GetName()
+	}
+
+	note, err := r.service.CreateNote(ctx, c.Param("app_id"), conversation, author, input)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error creating conversation note: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, note)
+}
@@ -0,0 +1,184 @@
+package conversation
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/acl"
+	"github.com/joinself/restful-client/pkg/filter"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+func TestConversationAPIEndpointsAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"ANY /apps/app_id/conversations", "ANY /apps/app_id/conversations/*", "ANY /apps/query_error/conversations", "ANY /apps/count_error/conversations"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "list",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"connection_id":"connection","name":"","assignee":"","status":"open","unread_count":2*`,
+		},
+		{
+			Name:         "list sorted",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations?sort=last_activity",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"last_message":{"id":"jti","iss":"connection","cid":"","body":"hello"*`,
+		},
+		{
+			Name:         "list by status",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations?status=open&assignee=agent",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"total_count":1*`,
+		},
+		{
+			Name:         "invalid status",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations?status=solved",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"status: must be a valid value."}`,
+		},
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations/connection",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"connection_id":"connection"*`,
+		},
+		{
+			Name:       "get not found",
+			Method:     "GET",
+			URL:        "/apps/app_id/conversations/not_found_id",
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:         "update",
+			Method:       "PUT",
+			URL:          "/apps/app_id/conversations/connection",
+			Body:         `{"assignee":"agent","status":"pending"}`,
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"assignee":"agent","status":"pending"*`,
+		},
+		{
+			Name:         "update invalid status",
+			Method:       "PUT",
+			URL:          "/apps/app_id/conversations/connection",
+			Body:         `{"status":"solved"}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"status: must be a valid value."}`,
+		},
+		{
+			Name:         "update unknown assignee",
+			Method:       "PUT",
+			URL:          "/apps/app_id/conversations/connection",
+			Body:         `{"assignee":"unknown"}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"assignee not found"}`,
+		},
+		{
+			Name:       "update not found",
+			Method:     "PUT",
+			URL:        "/apps/app_id/conversations/not_found_id",
+			Body:       `{"status":"closed"}`,
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:         "notes",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations/connection/notes",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"author":"agent","body":"called the customer"*`,
+		},
+		{
+			Name:       "notes not found",
+			Method:     "GET",
+			URL:        "/apps/app_id/conversations/not_found_id/notes",
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:         "create note",
+			Method:       "POST",
+			URL:          "/apps/app_id/conversations/connection/notes",
+			Body:         `{"body":"called the customer"}`,
+			WantStatus:   http.StatusCreated,
+			WantResponse: `*"body":"called the customer"*`,
+		},
+		{
+			Name:         "create blank note",
+			Method:       "POST",
+			URL:          "/apps/app_id/conversations/connection/notes",
+			Body:         `{"body":""}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"body: cannot be blank."}`,
+		},
+		{
+			Name:       "create note not found",
+			Method:     "POST",
+			URL:        "/apps/app_id/conversations/not_found_id/notes",
+			Body:       `{"body":"called the customer"}`,
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:       "create note error",
+			Method:     "POST",
+			URL:        "/apps/app_id/conversations/connection/notes",
+			Body:       `{"body":"error"}`,
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:         "invalid sort",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations?sort=name",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"sort: must be a valid value."}`,
+		},
+		{
+			Name:       "count error",
+			Method:     "GET",
+			URL:        "/apps/count_error/conversations",
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:       "query error",
+			Method:     "GET",
+			URL:        "/apps/query_error/conversations",
+			WantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestConversationAPIEndpointsAsPlainWithoutPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "list",
+			Method:       "GET",
+			URL:          "/apps/app_id/conversations",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
@@ -0,0 +1,163 @@
+package conversation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/webhook"
+)
+
+type mockService struct{}
+
+func (m mockService) Get(ctx context.Context, appID, selfID string) (Conversation, error) {
+	if selfID == "not_found_id" {
+		return Conversation{}, sql.ErrNoRows
+	}
+	return Conversation{ConnectionID: selfID, Status: entity.CONVERSATION_OPEN_STATUS}, nil
+}
+
+func (m mockService) Query(ctx context.Context, appID string, filter entity.ConversationFilter, sort string, offset, limit int) ([]Conversation, error) {
+	if appID == "query_error" {
+		return nil, errors.New("error!")
+	}
+	return []Conversation{{
+		ConnectionID: "connection",
+		Status:       entity.CONVERSATION_OPEN_STATUS,
+		UnreadCount:  2,
+		LastMessage:  &LastMessage{ID: "jti", ISS: "connection", Body: "hello"},
+	}}, nil
+}
+
+func (m mockService) Count(ctx context.Context, appID string, filter entity.ConversationFilter) (int, error) {
+	if appID == "count_error" {
+		return 0, errors.New("error!")
+	}
+	return 1, nil
+}
+
+func (m mockService) Update(ctx context.Context, appID, selfID string, req UpdateConversationRequest) (Conversation, error) {
+	if req.Assignee != nil && *req.Assignee == "unknown" {
+		return Conversation{}, ErrAssigneeNotFound
+	}
+	c := Conversation{ConnectionID: selfID, Status: entity.CONVERSATION_OPEN_STATUS}
+	if req.Assignee != nil {
+		c.Assignee = *req.Assignee
+	}
+	if req.Status != nil {
+		c.Status = *req.Status
+	}
+	return c, nil
+}
+
+func (m mockService) Notes(ctx context.Context, conversation Conversation, offset, limit int) ([]entity.ConversationNote, error) {
+	return []entity.ConversationNote{{ID: 1, Author: "agent", Body: "called the customer"}}, nil
+}
+
+func (m mockService) CountNotes(ctx context.Context, conversation Conversation) (int, error) {
+	return 1, nil
+}
+
+func (m mockService) CreateNote(ctx context.Context, appID string, conversation Conversation, author string, req CreateNoteRequest) (entity.ConversationNote, error) {
+	if req.Body == "error" {
+		return entity.ConversationNote{}, errors.New("error!")
+	}
+	return entity.ConversationNote{ID: 1, Author: author, Body: req.Body}, nil
+}
+
+type mockRepository struct {
+	conversations []entity.Conversation
+	messages      []entity.Message
+	states        map[int]entity.ConversationState
+	notes         []entity.ConversationNote
+	ascending     bool
+}
+
+func (m *mockRepository) Get(ctx context.Context, appID, selfID string) (entity.Conversation, error) {
+	for _, c := range m.conversations {
+		if c.SelfID == selfID {
+			if state, ok := m.states[c.ConnectionID]; ok {
+				c.Assignee = state.Assignee
+				c.Status = state.Status
+			}
+			return c, nil
+		}
+	}
+	return entity.Conversation{}, sql.ErrNoRows
+}
+
+func (m *mockRepository) Count(ctx context.Context, appID string, filter entity.ConversationFilter) (int, error) {
+	return len(m.conversations), nil
+}
+
+func (m *mockRepository) Query(ctx context.Context, appID string, filter entity.ConversationFilter, ascending bool, offset, limit int) ([]entity.Conversation, error) {
+	m.ascending = ascending
+	return m.conversations, nil
+}
+
+func (m *mockRepository) Messages(ctx context.Context, ids []int) ([]entity.Message, error) {
+	result := []entity.Message{}
+	for _, msg := range m.messages {
+		for _, id := range ids {
+			if msg.ID == id {
+				result = append(result, msg)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepository) State(ctx context.Context, connectionID int) (entity.ConversationState, error) {
+	if state, ok := m.states[connectionID]; ok {
+		return state, nil
+	}
+	return entity.ConversationState{}, sql.ErrNoRows
+}
+
+func (m *mockRepository) SaveState(ctx context.Context, state entity.ConversationState) error {
+	if m.states == nil {
+		m.states = map[int]entity.ConversationState{}
+	}
+	m.states[state.ConnectionID] = state
+	return nil
+}
+
+func (m *mockRepository) CountNotes(ctx context.Context, connectionID int) (int, error) {
+	notes, _ := m.Notes(ctx, connectionID, 0, 0)
+	return len(notes), nil
+}
+
+func (m *mockRepository) Notes(ctx context.Context, connectionID int, offset, limit int) ([]entity.ConversationNote, error) {
+	var notes []entity.ConversationNote
+	for _, n := range m.notes {
+		if n.ConnectionID == connectionID {
+			notes = append(notes, n)
+		}
+	}
+	return notes, nil
+}
+
+func (m *mockRepository) CreateNote(ctx context.Context, note *entity.ConversationNote) error {
+	note.ID = len(m.notes) + 1
+	m.notes = append(m.notes, *note)
+	return nil
+}
+
+type mockAccounts struct{}
+
+func (m mockAccounts) GetByUsername(ctx context.Context, username string) (entity.Account, error) {
+	if username == "agent" {
+		return entity.Account{ID: 1, UserName: username}, nil
+	}
+	return entity.Account{}, sql.ErrNoRows
+}
+
+type mockNotifier struct {
+	history []webhook.WebhookPayload
+}
+
+func (m *mockNotifier) Notify(appID string, payload webhook.WebhookPayload) error {
+	m.history = append(m.history, payload)
+	return nil
+}
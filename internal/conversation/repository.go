@@ -0,0 +1,183 @@
+package conversation
+
+import (
+	"context"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/dbcontext"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// Repository encapsulates the logic to access conversations from the data source.
+type Repository interface {
+	// Get returns the conversation with the connection with the given selfID.
+	Get(ctx context.Context, appID, selfID string) (entity.Conversation, error)
+	// Count returns the number of conversations of an app matching the filter.
+	Count(ctx context.Context, appID string, filter entity.ConversationFilter) (int, error)
+	// Query returns the conversations of an app matching the filter sorted by
+	// their last activity, most recent first unless ascending is set.
+	Query(ctx context.Context, appID string, filter entity.ConversationFilter, ascending bool, offset, limit int) ([]entity.Conversation, error)
+	// Messages returns the messages with the given ids.
+	Messages(ctx context.Context, ids []int) ([]entity.Message, error)
+	// State returns the support state of the conversation with a connection.
+	State(ctx context.Context, connectionID int) (entity.ConversationState, error)
+	// SaveState creates or updates the support state of a conversation.
+	SaveState(ctx context.Context, state entity.ConversationState) error
+	// CountNotes returns the number of notes of a conversation.
+	CountNotes(ctx context.Context, connectionID int) (int, error)
+	// Notes returns the notes of a conversation with the given offset and limit.
+	Notes(ctx context.Context, connectionID int, offset, limit int) ([]entity.ConversationNote, error)
+	// CreateNote saves a new note in the storage.
+	CreateNote(ctx context.Context, note *entity.ConversationNote) error
+}
+
+// repository builds the conversations from the connections and messages
+// stored in the database.
+type repository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+// NewRepository creates a new conversation repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+// Get reads the conversation with the given connection from the database.
+func (r repository) Get(ctx context.Context, appID, selfID string) (entity.Conversation, error) {
+	var conversation entity.Conversation
+	err := r.conversations(ctx, appID).
+		AndWhere(dbx.HashExp{"connection.selfid": selfID}).
+		One(&conversation)
+	return conversation, err
+}
+
+// Count returns the number of connections of an app matching the filter,
+// every connection has a conversation even if no messages have been
+// exchanged yet.
+func (r repository) Count(ctx context.Context, appID string, filter entity.ConversationFilter) (int, error) {
+	var count int
+	err := filterExp(r.db.With(ctx).
+		Select("COUNT(*)").
+		From("connection").
+		LeftJoin("conversation_state", dbx.NewExp("conversation_state.connection_id = connection.id")).
+		Where(dbx.HashExp{"connection.appid": appID}), filter).
+		Row(&count)
+	return count, err
+}
+
+// Query aggregates the messages of the connections of an app into
+// conversations.
+func (r repository) Query(ctx context.Context, appID string, filter entity.ConversationFilter, ascending bool, offset, limit int) ([]entity.Conversation, error) {
+	order := "last_activity DESC"
+	if ascending {
+		order = "last_activity ASC"
+	}
+
+	var conversations []entity.Conversation
+	err := filterExp(r.conversations(ctx, appID), filter).
+		OrderBy(order, "connection.id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&conversations)
+	return conversations, err
+}
+
+// conversations builds the query aggregating the messages of the
+// connections of an app. The last activity is the time of the last message,
+// or the connection creation time when there are no messages. Scheduled
+// messages haven't been sent yet so they're not taken into account.
+func (r repository) conversations(ctx context.Context, appID string) *dbx.SelectQuery {
+	return r.db.With(ctx).
+		Select(
+			"connection.id AS connection_id",
+			"connection.selfid",
+			"connection.name",
+			"connection.created_at",
+			"COALESCE(conversation_state.assignee, '') AS assignee",
+			"COALESCE(conversation_state.status, 'open') AS status",
+			"COALESCE((SELECT m.id FROM message m WHERE m.connection_id = connection.id AND m.status != 'scheduled' ORDER BY m.created_at DESC, m.id DESC LIMIT 1), 0) AS last_message_id",
+			"COALESCE((SELECT MAX(m.created_at) FROM message m WHERE m.connection_id = connection.id AND m.status != 'scheduled'), connection.created_at) AS last_activity",
+			"(SELECT COUNT(*) FROM message m WHERE m.connection_id = connection.id AND m.iss != 'me' AND m.read = 0) AS unread",
+		).
+		From("connection").
+		LeftJoin("conversation_state", dbx.NewExp("conversation_state.connection_id = connection.id")).
+		Where(dbx.HashExp{"connection.appid": appID})
+}
+
+// filterExp applies the given filter to a conversations query.
+func filterExp(q *dbx.SelectQuery, filter entity.ConversationFilter) *dbx.SelectQuery {
+	if filter.Status != "" {
+		q = q.AndWhere(dbx.NewExp("COALESCE(conversation_state.status, 'open') = {:status}", dbx.Params{"status": filter.Status}))
+	}
+	if filter.Assignee != "" {
+		q = q.AndWhere(dbx.HashExp{"conversation_state.assignee": filter.Assignee})
+	}
+	return q
+}
+
+// Messages reads the messages with the given ids from the database.
+func (r repository) Messages(ctx context.Context, ids []int) ([]entity.Message, error) {
+	messages := []entity.Message{}
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+
+	err := r.db.With(ctx).
+		Select().
+		From("message").
+		Where(dbx.In("id", values...)).
+		All(&messages)
+	return messages, err
+}
+
+// State reads the support state of a conversation from the database.
+func (r repository) State(ctx context.Context, connectionID int) (entity.ConversationState, error) {
+	var state entity.ConversationState
+	err := r.db.With(ctx).Select().Model(connectionID, &state)
+	return state, err
+}
+
+// SaveState creates or updates the support state of a conversation.
+func (r repository) SaveState(ctx context.Context, state entity.ConversationState) error {
+	_, err := r.State(ctx, state.ConnectionID)
+	if err != nil {
+		return r.db.With(ctx).Model(&state).Insert()
+	}
+	return r.db.With(ctx).Model(&state).Update()
+}
+
+// CountNotes returns the number of notes of a conversation in the database.
+func (r repository) CountNotes(ctx context.Context, connectionID int) (int, error) {
+	var count int
+	err := r.db.With(ctx).
+		Select("COUNT(*)").
+		From("conversation_note").
+		Where(dbx.HashExp{"connection_id": connectionID}).
+		Row(&count)
+	return count, err
+}
+
+// Notes retrieves the notes of a conversation newest first.
+func (r repository) Notes(ctx context.Context, connectionID int, offset, limit int) ([]entity.ConversationNote, error) {
+	var notes []entity.ConversationNote
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"connection_id": connectionID}).
+		OrderBy("id DESC").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&notes)
+	return notes, err
+}
+
+// CreateNote saves a new note in the database.
+func (r repository) CreateNote(ctx context.Context, note *entity.ConversationNote) error {
+	return r.db.With(ctx).Model(note).Insert()
+}
@@ -0,0 +1,125 @@
+package conversation
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	appID := "app_" + uuid.New().String()
+	now := time.Now()
+
+	// an idle connection and a connection with messages
+	idle := entity.Connection{ID: rand.Intn(99999999), AppID: appID, SelfID: "idle", CreatedAt: now.Add(-time.Hour)}
+	active := entity.Connection{ID: rand.Intn(99999999), AppID: appID, SelfID: "active", CreatedAt: now.Add(-2 * time.Hour)}
+	for _, c := range []entity.Connection{idle, active} {
+		assert.Nil(t, db.With(ctx).Model(&c).Insert())
+	}
+
+	messages := []entity.Message{
+		{ISS: "active", Body: "unread", CreatedAt: now.Add(-3 * time.Minute)},
+		{ISS: "active", Body: "read", Read: true, CreatedAt: now.Add(-2 * time.Minute)},
+		{ISS: "me", Body: "last", CreatedAt: now.Add(-time.Minute)},
+		{ISS: "me", Body: "scheduled", Status: entity.MESSAGE_SCHEDULED_STATUS, CreatedAt: now},
+	}
+	for i, m := range messages {
+		m.ConnectionID = active.ID
+		m.JTI = uuid.New().String() + strconv.Itoa(i)
+		assert.Nil(t, db.With(ctx).Model(&m).Insert())
+	}
+
+	// count
+	count, err := repo.Count(ctx, appID, entity.ConversationFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	// query by most recent activity
+	conversations, err := repo.Query(ctx, appID, entity.ConversationFilter{}, false, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(conversations))
+	assert.Equal(t, "active", conversations[0].SelfID)
+	assert.Equal(t, 1, conversations[0].Unread)
+	assert.NotZero(t, conversations[0].LastMessageID)
+	assert.Equal(t, "idle", conversations[1].SelfID)
+	assert.Equal(t, 0, conversations[1].Unread)
+	assert.Zero(t, conversations[1].LastMessageID)
+
+	// query by least recent activity
+	conversations, err = repo.Query(ctx, appID, entity.ConversationFilter{}, true, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "idle", conversations[0].SelfID)
+
+	// paginated
+	conversations, err = repo.Query(ctx, appID, entity.ConversationFilter{}, false, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, "idle", conversations[0].SelfID)
+
+	// last messages
+	conversations, _ = repo.Query(ctx, appID, entity.ConversationFilter{}, false, 0, 1)
+	last, err := repo.Messages(ctx, []int{conversations[0].LastMessageID})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(last))
+	assert.Equal(t, "last", last[0].Body)
+	last, err = repo.Messages(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(last))
+
+	// conversations without a state are open and unassigned
+	conversation, err := repo.Get(ctx, appID, "active")
+	assert.Nil(t, err)
+	assert.Equal(t, entity.CONVERSATION_OPEN_STATUS, conversation.Status)
+	assert.Equal(t, "", conversation.Assignee)
+	_, err = repo.Get(ctx, appID, "unknown")
+	assert.Equal(t, sql.ErrNoRows, err)
+	_, err = repo.State(ctx, active.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// save state
+	state := entity.ConversationState{ConnectionID: active.ID, Assignee: "agent", Status: entity.CONVERSATION_PENDING_STATUS, CreatedAt: now, UpdatedAt: now}
+	assert.Nil(t, repo.SaveState(ctx, state))
+	state.Status = entity.CONVERSATION_CLOSED_STATUS
+	assert.Nil(t, repo.SaveState(ctx, state))
+	state, err = repo.State(ctx, active.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.CONVERSATION_CLOSED_STATUS, state.Status)
+
+	// filter
+	closed := entity.ConversationFilter{Status: entity.CONVERSATION_CLOSED_STATUS}
+	count, _ = repo.Count(ctx, appID, closed)
+	assert.Equal(t, 1, count)
+	conversations, err = repo.Query(ctx, appID, closed, false, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, "agent", conversations[0].Assignee)
+	count, _ = repo.Count(ctx, appID, entity.ConversationFilter{Status: entity.CONVERSATION_OPEN_STATUS})
+	assert.Equal(t, 1, count)
+	count, _ = repo.Count(ctx, appID, entity.ConversationFilter{Assignee: "agent"})
+	assert.Equal(t, 1, count)
+
+	// notes
+	note := entity.ConversationNote{ConnectionID: active.ID, Author: "agent", Body: "called the customer", CreatedAt: now}
+	assert.Nil(t, repo.CreateNote(ctx, &note))
+	assert.NotZero(t, note.ID)
+	count, err = repo.CountNotes(ctx, active.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	notes, err := repo.Notes(ctx, active.ID, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "called the customer", notes[0].Body)
+}
@@ -0,0 +1,266 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/webhook"
+)
+
+// ErrAssigneeNotFound is returned when assigning a conversation to an
+// account that does not exist.
+var ErrAssigneeNotFound = errors.New("assignee not found")
+
+// Service encapsulates usecase logic for conversations.
+type Service interface {
+	Get(ctx context.Context, appID, selfID string) (Conversation, error)
+	Query(ctx context.Context, appID string, filter entity.ConversationFilter, sort string, offset, limit int) ([]Conversation, error)
+	Count(ctx context.Context, appID string, filter entity.ConversationFilter) (int, error)
+	Update(ctx context.Context, appID, selfID string, req UpdateConversationRequest) (Conversation, error)
+	Notes(ctx context.Context, conversation Conversation, offset, limit int) ([]entity.ConversationNote, error)
+	CountNotes(ctx context.Context, conversation Conversation) (int, error)
+	CreateNote(ctx context.Context, appID string, conversation Conversation, author string, req CreateNoteRequest) (entity.ConversationNote, error)
+}
+
+// Conversation represents the summary of the messages exchanged with a
+// connection.
+type Conversation struct {
+	ConnectionID string       `json:"connection_id"`
+	Name         string       `json:"name"`
+	Assignee     string       `json:"assignee"`
+	Status       string       `json:"status"`
+	UnreadCount  int          `json:"unread_count"`
+	LastActivity time.Time    `json:"last_activity"`
+	LastMessage  *LastMessage `json:"last_message,omitempty"`
+
+	connectionID int
+}
+
+// LastMessage represents the last message of a conversation.
+type LastMessage struct {
+	ID        string    `json:"id"`
+	ISS       string    `json:"iss"`
+	CID       string    `json:"cid"`
+	Body      string    `json:"body"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StateEvent is the payload of the webhook sent when the assignee or the
+// status of a conversation changes.
+type StateEvent struct {
+	ConnectionID     string    `json:"connection_id"`
+	Assignee         string    `json:"assignee"`
+	Status           string    `json:"status"`
+	PreviousAssignee string    `json:"previous_assignee"`
+	PreviousStatus   string    `json:"previous_status"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// NewStateEvent builds the webhook payload notifying a conversation state
+// change.
+func NewStateEvent(appID, selfID string, previous, current entity.ConversationState) webhook.WebhookPayload {
+	return webhook.WebhookPayload{
+		Type: webhook.TYPE_CONVERSATION,
+		URI:  fmt.Sprintf("/apps/%s/conversations/%s", appID, selfID),
+		Data: StateEvent{
+			ConnectionID:     selfID,
+			Assignee:         current.Assignee,
+			Status:           current.Status,
+			PreviousAssignee: previous.Assignee,
+			PreviousStatus:   previous.Status,
+			UpdatedAt:        current.UpdatedAt,
+		},
+	}
+}
+
+// AccountGetter looks up the accounts conversations are assigned to.
+type AccountGetter interface {
+	GetByUsername(ctx context.Context, username string) (entity.Account, error)
+}
+
+// Notifier sends webhooks to the callback of an app.
+type Notifier interface {
+	Notify(appID string, payload webhook.WebhookPayload) error
+}
+
+type service struct {
+	repo     Repository
+	accounts AccountGetter
+	notifier Notifier
+	logger   log.Logger
+}
+
+// NewService creates a new conversation service.
+func NewService(repo Repository, accounts AccountGetter, notifier Notifier, logger log.Logger) Service {
+	return service{repo, accounts, notifier, logger}
+}
+
+// Get returns the conversation with the connection with the given selfID.
+func (s service) Get(ctx context.Context, appID, selfID string) (Conversation, error) {
+	item, err := s.repo.Get(ctx, appID, selfID)
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	conversations, err := s.withLastMessages(ctx, []entity.Conversation{item})
+	if err != nil {
+		return Conversation{}, err
+	}
+	return conversations[0], nil
+}
+
+// Count returns the number of conversations of an app matching the filter.
+func (s service) Count(ctx context.Context, appID string, filter entity.ConversationFilter) (int, error) {
+	return s.repo.Count(ctx, appID, filter)
+}
+
+// Query returns the conversations of an app matching the filter with the
+// specified sort, offset and limit.
+func (s service) Query(ctx context.Context, appID string, filter entity.ConversationFilter, sort string, offset, limit int) ([]Conversation, error) {
+	items, err := s.repo.Query(ctx, appID, filter, sort == SORT_ACTIVITY_ASC, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.withLastMessages(ctx, items)
+}
+
+// Update changes the assignee or the status of a conversation, a webhook is
+// sent when any of them changes.
+func (s service) Update(ctx context.Context, appID, selfID string, req UpdateConversationRequest) (Conversation, error) {
+	conversation, err := s.repo.Get(ctx, appID, selfID)
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	now := time.Now()
+	state, err := s.repo.State(ctx, conversation.ConnectionID)
+	if err != nil {
+		state = entity.ConversationState{
+			ConnectionID: conversation.ConnectionID,
+			Status:       entity.CONVERSATION_OPEN_STATUS,
+			CreatedAt:    now,
+		}
+	}
+	previous := state
+
+	if req.Assignee != nil && *req.Assignee != "" {
+		if _, err := s.accounts.GetByUsername(ctx, *req.Assignee); err != nil {
+			return Conversation{}, ErrAssigneeNotFound
+		}
+	}
+	if req.Assignee != nil {
+		state.Assignee = *req.Assignee
+	}
+	if req.Status != nil {
+		state.Status = *req.Status
+	}
+	if state.Assignee == previous.Assignee && state.Status == previous.Status {
+		return s.Get(ctx, appID, selfID)
+	}
+
+	state.UpdatedAt = now
+	if err := s.repo.SaveState(ctx, state); err != nil {
+		return Conversation{}, err
+	}
+	s.notify(ctx, appID, NewStateEvent(appID, selfID, previous, state))
+
+	return s.Get(ctx, appID, selfID)
+}
+
+// CountNotes returns the number of notes of a conversation.
+func (s service) CountNotes(ctx context.Context, conversation Conversation) (int, error) {
+	return s.repo.CountNotes(ctx, conversation.connectionID)
+}
+
+// Notes returns the notes of a conversation with the specified offset and
+// limit.
+func (s service) Notes(ctx context.Context, conversation Conversation, offset, limit int) ([]entity.ConversationNote, error) {
+	notes, err := s.repo.Notes(ctx, conversation.connectionID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if notes == nil {
+		notes = []entity.ConversationNote{}
+	}
+	return notes, nil
+}
+
+// CreateNote adds an internal note to a conversation.
+func (s service) CreateNote(ctx context.Context, appID string, conversation Conversation, author string, req CreateNoteRequest) (entity.ConversationNote, error) {
+	note := entity.ConversationNote{
+		ConnectionID: conversation.connectionID,
+		Author:       author,
+		Body:         req.Body,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.repo.CreateNote(ctx, &note); err != nil {
+		return entity.ConversationNote{}, err
+	}
+
+	s.notify(ctx, appID, webhook.WebhookPayload{
+		Type: webhook.TYPE_CONVERSATION_NOTE,
+		URI:  fmt.Sprintf("/apps/%s/conversations/%s/notes", appID, conversation.ConnectionID),
+		Data: note,
+	})
+
+	return note, nil
+}
+
+// withLastMessages builds the conversations for the given aggregates along
+// with their last message.
+func (s service) withLastMessages(ctx context.Context, items []entity.Conversation) ([]Conversation, error) {
+	ids := []int{}
+	for _, item := range items {
+		if item.LastMessageID > 0 {
+			ids = append(ids, item.LastMessageID)
+		}
+	}
+
+	messages, err := s.repo.Messages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]entity.Message, len(messages))
+	for _, m := range messages {
+		byID[m.ID] = m
+	}
+
+	result := []Conversation{}
+	for _, item := range items {
+		c := Conversation{
+			ConnectionID: item.SelfID,
+			Name:         item.Name,
+			Assignee:     item.Assignee,
+			Status:       item.Status,
+			UnreadCount:  item.Unread,
+			LastActivity: item.CreatedAt,
+			connectionID: item.ConnectionID,
+		}
+		if m, ok := byID[item.LastMessageID]; ok {
+			c.LastActivity = m.CreatedAt
+			c.LastMessage = &LastMessage{
+				ID:        m.JTI,
+				ISS:       m.ISS,
+				CID:       m.CID,
+				Body:      m.Body,
+				Read:      m.Read,
+				CreatedAt: m.CreatedAt,
+			}
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// notify sends the given webhook, failures are logged as the change has
+// already been stored.
+func (s service) notify(ctx context.Context, appID string, payload webhook.WebhookPayload) {
+	if err := s.notifier.Notify(appID, payload); err != nil {
+		s.logger.With(ctx).Warnf("error sending %s webhook: %v", payload.Type, err)
+	}
+}
@@ -0,0 +1,142 @@
+package conversation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateConversationRequest_Validate(t *testing.T) {
+	status := func(s string) *string { return &s }
+	tests := []struct {
+		name      string
+		model     UpdateConversationRequest
+		wantError bool
+	}{
+		{"empty", UpdateConversationRequest{}, false},
+		{"assign", UpdateConversationRequest{Assignee: status("agent")}, false},
+		{"unassign", UpdateConversationRequest{Assignee: status("")}, false},
+		{"status", UpdateConversationRequest{Status: status(entity.CONVERSATION_CLOSED_STATUS)}, false},
+		{"blank status", UpdateConversationRequest{Status: status("")}, true},
+		{"unknown status", UpdateConversationRequest{Status: status("solved")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.Validate()
+			assert.Equal(t, tt.wantError, err != nil)
+		})
+	}
+}
+
+func Test_service_Query(t *testing.T) {
+	logger, _ := log.NewForTest()
+	now := time.Now()
+	created := now.Add(-time.Hour)
+	repo := &mockRepository{
+		conversations: []entity.Conversation{
+			{ConnectionID: 1, SelfID: "active", Name: "Active", LastMessageID: 10, Unread: 3, Status: entity.CONVERSATION_OPEN_STATUS, CreatedAt: created},
+			{ConnectionID: 2, SelfID: "idle", Name: "Idle", Status: entity.CONVERSATION_OPEN_STATUS, CreatedAt: created},
+		},
+		messages: []entity.Message{{ID: 10, ISS: "active", JTI: "jti", CID: "cid", Body: "hello", CreatedAt: now}},
+	}
+	s := NewService(repo, mockAccounts{}, &mockNotifier{}, logger)
+	ctx := context.Background()
+
+	count, err := s.Count(ctx, "app", entity.ConversationFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	conversations, err := s.Query(ctx, "app", entity.ConversationFilter{}, "", 0, 10)
+	assert.Nil(t, err)
+	assert.False(t, repo.ascending)
+	assert.Equal(t, 2, len(conversations))
+
+	assert.Equal(t, "active", conversations[0].ConnectionID)
+	assert.Equal(t, 3, conversations[0].UnreadCount)
+	assert.Equal(t, now, conversations[0].LastActivity)
+	assert.Equal(t, "jti", conversations[0].LastMessage.ID)
+	assert.Equal(t, "hello", conversations[0].LastMessage.Body)
+
+	// Conversations without messages are active since the connection was created
+	assert.Equal(t, "idle", conversations[1].ConnectionID)
+	assert.Equal(t, created, conversations[1].LastActivity)
+	assert.Nil(t, conversations[1].LastMessage)
+
+	_, err = s.Query(ctx, "app", entity.ConversationFilter{}, SORT_ACTIVITY_ASC, 0, 10)
+	assert.Nil(t, err)
+	assert.True(t, repo.ascending)
+}
+
+func Test_service_Update(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{
+		conversations: []entity.Conversation{{ConnectionID: 1, SelfID: "connection", Status: entity.CONVERSATION_OPEN_STATUS}},
+	}
+	notifier := &mockNotifier{}
+	s := NewService(repo, mockAccounts{}, notifier, logger)
+	ctx := context.Background()
+	assignee, pending := "agent", entity.CONVERSATION_PENDING_STATUS
+
+	// assign and change the status
+	conversation, err := s.Update(ctx, "app", "connection", UpdateConversationRequest{Assignee: &assignee, Status: &pending})
+	assert.Nil(t, err)
+	assert.Equal(t, "agent", conversation.Assignee)
+	assert.Equal(t, entity.CONVERSATION_PENDING_STATUS, conversation.Status)
+	assert.Equal(t, 1, len(notifier.history))
+	assert.Equal(t, webhook.TYPE_CONVERSATION, notifier.history[0].Type)
+	event := notifier.history[0].Data.(StateEvent)
+	assert.Equal(t, entity.CONVERSATION_OPEN_STATUS, event.PreviousStatus)
+	assert.Equal(t, entity.CONVERSATION_PENDING_STATUS, event.Status)
+
+	// no changes, no webhook
+	_, err = s.Update(ctx, "app", "connection", UpdateConversationRequest{Status: &pending})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(notifier.history))
+
+	// unknown assignee
+	unknown := "unknown"
+	_, err = s.Update(ctx, "app", "connection", UpdateConversationRequest{Assignee: &unknown})
+	assert.Equal(t, ErrAssigneeNotFound, err)
+
+	// unassign
+	unassigned := ""
+	conversation, err = s.Update(ctx, "app", "connection", UpdateConversationRequest{Assignee: &unassigned})
+	assert.Nil(t, err)
+	assert.Equal(t, "", conversation.Assignee)
+	assert.Equal(t, 2, len(notifier.history))
+
+	// unknown conversation
+	_, err = s.Update(ctx, "app", "unknown", UpdateConversationRequest{Status: &pending})
+	assert.NotNil(t, err)
+}
+
+func Test_service_Notes(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{
+		conversations: []entity.Conversation{{ConnectionID: 1, SelfID: "connection"}},
+	}
+	notifier := &mockNotifier{}
+	s := NewService(repo, mockAccounts{}, notifier, logger)
+	ctx := context.Background()
+
+	conversation, err := s.Get(ctx, "app", "connection")
+	assert.Nil(t, err)
+
+	notes, err := s.Notes(ctx, conversation, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.ConversationNote{}, notes)
+
+	note, err := s.CreateNote(ctx, "app", conversation, "agent", CreateNoteRequest{Body: "called the customer"})
+	assert.Nil(t, err)
+	assert.Equal(t, "agent", note.Author)
+	assert.Equal(t, webhook.TYPE_CONVERSATION_NOTE, notifier.history[0].Type)
+	assert.Equal(t, "/apps/app/conversations/connection/notes", notifier.history[0].URI)
+
+	count, _ := s.CountNotes(ctx, conversation)
+	assert.Equal(t, 1, count)
+}
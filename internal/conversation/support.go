@@ -0,0 +1,93 @@
+package conversation
+
+import (
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/response"
+)
+
+const (
+	// SORT_ACTIVITY_DESC sorts the conversations by last activity, most recent first.
+	SORT_ACTIVITY_DESC = "-last_activity"
+	// SORT_ACTIVITY_ASC sorts the conversations by last activity, oldest first.
+	SORT_ACTIVITY_ASC = "last_activity"
+)
+
+var statuses = []interface{}{
+	entity.CONVERSATION_OPEN_STATUS,
+	entity.CONVERSATION_PENDING_STATUS,
+	entity.CONVERSATION_CLOSED_STATUS,
+}
+
+// ExtListResponse represents the json object returned when listing conversations.
+type ExtListResponse struct {
+	Page       int            `json:"page"`
+	PerPage    int            `json:"per_page"`
+	PageCount  int            `json:"page_count"`
+	TotalCount int            `json:"total_count"`
+	Items      []Conversation `json:"items"`
+}
+
+// ExtNotesResponse represents the json object returned when listing the
+// notes of a conversation.
+type ExtNotesResponse struct {
+	Page       int                       `json:"page"`
+	PerPage    int                       `json:"per_page"`
+	PageCount  int                       `json:"page_count"`
+	TotalCount int                       `json:"total_count"`
+	Items      []entity.ConversationNote `json:"items"`
+}
+
+// UpdateConversationRequest represents a conversation update request, only
+// the provided fields are changed.
+type UpdateConversationRequest struct {
+	// Assignee is the user name of the account handling the conversation,
+	// empty to unassign it.
+	Assignee *string `json:"assignee,omitempty"`
+	// Status is one of open, pending or closed.
+	Status *string `json:"status,omitempty"`
+}
+
+// Validate validates the UpdateConversationRequest fields.
+func (m UpdateConversationRequest) Validate() *response.Error {
+	err := validation.ValidateStruct(&m,
+		validation.Field(&m.Assignee, validation.Length(0, 255)),
+		validation.Field(&m.Status, validation.NilOrNotEmpty, validation.In(statuses...)),
+	)
+	return invalidInput(err)
+}
+
+// CreateNoteRequest represents a conversation note creation request.
+type CreateNoteRequest struct {
+	Body string `json:"body"`
+}
+
+// Validate validates the CreateNoteRequest fields.
+func (m CreateNoteRequest) Validate() *response.Error {
+	err := validation.ValidateStruct(&m,
+		validation.Field(&m.Body, validation.Required, validation.Length(1, 4096)),
+	)
+	return invalidInput(err)
+}
+
+// validateListParams validates the params used to list conversations.
+func validateListParams(sort string, filter entity.ConversationFilter) *response.Error {
+	err := validation.Errors{
+		"sort":   validation.Validate(sort, validation.In(SORT_ACTIVITY_DESC, SORT_ACTIVITY_ASC)),
+		"status": validation.Validate(filter.Status, validation.In(statuses...)),
+	}.Filter()
+	return invalidInput(err)
+}
+
+func invalidInput(err error) *response.Error {
+	if err == nil {
+		return nil
+	}
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: err.Error(),
+	}
+}
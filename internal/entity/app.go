@@ -22,8 +22,48 @@ type App struct {
 	// Callback is the url that will be hit when a message is received.
 	Callback string `json:"callback"`
 	// CallbackSecret is the secret that will be used to sign your callbacks.
-	CallbackSecret string    `json:"callback_secret,omitempty"`
-	Status         string    `json:"status"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	CallbackSecret string `json:"callback_secret,omitempty"`
+	// OutOfBandConfig customizes the QR codes and deep links of the app out
+	// of band requests.
+	OutOfBandConfig
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OutOfBandConfig represents the settings used to build the QR codes and deep
+// links of the out of band requests.
+type OutOfBandConfig struct {
+	// DLCode is the deep link code of the app at the developer portal.
+	DLCode string `json:"dl_code,omitempty" db:"dl_code"`
+	// QRSize is the size in pixels of the QR codes.
+	QRSize int `json:"qr_size,omitempty" db:"qr_size"`
+	// QRForegroundColor is the hex color of the QR codes modules.
+	QRForegroundColor string `json:"qr_foreground_color,omitempty" db:"qr_foreground_color"`
+	// QRBackgroundColor is the hex color of the QR codes background.
+	QRBackgroundColor string `json:"qr_background_color,omitempty" db:"qr_background_color"`
+	// QRLogo is the URL of the logo displayed with the QR codes. The logo is
+	// not drawn into the generated QR codes, clients overlay it themselves.
+	QRLogo string `json:"qr_logo,omitempty" db:"qr_logo"`
+}
+
+// Override returns a copy of the config with the non empty settings of the
+// given config.
+func (c OutOfBandConfig) Override(o OutOfBandConfig) OutOfBandConfig {
+	if o.DLCode != "" {
+		c.DLCode = o.DLCode
+	}
+	if o.QRSize != 0 {
+		c.QRSize = o.QRSize
+	}
+	if o.QRForegroundColor != "" {
+		c.QRForegroundColor = o.QRForegroundColor
+	}
+	if o.QRBackgroundColor != "" {
+		c.QRBackgroundColor = o.QRBackgroundColor
+	}
+	if o.QRLogo != "" {
+		c.QRLogo = o.QRLogo
+	}
+	return c
 }
@@ -6,10 +6,17 @@ import (
 
 // Attestation represents an attestation record.
 type Attestation struct {
-	ID        string    `json:"id"`
-	FactID    string    `json:"-"`
-	Body      string    `json:"body"`
-	Value     string    `json:"value"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string     `json:"id"`
+	FactID    string     `json:"-"`
+	Body      string     `json:"body"`
+	Value     string     `json:"value"`
+	Result    *bool      `json:"result,omitempty"`
+	Issuer    string     `json:"issuer"`
+	Source    string     `json:"source"`
+	KID       string     `json:"kid" db:"kid"`
+	IssuedAt  *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	History   []byte     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
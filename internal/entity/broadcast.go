@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	BROADCAST_PENDING_STATUS   = "pending"
+	BROADCAST_RUNNING_STATUS   = "running"
+	BROADCAST_COMPLETED_STATUS = "completed"
+	BROADCAST_CANCELLED_STATUS = "cancelled"
+
+	BROADCAST_TARGET_ALL         = "all"
+	BROADCAST_TARGET_CONNECTIONS = "connections"
+	BROADCAST_TARGET_TAGS        = "tags"
+
+	RECIPIENT_PENDING_STATUS   = "pending"
+	RECIPIENT_SENDING_STATUS   = "sending"
+	RECIPIENT_SENT_STATUS      = "sent"
+	RECIPIENT_FAILED_STATUS    = "failed"
+	RECIPIENT_CANCELLED_STATUS = "cancelled"
+)
+
+// Broadcast represents a message sent to many connections of an app.
+type Broadcast struct {
+	ID        string    `json:"id"`
+	AppID     string    `json:"app_id"`
+	Body      string    `json:"body"`
+	Target    string    `json:"target"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BroadcastRecipient represents the delivery of a broadcast to a connection.
+type BroadcastRecipient struct {
+	ID           int       `json:"id"`
+	BroadcastID  string    `json:"broadcast_id"`
+	ConnectionID int       `json:"connection_id"`
+	SelfID       string    `json:"selfid" db:"selfid"`
+	Status       string    `json:"status"`
+	JTI          string    `json:"jti"`
+	Error        string    `json:"error"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -10,6 +10,7 @@ type Connection struct {
 	SelfID    string    `json:"selfid" db:"selfid"`
 	AppID     string    `json:"appid" db:"appid"`
 	Name      string    `json:"name"`
+	Tags      []string  `json:"tags" db:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+const (
+	// CONVERSATION_OPEN_STATUS the conversation is waiting for an answer from the support team.
+	CONVERSATION_OPEN_STATUS = "open"
+	// CONVERSATION_PENDING_STATUS the conversation is waiting for an answer from the connection.
+	CONVERSATION_PENDING_STATUS = "pending"
+	// CONVERSATION_CLOSED_STATUS the conversation has been resolved.
+	CONVERSATION_CLOSED_STATUS = "closed"
+)
+
+// Conversation represents the summary of the messages exchanged with a
+// connection.
+type Conversation struct {
+	ConnectionID int    `db:"connection_id"`
+	SelfID       string `db:"selfid"`
+	Name         string `db:"name"`
+	// LastMessageID is the id of the last message exchanged with the
+	// connection, zero when there are no messages.
+	LastMessageID int `db:"last_message_id"`
+	// Unread is the number of inbound messages not read yet.
+	Unread int `db:"unread"`
+	// Assignee is the user name of the account handling the conversation.
+	Assignee string `db:"assignee"`
+	Status   string `db:"status"`
+	// CreatedAt is the creation time of the connection.
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ConversationFilter represents the filters applied when listing
+// conversations.
+type ConversationFilter struct {
+	Status   string
+	Assignee string
+}
+
+// ConversationState represents the support workflow state of the
+// conversation with a connection. Conversations without a state are open and
+// unassigned.
+type ConversationState struct {
+	ConnectionID int       `json:"-" db:"pk,connection_id"`
+	Assignee     string    `json:"assignee"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ConversationNote represents an internal note on a conversation, notes are
+// never sent to the connection.
+type ConversationNote struct {
+	ID           int       `json:"id"`
+	ConnectionID int       `json:"-"`
+	Author       string    `json:"author"`
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -11,28 +11,52 @@ const (
 	STATUS_ERRORED  string = "errored"
 )
 
-// Fact represents a fact record.
+// Fact represents a fact record. The facts received for predicate requests
+// have an Operator and ExpectedValue, and only disclose whether the predicate
+// holds in Result.
 type Fact struct {
-	ID           string    `json:"id"`
-	ConnectionID int       `json:"-"`
-	RequestID    *string   `json:"request_id"`
-	ISS          string    `json:"iss"`
-	CID          string    `json:"cid,omitempty"`
-	JTI          string    `json:"jti,omitempty"`
-	Status       string    `json:"status"`
-	Source       string    `json:"source"`
-	Fact         string    `json:"fact"`
-	Body         string    `json:"body"`
-	IAT          time.Time `json:"iat"`
-	URL          string    `json:"uri,omitempty" db:"-"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	ConnectionID  int       `json:"-"`
+	RequestID     *string   `json:"request_id"`
+	ISS           string    `json:"iss"`
+	CID           string    `json:"cid,omitempty"`
+	JTI           string    `json:"jti,omitempty"`
+	Status        string    `json:"status"`
+	Source        string    `json:"source"`
+	Fact          string    `json:"fact"`
+	Body          string    `json:"body"`
+	Operator      string    `json:"operator,omitempty"`
+	ExpectedValue string    `json:"expected_value,omitempty"`
+	Result        *bool     `json:"result,omitempty"`
+	IAT           time.Time `json:"iat"`
+	URL           string    `json:"uri,omitempty" db:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 func (f *Fact) URI(app string) string {
 	return fmt.Sprintf("/v1/apps/%s/connections/%d/facts/%s", app, f.ConnectionID, f.ID)
 }
 
+// FactFilter represents the filters applied when listing the facts of an app.
+type FactFilter struct {
+	Fact   string `json:"fact"`
+	Source string `json:"source"`
+	Status string `json:"status"`
+}
+
+// FactSummary represents a fact listed with the connection it was received
+// from.
+type FactSummary struct {
+	Fact
+	Connection     string `db:"selfid"`
+	ConnectionName string `db:"connection_name"`
+}
+
 type Response struct {
 	Facts []Fact `json:"facts"`
+	// Status is the status of the request the response belongs to.
+	Status string `json:"status,omitempty"`
+	// Results are the outcomes of the requested facts.
+	Results []FactResult `json:"results,omitempty"`
 }
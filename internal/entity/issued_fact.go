@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	ISSUED_FACT_SENT_STATUS      = "sent"
+	ISSUED_FACT_FAILED_STATUS    = "failed"
+	ISSUED_FACT_DELIVERED_STATUS = "delivered"
+
+	// ISSUED_FACT_ACTION is the billing action of the transactions issuing facts.
+	ISSUED_FACT_ACTION = "identities.facts.issue"
+)
+
+// IssuedFact represents a fact issued by an app to one of its connections.
+// The issued value is not stored, only its SHA-256 hash.
+type IssuedFact struct {
+	ID string `json:"id"`
+	// IssueID groups the facts issued together on the same message.
+	IssueID      string `json:"issue_id"`
+	AppID        string `json:"-"`
+	ConnectionID int    `json:"-"`
+	SelfID       string `json:"selfid"`
+	Key          string `json:"key" db:"fact_key"`
+	ValueHash    string `json:"value_hash"`
+	Source       string `json:"source"`
+	GroupName    string `json:"group_name,omitempty"`
+	GroupIcon    string `json:"group_icon,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	// MetricUUID is the transaction reported back by Self for the delivery.
+	MetricUUID  *int       `json:"metric_uuid,omitempty" db:"metric_uuid"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
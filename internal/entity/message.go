@@ -1,21 +1,108 @@
 package entity
 
 import (
+	"fmt"
 	"time"
 )
 
+const (
+	// MESSAGE_SCHEDULED_STATUS the message will be sent to the connection on its send_at time.
+	MESSAGE_SCHEDULED_STATUS = "scheduled"
+	// MESSAGE_SENT_STATUS the message has been sent to the connection.
+	MESSAGE_SENT_STATUS = "sent"
+	// MESSAGE_ERRORED_STATUS the scheduled message could not be sent.
+	MESSAGE_ERRORED_STATUS = "errored"
+)
+
+const (
+	// MESSAGE_DIRECTION_INBOUND messages received from a connection.
+	MESSAGE_DIRECTION_INBOUND = "inbound"
+	// MESSAGE_DIRECTION_OUTBOUND messages sent to a connection.
+	MESSAGE_DIRECTION_OUTBOUND = "outbound"
+)
+
+const (
+	// ATTACHMENT_PENDING_STATUS the attachment metadata has been stored, but its content has not been downloaded.
+	ATTACHMENT_PENDING_STATUS = "pending"
+	// ATTACHMENT_DOWNLOADED_STATUS the attachment content is available on the local storage.
+	ATTACHMENT_DOWNLOADED_STATUS = "downloaded"
+	// ATTACHMENT_FAILED_STATUS the attachment content could not be downloaded.
+	ATTACHMENT_FAILED_STATUS = "failed"
+)
+
 // Message represents a message record.
 type Message struct {
-	ID           int       `json:"-"`
-	ConnectionID int       `json:"-"`
-	ISS          string    `json:"iss"`
-	CID          string    `json:"cid"`
-	JTI          string    `json:"jti"`
-	RID          string    `json:"rid"`
-	Body         string    `json:"body"`
-	IAT          time.Time `json:"iat"`
-	Read         bool      `json:"read"`
-	Received     bool      `json:"received"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int                 `json:"-"`
+	ConnectionID int                 `json:"-"`
+	ISS          string              `json:"iss"`
+	CID          string              `json:"cid"`
+	JTI          string              `json:"jti"`
+	RID          string              `json:"rid"`
+	Body         string              `json:"body"`
+	IAT          time.Time           `json:"iat"`
+	Read         bool                `json:"read"`
+	Received     bool                `json:"received"`
+	Status       string              `json:"status"`
+	SendAt       *time.Time          `json:"send_at,omitempty"`
+	Content      []byte              `json:"-"`
+	Attachments  []MessageAttachment `json:"attachments,omitempty" db:"-"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// MessageAttachment represents an object attached to a message.
+type MessageAttachment struct {
+	ID        int       `json:"-"`
+	MessageID int       `json:"-"`
+	Position  int       `json:"position"`
+	Name      string    `json:"name"`
+	Link      string    `json:"-"`
+	Mime      string    `json:"mime"`
+	Key       string    `json:"-"`
+	Digest    string    `json:"digest,omitempty"`
+	Expires   int64     `json:"expires,omitempty"`
+	Public    bool      `json:"public"`
+	Status    string    `json:"status"`
+	Path      string    `json:"-"`
+	Size      int       `json:"size,omitempty"`
+	URL       string    `json:"uri,omitempty" db:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// URI returns the api path to download the attachment content.
+func (a *MessageAttachment) URI(app, connection, jti string) string {
+	return fmt.Sprintf("/v1/apps/%s/connections/%s/messages/%s/attachments/%d", app, connection, jti, a.Position)
+}
+
+// MessageRevision represents a previous version of an edited message.
+type MessageRevision struct {
+	ID        int    `json:"-"`
+	MessageID int    `json:"-"`
+	ISS       string `json:"iss"`
+	// Body is the message body before the edit.
+	Body string `json:"body"`
+	// CreatedAt is the time the message was edited.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MessageSearch represents the filters for a message search.
+type MessageSearch struct {
+	// Query is the text to look for.
+	Query string `json:"q"`
+	// Connection is the self id of the connection to search in, all app
+	// connections are searched when empty.
+	Connection string `json:"connection"`
+	// Direction filters inbound or outbound messages.
+	Direction string `json:"direction"`
+	// From and To define the creation date range of the messages.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// MessageMatch represents a message matching a search.
+type MessageMatch struct {
+	Message
+	Connection string `db:"selfid"`
+	Snippet    string
 }
@@ -1,6 +1,9 @@
 package entity
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Metric struct {
 	ID        int       `json:"id"`
@@ -11,3 +14,17 @@ type Metric struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// HasAction reports whether the given action is one of the metric actions.
+func (m Metric) HasAction(action string) bool {
+	var actions []string
+	if json.Unmarshal([]byte(m.Actions), &actions) != nil {
+		return false
+	}
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
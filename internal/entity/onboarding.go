@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+// Onboarding represents the onboarding policy of an app, run when a new
+// connection is established. Apps without a policy use the default one.
+type Onboarding struct {
+	AppID string `json:"-" db:"pk,app_id"`
+	// WelcomeMessage is sent to the new connections, none is sent when empty.
+	WelcomeMessage string `json:"welcome_message"`
+	// Facts is the JSON list of the facts requested to the new connections.
+	Facts       []byte `json:"facts"`
+	Description string `json:"description"`
+	// Tags is the JSON list of the tags applied to the new connections.
+	Tags []byte `json:"tags"`
+	// Notify sends the connection webhook to the app callback.
+	Notify    bool      `json:"notify"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
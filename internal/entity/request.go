@@ -4,13 +4,57 @@ import (
 	"time"
 )
 
+const (
+	REQUEST_REQUESTED_STATUS = "requested"
+	REQUEST_RESPONDED_STATUS = "responded"
+	REQUEST_EXPIRED_STATUS   = "expired"
+	REQUEST_CANCELLED_STATUS = "cancelled"
+	// REQUEST_PARTIAL_STATUS is set when some of the requested facts are
+	// missing from the response.
+	REQUEST_PARTIAL_STATUS = "partial"
+	// REQUEST_MISMATCHED_STATUS is set when the response contains facts or
+	// sources which were not requested.
+	REQUEST_MISMATCHED_STATUS = "mismatched"
+)
+
+const (
+	FACT_RESULT_SATISFIED  = "satisfied"
+	FACT_RESULT_MISSING    = "missing"
+	FACT_RESULT_MISMATCHED = "mismatched"
+	FACT_RESULT_UNEXPECTED = "unexpected"
+)
+
+// DEFAULT_REQUEST_EXPIRY is the expiry of the requests created without one.
+const DEFAULT_REQUEST_EXPIRY = 5 * time.Minute
+
 type Resource struct {
 	URI string `json:"uri"`
 }
 
 type RequestFacts struct {
-	Sources []string `json:"sources"`
-	Name    string   `json:"name"`
+	Sources       []string `json:"sources"`
+	Name          string   `json:"name"`
+	Operator      string   `json:"operator,omitempty"`
+	ExpectedValue string   `json:"expected_value,omitempty"`
+}
+
+// IsPredicate checks whether the fact is requested as a predicate, which
+// responses only disclose whether it holds.
+func (f RequestFacts) IsPredicate() bool {
+	return f.Operator != ""
+}
+
+// FactResult represents the outcome of a requested fact, or of a received fact
+// which was not requested.
+type FactResult struct {
+	Fact string `json:"fact"`
+	// Sources are the requested sources of the fact.
+	Sources []string `json:"sources,omitempty"`
+	// Source is the source of the received fact.
+	Source string `json:"source,omitempty"`
+	Status string `json:"status"`
+	// Result is whether the predicate of a predicate fact holds.
+	Result *bool `json:"result,omitempty"`
 }
 
 // Request represents a request record.
@@ -26,14 +70,63 @@ type Request struct {
 	Status       string        `json:"status"`
 	Callback     string        `json:"callback"`
 	AllowedFor   time.Duration `json:"allowed_for" db:"-"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
+	// OutOfBandConfig is the JSON encoded OutOfBandConfig used to build the
+	// QR code and deep link of out of band requests.
+	OutOfBandConfig []byte `json:"-"`
+	// ExpiresAt is the time the request stops accepting responses, requests
+	// created before expiry tracking don't expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
 func (r *Request) IsResponded() bool {
-	return (r.Status == "responded")
+	return r.Status == REQUEST_RESPONDED_STATUS || r.Status == REQUEST_PARTIAL_STATUS || r.Status == REQUEST_MISMATCHED_STATUS
 }
 
 func (r *Request) IsOutOfBand() bool {
 	return r.OutOfBand
 }
+
+// IsPending checks whether the request is still waiting for a response.
+func (r *Request) IsPending() bool {
+	return r.Status == REQUEST_REQUESTED_STATUS
+}
+
+// IsCancelled checks whether the request was cancelled by the app.
+func (r *Request) IsCancelled() bool {
+	return r.Status == REQUEST_CANCELLED_STATUS
+}
+
+// IsExpired checks whether the request was expired at the given time.
+func (r *Request) IsExpired(at time.Time) bool {
+	return r.Status == REQUEST_EXPIRED_STATUS || (r.ExpiresAt != nil && !at.Before(*r.ExpiresAt))
+}
+
+// Expiry returns the time the request is valid for since its creation.
+func (r *Request) Expiry() time.Duration {
+	if r.ExpiresAt == nil {
+		return DEFAULT_REQUEST_EXPIRY
+	}
+	return r.ExpiresAt.Sub(r.CreatedAt)
+}
+
+// RequestFilter represents the filters applied when listing requests.
+type RequestFilter struct {
+	Status string `json:"status"`
+	Type   string `json:"type"`
+	// Connection is the self id of the connection the requests were sent to.
+	Connection string `json:"connection"`
+	// OutOfBand filters the out of band requests when set.
+	OutOfBand *bool `json:"out_of_band"`
+	// From and To define the creation date range of the requests.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// RequestSummary represents a request listed with the self id of its
+// connection, empty for out of band requests.
+type RequestSummary struct {
+	Request
+	Connection string `db:"selfid"`
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+// Rule represents an inbound message rule record.
+type Rule struct {
+	ID      string `json:"id"`
+	AppID   string `json:"app_id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Priority sets the evaluation order of the rules of an app, lowest
+	// first.
+	Priority int `json:"priority"`
+	// Conditions is the JSON list of the conditions matched by the rule.
+	Conditions []byte `json:"conditions"`
+	// Actions is the JSON list of the actions run when the rule matches.
+	Actions   []byte    `json:"actions"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
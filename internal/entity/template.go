@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+)
+
+// Template represents a message template record.
+type Template struct {
+	ID    string `json:"id"`
+	AppID string `json:"app_id"`
+	Name  string `json:"name"`
+	Body  string `json:"body"`
+	// Variables is the JSON list of the variables the template declares.
+	Variables []byte    `json:"variables"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -1,9 +1,12 @@
 package fact
 
 import (
+	"encoding/csv"
 	"net/http"
+	"strconv"
 
 	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/pagination"
 	"github.com/joinself/restful-client/pkg/response"
@@ -16,10 +19,18 @@ func RegisterHandlers(r *echo.Group, service Service, cService connection.Servic
 
 	r.GET("/:app_id/connections/:connection_id/facts", res.query)
 	r.POST("/:app_id/connections/:connection_id/facts", res.create)
+	r.GET("/:app_id/connections/:connection_id/facts/export", res.export)
 	r.GET("/:app_id/connections/:connection_id/facts/:id", res.get)
 	r.DELETE("/:app_id/connections/:connection_id/facts/:id", res.delete)
+	r.GET("/:app_id/facts", res.queryByApp)
+	r.POST("/:app_id/facts/:id/verify", res.verify)
+	r.GET("/:app_id/connections/:connection_id/issued-facts", res.queryIssued)
+	r.GET("/:app_id/connections/:connection_id/profile", res.profile)
 }
 
+// CSV_PAGE_SIZE is the number of facts fetched at a time when streaming CSV.
+const CSV_PAGE_SIZE = 500
+
 type resource struct {
 	service  Service
 	cService connection.Service
@@ -179,3 +190,237 @@ func (r resource) delete(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// VerifyFact godoc
+// @Summary         Verify a fact
+// @Description     Checks the signatures of the fact attestations against the issuer public keys cached when the fact was received.
+// @Tags            Facts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "The unique identifier (ID) of the application that received the fact."
+// @Param           id      path      string  true  "The unique identifier (ID) of the fact to be verified."
+// @Success         200     {object}  ExtVerification "Verification result, valid is only true when all the attestations are valid."
+// @Failure         404     {object}  response.Error  "Not Found - The requested fact does not exist, or the authenticated user does not have the necessary permissions."
+// @Router          /apps/{app_id}/facts/{id}/verify [post]
+func (r resource) verify(c echo.Context) error {
+	ctx := c.Request().Context()
+	v, err := r.service.Verify(ctx, c.Param("app_id"), c.Param("id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error verifying fact: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, v)
+}
+
+// ExportFacts godoc
+// @Summary         Export the facts of a connection
+// @Description     Exports the facts received from a connection in a self-contained bundle, with the original signed attestations, the requests they were received for and a manifest signed by the app. Bundles can be verified with the bundle.Verify function, against the public keys of the app and the issuers retrieved from Self.
+// @Tags            Facts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id        path string true "The unique identifier (ID) of the application associated with the connection."
+// @Param           connection_id path string true "The unique identifier (ID) of the connection."
+// @Success         200 {object}  bundle.Bundle  "The verifiable bundle of facts."
+// @Failure         404 {object}  response.Error "Not Found - The requested resource does not exist, or the authenticated user does not have the necessary permissions."
+// @Failure         500 {object}  response.Error "Internal Server Error - The app is not running or its keys are not available."
+// @Router          /apps/{app_id}/connections/{connection_id}/facts/export [get]
+func (r resource) export(c echo.Context) error {
+	ctx := c.Request().Context()
+	conn, err := r.cService.Get(ctx, c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving connection: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	b, err := r.service.Export(ctx, c.Param("app_id"), conn.Connection)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error exporting facts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, b)
+}
+
+// ListIssuedFacts godoc
+// @Summary         List the issued facts
+// @Description     Retrieves the facts issued to a connection, newest first, with their delivery status. Issued values are not stored, only their SHA-256 hash.
+// @Tags            Facts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id        path  string true  "The unique identifier (ID) of the application associated with the connection."
+// @Param           connection_id path  string true  "The unique identifier (ID) of the connection."
+// @Param           page          query int    false "Page number for the results pagination."
+// @Param           per_page      query int    false "Number of results per page."
+// @Success         200 {object}  ExtIssuedListResponse "Successfully retrieved the list of issued facts."
+// @Failure         404 {object}  response.Error "Not Found - The requested resource does not exist, or the authenticated user does not have the necessary permissions."
+// @Failure         500 {object}  response.Error "Internal Server Error - An error occurred while processing the request."
+// @Router          /apps/{app_id}/connections/{connection_id}/issued-facts [get]
+func (r resource) queryIssued(c echo.Context) error {
+	ctx := c.Request().Context()
+	conn, err := r.cService.Get(ctx, c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	count, err := r.service.CountIssued(ctx, conn.ID)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving total issued facts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+	pages := pagination.NewFromRequest(c.Request(), count)
+	facts, err := r.service.QueryIssued(ctx, conn.ID, pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving the list of issued facts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+	pages.Items = facts
+	return c.JSON(http.StatusOK, pages)
+}
+
+// ListAppFacts godoc
+// @Summary         Search the facts of an app
+// @Description     Retrieves the facts received by any of the app connections, oldest first, along with the connection they were received from and their attested values. Use format=csv to stream all the matching facts as CSV.
+// @Tags            Facts
+// @Accept          json
+// @Produce         json,text/csv
+// @Security        BearerAuth
+// @Param           app_id   path  string true  "The unique identifier (ID) of the application."
+// @Param           fact     query string false "Filter by the fact, e.g. email_address."
+// @Param           source   query string false "Filter by the source of the fact."
+// @Param           status   query string false "Filter by the status of the fact." Enums(accepted, rejected, errored)
+// @Param           format   query string false "Response format, defaults to json." Enums(json, csv)
+// @Param           page     query int    false "Page number for the results pagination, ignored for csv."
+// @Param           per_page query int    false "Number of results per page, ignored for csv."
+// @Success         200 {object}  ExtAppListResponse "Successfully retrieved the list of facts."
+// @Failure         400 {object}  response.Error "Bad Request - The filters are not valid."
+// @Failure         404 {object}  response.Error "Not Found - The requested resource does not exist, or the authenticated user does not have the necessary permissions."
+// @Failure         500 {object}  response.Error "Internal Server Error - An error occurred while processing the request."
+// @Router          /apps/{app_id}/facts [get]
+func (r resource) queryByApp(c echo.Context) error {
+	ctx := c.Request().Context()
+	appID := c.Param("app_id")
+
+	params := AppFactsParams{
+		FactFilter: entity.FactFilter{
+			Fact:   c.QueryParam("fact"),
+			Source: c.QueryParam("source"),
+			Status: c.QueryParam("status"),
+		},
+		Format: c.QueryParam("format"),
+	}
+	if err := params.Validate(); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	if params.Format == FORMAT_CSV {
+		return r.streamCSV(c, appID, params.FactFilter)
+	}
+
+	count, err := r.service.CountByApp(ctx, appID, params.FactFilter)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving total facts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+	pages := pagination.NewFromRequest(c.Request(), count)
+	facts, err := r.service.QueryByApp(ctx, appID, params.FactFilter, pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving the list of facts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+	pages.Items = facts
+	return c.JSON(http.StatusOK, pages)
+}
+
+// streamCSV writes all the facts matching the filter as CSV, fetching them
+// page by page so large exports are not held in memory.
+func (r resource) streamCSV(c echo.Context, appID string, filter entity.FactFilter) error {
+	ctx := c.Request().Context()
+
+	// Fetch the first page before writing the headers, so errors can still
+	// be reported.
+	facts, err := r.service.QueryByApp(ctx, appID, filter, 0, CSV_PAGE_SIZE)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving the list of facts: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="facts.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	if err := w.Write(CSVHeader); err != nil {
+		return err
+	}
+
+	offset := 0
+	for {
+		for _, f := range facts {
+			if err := w.Write(f.CSVRecord()); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		c.Response().Flush()
+
+		if len(facts) < CSV_PAGE_SIZE {
+			return w.Error()
+		}
+
+		offset += CSV_PAGE_SIZE
+		facts, err = r.service.QueryByApp(ctx, appID, filter, offset, CSV_PAGE_SIZE)
+		if err != nil {
+			// The response is already on its way, so the export is cut short.
+			r.logger.With(ctx).Warnf("error streaming the list of facts: %s", err.Error())
+			return nil
+		}
+	}
+}
+
+// GetProfile godoc
+// @Summary         Retrieve the profile of a connection
+// @Description     Retrieves the latest attested value of each fact received from a connection, with its source and age. Use history=true to include all the values received for each fact.
+// @Tags            Facts
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id        path  string true  "The unique identifier (ID) of the application associated with the connection."
+// @Param           connection_id path  string true  "The unique identifier (ID) of the connection."
+// @Param           history       query bool   false "Include the values received before the latest one."
+// @Success         200 {object}  ExtProfile     "Successfully retrieved the profile."
+// @Failure         400 {object}  response.Error "Bad Request - The history parameter is not a boolean."
+// @Failure         404 {object}  response.Error "Not Found - The requested resource does not exist, or the authenticated user does not have the necessary permissions."
+// @Failure         500 {object}  response.Error "Internal Server Error - An error occurred while processing the request."
+// @Router          /apps/{app_id}/connections/{connection_id}/profile [get]
+func (r resource) profile(c echo.Context) error {
+	ctx := c.Request().Context()
+	conn, err := r.cService.Get(ctx, c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	history := false
+	if v := c.QueryParam("history"); v != "" {
+		history, err = strconv.ParseBool(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &response.Error{
+				Status:  http.StatusBadRequest,
+				Error:   "Invalid input",
+				Details: "history: must be a boolean.",
+			})
+		}
+	}
+
+	p, err := r.service.Profile(ctx, conn.ID, history)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving the profile: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	p.Connection = conn.SelfID
+	return c.JSON(http.StatusOK, p)
+}
@@ -2,6 +2,7 @@ package fact
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/internal/test"
 	"github.com/joinself/restful-client/pkg/acl"
+	"github.com/joinself/restful-client/pkg/bundle"
 	"github.com/joinself/restful-client/pkg/filter"
 	"github.com/joinself/restful-client/pkg/log"
 )
@@ -60,6 +62,76 @@ func (m mockService) Delete(ctx context.Context, connID int, id string) error {
 	return nil
 }
 
+func (m mockService) Verify(ctx context.Context, appID, id string) (ExtVerification, error) {
+	if id == "not_found_id" {
+		return ExtVerification{}, errors.New("not found")
+	}
+	return ExtVerification{
+		FactID: id,
+		Valid:  false,
+		Attestations: []ExtAttestationVerification{{
+			ID:     "attestation_id",
+			Issuer: "issuer",
+			Source: "source",
+			KID:    "kid",
+			Valid:  false,
+			Error:  "issuer public keys are not available",
+		}},
+	}, nil
+}
+
+func (m mockService) Export(ctx context.Context, appID string, conn entity.Connection) (bundle.Bundle, error) {
+	if appID == "not_running" {
+		return bundle.Bundle{}, ErrAppNotRunning
+	}
+	return bundle.Bundle{
+		Version:  bundle.VERSION,
+		Facts:    []bundle.Fact{{ID: "fact_id", ISS: conn.SelfID, Fact: "fact", Attestations: []bundle.Attestation{}}},
+		AppKeys:  []json.RawMessage{},
+		Manifest: json.RawMessage(`{"payload":"payload","protected":"protected","signature":"signature"}`),
+	}, nil
+}
+
+func (m mockService) QueryIssued(ctx context.Context, conn int, offset, limit int) ([]entity.IssuedFact, error) {
+	return []entity.IssuedFact{{ID: "issued_id", IssueID: "issue_id", SelfID: "selfid", Key: "key", ValueHash: "hash", Status: entity.ISSUED_FACT_SENT_STATUS}}, nil
+}
+
+func (m mockService) CountIssued(ctx context.Context, conn int) (int, error) {
+	return 1, nil
+}
+
+func (m mockService) QueryByApp(ctx context.Context, appID string, filter entity.FactFilter, offset, limit int) ([]ExtAppFact, error) {
+	if filter.Source == "invalid_query" {
+		return nil, errors.New("expected error")
+	}
+	if offset > 0 {
+		return []ExtAppFact{}, nil
+	}
+	return []ExtAppFact{{
+		ID:             "fact_id",
+		ExtFact:        ExtFact{ISS: "selfid", Key: filter.Fact, Source: "user_specified", Values: []string{"test@example.com"}},
+		Status:         entity.STATUS_ACCEPTED,
+		Connection:     "selfid",
+		ConnectionName: "name",
+	}}, nil
+}
+
+func (m mockService) CountByApp(ctx context.Context, appID string, filter entity.FactFilter) (int, error) {
+	return 1, nil
+}
+
+func (m mockService) Profile(ctx context.Context, conn int, history bool) (ExtProfile, error) {
+	f := ExtProfileFact{
+		Key:             "email_address",
+		ExtProfileValue: ExtProfileValue{FactID: "fact_id", Value: "test@example.com", Source: "user_specified"},
+		Age:             60,
+	}
+	if history {
+		f.History = []ExtProfileValue{f.ExtProfileValue}
+	}
+	return ExtProfile{Facts: []ExtProfileFact{f}}, nil
+}
+
 type mockConnectionService struct{}
 
 func (m mockConnectionService) Get(ctx context.Context, appid, selfid string) (connection.Connection, error) {
@@ -443,3 +515,245 @@ func TestDeleteFactAPIEndpoint(t *testing.T) {
 		test.Endpoint(t, router, tc)
 	}
 }
+
+func TestVerifyFactAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"POST /apps/app_id/facts/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "POST",
+			URL:          "/apps/app_id/facts/fact_id/verify",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"fact_id":"fact_id","valid":false,"verified_at":"0001-01-01T00:00:00Z","attestations":[{"id":"attestation_id","issuer":"issuer","source":"source","kid":"kid","valid":false,"error":"issuer public keys are not available"}]}`,
+		},
+		{
+			Name:         "fact not found",
+			Method:       "POST",
+			URL:          "/apps/app_id/facts/not_found_id/verify",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "other app",
+			Method:       "POST",
+			URL:          "/apps/other_app/facts/fact_id/verify",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestExportFactsAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"GET /apps/app_id/connections/*", "GET /apps/not_running/connections/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/facts/export",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"version":"1.0.0","facts":[{"id":"fact_id","iss":"selfid","fact":"fact","source":"","status":"","attestations":[],"created_at":"0001-01-01T00:00:00Z"}],"app_keys":[],"manifest":{"payload":"payload","protected":"protected","signature":"signature"}}`,
+		},
+		{
+			Name:         "connection not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/not_found_id/facts/export",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "app not running",
+			Method:       "GET",
+			URL:          "/apps/not_running/connections/conn_id/facts/export",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusInternalServerError,
+			WantResponse: `*Internal error*`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestListIssuedFactsAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"GET /apps/app_id/connections/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/issued-facts",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"page":1,"per_page":100,"page_count":1,"total_count":1,"items":[{"id":"issued_id","issue_id":"issue_id","selfid":"selfid","key":"key","value_hash":"hash","source":"","status":"sent","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}]}`,
+		},
+		{
+			Name:         "connection not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/not_found_id/issued-facts",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestListAppFactsAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"GET /apps/app_id/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "GET",
+			URL:          "/apps/app_id/facts?fact=email_address&status=accepted",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"page":1,"per_page":100,"page_count":1,"total_count":1,"items":[{"id":"fact_id","iss":"selfid","key":"email_address","source":"user_specified","created_at":"0001-01-01T00:00:00Z","values":["test@example.com"],"status":"accepted","connection":"selfid","connection_name":"name"}]}`,
+		},
+		{
+			Name:         "csv",
+			Method:       "GET",
+			URL:          "/apps/app_id/facts?fact=email_address&format=csv",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `*fact_id,selfid,name,email_address,user_specified,accepted,test@example.com,0001-01-01T00:00:00Z*`,
+		},
+		{
+			Name:         "invalid status",
+			Method:       "GET",
+			URL:          "/apps/app_id/facts?status=unknown",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"status: must be a valid value."}`,
+		},
+		{
+			Name:         "invalid format",
+			Method:       "GET",
+			URL:          "/apps/app_id/facts?format=xml",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"format: must be a valid value."}`,
+		},
+		{
+			Name:         "query error",
+			Method:       "GET",
+			URL:          "/apps/app_id/facts?source=invalid_query&format=csv",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusInternalServerError,
+			WantResponse: `*Internal error*`,
+		},
+		{
+			Name:         "other app",
+			Method:       "GET",
+			URL:          "/apps/other_app/facts",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestGetProfileAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"GET /apps/app_id/connections/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/profile",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"connection":"selfid","facts":[{"key":"email_address","fact_id":"fact_id","value":"test@example.com","source":"user_specified","received_at":"0001-01-01T00:00:00Z","age":60}]}`,
+		},
+		{
+			Name:         "with history",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/profile?history=true",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"history":[{"fact_id":"fact_id","value":"test@example.com","source":"user_specified","received_at":"0001-01-01T00:00:00Z"}]*`,
+		},
+		{
+			Name:         "invalid history",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/profile?history=yes",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"history: must be a boolean."}`,
+		},
+		{
+			Name:         "connection not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/not_found_id/profile",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
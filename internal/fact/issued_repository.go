@@ -0,0 +1,113 @@
+package fact
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/dbcontext"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// IssuedRepository encapsulates the logic to access issued facts from the data source.
+type IssuedRepository interface {
+	// Count returns the number of facts issued to the connection.
+	Count(ctx context.Context, conn int) (int, error)
+	// Query returns the list of facts issued to the connection with the given offset and limit.
+	Query(ctx context.Context, conn int, offset, limit int) ([]entity.IssuedFact, error)
+	// Create saves new issued facts in the storage.
+	Create(ctx context.Context, facts []entity.IssuedFact) error
+	// Deliver marks the oldest facts sent to the recipient as delivered by
+	// the given Self transaction.
+	Deliver(ctx context.Context, appID, selfID string, uuid int, at time.Time) error
+}
+
+// issuedRepository persists issued facts in database
+type issuedRepository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+// NewIssuedRepository creates a new issued fact repository
+func NewIssuedRepository(db *dbcontext.DB, logger log.Logger) IssuedRepository {
+	return issuedRepository{db, logger}
+}
+
+// Count returns the number of facts issued to the connection.
+func (r issuedRepository) Count(ctx context.Context, conn int) (int, error) {
+	var count int
+	err := r.db.With(ctx).
+		Select("COUNT(*)").
+		From("issued_fact").
+		Where(dbx.HashExp{"connection_id": conn}).
+		Row(&count)
+	return count, err
+}
+
+// Query retrieves the facts issued to the connection, newest first.
+func (r issuedRepository) Query(ctx context.Context, conn int, offset, limit int) ([]entity.IssuedFact, error) {
+	var facts []entity.IssuedFact
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"connection_id": conn}).
+		OrderBy("created_at DESC", "id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&facts)
+	return facts, err
+}
+
+// Create saves the facts issued together in the database.
+func (r issuedRepository) Create(ctx context.Context, facts []entity.IssuedFact) error {
+	return r.db.Transactional(ctx, func(ctx context.Context) error {
+		for _, f := range facts {
+			err := r.db.With(ctx).Model(&f).Insert()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Deliver marks the oldest facts issued together to the recipient which are
+// still pending as delivered. Only the facts issued before the transaction
+// date are considered. Transactions which were already correlated are
+// ignored, as Self may report them more than once, the check is part of the
+// update so concurrent reports can't correlate the same transaction twice.
+func (r issuedRepository) Deliver(ctx context.Context, appID, selfID string, uuid int, at time.Time) error {
+	var pending entity.IssuedFact
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"app_id": appID, "self_id": selfID, "status": entity.ISSUED_FACT_SENT_STATUS}).
+		// Transaction dates have second precision.
+		AndWhere(dbx.NewExp("created_at < {:before}", dbx.Params{"before": at.Truncate(time.Second).Add(time.Second)})).
+		OrderBy("created_at", "id").
+		Limit(1).
+		One(&pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.With(ctx).Update("issued_fact", dbx.Params{
+		"status":       entity.ISSUED_FACT_DELIVERED_STATUS,
+		"metric_uuid":  uuid,
+		"delivered_at": at,
+		"updated_at":   time.Now(),
+	}, dbx.And(
+		dbx.HashExp{"issue_id": pending.IssueID, "status": entity.ISSUED_FACT_SENT_STATUS},
+		// The facts of the issue updated by this statement are excluded, so
+		// only the previous correlations are checked.
+		dbx.NewExp(
+			"NOT EXISTS (SELECT 1 FROM issued_fact WHERE app_id = {:app} AND metric_uuid = {:uuid} AND issue_id != {:issue})",
+			dbx.Params{"app": appID, "uuid": uuid, "issue": pending.IssueID},
+		),
+	)).Execute()
+	return err
+}
@@ -0,0 +1,94 @@
+package fact
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuedRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "issued_fact")
+	repo := NewIssuedRepository(db, logger)
+
+	ctx := context.Background()
+	connection := 1
+	err := test.CreateConnection(ctx, db, connection)
+	require.Nil(t, err)
+
+	now := time.Now()
+	issued := func(id, issueID string, at time.Time) entity.IssuedFact {
+		return entity.IssuedFact{
+			ID:           id,
+			IssueID:      issueID,
+			AppID:        "app_1",
+			ConnectionID: connection,
+			SelfID:       "connection_1",
+			Key:          "key",
+			ValueHash:    "hash",
+			Status:       entity.ISSUED_FACT_SENT_STATUS,
+			CreatedAt:    at,
+			UpdatedAt:    at,
+		}
+	}
+
+	// create
+	err = repo.Create(ctx, []entity.IssuedFact{issued("f1", "i1", now.Add(-time.Minute)), issued("f2", "i1", now.Add(-time.Minute))})
+	require.Nil(t, err)
+	err = repo.Create(ctx, []entity.IssuedFact{issued("f3", "i2", now)})
+	require.Nil(t, err)
+
+	// count & query
+	count, err := repo.Count(ctx, connection)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	facts, err := repo.Query(ctx, connection, 0, 10)
+	assert.Nil(t, err)
+	require.Len(t, facts, 3)
+	assert.Equal(t, "f3", facts[0].ID)
+
+	// transactions dated before the facts were issued are ignored.
+	err = repo.Deliver(ctx, "app_1", "connection_1", 99, now.Add(-time.Hour))
+	assert.Nil(t, err)
+	facts, _ = repo.Query(ctx, connection, 0, 10)
+	for _, f := range facts {
+		assert.Equal(t, entity.ISSUED_FACT_SENT_STATUS, f.Status)
+	}
+
+	// the oldest pending facts are delivered first.
+	err = repo.Deliver(ctx, "app_1", "connection_1", 100, now)
+	assert.Nil(t, err)
+	facts, _ = repo.Query(ctx, connection, 0, 10)
+	for _, f := range facts {
+		if f.IssueID == "i1" {
+			assert.Equal(t, entity.ISSUED_FACT_DELIVERED_STATUS, f.Status)
+			require.NotNil(t, f.MetricUUID)
+			assert.Equal(t, 100, *f.MetricUUID)
+			assert.NotNil(t, f.DeliveredAt)
+		} else {
+			assert.Equal(t, entity.ISSUED_FACT_SENT_STATUS, f.Status)
+		}
+	}
+
+	// transactions reported twice are ignored.
+	err = repo.Deliver(ctx, "app_1", "connection_1", 100, now)
+	assert.Nil(t, err)
+	facts, _ = repo.Query(ctx, connection, 0, 1)
+	assert.Equal(t, entity.ISSUED_FACT_SENT_STATUS, facts[0].Status)
+
+	err = repo.Deliver(ctx, "app_1", "connection_1", 101, now)
+	assert.Nil(t, err)
+	facts, _ = repo.Query(ctx, connection, 0, 1)
+	assert.Equal(t, entity.ISSUED_FACT_DELIVERED_STATUS, facts[0].Status)
+
+	// nothing left to deliver.
+	err = repo.Deliver(ctx, "app_1", "connection_1", 102, now)
+	assert.Nil(t, err)
+}
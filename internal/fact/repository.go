@@ -14,10 +14,20 @@ import (
 type Repository interface {
 	// Get returns the fact with the specified fact ID.
 	Get(ctx context.Context, connectionID int, id string) (entity.Fact, error)
+	// GetByApp returns the fact with the specified fact ID received by any of the app connections.
+	GetByApp(ctx context.Context, appID, id string) (entity.Fact, error)
 	// Count returns the number of facts.
 	Count(ctx context.Context, conn int, source, fact string) (int, error)
 	// Query returns the list of facts with the given offset and limit.
 	Query(ctx context.Context, conn int, source, fact string, offset, limit int) ([]entity.Fact, error)
+	// CountByApp returns the number of facts received by the app connections matching the given filter.
+	CountByApp(ctx context.Context, appID string, filter entity.FactFilter) (int, error)
+	// QueryByApp returns the facts received by the app connections matching the given filter, with the given offset and limit.
+	QueryByApp(ctx context.Context, appID string, filter entity.FactFilter, offset, limit int) ([]entity.FactSummary, error)
+	// Latest returns the latest accepted fact of each fact name received from the connection.
+	Latest(ctx context.Context, conn int) ([]entity.Fact, error)
+	// History returns the accepted facts received from the connection, newest first.
+	History(ctx context.Context, conn int) ([]entity.Fact, error)
 	// Create saves a new fact in the storage.
 	Create(ctx context.Context, fact entity.Fact) error
 	// Update updates the fact with given ID in the storage.
@@ -48,6 +58,19 @@ func (r repository) Get(ctx context.Context, connectionID int, id string) (entit
 	return fact, err
 }
 
+// GetByApp reads the fact with the specified ID from the database, as long
+// as it belongs to one of the given app connections.
+func (r repository) GetByApp(ctx context.Context, appID, id string) (entity.Fact, error) {
+	var fact entity.Fact
+	err := r.db.With(ctx).
+		Select("fact.*").
+		From("fact").
+		InnerJoin("connection", dbx.NewExp("connection.id = fact.connection_id")).
+		Where(&dbx.HashExp{"fact.id": id, "connection.appid": appID}).
+		One(&fact)
+	return fact, err
+}
+
 // Create saves a new fact record in the database.
 // It returns the ID of the newly inserted fact record.
 func (r repository) Create(ctx context.Context, fact entity.Fact) error {
@@ -106,6 +129,84 @@ func (r repository) Query(ctx context.Context, conn int, source, fact string, of
 	return facts, err
 }
 
+// CountByApp returns the number of facts received by the app connections
+// matching the given filter.
+func (r repository) CountByApp(ctx context.Context, appID string, filter entity.FactFilter) (int, error) {
+	var count int
+	err := r.appQuery(ctx, appID, filter, "COUNT(*)").Row(&count)
+	return count, err
+}
+
+// QueryByApp retrieves the facts received by the app connections matching the
+// given filter, oldest first.
+func (r repository) QueryByApp(ctx context.Context, appID string, filter entity.FactFilter, offset, limit int) ([]entity.FactSummary, error) {
+	var facts []entity.FactSummary
+	err := r.appQuery(ctx, appID, filter, "fact.*", "connection.selfid AS selfid", "connection.name AS connection_name").
+		OrderBy("fact.created_at", "fact.id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&facts)
+	return facts, err
+}
+
+func (r repository) appQuery(ctx context.Context, appID string, filter entity.FactFilter, columns ...string) *dbx.SelectQuery {
+	q := r.db.With(ctx).
+		Select(columns...).
+		From("fact").
+		InnerJoin("connection", dbx.NewExp("connection.id = fact.connection_id")).
+		Where(dbx.HashExp{"connection.appid": appID})
+
+	if filter.Fact != "" {
+		q = q.AndWhere(dbx.HashExp{"fact.fact": filter.Fact})
+	}
+	if filter.Source != "" {
+		q = q.AndWhere(dbx.HashExp{"fact.source": filter.Source})
+	}
+	if filter.Status != "" {
+		q = q.AndWhere(dbx.HashExp{"fact.status": filter.Status})
+	}
+
+	return q
+}
+
+// Latest retrieves the latest accepted fact of each fact name received from
+// the connection. Predicate facts are left out as they don't disclose values.
+func (r repository) Latest(ctx context.Context, conn int) ([]entity.Fact, error) {
+	var facts []entity.Fact
+	err := r.profileQuery(ctx, conn).
+		AndWhere(dbx.NewExp(`NOT EXISTS (
+			SELECT 1 FROM fact newer
+			WHERE newer.connection_id = fact.connection_id
+			AND newer.fact = fact.fact
+			AND newer.status = fact.status
+			AND newer.operator = ''
+			AND (newer.created_at > fact.created_at OR (newer.created_at = fact.created_at AND newer.id > fact.id)))`)).
+		OrderBy("fact.fact").
+		All(&facts)
+	return facts, err
+}
+
+// History retrieves the accepted facts received from the connection, grouped
+// by fact name and newest first.
+func (r repository) History(ctx context.Context, conn int) ([]entity.Fact, error) {
+	var facts []entity.Fact
+	err := r.profileQuery(ctx, conn).
+		OrderBy("fact.fact", "fact.created_at DESC", "fact.id DESC").
+		All(&facts)
+	return facts, err
+}
+
+func (r repository) profileQuery(ctx context.Context, conn int) *dbx.SelectQuery {
+	return r.db.With(ctx).
+		Select("fact.*").
+		From("fact").
+		Where(dbx.HashExp{
+			"fact.connection_id": conn,
+			"fact.status":        entity.STATUS_ACCEPTED,
+			"fact.operator":      "",
+		})
+}
+
 func (r repository) SetStatus(ctx context.Context, connID int, id string, status string) error {
 	fact, err := r.Get(ctx, connID, id)
 	if err != nil {
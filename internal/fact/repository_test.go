@@ -57,6 +57,53 @@ func TestRepository(t *testing.T) {
 	_, err = repo.Get(ctx, connection, "test0")
 	assert.Equal(t, sql.ErrNoRows, err)
 
+	// get by app
+	fact, err = repo.GetByApp(ctx, "app_1", "test1")
+	assert.Nil(t, err)
+	assert.Equal(t, "fact1", fact.Body)
+	_, err = repo.GetByApp(ctx, "app_2", "test1")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// query by app
+	total, err := repo.CountByApp(ctx, "app_1", entity.FactFilter{})
+	assert.Nil(t, err)
+	byApp, err := repo.QueryByApp(ctx, "app_1", entity.FactFilter{}, 0, total)
+	assert.Nil(t, err)
+	assert.Equal(t, total, len(byApp))
+	assert.Equal(t, "connection_1", byApp[len(byApp)-1].Connection)
+	assert.Equal(t, "connection_1", byApp[len(byApp)-1].ConnectionName)
+	count3, err := repo.CountByApp(ctx, "app_1", entity.FactFilter{Fact: "unknown"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count3)
+	count3, err = repo.CountByApp(ctx, "app_2", entity.FactFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count3)
+
+	// profile
+	now := time.Now()
+	for _, f := range []entity.Fact{
+		{ID: "email_old", Fact: "email_address", Status: entity.STATUS_ACCEPTED, CreatedAt: now.Add(-time.Hour)},
+		{ID: "email_new", Fact: "email_address", Status: entity.STATUS_ACCEPTED, CreatedAt: now},
+		{ID: "email_rejected", Fact: "email_address", Status: entity.STATUS_REJECTED, CreatedAt: now.Add(time.Hour)},
+	} {
+		f.ConnectionID = connection
+		f.IAT = f.CreatedAt
+		f.UpdatedAt = f.CreatedAt
+		err = repo.Create(ctx, f)
+		assert.Nil(t, err)
+	}
+	latest, err := repo.Latest(ctx, connection)
+	assert.Nil(t, err)
+	assert.Len(t, latest, 1)
+	assert.Equal(t, "email_new", latest[0].ID)
+	history, err := repo.History(ctx, connection)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "email_old", history[1].ID)
+	for _, id := range []string{"email_old", "email_new", "email_rejected"} {
+		assert.Nil(t, repo.Delete(ctx, connection, id))
+	}
+
 	// update
 	err = repo.Update(ctx, entity.Fact{
 		ID:           "test1",
@@ -2,15 +2,26 @@ package fact
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/joinself/restful-client/internal/attestation"
 	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/bundle"
+	"github.com/joinself/restful-client/pkg/helper"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/support"
 	"github.com/joinself/self-go-sdk/fact"
 )
 
+// ErrAppNotRunning is returned when the Self client of the app is not running.
+var ErrAppNotRunning = errors.New("app is not running")
+
+// BUNDLE_PAGE_SIZE is the number of facts loaded at once to build the export
+// bundles.
+const BUNDLE_PAGE_SIZE = 100
+
 // Service encapsulates usecase logic for facts.
 type Service interface {
 	Get(ctx context.Context, connectionID int, id string) (Fact, error)
@@ -18,6 +29,23 @@ type Service interface {
 	Count(ctx context.Context, conn int, source, fact string) (int, error)
 	Create(ctx context.Context, appID, selfID string, connection int, input CreateFactRequest) error
 	Delete(ctx context.Context, connID int, id string) error
+	Verify(ctx context.Context, appID, id string) (ExtVerification, error)
+	Export(ctx context.Context, appID string, conn entity.Connection) (bundle.Bundle, error)
+	QueryIssued(ctx context.Context, conn int, offset, limit int) ([]entity.IssuedFact, error)
+	CountIssued(ctx context.Context, conn int) (int, error)
+	QueryByApp(ctx context.Context, appID string, filter entity.FactFilter, offset, limit int) ([]ExtAppFact, error)
+	CountByApp(ctx context.Context, appID string, filter entity.FactFilter) (int, error)
+	Profile(ctx context.Context, conn int, history bool) (ExtProfile, error)
+}
+
+// RequestGetter retrieves the requests the facts were received for.
+type RequestGetter interface {
+	Get(ctx context.Context, appID, id string) (entity.Request, error)
+}
+
+// AppGetter retrieves the apps signing the fact exports.
+type AppGetter interface {
+	Get(ctx context.Context, id string) (entity.App, error)
 }
 
 // RequesterService service to manage sending and receiving fact requests
@@ -41,15 +69,18 @@ type FactToIssue struct {
 }
 
 type service struct {
-	repo   Repository
-	atRepo attestation.Repository
-	runner support.SelfClientGetter
-	logger log.Logger
+	repo     Repository
+	issued   IssuedRepository
+	atRepo   attestation.Repository
+	requests RequestGetter
+	apps     AppGetter
+	runner   support.SelfClientGetter
+	logger   log.Logger
 }
 
 // NewService creates a new fact service.
-func NewService(repo Repository, atRepo attestation.Repository, runner support.SelfClientGetter, logger log.Logger) Service {
-	return service{repo, atRepo, runner, logger}
+func NewService(repo Repository, issued IssuedRepository, atRepo attestation.Repository, requests RequestGetter, apps AppGetter, runner support.SelfClientGetter, logger log.Logger) Service {
+	return service{repo, issued, atRepo, requests, apps, runner, logger}
 }
 
 // Get returns the fact with the specified the fact ID.
@@ -71,11 +102,27 @@ func (s service) Get(ctx context.Context, connectionID int, id string) (Fact, er
 	}, nil
 }
 
-// Create creates a new fact.
+// Create issues new facts to the connection, and keeps track of them.
 func (s service) Create(ctx context.Context, appID, selfID string, connection int, req CreateFactRequest) error {
-	s.issueFact(req, appID, selfID)
+	issueErr := s.issueFact(req, appID, selfID)
 
-	return nil
+	issued := newIssuedFacts(req, appID, selfID, connection, issueErr)
+	err := s.issued.Create(ctx, issued)
+	if err != nil {
+		s.logger.Errorf("failed storing issued facts: %v", err)
+	}
+
+	return issueErr
+}
+
+// QueryIssued returns the facts issued to the connection with the specified offset and limit.
+func (s service) QueryIssued(ctx context.Context, conn int, offset, limit int) ([]entity.IssuedFact, error) {
+	return s.issued.Query(ctx, conn, offset, limit)
+}
+
+// CountIssued returns the number of facts issued to the connection.
+func (s service) CountIssued(ctx context.Context, conn int) (int, error) {
+	return s.issued.Count(ctx, conn)
 }
 
 // Delete deletes the fact with the specified ID.
@@ -86,6 +133,292 @@ func (s service) Delete(ctx context.Context, connID int, id string) error {
 	return nil
 }
 
+// Verify checks the signatures of the fact attestations against the issuer
+// public keys cached when the fact was received.
+func (s service) Verify(ctx context.Context, appID, id string) (ExtVerification, error) {
+	fact, err := s.repo.GetByApp(ctx, appID, id)
+	if err != nil {
+		return ExtVerification{}, err
+	}
+
+	attestations, err := s.atRepo.Query(ctx, fact.ID, 0, 1000)
+	if err != nil {
+		return ExtVerification{}, err
+	}
+
+	output := ExtVerification{
+		FactID:       fact.ID,
+		Valid:        len(attestations) > 0,
+		Attestations: []ExtAttestationVerification{},
+		VerifiedAt:   time.Now(),
+	}
+	for _, a := range attestations {
+		v := ExtAttestationVerification{
+			ID:        a.ID,
+			Issuer:    a.Issuer,
+			Source:    a.Source,
+			KID:       a.KID,
+			IssuedAt:  a.IssuedAt,
+			ExpiresAt: a.ExpiresAt,
+			Valid:     true,
+		}
+		err := attestation.Verify(a)
+		if err != nil {
+			v.Valid = false
+			v.Error = err.Error()
+			output.Valid = false
+		}
+		output.Attestations = append(output.Attestations, v)
+	}
+
+	return output, nil
+}
+
+// Export returns the facts received from the given connection in a bundle
+// signed by the app, which can be verified with bundle.Verify against the
+// app and issuers public keys.
+func (s service) Export(ctx context.Context, appID string, conn entity.Connection) (bundle.Bundle, error) {
+	app, err := s.apps.Get(ctx, appID)
+	if err != nil {
+		return bundle.Bundle{}, err
+	}
+
+	kid, sk, err := helper.DeviceKey(app.DeviceSecret)
+	if err != nil {
+		return bundle.Bundle{}, err
+	}
+
+	client, ok := s.runner.Get(appID)
+	if !ok || client == nil {
+		return bundle.Bundle{}, ErrAppNotRunning
+	}
+
+	// The app keys are included so the manifest can be verified offline.
+	appKeys, err := client.IdentityService().GetHistory(appID)
+	if err != nil {
+		return bundle.Bundle{}, err
+	}
+
+	b, err := s.bundle(ctx, appID, conn)
+	if err != nil {
+		return bundle.Bundle{}, err
+	}
+	b.AppKeys = appKeys
+
+	err = b.Sign(appID, conn.SelfID, time.Now().UTC(), kid, sk)
+	return b, err
+}
+
+// bundle builds an unsigned bundle with the facts of the given connection,
+// their attestations and the requests they were received for.
+func (s service) bundle(ctx context.Context, appID string, conn entity.Connection) (bundle.Bundle, error) {
+	count, err := s.repo.Count(ctx, conn.ID, "", "")
+	if err != nil {
+		return bundle.Bundle{}, err
+	}
+
+	requests := map[string]*bundle.Request{}
+	b := bundle.Bundle{Facts: []bundle.Fact{}}
+	for offset := 0; offset < count; offset += BUNDLE_PAGE_SIZE {
+		facts, err := s.repo.Query(ctx, conn.ID, "", "", offset, BUNDLE_PAGE_SIZE)
+		if err != nil {
+			return bundle.Bundle{}, err
+		}
+
+		ids := make([]string, len(facts))
+		for i, f := range facts {
+			ids[i] = f.ID
+		}
+		attestations, err := s.attestationsByFact(ctx, ids)
+		if err != nil {
+			return bundle.Bundle{}, err
+		}
+
+		for _, f := range facts {
+			b.Facts = append(b.Facts, s.bundleFact(ctx, appID, f, attestations[f.ID], requests))
+		}
+	}
+
+	return b, nil
+}
+
+// bundleFact builds the bundle fact of the given fact and its attestations.
+func (s service) bundleFact(ctx context.Context, appID string, f entity.Fact, attestations []entity.Attestation, requests map[string]*bundle.Request) bundle.Fact {
+	bf := bundle.Fact{
+		ID:            f.ID,
+		ISS:           f.ISS,
+		Fact:          f.Fact,
+		Source:        f.Source,
+		Status:        f.Status,
+		Operator:      f.Operator,
+		ExpectedValue: f.ExpectedValue,
+		Result:        f.Result,
+		Attestations:  []bundle.Attestation{},
+		CreatedAt:     f.CreatedAt,
+	}
+	if f.RequestID != nil {
+		bf.Request = s.bundleRequest(ctx, appID, *f.RequestID, requests)
+	}
+	for _, a := range attestations {
+		ba := bundle.Attestation{
+			ID:    a.ID,
+			Value: a.Value,
+			Body:  a.Body,
+		}
+		if len(a.History) > 0 {
+			_ = json.Unmarshal(a.History, &ba.IssuerKeys)
+		}
+		bf.Attestations = append(bf.Attestations, ba)
+	}
+
+	return bf
+}
+
+// attestationsByFact loads the attestations of the given facts, grouped by
+// fact. They're loaded BUNDLE_PAGE_SIZE facts at a time to keep the queries
+// within the database parameters limit.
+func (s service) attestationsByFact(ctx context.Context, ids []string) (map[string][]entity.Attestation, error) {
+	byFact := map[string][]entity.Attestation{}
+	for start := 0; start < len(ids); start += BUNDLE_PAGE_SIZE {
+		end := min(start+BUNDLE_PAGE_SIZE, len(ids))
+		attestations, err := s.atRepo.QueryByFacts(ctx, ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range attestations {
+			byFact[a.FactID] = append(byFact[a.FactID], a)
+		}
+	}
+	return byFact, nil
+}
+
+// bundleRequest returns the request context of the facts, requests are
+// cached as they're usually shared by several facts.
+func (s service) bundleRequest(ctx context.Context, appID, id string, requests map[string]*bundle.Request) *bundle.Request {
+	if r, ok := requests[id]; ok {
+		return r
+	}
+
+	var output *bundle.Request
+	req, err := s.requests.Get(ctx, appID, id)
+	if err == nil {
+		output = &bundle.Request{
+			ID:          req.ID,
+			Type:        req.Type,
+			Description: req.Description,
+			Status:      req.Status,
+			ExpiresAt:   req.ExpiresAt,
+			CreatedAt:   req.CreatedAt,
+		}
+		if json.Valid(req.Facts) {
+			output.Facts = req.Facts
+		}
+	} else {
+		s.logger.Warnf("failed retrieving request %s: %v", id, err)
+	}
+	requests[id] = output
+
+	return output
+}
+
+// CountByApp returns the number of facts received by the app connections.
+func (s service) CountByApp(ctx context.Context, appID string, filter entity.FactFilter) (int, error) {
+	return s.repo.CountByApp(ctx, appID, filter)
+}
+
+// QueryByApp returns the facts received by the app connections, along with
+// the connection and the attested values.
+func (s service) QueryByApp(ctx context.Context, appID string, filter entity.FactFilter, offset, limit int) ([]ExtAppFact, error) {
+	items, err := s.repo.QueryByApp(ctx, appID, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	// The attestations of the page are loaded at once, as CSV exports list
+	// many facts.
+	byFact, err := s.attestationsByFact(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ExtAppFact{}
+	for _, item := range items {
+		result = append(result, NewExtAppFact(item, byFact[item.ID]))
+	}
+	return result, nil
+}
+
+// Profile returns the latest value of each fact received from the connection,
+// and optionally the values received before. The profile is derived from the
+// stored facts, so it reflects new responses as soon as they're received.
+func (s service) Profile(ctx context.Context, conn int, history bool) (ExtProfile, error) {
+	latest, err := s.repo.Latest(ctx, conn)
+	if err != nil {
+		return ExtProfile{}, err
+	}
+
+	var previous []entity.Fact
+	if history {
+		previous, err = s.repo.History(ctx, conn)
+		if err != nil {
+			return ExtProfile{}, err
+		}
+	}
+
+	ids := []string{}
+	for _, f := range latest {
+		ids = append(ids, f.ID)
+	}
+	for _, f := range previous {
+		ids = append(ids, f.ID)
+	}
+	byFact, err := s.attestationsByFact(ctx, ids)
+	if err != nil {
+		return ExtProfile{}, err
+	}
+
+	histories := map[string][]ExtProfileValue{}
+	for _, f := range previous {
+		histories[f.Fact] = append(histories[f.Fact], profileValue(f, byFact[f.ID]))
+	}
+
+	now := time.Now()
+	profile := ExtProfile{Facts: []ExtProfileFact{}}
+	for _, f := range latest {
+		pf := ExtProfileFact{
+			Key:             f.Fact,
+			ExtProfileValue: profileValue(f, byFact[f.ID]),
+			Age:             int64(now.Sub(f.CreatedAt).Seconds()),
+		}
+		if history {
+			pf.History = histories[f.Fact]
+		}
+		profile.Facts = append(profile.Facts, pf)
+	}
+
+	return profile, nil
+}
+
+// profileValue returns the attested value of the fact, taken from its first
+// attestation.
+func profileValue(f entity.Fact, attestations []entity.Attestation) ExtProfileValue {
+	v := ExtProfileValue{
+		FactID:     f.ID,
+		Source:     f.Source,
+		ReceivedAt: f.CreatedAt,
+	}
+	if len(attestations) > 0 {
+		v.Value = attestations[0].Value
+	}
+	return v
+}
+
 // Count returns the number of facts.
 func (s service) Count(ctx context.Context, conn int, source, fact string) (int, error) {
 	return s.repo.Count(ctx, conn, source, fact)
@@ -114,11 +447,11 @@ func (s service) Query(ctx context.Context, conn int, source, fact string, offse
 }
 
 // issueFact issues a new fact and sends it to the hwe
-func (s service) issueFact(f CreateFactRequest, appid, selfid string) {
+func (s service) issueFact(f CreateFactRequest, appid, selfid string) error {
 	client, ok := s.runner.Get(appid)
-	if !ok {
+	if !ok || client == nil {
 		s.logger.Debug("skipping as self is not initialized")
-		return
+		return ErrAppNotRunning
 	}
 
 	fi := []fact.FactToIssue{}
@@ -141,5 +474,5 @@ func (s service) issueFact(f CreateFactRequest, appid, selfid string) {
 		fi = append(fi, nf)
 	}
 
-	client.FactService().Issue(selfid, fi, []string{})
+	return client.FactService().Issue(selfid, fi, []string{})
 }
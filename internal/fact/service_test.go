@@ -2,11 +2,17 @@ package fact
 
 import (
 	"context"
+	"database/sql"
 	"testing"
+	"time"
 
+	"github.com/joinself/restful-client/internal/attestation"
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/mock"
+	selffact "github.com/joinself/self-go-sdk/fact"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateFactRequest_Validate(t *testing.T) {
@@ -51,7 +57,8 @@ func TestCreateFactRequest_Validate(t *testing.T) {
 func Test_service_CRUD(t *testing.T) {
 	logger, _ := log.NewForTest()
 	runner := mock.NewRunnerMock()
-	s := NewService(&mock.FactRepositoryMock{}, &mock.AttestationRepositoryMock{}, runner, logger)
+	issued := &mock.IssuedFactRepositoryMock{}
+	s := NewService(&mock.FactRepositoryMock{}, issued, &mock.AttestationRepositoryMock{}, &mock.RequestRepositoryMock{}, &mock.AppRepositoryMock{}, runner, logger)
 
 	ctx := context.Background()
 
@@ -59,12 +66,153 @@ func Test_service_CRUD(t *testing.T) {
 	count, _ := s.Count(ctx, 1, "", "")
 	assert.Equal(t, 0, count)
 
-	// successful creation
+	// the app is not running, so the facts can't be issued.
 	err := s.Create(ctx, "app", "connection", 1, CreateFactRequest{
 		Facts: []FactToIssue{{
 			Key:   "test",
 			Value: "test",
+			Group: &selffact.FactGroup{Name: "group"},
 		}},
 	})
+	assert.Equal(t, ErrAppNotRunning, err)
+
+	// the failed facts are tracked.
+	count, _ = s.CountIssued(ctx, 1)
+	assert.Equal(t, 1, count)
+	facts, err := s.QueryIssued(ctx, 1, 0, 10)
+	assert.Nil(t, err)
+	require.Len(t, facts, 1)
+	assert.Equal(t, "test", facts[0].Key)
+	assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", facts[0].ValueHash)
+	assert.Equal(t, "group", facts[0].GroupName)
+	assert.Equal(t, entity.ISSUED_FACT_FAILED_STATUS, facts[0].Status)
+	assert.Equal(t, ErrAppNotRunning.Error(), facts[0].Error)
+	require.NotNil(t, facts[0].ExpiresAt)
+	assert.WithinDuration(t, facts[0].CreatedAt.Add(DEFAULT_ISSUED_FACT_EXPIRY), *facts[0].ExpiresAt, time.Second)
+}
+
+func Test_service_Verify(t *testing.T) {
+	logger, _ := log.NewForTest()
+	runner := mock.NewRunnerMock()
+	fRepo := &mock.FactRepositoryMock{Items: []entity.Fact{{ID: "fact_id"}}}
+	atRepo := &mock.AttestationRepositoryMock{}
+	s := NewService(fRepo, &mock.IssuedFactRepositoryMock{}, atRepo, &mock.RequestRepositoryMock{}, &mock.AppRepositoryMock{}, runner, logger)
+	ctx := context.Background()
+
+	_, err := s.Verify(ctx, "app", "unknown")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// facts without attestations can't be verified.
+	v, err := s.Verify(ctx, "app", "fact_id")
+	assert.Nil(t, err)
+	assert.False(t, v.Valid)
+	assert.Empty(t, v.Attestations)
+
+	// attestations received without the issuer keys are not valid.
+	atRepo.Items = []entity.Attestation{{ID: "attestation_id", FactID: "fact_id", Body: "TODO"}}
+	v, err = s.Verify(ctx, "app", "fact_id")
+	assert.Nil(t, err)
+	assert.False(t, v.Valid)
+	assert.Len(t, v.Attestations, 1)
+	assert.Equal(t, attestation.ErrInvalidBody.Error(), v.Attestations[0].Error)
+}
+
+func Test_service_Export(t *testing.T) {
+	logger, _ := log.NewForTest()
+	rid := "request_id"
+	fRepo := &mock.FactRepositoryMock{Items: []entity.Fact{{ID: "fact_id", ISS: "selfid", Fact: "email_address", RequestID: &rid}}}
+	atRepo := &mock.AttestationRepositoryMock{Items: []entity.Attestation{{
+		ID:      "attestation_id",
+		FactID:  "fact_id",
+		Value:   "test@example.com",
+		Body:    `{"payload":"payload","protected":"protected","signature":"signature"}`,
+		History: []byte(`[{"payload":"op","protected":"hdr","signature":"sig"}]`),
+	}}}
+	requests := &mock.RequestRepositoryMock{Items: []entity.Request{{ID: rid, Type: "fact", Description: "info", Facts: []byte(`[{"name":"email_address"}]`)}}}
+	apps := &mock.AppRepositoryMock{Items: []entity.App{{ID: "app", DeviceSecret: "1:0000000000000000000000000000000000000000000"}}}
+	s := service{fRepo, &mock.IssuedFactRepositoryMock{}, atRepo, requests, apps, mock.NewRunnerMock(), logger}
+	ctx := context.Background()
+	conn := entity.Connection{ID: 1, AppID: "app", SelfID: "selfid"}
+
+	b, err := s.bundle(ctx, "app", conn)
+	require.Nil(t, err)
+	require.Len(t, b.Facts, 1)
+	assert.Equal(t, "email_address", b.Facts[0].Fact)
+	require.NotNil(t, b.Facts[0].Request)
+	assert.Equal(t, "info", b.Facts[0].Request.Description)
+	assert.JSONEq(t, `[{"name":"email_address"}]`, string(b.Facts[0].Request.Facts))
+	require.Len(t, b.Facts[0].Attestations, 1)
+	assert.Equal(t, atRepo.Items[0].Body, b.Facts[0].Attestations[0].Body)
+	assert.Len(t, b.Facts[0].Attestations[0].IssuerKeys, 1)
+
+	_, err = s.Export(ctx, "unknown", conn)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	_, err = s.Export(ctx, "app", conn)
+	assert.Equal(t, ErrAppNotRunning, err)
+}
+
+func Test_service_QueryByApp(t *testing.T) {
+	logger, _ := log.NewForTest()
+	fRepo := &mock.FactRepositoryMock{Items: []entity.Fact{
+		{ID: "f1", ISS: "selfid", Fact: "email_address", Status: entity.STATUS_ACCEPTED},
+		{ID: "f2", ISS: "selfid", Fact: "phone_number", Status: entity.STATUS_ACCEPTED},
+	}}
+	atRepo := &mock.AttestationRepositoryMock{Items: []entity.Attestation{{ID: "a1", FactID: "f1", Value: "test@example.com"}}}
+	s := NewService(fRepo, &mock.IssuedFactRepositoryMock{}, atRepo, &mock.RequestRepositoryMock{}, &mock.AppRepositoryMock{}, mock.NewRunnerMock(), logger)
+	ctx := context.Background()
+
+	filter := entity.FactFilter{Fact: "email_address"}
+	count, err := s.CountByApp(ctx, "app", filter)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	facts, err := s.QueryByApp(ctx, "app", filter, 0, 10)
+	assert.Nil(t, err)
+	require.Len(t, facts, 1)
+	assert.Equal(t, "f1", facts[0].ID)
+	assert.Equal(t, "selfid", facts[0].Connection)
+	assert.Equal(t, []string{"test@example.com"}, facts[0].Values)
+	assert.Equal(t, []string{"f1", "selfid", "", "email_address", "", "accepted", "test@example.com", "0001-01-01T00:00:00Z"}, facts[0].CSVRecord())
+
+	// formulas are neutralised
+	facts[0].ConnectionName = "=HYPERLINK(\"http://example.com\")"
+	facts[0].Values = []string{"+1 555", "@SUM(A1)", "-1", "\t1"}
+	record := facts[0].CSVRecord()
+	assert.Equal(t, "'=HYPERLINK(\"http://example.com\")", record[2])
+	assert.Equal(t, "'+1 555;@SUM(A1);-1;\t1", record[6])
+	assert.Equal(t, "'\rcmd", csvCell("\rcmd"))
+}
+
+func Test_service_Profile(t *testing.T) {
+	logger, _ := log.NewForTest()
+	now := time.Now()
+	fRepo := &mock.FactRepositoryMock{Items: []entity.Fact{
+		{ID: "f1", ConnectionID: 1, Fact: "email_address", Source: "user_specified", Status: entity.STATUS_ACCEPTED, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "f2", ConnectionID: 1, Fact: "email_address", Source: "user_specified", Status: entity.STATUS_ACCEPTED, CreatedAt: now.Add(-time.Hour)},
+		{ID: "f3", ConnectionID: 1, Fact: "phone_number", Source: "user_specified", Status: entity.STATUS_ACCEPTED, CreatedAt: now},
+	}}
+	atRepo := &mock.AttestationRepositoryMock{Items: []entity.Attestation{
+		{ID: "a1", FactID: "f1", Value: "old@example.com"},
+		{ID: "a2", FactID: "f2", Value: "new@example.com"},
+	}}
+	s := NewService(fRepo, &mock.IssuedFactRepositoryMock{}, atRepo, &mock.RequestRepositoryMock{}, &mock.AppRepositoryMock{}, mock.NewRunnerMock(), logger)
+	ctx := context.Background()
+
+	profile, err := s.Profile(ctx, 1, false)
+	assert.Nil(t, err)
+	require.Len(t, profile.Facts, 2)
+	assert.Equal(t, "email_address", profile.Facts[0].Key)
+	assert.Equal(t, "f2", profile.Facts[0].FactID)
+	assert.Equal(t, "user_specified", profile.Facts[0].Source)
+	assert.InDelta(t, 3600, profile.Facts[0].Age, 5)
+	assert.Nil(t, profile.Facts[0].History)
+	assert.Equal(t, "phone_number", profile.Facts[1].Key)
+
+	profile, err = s.Profile(ctx, 1, true)
 	assert.Nil(t, err)
+	require.Len(t, profile.Facts[0].History, 2)
+	assert.Equal(t, "f2", profile.Facts[0].History[0].FactID)
+	assert.Equal(t, "f1", profile.Facts[0].History[1].FactID)
+	require.Len(t, profile.Facts[1].History, 1)
 }
@@ -1,30 +1,45 @@
 package fact
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
+	"strings"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/response"
 )
 
+// DEFAULT_ISSUED_FACT_EXPIRY is the expiry Self applies to the facts issued
+// without one.
+const DEFAULT_ISSUED_FACT_EXPIRY = 15 * time.Minute
+
 type ExtFact struct {
-	ISS       string    `json:"iss"`
-	Key       string    `json:"key"`
-	Source    string    `json:"source"`
-	CreatedAt time.Time `json:"created_at"`
-	Values    []string  `json:"values"`
+	ISS           string    `json:"iss"`
+	Key           string    `json:"key"`
+	Source        string    `json:"source"`
+	Operator      string    `json:"operator,omitempty"`
+	ExpectedValue string    `json:"expected_value,omitempty"`
+	Result        *bool     `json:"result,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	Values        []string  `json:"values"`
 	// TODO: is this something that the user provides on the response?
 	// Group     string `json:"group"`
 }
 
 func NewExtFact(f Fact) ExtFact {
 	output := ExtFact{
-		ISS:       f.ISS,
-		Key:       f.Fact.Fact,
-		Source:    f.Source,
-		CreatedAt: f.CreatedAt,
-		Values:    []string{},
+		ISS:           f.ISS,
+		Key:           f.Fact.Fact,
+		Source:        f.Source,
+		Operator:      f.Operator,
+		ExpectedValue: f.ExpectedValue,
+		Result:        f.Result,
+		CreatedAt:     f.CreatedAt,
+		Values:        []string{},
 	}
 	for _, a := range f.Attestations {
 		output.Values = append(output.Values, a.Value)
@@ -32,6 +47,191 @@ func NewExtFact(f Fact) ExtFact {
 	return output
 }
 
+// ExtVerification is the result of verifying the attestations of a fact.
+type ExtVerification struct {
+	FactID       string                       `json:"fact_id"`
+	Valid        bool                         `json:"valid"`
+	Attestations []ExtAttestationVerification `json:"attestations"`
+	VerifiedAt   time.Time                    `json:"verified_at"`
+}
+
+// ExtAttestationVerification is the result of verifying a single attestation.
+type ExtAttestationVerification struct {
+	ID        string     `json:"id"`
+	Issuer    string     `json:"issuer"`
+	Source    string     `json:"source"`
+	KID       string     `json:"kid"`
+	IssuedAt  *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Valid     bool       `json:"valid"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// newIssuedFacts builds the records of the facts issued on the request, with
+// the status resulting of sending them.
+func newIssuedFacts(req CreateFactRequest, appID, selfID string, connection int, issueErr error) []entity.IssuedFact {
+	now := time.Now()
+	issueID := uuid.New().String()
+
+	status := entity.ISSUED_FACT_SENT_STATUS
+	errMsg := ""
+	if issueErr != nil {
+		status = entity.ISSUED_FACT_FAILED_STATUS
+		errMsg = issueErr.Error()
+	}
+
+	facts := []entity.IssuedFact{}
+	for _, f := range req.Facts {
+		hash := sha256.Sum256([]byte(f.Value))
+		exp := DEFAULT_ISSUED_FACT_EXPIRY
+		if f.ExpTimeout != nil {
+			exp = *f.ExpTimeout
+		}
+		expiresAt := now.Add(exp)
+
+		issued := entity.IssuedFact{
+			ID:           uuid.New().String(),
+			IssueID:      issueID,
+			AppID:        appID,
+			ConnectionID: connection,
+			SelfID:       selfID,
+			Key:          f.Key,
+			ValueHash:    hex.EncodeToString(hash[:]),
+			Source:       f.Source,
+			Type:         f.Type,
+			Status:       status,
+			Error:        errMsg,
+			ExpiresAt:    &expiresAt,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if f.Group != nil {
+			issued.GroupName = f.Group.Name
+			issued.GroupIcon = f.Group.Icon
+		}
+		facts = append(facts, issued)
+	}
+
+	return facts
+}
+
+// ExtIssuedListResponse is the paginated list of issued facts.
+type ExtIssuedListResponse struct {
+	Page       int                 `json:"page"`
+	PerPage    int                 `json:"per_page"`
+	PageCount  int                 `json:"page_count"`
+	TotalCount int                 `json:"total_count"`
+	Items      []entity.IssuedFact `json:"items"`
+}
+
+// ExtAppFact is a fact listed with the connection it was received from.
+type ExtAppFact struct {
+	ID string `json:"id"`
+	ExtFact
+	Status         string `json:"status"`
+	Connection     string `json:"connection"`
+	ConnectionName string `json:"connection_name"`
+}
+
+func NewExtAppFact(f entity.FactSummary, attestations []entity.Attestation) ExtAppFact {
+	return ExtAppFact{
+		ID:             f.ID,
+		ExtFact:        NewExtFact(Fact{Fact: f.Fact, Attestations: attestations}),
+		Status:         f.Status,
+		Connection:     f.Connection,
+		ConnectionName: f.ConnectionName,
+	}
+}
+
+// CSVHeader is the header of the facts exported as CSV.
+var CSVHeader = []string{"id", "connection", "connection_name", "fact", "source", "status", "values", "created_at"}
+
+// CSVRecord returns the fact as a CSV record, multiple values are separated
+// by semicolons.
+func (f ExtAppFact) CSVRecord() []string {
+	return []string{
+		csvCell(f.ID),
+		csvCell(f.Connection),
+		csvCell(f.ConnectionName),
+		csvCell(f.Key),
+		csvCell(f.Source),
+		csvCell(f.Status),
+		csvCell(strings.Join(f.Values, ";")),
+		f.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// csvCell neutralises the values spreadsheets would evaluate as formulas, as
+// connection names and fact values are set by the connections.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type ExtAppListResponse struct {
+	Page       int          `json:"page"`
+	PerPage    int          `json:"per_page"`
+	PageCount  int          `json:"page_count"`
+	TotalCount int          `json:"total_count"`
+	Items      []ExtAppFact `json:"items"`
+}
+
+const (
+	FORMAT_JSON = "json"
+	FORMAT_CSV  = "csv"
+)
+
+// AppFactsParams represents the parameters of the app facts listing.
+type AppFactsParams struct {
+	entity.FactFilter
+	Format string `json:"format"`
+}
+
+// Validate validates the AppFactsParams fields.
+func (m AppFactsParams) Validate() *response.Error {
+	err := validation.ValidateStruct(&m,
+		validation.Field(&m.FactFilter.Fact, validation.Length(0, 128)),
+		validation.Field(&m.FactFilter.Source, validation.Length(0, 128)),
+		validation.Field(&m.FactFilter.Status, validation.In(entity.STATUS_ACCEPTED, entity.STATUS_REJECTED, entity.STATUS_ERRORED)),
+		validation.Field(&m.Format, validation.In(FORMAT_JSON, FORMAT_CSV)),
+	)
+	if err == nil {
+		return nil
+	}
+
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: err.Error(),
+	}
+}
+
+// ExtProfile is the latest value of each fact received from a connection.
+type ExtProfile struct {
+	Connection string           `json:"connection"`
+	Facts      []ExtProfileFact `json:"facts"`
+}
+
+// ExtProfileFact is the latest value of a fact.
+type ExtProfileFact struct {
+	Key string `json:"key"`
+	ExtProfileValue
+	// Age is the number of seconds since the value was received.
+	Age int64 `json:"age"`
+	// History holds all the values received for the fact, newest first.
+	History []ExtProfileValue `json:"history,omitempty"`
+}
+
+// ExtProfileValue is a value received for a fact.
+type ExtProfileValue struct {
+	FactID     string    `json:"fact_id"`
+	Value      string    `json:"value"`
+	Source     string    `json:"source"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
 type ExtListResponse struct {
 	Page       int       `json:"page"`
 	PerPage    int       `json:"per_page"`
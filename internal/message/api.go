@@ -1,14 +1,26 @@
 package message
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/template"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/pagination"
 	"github.com/joinself/restful-client/pkg/response"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
 // RegisterHandlers sets up the routing of the HTTP handlers.
@@ -17,16 +29,21 @@ func RegisterHandlers(r *echo.Group, service Service, cService connection.Servic
 
 	r.GET("/:app_id/connections/:connection_id/messages/:id", res.get)
 	r.GET("/:app_id/connections/:connection_id/messages", res.query)
-	r.POST("/:app_id/connections/:connection_id/messages", res.create)
+	r.POST("/:app_id/connections/:connection_id/messages", res.create, middleware.BodyLimit(fmt.Sprintf("%dB", MAX_UPLOAD_SIZE)))
 	r.PUT("/:app_id/connections/:connection_id/messages/:id", res.update)
 	r.DELETE("/:app_id/connections/:connection_id/messages/:id", res.delete)
 	r.POST("/:app_id/connections/:connection_id/messages/:id/read", res.read)
 	r.POST("/:app_id/connections/:connection_id/messages/:id/received", res.received)
+	r.GET("/:app_id/connections/:connection_id/messages/:id/attachments/:position", res.attachment)
+	r.GET("/:app_id/connections/:connection_id/messages/:id/revisions", res.revisions)
+	r.GET("/:app_id/messages/search", res.search)
 }
 
 var (
 	// LastMessage specifies the message id from what you want to get new messages.
 	LastMessage = "messages_since"
+	// Thread specifies the conversation id of the messages you want to get.
+	Thread = "thread"
 )
 
 type resource struct {
@@ -67,12 +84,13 @@ func (r resource) get(c echo.Context) error {
 
 // ListMessages    godoc
 // @Summary        List conversation messages.
-// @Description    Retrieves all messages for a specific connection within an app. Supports pagination and can filter messages since a specific message ID.
+// @Description    Retrieves all messages for a specific connection within an app. Supports pagination and can filter messages since a specific message ID or by conversation ID, in which case the thread is returned in order. Conversation IDs are only tracked locally, inbound messages join a conversation when they reply to one of its messages.
 // @Tags           messages
 // @Accept         json
 // @Produce        json
 // @Security       BearerAuth
 // @Param          messages_since query int false "Return elements since a message ID"
+// @Param          thread query string false "Return the messages of a conversation ID"
 // @Param          page query int false "Page number for results pagination"
 // @Param          per_page query int false "Number of results per page for pagination"
 // @Param          app_id   path      string  true  "Application ID"
@@ -97,8 +115,10 @@ func (r resource) query(c echo.Context) error {
 		messagesSince = 0
 	}
 
+	thread := c.Request().URL.Query().Get(Thread)
+
 	// Get the total of entries.
-	count, err := r.service.Count(ctx, conn.ID, messagesSince)
+	count, err := r.service.Count(ctx, conn.ID, messagesSince, thread)
 	if err != nil {
 		r.logger.With(ctx).Warnf("error retrieving total messages: %s", err.Error())
 		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
@@ -106,7 +126,7 @@ func (r resource) query(c echo.Context) error {
 
 	// Get the messages
 	pages := pagination.NewFromRequest(c.Request(), count)
-	messages, err := r.service.Query(ctx, conn.ID, messagesSince, pages.Offset(), pages.Limit())
+	messages, err := r.service.Query(ctx, conn.ID, messagesSince, thread, pages.Offset(), pages.Limit())
 	if err != nil {
 		r.logger.With(ctx).Warnf("error retrieving paginated list: %s", err.Error())
 		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
@@ -118,9 +138,9 @@ func (r resource) query(c echo.Context) error {
 
 // SendMessage    godoc
 // @Summary       Sends a message.
-// @Description   Sends a message to a specific connection within an app. Requires Bearer authentication.
+// @Description   Sends a message to a specific connection within an app. Messages with a send_at time are stored as scheduled and sent when due, receiving a new ID once sent. Messages are grouped in conversations by their cid, which is only tracked locally and not sent to the connection; replies are linked to the replied message through their rid, which is sent. The body can be built from a template by providing its template_id and variables. Files can be attached by sending a multipart/form-data request with the body, rid, cid and send_at fields and one or more files parts. Requires Bearer authentication.
 // @Tags          messages
-// @Accept        json
+// @Accept        json,mpfd
 // @Produce       json
 // @Security      BearerAuth
 // @Param         app_id   path      string  true  "Application ID"
@@ -134,9 +154,13 @@ func (r resource) query(c echo.Context) error {
 func (r resource) create(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var input CreateMessageRequest
-	if err := c.Bind(&input); err != nil {
+	input, err := bindCreateMessageRequest(c)
+	if err != nil {
 		r.logger.With(ctx).Warnf("invalid input for creating a message: %s", err.Error())
+		var field unsupportedFieldError
+		if errors.As(err, &field) {
+			return c.JSON(http.StatusBadRequest, invalidParam(string(field), "not supported in multipart requests"))
+		}
 		return c.JSON(response.DefaultBadRequestError())
 	}
 
@@ -154,6 +178,13 @@ func (r resource) create(c echo.Context) error {
 
 	// Create the message
 	message, err := r.service.Create(c.Request().Context(), c.Param("app_id"), c.Param("connection_id"), connection.ID, input)
+	if errors.Is(err, ErrRepliedMessageNotFound) || errors.Is(err, ErrBodyTooLong) || errors.Is(err, template.ErrTemplateNotFound) || errors.Is(err, template.ErrInvalidVariables) {
+		return c.JSON(http.StatusBadRequest, &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		})
+	}
 	if err != nil {
 		r.logger.With(ctx).Warnf("error creating a message: %s", err.Error())
 		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
@@ -164,7 +195,7 @@ func (r resource) create(c echo.Context) error {
 
 // EditMessage    godoc
 // @Summary       Edits a message.
-// @Description   Updates an existing message in a specific connection within an app. Requires Bearer authentication.
+// @Description   Updates an existing message in a specific connection within an app. Scheduled messages can also be rescheduled with send_at. Requires Bearer authentication.
 // @Tags          messages
 // @Accept        json
 // @Produce       json
@@ -205,6 +236,13 @@ func (r resource) update(c echo.Context) error {
 		c.Param("connection_id"),
 		c.Param("id"),
 		input)
+	if errors.Is(err, ErrMessageNotScheduled) {
+		return c.JSON(http.StatusBadRequest, &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		})
+	}
 	if err != nil {
 		r.logger.With(ctx).Warnf("error updating a message: %s", err.Error())
 		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
@@ -215,7 +253,7 @@ func (r resource) update(c echo.Context) error {
 
 // DeleteMessage    godoc
 // @Summary         Deletes a message.
-// @Description     Deletes a specific message from a specific connection within an app.
+// @Description     Deletes a specific message from a specific connection within an app. Messages sent by the app are also deleted for the recipient, and scheduled messages are cancelled.
 // @Tags            messages
 // @Security        BearerAuth
 // @Param           app_id   path   string  true  "Application ID"
@@ -231,7 +269,7 @@ func (r resource) delete(c echo.Context) error {
 		r.logger.With(ctx).Warnf("error retrieving connection: %s", err.Error())
 		return c.JSON(response.DefaultNotFoundError())
 	}
-	err = r.service.Delete(ctx, conn.ID, c.Param("id"))
+	err = r.service.Delete(ctx, c.Param("app_id"), conn.ID, c.Param("connection_id"), c.Param("id"))
 	if err != nil {
 		r.logger.With(ctx).Warnf("error deleting message: %s", err.Error())
 		return c.JSON(response.DefaultNotFoundError())
@@ -299,3 +337,230 @@ func (r resource) received(c echo.Context) error {
 
 	return c.NoContent(http.StatusOK)
 }
+
+// GetMessageAttachment godoc
+// @Summary         Gets a message attachment.
+// @Description     Retrieves the content of the attachment on the given position of a message. Public attachments are redirected to their original link.
+// @Tags            messages
+// @Security        BearerAuth
+// @Param           app_id   path   string  true  "Application ID"
+// @Param           connection_id   path   string  true  "Connection ID"
+// @Param           id   path   string  true  "Message ID"
+// @Param           position   path   int  true  "Attachment position"
+// @Success         200  {file}  binary "Attachment content"
+// @Success         302  {object}  nil "Redirection to a public attachment"
+// @Failure         404  {object}  response.Error "Attachment not found or unauthorized access"
+// @Failure         500  {object}  response.Error "Internal server error while processing your request"
+// @Router          /apps/{app_id}/connections/{connection_id}/messages/{id}/attachments/{position} [get]
+func (r resource) attachment(c echo.Context) error {
+	ctx := c.Request().Context()
+	connection, err := r.cService.Get(ctx, c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving connection: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	position, err := strconv.Atoi(c.Param("position"))
+	if err != nil {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	attachment, content, err := r.service.GetAttachment(ctx, c.Param("app_id"), connection.ID, c.Param("id"), position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving attachment: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	if attachment.Public {
+		return c.Redirect(http.StatusFound, attachment.Link)
+	}
+
+	mime := attachment.Mime
+	if len(mime) == 0 {
+		mime = echo.MIMEOctetStream
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", attachment.Name))
+	return c.Blob(http.StatusOK, mime, content)
+}
+
+// SearchMessages    godoc
+// @Summary        Searches messages.
+// @Description    Full-text search over the messages of all the connections of an app. Results are sorted by relevance and include a snippet of the matching text, HTML escaped and with the matches wrapped in mark tags.
+// @Tags           messages
+// @Accept         json
+// @Produce        json
+// @Security       BearerAuth
+// @Param          app_id   path      string  true  "Application ID"
+// @Param          q query string true "Text to search for"
+// @Param          connection query string false "Only search the messages of the given connection"
+// @Param          direction query string false "Only search inbound or outbound messages" Enums(inbound, outbound)
+// @Param          from query int false "Only search messages created after the given Unix timestamp"
+// @Param          to query int false "Only search messages created before the given Unix timestamp"
+// @Param          page query int false "Page number for results pagination"
+// @Param          per_page query int false "Number of results per page for pagination"
+// @Success        200  {object}  ExtSearchResponse "Successfully retrieved the matching messages"
+// @Failure        400  {object}  response.Error "Invalid input"
+// @Failure        404  {object}  response.Error "Resource not found or unauthorized access"
+// @Failure        500  {object}  response.Error "Internal server error"
+// @Router         /apps/{app_id}/messages/search [get]
+func (r resource) search(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	params := entity.MessageSearch{
+		Query:      c.QueryParam("q"),
+		Connection: c.QueryParam("connection"),
+		Direction:  c.QueryParam("direction"),
+	}
+	if p := c.QueryParam("from"); p != "" {
+		v, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, invalidParam("from", "must be a Unix timestamp"))
+		}
+		params.From = time.Unix(v, 0)
+	}
+	if p := c.QueryParam("to"); p != "" {
+		v, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, invalidParam("to", "must be a Unix timestamp"))
+		}
+		params.To = time.Unix(v, 0)
+	}
+
+	if err := validateSearchParams(params); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	count, err := r.service.CountSearch(ctx, c.Param("app_id"), params)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting matching messages: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	results, err := r.service.Search(ctx, c.Param("app_id"), params, pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error searching messages: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = results
+	return c.JSON(http.StatusOK, pages)
+}
+
+// ListMessageRevisions godoc
+// @Summary         Lists message revisions.
+// @Description     Retrieves the previous versions of an edited message, oldest first.
+// @Tags            messages
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path   string  true  "Application ID"
+// @Param           connection_id   path   string  true  "Connection ID"
+// @Param           id   path   string  true  "Message ID"
+// @Success         200  {array}  entity.MessageRevision "Successfully retrieved the message revisions"
+// @Failure         404  {object}  response.Error "Message not found or unauthorized access"
+// @Router          /apps/{app_id}/connections/{connection_id}/messages/{id}/revisions [get]
+func (r resource) revisions(c echo.Context) error {
+	ctx := c.Request().Context()
+	conn, err := r.cService.Get(ctx, c.Param("app_id"), c.Param("connection_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving connection: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	revisions, err := r.service.Revisions(ctx, conn.ID, c.Param("id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving message revisions: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, revisions)
+}
+
+// unsupportedFieldError is returned for the message fields which can't be
+// sent in a multipart request.
+type unsupportedFieldError string
+
+func (e unsupportedFieldError) Error() string {
+	return string(e) + ": not supported in multipart requests"
+}
+
+// bindCreateMessageRequest reads the message creation request from either a
+// JSON or a multipart/form-data body. Multipart requests carry the plain
+// message fields as values and the uploaded files as files parts, any other
+// value is rejected.
+func bindCreateMessageRequest(c echo.Context) (CreateMessageRequest, error) {
+	var input CreateMessageRequest
+	if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		err := c.Bind(&input)
+		return input, err
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return input, err
+	}
+
+	for key := range form.Value {
+		if !slices.Contains(multipartFields, key) {
+			return input, unsupportedFieldError(key)
+		}
+	}
+
+	input.Body = formValue(form, "body")
+	input.RID = formValue(form, "rid")
+	input.CID = formValue(form, "cid")
+	if sendAt := formValue(form, "send_at"); sendAt != "" {
+		t, err := time.Parse(time.RFC3339, sendAt)
+		if err != nil {
+			return input, err
+		}
+		input.SendAt = &t
+	}
+
+	for _, fh := range form.File["files"] {
+		file, err := readFile(fh)
+		if err != nil {
+			return input, err
+		}
+		input.Files = append(input.Files, file)
+	}
+
+	return input, nil
+}
+
+func formValue(form *multipart.Form, key string) string {
+	if values := form.Value[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// readFile reads an uploaded file, files bigger than the allowed size are
+// truncated just past the limit so they're rejected by the validation.
+func readFile(fh *multipart.FileHeader) (MessageFile, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return MessageFile{}, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MAX_FILE_SIZE+1))
+	if err != nil {
+		return MessageFile{}, err
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(fh.Header.Get(echo.HeaderContentType))
+	if mimeType == "" || mimeType == echo.MIMEOctetStream {
+		mimeType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	return MessageFile{
+		Name: fh.Filename,
+		Mime: mimeType,
+		Data: data,
+	}, nil
+}
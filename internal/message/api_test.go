@@ -1,6 +1,9 @@
 package message
 
 import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"testing"
 
@@ -235,6 +238,42 @@ func TestCreateMessageAPIEndpoint(t *testing.T) {
 			WantStatus:   http.StatusInternalServerError,
 			WantResponse: `There was a problem with your request. *`,
 		},
+		{
+			Name:         "success-as-reply",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         `{"body":"hello", "rid":"message_jti"}`,
+			Header:       nil,
+			WantStatus:   http.StatusAccepted,
+			WantResponse: ``,
+		},
+		{
+			Name:         "replied message not found",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         `{"body":"hello", "rid":"not_found_id"}`,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"replied message not found", "error":"Invalid input", "status":400}`,
+		},
+		{
+			Name:         "template not found",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         `{"template_id":"not_found_id", "variables":{"name":"john"}}`,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"template not found", "error":"Invalid input", "status":400}`,
+		},
+		{
+			Name:         "template too long",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         `{"template_id":"too_long", "variables":{"name":"john"}}`,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"body: the length must be no more than 4096", "error":"Invalid input", "status":400}`,
+		},
 		{
 			Name:         "success-with-objects",
 			Method:       "POST",
@@ -286,6 +325,106 @@ func TestCreateMessageAPIEndpoint(t *testing.T) {
 	}
 }
 
+func TestCreateMessageWithFilesAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsAdminMiddleware())
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	text := map[string]string{"note.txt": "hello"}
+	body, header := multipartBody(map[string]string{"body": "hello"}, text)
+	tooMany := map[string]string{}
+	for i := 0; i <= MAX_FILES; i++ {
+		tooMany[fmt.Sprintf("note%d.txt", i)] = "hello"
+	}
+	tooManyBody, tooManyHeader := multipartBody(map[string]string{"body": "hello"}, tooMany)
+	binaryBody, binaryHeader := multipartBody(map[string]string{"body": "hello"}, map[string]string{"app.bin": "\x00\x01\x02"})
+	scheduledBody, scheduledHeader := multipartBody(map[string]string{"body": "hello", "send_at": "2100-01-01T00:00:00Z"}, text)
+	blankBody, blankHeader := multipartBody(map[string]string{}, text)
+	templateBody, templateHeader := multipartBody(map[string]string{"body": "hello", "template_id": "tpl"}, text)
+
+	tests := []test.APITestCase{
+		{
+			Name:       "success",
+			Method:     "POST",
+			URL:        "/apps/app_id/connections/conn_id/messages",
+			Body:       body,
+			Header:     header,
+			WantStatus: http.StatusAccepted,
+		},
+		{
+			Name:         "too many files",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         tooManyBody,
+			Header:       tooManyHeader,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"files: the length must be no more than 5."}`,
+		},
+		{
+			Name:         "mime not allowed",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         binaryBody,
+			Header:       binaryHeader,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `*mime type application/octet-stream is not allowed*`,
+		},
+		{
+			Name:         "scheduled with files",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         scheduledBody,
+			Header:       scheduledHeader,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"options: scheduled messages cannot contain objects."}`,
+		},
+		{
+			Name:         "validation error",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         blankBody,
+			Header:       blankHeader,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"body: cannot be blank.", "error":"Invalid input", "status":400}`,
+		},
+		{
+			Name:         "unsupported field",
+			Method:       "POST",
+			URL:          "/apps/app_id/connections/conn_id/messages",
+			Body:         templateBody,
+			Header:       templateHeader,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"template_id: not supported in multipart requests."}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+// multipartBody builds a multipart/form-data body with the given values and
+// files, along with the headers to send it.
+func multipartBody(values map[string]string, files map[string]string) (string, http.Header) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	for k, v := range values {
+		_ = w.WriteField(k, v)
+	}
+	for name, content := range files {
+		part, _ := w.CreateFormFile("files", name)
+		_, _ = part.Write([]byte(content))
+	}
+	_ = w.Close()
+
+	header := http.Header{}
+	header.Set("Content-Type", w.FormDataContentType())
+	return b.String(), header
+}
+
 func TestDeleteMessageAPIEndpointAsPlainWithoutPermissions(t *testing.T) {
 	logger, _ := log.NewForTest()
 	router := test.MockRouter(logger)
@@ -438,3 +577,195 @@ func TestDeleteMessageAPIEndpoint(t *testing.T) {
 		test.Endpoint(t, router, tc)
 	}
 }
+
+func TestGetMessageAttachmentAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsAdminMiddleware())
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/messages/message_jti/attachments/0",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `*content*`,
+		},
+		{
+			Name:         "public attachment",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/messages/public/attachments/0",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusFound,
+			WantResponse: "",
+		},
+		{
+			Name:         "connection not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/not_found_id/messages/message_jti/attachments/0",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "invalid position",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/messages/message_jti/attachments/first",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "attachment not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/messages/not_found_id/attachments/0",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "retrieval error",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/messages/error/attachments/0",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusInternalServerError,
+			WantResponse: "",
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestSearchMessagesAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsAdminMiddleware())
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "GET",
+			URL:          "/apps/app_id/messages/search?q=hello&direction=inbound&from=0&to=1700000000",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"snippet":"\u003cmark\u003ehello\u003c/mark\u003e world"*`,
+		},
+		{
+			Name:         "missing query",
+			Method:       "GET",
+			URL:          "/apps/app_id/messages/search",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"q: cannot be blank.", "error":"Invalid input", "status":400}`,
+		},
+		{
+			Name:         "invalid direction",
+			Method:       "GET",
+			URL:          "/apps/app_id/messages/search?q=hello&direction=sideways",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"direction: must be a valid value.", "error":"Invalid input", "status":400}`,
+		},
+		{
+			Name:         "invalid from",
+			Method:       "GET",
+			URL:          "/apps/app_id/messages/search?q=hello&from=yesterday",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"from: must be a Unix timestamp.", "error":"Invalid input", "status":400}`,
+		},
+		{
+			Name:         "invalid to",
+			Method:       "GET",
+			URL:          "/apps/app_id/messages/search?q=hello&to=2024-01-01",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"details":"to: must be a Unix timestamp.", "error":"Invalid input", "status":400}`,
+		},
+		{
+			Name:         "count error",
+			Method:       "GET",
+			URL:          "/apps/app_id/messages/search?q=count_error",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusInternalServerError,
+			WantResponse: "",
+		},
+		{
+			Name:         "search error",
+			Method:       "GET",
+			URL:          "/apps/app_id/messages/search?q=query_error",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusInternalServerError,
+			WantResponse: "",
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestListMessageRevisionsAPIEndpoint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsAdminMiddleware())
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "success",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/messages/message_jti/revisions",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusOK,
+			WantResponse: `[{"iss":"me", "body":"original", "created_at":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			Name:         "connection not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/not_found_id/messages/message_jti/revisions",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "message not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/conn_id/messages/not_found_id/revisions",
+			Body:         ``,
+			Header:       nil,
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
@@ -2,10 +2,13 @@ package message
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 
 	"github.com/joinself/restful-client/internal/connection"
 	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/template"
+	"github.com/joinself/restful-client/pkg/worker"
 )
 
 type mockService struct{}
@@ -22,13 +25,13 @@ func (m mockService) Get(ctx context.Context, connectionID int, jti string) (Mes
 	}, nil
 }
 
-func (m mockService) Query(ctx context.Context, connection int, messagesSince int, offset, limit int) ([]Message, error) {
+func (m mockService) Query(ctx context.Context, connection int, messagesSince int, thread string, offset, limit int) ([]Message, error) {
 	if messagesSince == 98 {
 		return []Message{}, errors.New("expected error")
 	}
 	return []Message{}, nil
 }
-func (m mockService) Count(ctx context.Context, connectionID, messagesSince int) (int, error) {
+func (m mockService) Count(ctx context.Context, connectionID, messagesSince int, thread string) (int, error) {
 	if messagesSince == 99 {
 		return 0, errors.New("expected count error")
 	}
@@ -38,6 +41,15 @@ func (m mockService) Create(ctx context.Context, appID, connectionID string, con
 	if input.Body == "error" {
 		return Message{}, errors.New("error!")
 	}
+	if input.RID == "not_found_id" {
+		return Message{}, ErrRepliedMessageNotFound
+	}
+	if input.TemplateID == "not_found_id" {
+		return Message{}, template.ErrTemplateNotFound
+	}
+	if input.TemplateID == "too_long" {
+		return Message{}, ErrBodyTooLong
+	}
 	return Message{}, nil
 }
 func (m mockService) Update(ctx context.Context, appID string, connectionID int, selfID string, jti string, req UpdateMessageRequest) (Message, error) {
@@ -46,7 +58,7 @@ func (m mockService) Update(ctx context.Context, appID string, connectionID int,
 	}
 	return Message{}, nil
 }
-func (m mockService) Delete(ctx context.Context, connectionID int, jti string) error {
+func (m mockService) Delete(ctx context.Context, appID string, connectionID int, selfID string, jti string) error {
 	if jti == "error" {
 		return errors.New("error!")
 	}
@@ -59,6 +71,52 @@ func (m mockService) MarkAsReceived(ctx context.Context, appID, connection, jti
 	return nil
 }
 
+func (m mockService) GetAttachment(ctx context.Context, appID string, connectionID int, jti string, position int) (entity.MessageAttachment, []byte, error) {
+	switch jti {
+	case "not_found_id":
+		return entity.MessageAttachment{}, nil, sql.ErrNoRows
+	case "error":
+		return entity.MessageAttachment{}, nil, errors.New("error!")
+	case "public":
+		return entity.MessageAttachment{Public: true, Link: "https://example.com/file"}, nil, nil
+	}
+	return entity.MessageAttachment{Name: "file.txt", Mime: "text/plain"}, []byte("content"), nil
+}
+
+func (m mockService) Search(ctx context.Context, appID string, params entity.MessageSearch, offset, limit int) ([]SearchResult, error) {
+	if params.Query == "query_error" {
+		return nil, errors.New("error!")
+	}
+	return []SearchResult{{Message: Message{Body: "hello world"}, Connection: "selfid", Snippet: "<mark>hello</mark> world"}}, nil
+}
+
+func (m mockService) CountSearch(ctx context.Context, appID string, params entity.MessageSearch) (int, error) {
+	if params.Query == "count_error" {
+		return 0, errors.New("error!")
+	}
+	return 1, nil
+}
+
+func (m mockService) Revisions(ctx context.Context, connectionID int, jti string) ([]entity.MessageRevision, error) {
+	if jti == "not_found_id" {
+		return nil, errors.New("not found")
+	}
+	return []entity.MessageRevision{{ISS: "me", Body: "original"}}, nil
+}
+
+func (m mockService) Dispatch(ctx context.Context, task worker.ScheduledTask) error {
+	return nil
+}
+
+type mockTemplateRenderer struct{}
+
+func (m mockTemplateRenderer) Render(ctx context.Context, appID, id, selfID string, variables map[string]string) (string, error) {
+	if id != "welcome" {
+		return "", template.ErrTemplateNotFound
+	}
+	return "hello " + selfID + ", you owe " + variables["amount"], nil
+}
+
 type mockConnectionService struct{}
 
 func (m mockConnectionService) Get(ctx context.Context, appid, selfid string) (connection.Connection, error) {
@@ -3,7 +3,10 @@ package message
 import (
 	"context"
 	"errors"
-	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/joinself/restful-client/internal/entity"
@@ -15,16 +18,30 @@ import (
 type Repository interface {
 	// Get returns the message with the specified message ID.
 	Get(ctx context.Context, connectionID int, id string) (entity.Message, error)
+	// Search returns the messages of an app matching the given search params.
+	Search(ctx context.Context, appID string, params entity.MessageSearch, offset, limit int) ([]entity.MessageMatch, error)
+	// CountSearch returns the number of messages of an app matching the given search params.
+	CountSearch(ctx context.Context, appID string, params entity.MessageSearch) (int, error)
 	// Count returns the number of messages.
-	Count(ctx context.Context, connectionID, messagesSince int) (int, error)
+	Count(ctx context.Context, connectionID, messagesSince int, thread string) (int, error)
 	// Query returns the list of messages with the given offset and limit.
-	Query(ctx context.Context, connection int, messagesSince int, offset, limit int) ([]entity.Message, error)
+	Query(ctx context.Context, connection int, messagesSince int, thread string, offset, limit int) ([]entity.Message, error)
 	// Create saves a new message in the storage.
 	Create(ctx context.Context, message *entity.Message) error
 	// Update updates the message with given ID in the storage.
 	Update(ctx context.Context, message entity.Message) error
 	// Delete removes the message with given ID from the storage.
 	Delete(ctx context.Context, connectionID int, id string) error
+	// CreateAttachment saves a new message attachment in the storage.
+	CreateAttachment(ctx context.Context, attachment *entity.MessageAttachment) error
+	// UpdateAttachment updates the given message attachment in the storage.
+	UpdateAttachment(ctx context.Context, attachment entity.MessageAttachment) error
+	// Attachments returns the list of attachments for the given message.
+	Attachments(ctx context.Context, messageID int) ([]entity.MessageAttachment, error)
+	// Edit updates the message and saves its previous version in the storage.
+	Edit(ctx context.Context, message entity.Message, revision *entity.MessageRevision) error
+	// Revisions returns the previous versions of the given message.
+	Revisions(ctx context.Context, messageID int) ([]entity.MessageRevision, error)
 }
 
 // repository persists messages in database
@@ -72,40 +89,210 @@ func (r repository) Delete(ctx context.Context, connectionID int, jti string) er
 	if err != nil {
 		return err
 	}
-	return r.db.With(ctx).Model(&message).Delete()
+
+	attachments, err := r.Attachments(ctx, message.ID)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.Transactional(ctx, func(ctx context.Context) error {
+		_, err := r.db.With(ctx).Delete("message_attachment", dbx.HashExp{"message_id": message.ID}).Execute()
+		if err != nil {
+			return err
+		}
+
+		_, err = r.db.With(ctx).Delete("message_revision", dbx.HashExp{"message_id": message.ID}).Execute()
+		if err != nil {
+			return err
+		}
+
+		return r.db.With(ctx).Model(&message).Delete()
+	})
+	if err != nil {
+		return err
+	}
+
+	// The files are only removed once the rows are gone for good.
+	r.removeAttachments(ctx, attachments)
+	return nil
 }
 
 // Count returns the number of the message records in the database.
-func (r repository) Count(ctx context.Context, connectionID, messagesSince int) (int, error) {
+func (r repository) Count(ctx context.Context, connectionID, messagesSince int, thread string) (int, error) {
 	var count int
-	exp := dbx.NewExp("connection_id={:id}", dbx.Params{"id": connectionID})
-	if messagesSince > 0 {
-		exp = dbx.And(dbx.HashExp{"connection_id": connectionID}, dbx.NewExp(fmt.Sprintf("id>%d", messagesSince)))
-	}
-
 	err := r.db.With(ctx).
 		Select("COUNT(*)").
 		From("message").
-		Where(exp).
+		Where(messagesExp(connectionID, messagesSince, thread)).
 		Row(&count)
 	return count, err
 }
 
 // Query retrieves the message records with the specified offset and limit from the database.
-func (r repository) Query(ctx context.Context, connection int, messagesSince int, offset, limit int) ([]entity.Message, error) {
+// Thread messages are returned in the order they were created, the rest are
+// returned newest first.
+func (r repository) Query(ctx context.Context, connection int, messagesSince int, thread string, offset, limit int) ([]entity.Message, error) {
 	var messages []entity.Message
-	exp := dbx.NewExp("connection_id={:id}", dbx.Params{"id": connection})
-	if messagesSince > 0 {
-		exp = dbx.And(dbx.HashExp{"connection_id": connection}, dbx.NewExp(fmt.Sprintf("id>%d", messagesSince)))
+
+	order := "created_at DESC"
+	if thread != "" {
+		order = "id"
 	}
 
 	err := r.db.With(ctx).
 		Select().
-		OrderBy("id").
-		Where(exp).
+		Where(messagesExp(connection, messagesSince, thread)).
 		Offset(int64(offset)).
 		Limit(int64(limit)).
-		OrderBy("created_at DESC").
+		OrderBy(order).
 		All(&messages)
 	return messages, err
 }
+
+// messagesExp builds the expression to filter the messages of a connection.
+func messagesExp(connection int, messagesSince int, thread string) dbx.Expression {
+	exp := dbx.And(dbx.HashExp{"connection_id": connection})
+	if messagesSince > 0 {
+		exp = dbx.And(exp, dbx.NewExp("id>{:since}", dbx.Params{"since": messagesSince}))
+	}
+	if thread != "" {
+		exp = dbx.And(exp, dbx.HashExp{"cid": thread})
+	}
+	return exp
+}
+
+// removeAttachments removes the downloaded content of the given attachments
+// from the local storage.
+func (r repository) removeAttachments(ctx context.Context, attachments []entity.MessageAttachment) {
+	for _, a := range attachments {
+		if a.Path == "" {
+			continue
+		}
+		err := os.Remove(a.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			r.logger.With(ctx).Warnf("error removing message attachment: %v", err)
+			continue
+		}
+		// The attachments of a message share a directory, which is removed
+		// once it's empty.
+		_ = os.Remove(filepath.Dir(a.Path))
+	}
+}
+
+// CreateAttachment saves a new message attachment record in the database.
+func (r repository) CreateAttachment(ctx context.Context, attachment *entity.MessageAttachment) error {
+	return r.db.With(ctx).Model(attachment).Insert()
+}
+
+// UpdateAttachment saves the changes to a message attachment in the database.
+func (r repository) UpdateAttachment(ctx context.Context, attachment entity.MessageAttachment) error {
+	return r.db.With(ctx).Model(&attachment).Update()
+}
+
+// Attachments retrieves the attachment records of the given message ordered by position.
+func (r repository) Attachments(ctx context.Context, messageID int) ([]entity.MessageAttachment, error) {
+	var attachments []entity.MessageAttachment
+	err := r.db.With(ctx).
+		Select().
+		Where(&dbx.HashExp{"message_id": messageID}).
+		OrderBy("position").
+		All(&attachments)
+	return attachments, err
+}
+
+// Edit updates the message and saves its previous version in the database,
+// in a transaction so no revision is kept for a failed update.
+func (r repository) Edit(ctx context.Context, message entity.Message, revision *entity.MessageRevision) error {
+	return r.db.Transactional(ctx, func(ctx context.Context) error {
+		err := r.db.With(ctx).Model(&message).Update()
+		if err != nil {
+			return err
+		}
+		return r.db.With(ctx).Model(revision).Insert()
+	})
+}
+
+// Revisions retrieves the previous versions of a message, oldest first.
+func (r repository) Revisions(ctx context.Context, messageID int) ([]entity.MessageRevision, error) {
+	var revisions []entity.MessageRevision
+	err := r.db.With(ctx).
+		Select().
+		Where(&dbx.HashExp{"message_id": messageID}).
+		OrderBy("id").
+		All(&revisions)
+	return revisions, err
+}
+
+// Search retrieves the messages of an app matching the given search params,
+// best matches first.
+func (r repository) Search(ctx context.Context, appID string, params entity.MessageSearch, offset, limit int) ([]entity.MessageMatch, error) {
+	var matches []entity.MessageMatch
+	err := r.searchQuery(ctx, appID, params, "message.*, connection.selfid, "+
+		"snippet(message_fts, 0, char("+markStart+"), char("+markEnd+"), '...', 16) AS snippet").
+		OrderBy("message_fts.rank").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&matches)
+	for i := range matches {
+		matches[i].Snippet = highlight(matches[i].Snippet)
+	}
+	return matches, err
+}
+
+// The matches are delimited in the snippets with control characters, which
+// are replaced with mark tags once the message body is escaped.
+const (
+	markStart = "2"
+	markEnd   = "3"
+)
+
+// highlight escapes the given snippet and wraps its matches in mark tags.
+func highlight(snippet string) string {
+	return strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// CountSearch returns the number of messages of an app matching the given
+// search params.
+func (r repository) CountSearch(ctx context.Context, appID string, params entity.MessageSearch) (int, error) {
+	var count int
+	err := r.searchQuery(ctx, appID, params, "COUNT(*)").Row(&count)
+	return count, err
+}
+
+func (r repository) searchQuery(ctx context.Context, appID string, params entity.MessageSearch, columns ...string) *dbx.SelectQuery {
+	q := r.db.With(ctx).
+		Select(columns...).
+		From("message_fts").
+		InnerJoin("message", dbx.NewExp("message.id = message_fts.rowid")).
+		InnerJoin("connection", dbx.NewExp("connection.id = message.connection_id")).
+		Where(dbx.NewExp("message_fts MATCH {:query}", dbx.Params{"query": ftsQuery(params.Query)})).
+		AndWhere(dbx.HashExp{"connection.appid": appID})
+
+	if params.Connection != "" {
+		q = q.AndWhere(dbx.HashExp{"connection.selfid": params.Connection})
+	}
+	switch params.Direction {
+	case entity.MESSAGE_DIRECTION_INBOUND:
+		q = q.AndWhere(dbx.NewExp("message.iss != 'me'"))
+	case entity.MESSAGE_DIRECTION_OUTBOUND:
+		q = q.AndWhere(dbx.HashExp{"message.iss": "me"})
+	}
+	if !params.From.IsZero() {
+		q = q.AndWhere(dbx.NewExp("message.created_at >= {:from}", dbx.Params{"from": params.From}))
+	}
+	if !params.To.IsZero() {
+		q = q.AndWhere(dbx.NewExp("message.created_at <= {:to}", dbx.Params{"to": params.To}))
+	}
+
+	return q
+}
+
+// ftsQuery quotes every term of the given text so it's matched literally
+// instead of being parsed as an FTS5 query expression.
+func ftsQuery(text string) string {
+	terms := strings.Fields(text)
+	for i, t := range terms {
+		terms[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
@@ -4,19 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/internal/test"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRepository(t *testing.T) {
 	logger, _ := log.NewForTest()
 	db := test.DB(t)
-	test.ResetTables(t, db, "message")
+	test.ResetTables(t, db, "message_revision", "message_attachment", "message")
 	repo := NewRepository(db, logger)
 
 	ctx := context.Background()
@@ -24,7 +30,7 @@ func TestRepository(t *testing.T) {
 	connection := rand.Intn(99999999)
 
 	// initial count
-	count, err := repo.Count(ctx, connection, 0)
+	count, err := repo.Count(ctx, connection, 0, "")
 	assert.Nil(t, err)
 
 	// create a new connection
@@ -42,13 +48,35 @@ func TestRepository(t *testing.T) {
 	}
 	err = repo.Create(ctx, &msg)
 	assert.Nil(t, err)
-	count2, _ := repo.Count(ctx, connection, 0)
+	count2, _ := repo.Count(ctx, connection, 0, "")
 	assert.Equal(t, 1, count2-count)
 
 	// get
 	message, err := repo.Get(ctx, connection, msg.JTI)
 	assert.Nil(t, err)
 	assert.Equal(t, "message1", message.Body)
+	assert.Nil(t, message.SendAt)
+
+	// scheduled
+	sendAt := time.Now().Add(time.Hour)
+	scheduled := entity.Message{
+		ConnectionID: connection,
+		Body:         "scheduled",
+		JTI:          "jti-scheduled",
+		Status:       entity.MESSAGE_SCHEDULED_STATUS,
+		SendAt:       &sendAt,
+		IAT:          time.Now(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	err = repo.Create(ctx, &scheduled)
+	assert.Nil(t, err)
+	scheduled, err = repo.Get(ctx, connection, scheduled.JTI)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.MESSAGE_SCHEDULED_STATUS, scheduled.Status)
+	assert.NotNil(t, scheduled.SendAt)
+	assert.True(t, sendAt.Equal(*scheduled.SendAt))
+	count2++
 	_, err = repo.Get(ctx, connection, "0")
 	assert.Equal(t, sql.ErrNoRows, err)
 
@@ -67,15 +95,186 @@ func TestRepository(t *testing.T) {
 	assert.Equal(t, "message1 updated", message.Body)
 
 	// query
-	messages, err := repo.Query(ctx, connection, 0, 0, count2)
+	messages, err := repo.Query(ctx, connection, 0, "", 0, count2)
 	assert.Nil(t, err)
 	assert.Equal(t, count2, len(messages))
 
+	// thread
+	reply := entity.Message{
+		ConnectionID: connection,
+		Body:         "reply",
+		CID:          "thread",
+		JTI:          "jti-reply",
+		RID:          msg.JTI,
+		IAT:          time.Now(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	err = repo.Create(ctx, &reply)
+	assert.Nil(t, err)
+	thread, err := repo.Query(ctx, connection, 0, "thread", 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(thread))
+	assert.Equal(t, msg.JTI, thread[0].RID)
+	threadCount, _ := repo.Count(ctx, connection, 0, "thread")
+	assert.Equal(t, 1, threadCount)
+
+	// attachments
+	path := filepath.Join(t.TempDir(), "0")
+	require.NoError(t, os.WriteFile(path, []byte("content"), 0644))
+	attachment := entity.MessageAttachment{
+		MessageID: msg.ID,
+		Position:  0,
+		Name:      "file.txt",
+		Path:      path,
+		Status:    entity.ATTACHMENT_PENDING_STATUS,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	err = repo.CreateAttachment(ctx, &attachment)
+	assert.Nil(t, err)
+	attachment.Status = entity.ATTACHMENT_DOWNLOADED_STATUS
+	err = repo.UpdateAttachment(ctx, attachment)
+	assert.Nil(t, err)
+	attachments, err := repo.Attachments(ctx, msg.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(attachments))
+	assert.Equal(t, entity.ATTACHMENT_DOWNLOADED_STATUS, attachments[0].Status)
+
+	// revisions
+	revision := entity.MessageRevision{
+		MessageID: msg.ID,
+		Body:      msg.Body,
+		CreatedAt: time.Now(),
+	}
+	msg.Body = "message2"
+	err = repo.Edit(ctx, msg, &revision)
+	assert.Nil(t, err)
+	revisions, err := repo.Revisions(ctx, msg.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(revisions))
+	assert.Equal(t, "message1", revisions[0].Body)
+	msg, err = repo.Get(ctx, connection, msg.JTI)
+	assert.Nil(t, err)
+	assert.Equal(t, "message2", msg.Body)
+
+	// failed revisions roll the update back
+	edited := msg
+	edited.Body = "message3"
+	err = repo.Edit(ctx, edited, &revisions[0])
+	assert.NotNil(t, err)
+	msg, err = repo.Get(ctx, connection, msg.JTI)
+	assert.Nil(t, err)
+	assert.Equal(t, "message2", msg.Body)
+	revisions, err = repo.Revisions(ctx, msg.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(revisions))
+
 	// delete
 	err = repo.Delete(ctx, connection, msg.JTI)
 	assert.Nil(t, err)
 	_, err = repo.Get(ctx, connection, msg.JTI)
 	assert.Equal(t, sql.ErrNoRows, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
 	err = repo.Delete(ctx, connection, msg.JTI)
 	assert.Equal(t, sql.ErrNoRows, err)
 }
+
+func TestRepositorySearch(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "message_revision", "message_attachment", "message")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	connection := rand.Intn(99999999)
+	err := test.CreateConnection(ctx, db, connection)
+	assert.Nil(t, err)
+	appID := "app_" + strconv.Itoa(connection)
+
+	for i, body := range []string{"where is my parcel", "your parcel is on its way", "thanks!"} {
+		iss := "me"
+		if i%2 == 0 {
+			iss = "connection"
+		}
+		err = repo.Create(ctx, &entity.Message{
+			ConnectionID: connection,
+			ISS:          iss,
+			Body:         body,
+			JTI:          "jti" + strconv.Itoa(i),
+			IAT:          time.Now(),
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		assert.Nil(t, err)
+	}
+
+	count, err := repo.CountSearch(ctx, appID, entity.MessageSearch{Query: "parcel"})
+	if err != nil && strings.Contains(err.Error(), "no such module") {
+		t.Skip("sqlite driver built without FTS5 support")
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	// search
+	matches, err := repo.Search(ctx, appID, entity.MessageSearch{Query: "parcel"}, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(matches))
+	assert.Contains(t, matches[0].Snippet, "<mark>parcel</mark>")
+	assert.Equal(t, "connection_"+strconv.Itoa(connection), matches[0].Connection)
+
+	// direction
+	matches, err = repo.Search(ctx, appID, entity.MessageSearch{Query: "parcel", Direction: entity.MESSAGE_DIRECTION_OUTBOUND}, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+	assert.Equal(t, "your parcel is on its way", matches[0].Body)
+
+	// date range
+	count, err = repo.CountSearch(ctx, appID, entity.MessageSearch{Query: "parcel", To: time.Now().Add(-time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// other apps
+	count, err = repo.CountSearch(ctx, "other", entity.MessageSearch{Query: "parcel"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// updated and deleted messages
+	message, _ := repo.Get(ctx, connection, "jti1")
+	message.Body = "delivered"
+	err = repo.Update(ctx, message)
+	assert.Nil(t, err)
+	err = repo.Delete(ctx, connection, "jti0")
+	assert.Nil(t, err)
+	count, err = repo.CountSearch(ctx, appID, entity.MessageSearch{Query: "parcel"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// messages removed outside the repository, like the clean job does
+	_, err = db.DB().Delete("message", dbx.HashExp{"jti": "jti1"}).Execute()
+	assert.Nil(t, err)
+	count, err = repo.CountSearch(ctx, appID, entity.MessageSearch{Query: "delivered"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+	var orphans int
+	err = db.DB().NewQuery("SELECT COUNT(*) FROM message_fts WHERE rowid NOT IN (SELECT id FROM message)").Row(&orphans)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, orphans)
+
+	// snippets are escaped
+	err = repo.Create(ctx, &entity.Message{
+		ConnectionID: connection,
+		ISS:          "connection",
+		Body:         "<b>parcel</b> & co",
+		JTI:          "jti-html",
+		IAT:          time.Now(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	})
+	assert.Nil(t, err)
+	matches, err = repo.Search(ctx, appID, entity.MessageSearch{Query: "parcel"}, 0, 10)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(matches))
+	assert.Equal(t, "&lt;b&gt;<mark>parcel</mark>&lt;/b&gt; &amp; co", matches[0].Snippet)
+}
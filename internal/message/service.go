@@ -2,39 +2,78 @@ package message
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/support"
+	"github.com/joinself/restful-client/pkg/worker"
 	"github.com/joinself/self-go-sdk/chat"
 )
 
+// TASK_SEND_MESSAGE is the type of the scheduled tasks sending messages.
+const TASK_SEND_MESSAGE = "send_message"
+
+// ErrMessageNotScheduled is returned when rescheduling a message that is
+// not scheduled.
+var ErrMessageNotScheduled = errors.New("message is not scheduled")
+
+// ErrRepliedMessageNotFound is returned when replying to a message that
+// does not exist on the connection.
+var ErrRepliedMessageNotFound = errors.New("replied message not found")
+
+// ErrBodyTooLong is returned when the body rendered from a template is
+// longer than MAX_BODY_LENGTH.
+var ErrBodyTooLong = fmt.Errorf("body: the length must be no more than %d", MAX_BODY_LENGTH)
+
 // Service encapsulates usecase logic for messages.
 type Service interface {
 	Get(ctx context.Context, connectionID int, jti string) (Message, error)
-	Query(ctx context.Context, connection int, messagesSince int, offset, limit int) ([]Message, error)
-	Count(ctx context.Context, connectionID, messagesSince int) (int, error)
+	Query(ctx context.Context, connection int, messagesSince int, thread string, offset, limit int) ([]Message, error)
+	Count(ctx context.Context, connectionID, messagesSince int, thread string) (int, error)
 	Create(ctx context.Context, appID, connectionID string, connection int, input CreateMessageRequest) (Message, error)
 	Update(ctx context.Context, appID string, connectionID int, selfID string, jti string, req UpdateMessageRequest) (Message, error)
-	Delete(ctx context.Context, connectionID int, jti string) error
+	Delete(ctx context.Context, appID string, connectionID int, selfID string, jti string) error
+	Revisions(ctx context.Context, connectionID int, jti string) ([]entity.MessageRevision, error)
+	Dispatch(ctx context.Context, task worker.ScheduledTask) error
 	MarkAsRead(ctx context.Context, appID, connection, jti string, connectionID int) error
 	MarkAsReceived(ctx context.Context, appID, connection, jti string, connectionID int) error
+	GetAttachment(ctx context.Context, appID string, connectionID int, jti string, position int) (entity.MessageAttachment, []byte, error)
+	Search(ctx context.Context, appID string, params entity.MessageSearch, offset, limit int) ([]SearchResult, error)
+	CountSearch(ctx context.Context, appID string, params entity.MessageSearch) (int, error)
 }
 
 // Message represents the data about an message.
 type Message struct {
-	ID           string    `json:"id"`
-	ConnectionID string    `json:"connection_id"`
-	CID          string    `json:"cid"`
-	RID          string    `json:"rid"`
-	Body         string    `json:"body"`
-	IAT          time.Time `json:"iat"`
-	Read         bool      `json:"read"`
-	Received     bool      `json:"received"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string     `json:"id"`
+	ConnectionID string     `json:"connection_id"`
+	CID          string     `json:"cid"`
+	RID          string     `json:"rid"`
+	Body         string     `json:"body"`
+	IAT          time.Time  `json:"iat"`
+	Read         bool       `json:"read"`
+	Received     bool       `json:"received"`
+	Status       string     `json:"status,omitempty"`
+	SendAt       *time.Time `json:"send_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	Content     *MessageContent            `json:"content,omitempty"`
+	Attachments []entity.MessageAttachment `json:"attachments,omitempty"`
+}
+
+// SearchResult represents a message matching a search.
+type SearchResult struct {
+	Message
+	Connection string `json:"connection"`
+	Snippet    string `json:"snippet"`
 }
 
 func newMessageFromEntity(m entity.Message) Message {
@@ -47,21 +86,58 @@ func newMessageFromEntity(m entity.Message) Message {
 		IAT:          m.IAT,
 		Read:         m.Read,
 		Received:     m.Received,
+		Status:       m.Status,
+		SendAt:       m.SendAt,
 		CreatedAt:    m.CreatedAt,
 		UpdatedAt:    m.UpdatedAt,
+		Attachments:  m.Attachments,
+		Content:      decodeContent(m.Content),
+	}
+}
+
+// decodeContent returns the structured content stored with a message, if
+// any.
+func decodeContent(data []byte) *MessageContent {
+	if len(data) == 0 {
+		return nil
 	}
+
+	var content MessageContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil
+	}
+	return &content
 }
 
 // CreateMessageRequest represents an message creation request.
 type service struct {
-	repo   Repository
-	runner support.SelfClientGetter
-	logger log.Logger
+	repo      Repository
+	runner    support.SelfClientGetter
+	scheduler Scheduler
+	templates TemplateRenderer
+	logger    log.Logger
+}
+
+// Scheduler queues tasks to be run at a given time.
+type Scheduler interface {
+	Schedule(task worker.ScheduledTask, at time.Time) error
+}
+
+// TemplateRenderer builds message bodies from the templates of an app.
+type TemplateRenderer interface {
+	Render(ctx context.Context, appID, id, selfID string, variables map[string]string) (string, error)
+}
+
+// scheduledMessage is the payload of the task sending a scheduled message.
+type scheduledMessage struct {
+	ConnectionID int    `json:"connection_id"`
+	Connection   string `json:"connection"`
+	JTI          string `json:"jti"`
 }
 
 // NewService creates a new message service.
-func NewService(repo Repository, runner support.SelfClientGetter, logger log.Logger) Service {
-	return service{repo, runner, logger}
+func NewService(repo Repository, runner support.SelfClientGetter, scheduler Scheduler, templates TemplateRenderer, logger log.Logger) Service {
+	return service{repo, runner, scheduler, templates, logger}
 }
 
 // Get returns the message with the specified the message ID.
@@ -70,6 +146,12 @@ func (s service) Get(ctx context.Context, connectionID int, jti string) (Message
 	if err != nil {
 		return Message{}, err
 	}
+
+	message.Attachments, err = s.repo.Attachments(ctx, message.ID)
+	if err != nil {
+		return Message{}, err
+	}
+
 	return newMessageFromEntity(message), nil
 }
 
@@ -77,23 +159,77 @@ func (s service) Get(ctx context.Context, connectionID int, jti string) (Message
 func (s service) Create(ctx context.Context, appID, selfID string, connection int, req CreateMessageRequest) (Message, error) {
 	now := time.Now()
 
-	cid := uuid.New().String()
+	if len(req.TemplateID) > 0 {
+		body, err := s.templates.Render(ctx, appID, req.TemplateID, selfID, req.Variables)
+		if err != nil {
+			return Message{}, err
+		}
+		// The variables are not limited, so the rendered body is checked
+		// like the ones sent directly.
+		if utf8.RuneCountInString(body) > MAX_BODY_LENGTH {
+			return Message{}, ErrBodyTooLong
+		}
+		req.Body = body
+	}
+
+	cid := req.CID
+	if len(req.RID) > 0 {
+		replied, err := s.repo.Get(ctx, connection, req.RID)
+		if err != nil {
+			return Message{}, ErrRepliedMessageNotFound
+		}
+		if len(cid) == 0 {
+			cid = replied.CID
+		}
+	}
+	if len(cid) == 0 {
+		cid = uuid.New().String()
+	}
+
+	var content []byte
+	if req.Content != nil {
+		var err error
+		content, err = json.Marshal(req.Content)
+		if err != nil {
+			return Message{}, err
+		}
+	}
+
 	jti := uuid.New().String()
 	msg := entity.Message{
 		ISS:          "me",
 		ConnectionID: connection,
 		CID:          cid,
 		JTI:          jti,
-		// RID:          req.RID,
-		Body: req.Body,
-		// IAT:          req.IAT,
-		IAT:       now,
-		CreatedAt: now,
-		UpdatedAt: now,
+		RID:          req.RID,
+		Body:         req.Body,
+		Content:      content,
+		Status:       entity.MESSAGE_SENT_STATUS,
+		IAT:          now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	// Scheduled messages are stored and sent later by the scheduler.
+	if req.SendAt != nil {
+		msg.Status = entity.MESSAGE_SCHEDULED_STATUS
+		msg.SendAt = req.SendAt
+
+		err := s.repo.Create(ctx, &msg)
+		if err != nil {
+			return Message{}, err
+		}
+
+		err = s.schedule(appID, selfID, msg)
+		if err != nil {
+			return Message{}, err
+		}
+
+		return s.Get(ctx, connection, msg.JTI)
 	}
 
 	// Send the message to the connection.
-	m, err := s.sendMessage(appID, selfID, req)
+	m, err := s.sendMessage(appID, selfID, "", req)
 	if err != nil {
 		return Message{}, err
 	}
@@ -116,36 +252,158 @@ func (s service) Update(ctx context.Context, appID string, connectionID int, sel
 		return Message{}, err
 	}
 
+	scheduled := message.Status == entity.MESSAGE_SCHEDULED_STATUS
+	if req.SendAt != nil && !scheduled {
+		return newMessageFromEntity(message), ErrMessageNotScheduled
+	}
+
+	revision := entity.MessageRevision{
+		MessageID: message.ID,
+		ISS:       message.ISS,
+		Body:      message.Body,
+		CreatedAt: time.Now(),
+	}
+	edited := message.Body != req.Body
+
 	message.Body = req.Body
 	message.UpdatedAt = time.Now()
+	if req.SendAt != nil {
+		message.SendAt = req.SendAt
+	}
 
-	if err := s.repo.Update(ctx, message); err != nil {
+	if edited {
+		err = s.repo.Edit(ctx, message, &revision)
+	} else {
+		err = s.repo.Update(ctx, message)
+	}
+	if err != nil {
 		return newMessageFromEntity(message), err
 	}
 
-	s.updateMessage(appID, selfID, message.JTI, req.Body)
+	// Scheduled messages have not been sent yet, so the changes are only
+	// applied locally.
+	if !scheduled {
+		s.updateMessage(appID, selfID, message.JTI, req.Body)
+	} else if req.SendAt != nil {
+		if err := s.schedule(appID, selfID, message); err != nil {
+			return newMessageFromEntity(message), err
+		}
+	}
 
 	return newMessageFromEntity(message), nil
 }
 
-// Delete deletes the message with the specified ID.
-func (s service) Delete(ctx context.Context, connectionID int, jti string) error {
-	return s.repo.Delete(ctx, connectionID, jti)
+// Dispatch sends a scheduled message when it's due. Cancelled, already sent
+// and rescheduled messages are ignored.
+func (s service) Dispatch(ctx context.Context, task worker.ScheduledTask) error {
+	var sm scheduledMessage
+	if err := json.Unmarshal(task.Payload, &sm); err != nil {
+		s.logger.With(ctx).Warnf("invalid scheduled message: %v", err)
+		return nil
+	}
+
+	message, err := s.repo.Get(ctx, sm.ConnectionID, sm.JTI)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if message.Status != entity.MESSAGE_SCHEDULED_STATUS {
+		return nil
+	}
+	if message.SendAt != nil && message.SendAt.After(time.Now().Add(time.Second)) {
+		return nil
+	}
+
+	// The message is sent with its jti, so its id doesn't change once sent.
+	m, err := s.sendMessage(task.AppID, sm.Connection, message.JTI, CreateMessageRequest{
+		Body:    message.Body,
+		RID:     message.RID,
+		Content: decodeContent(message.Content),
+	})
+	if err == nil && m == nil {
+		err = errors.New("app not running")
+	}
+	if err != nil {
+		return s.failDispatch(ctx, message, err)
+	}
+
+	now := time.Now()
+	message.Status = entity.MESSAGE_SENT_STATUS
+	message.IAT = now
+	message.UpdatedAt = now
+	if err := s.repo.Update(ctx, message); err != nil {
+		// The message is not retried, as it has already been sent.
+		s.logger.With(ctx).Warnf("error updating sent message %s: %v", message.JTI, err)
+	}
+	return nil
+}
+
+// failDispatch returns the error the scheduled message could not be sent
+// with, so it's retried, until the retry period is over and the message is
+// marked as errored.
+func (s service) failDispatch(ctx context.Context, message entity.Message, err error) error {
+	due := message.CreatedAt
+	if message.SendAt != nil {
+		due = *message.SendAt
+	}
+	if time.Since(due) < worker.RetryPeriod {
+		return err
+	}
+
+	s.logger.With(ctx).Warnf("giving up on scheduled message %s: %v", message.JTI, err)
+	message.Status = entity.MESSAGE_ERRORED_STATUS
+	message.UpdatedAt = time.Now()
+	return s.repo.Update(ctx, message)
+}
+
+// Delete removes the message with the given jti, messages sent by the app
+// are also deleted for the recipient.
+func (s service) Delete(ctx context.Context, appID string, connectionID int, selfID string, jti string) error {
+	message, err := s.repo.Get(ctx, connectionID, jti)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, connectionID, jti); err != nil {
+		return err
+	}
+
+	if message.ISS == "me" && message.Status != entity.MESSAGE_SCHEDULED_STATUS {
+		s.deleteMessage(appID, selfID, message.JTI)
+	}
+
+	return nil
+}
+
+// Revisions returns the previous versions of the message with the given jti.
+func (s service) Revisions(ctx context.Context, connectionID int, jti string) ([]entity.MessageRevision, error) {
+	message, err := s.repo.Get(ctx, connectionID, jti)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.Revisions(ctx, message.ID)
 }
 
 // Count returns the number of messages.
-func (s service) Count(ctx context.Context, connectionID, messagesSince int) (int, error) {
-	return s.repo.Count(ctx, connectionID, messagesSince)
+func (s service) Count(ctx context.Context, connectionID, messagesSince int, thread string) (int, error) {
+	return s.repo.Count(ctx, connectionID, messagesSince, thread)
 }
 
 // Query returns the messages with the specified offset and limit.
-func (s service) Query(ctx context.Context, connection int, messagesSince int, offset, limit int) ([]Message, error) {
-	items, err := s.repo.Query(ctx, connection, messagesSince, offset, limit)
+func (s service) Query(ctx context.Context, connection int, messagesSince int, thread string, offset, limit int) ([]Message, error) {
+	items, err := s.repo.Query(ctx, connection, messagesSince, thread, offset, limit)
 	if err != nil {
 		return nil, err
 	}
 	result := []Message{}
 	for _, item := range items {
+		item.Attachments, err = s.repo.Attachments(ctx, item.ID)
+		if err != nil {
+			return nil, err
+		}
 		result = append(result, newMessageFromEntity(item))
 	}
 	return result, nil
@@ -190,12 +448,84 @@ func (s service) MarkAsReceived(ctx context.Context, appID, connection, jti stri
 	return nil
 }
 
-func (s service) sendMessage(appID, connection string, req CreateMessageRequest) (*chat.Message, error) {
+// Search returns the messages of an app matching the given search params.
+func (s service) Search(ctx context.Context, appID string, params entity.MessageSearch, offset, limit int) ([]SearchResult, error) {
+	matches, err := s.repo.Search(ctx, appID, params, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []SearchResult{}
+	for _, m := range matches {
+		result = append(result, SearchResult{
+			Message:    newMessageFromEntity(m.Message),
+			Connection: m.Connection,
+			Snippet:    m.Snippet,
+		})
+	}
+	return result, nil
+}
+
+// CountSearch returns the number of messages of an app matching the given
+// search params.
+func (s service) CountSearch(ctx context.Context, appID string, params entity.MessageSearch) (int, error) {
+	return s.repo.CountSearch(ctx, appID, params)
+}
+
+// GetAttachment returns the attachment on the given position of a message
+// along with its content. The content is read from the local storage when
+// it has already been downloaded, otherwise it's fetched through the Self
+// network. Public attachments are returned without content.
+func (s service) GetAttachment(ctx context.Context, appID string, connectionID int, jti string, position int) (entity.MessageAttachment, []byte, error) {
+	message, err := s.repo.Get(ctx, connectionID, jti)
+	if err != nil {
+		return entity.MessageAttachment{}, nil, err
+	}
+
+	attachments, err := s.repo.Attachments(ctx, message.ID)
+	if err != nil {
+		return entity.MessageAttachment{}, nil, err
+	}
+
+	for _, a := range attachments {
+		if a.Position != position {
+			continue
+		}
+
+		if a.Public {
+			return a, nil, nil
+		}
+
+		if len(a.Path) > 0 {
+			content, err := os.ReadFile(a.Path)
+			if err == nil {
+				return a, content, nil
+			}
+			s.logger.With(ctx).Infof("error reading stored attachment, downloading it: %v", err)
+		}
+
+		client, ok := s.runner.Get(appID)
+		if !ok {
+			return a, nil, errors.New("app not found")
+		}
+
+		content, err := support.DownloadObject(client, a.Link, a.Key, a.Digest)
+		return a, content, err
+	}
+
+	return entity.MessageAttachment{}, nil, sql.ErrNoRows
+}
+
+func (s service) sendMessage(appID, connection, jti string, req CreateMessageRequest) (*chat.Message, error) {
 	client, ok := s.runner.Get(appID)
 	if !ok {
 		return nil, nil
 	}
 
+	opts := chat.MessageOptions{
+		JTI: jti,
+		RID: req.RID,
+	}
 	if len(req.Options.Objects) > 0 {
 		objects := make([]chat.MessageObject, len(req.Options.Objects))
 		for i, o := range req.Options.Objects {
@@ -208,11 +538,36 @@ func (s service) sendMessage(appID, connection string, req CreateMessageRequest)
 			}
 		}
 
-		return client.ChatService().Message([]string{connection}, req.Body, chat.MessageOptions{
-			Objects: objects,
+		opts.Objects = objects
+	}
+	for _, f := range req.Files {
+		obj, err := client.NewObject(f.Data, f.Name, f.Mime)
+		if err != nil {
+			return nil, fmt.Errorf("building object for %s: %w", f.Name, err)
+		}
+		opts.Objects = append(opts.Objects, chat.MessageObject{
+			Link:    obj.Link,
+			Name:    obj.Name,
+			Mime:    obj.Mime,
+			Key:     obj.Key,
+			Expires: obj.Expires,
 		})
 	}
-	return client.ChatService().Message([]string{connection}, req.Body)
+
+	body := req.Body
+	if req.Content != nil {
+		body = req.Content.Text(body)
+	}
+	return client.ChatService().Message([]string{connection}, body, sendOptions(opts)...)
+}
+
+// sendOptions returns the options to send a message with, empty options are
+// not sent as the SDK would add an empty objects list to the message.
+func sendOptions(opts chat.MessageOptions) []chat.MessageOptions {
+	if opts.JTI == "" && opts.RID == "" && len(opts.Objects) == 0 {
+		return nil
+	}
+	return []chat.MessageOptions{opts}
 }
 
 func (s service) updateMessage(appID, connection, jti, body string) {
@@ -228,3 +583,33 @@ func (s service) updateMessage(appID, connection, jti, body string) {
 		"",
 	)
 }
+
+func (s service) schedule(appID, connection string, message entity.Message) error {
+	payload, err := json.Marshal(scheduledMessage{
+		ConnectionID: message.ConnectionID,
+		Connection:   connection,
+		JTI:          message.JTI,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.scheduler.Schedule(worker.ScheduledTask{
+		Type:    TASK_SEND_MESSAGE,
+		AppID:   appID,
+		Payload: payload,
+	}, *message.SendAt)
+}
+
+func (s service) deleteMessage(appID, connection, jti string) {
+	client, ok := s.runner.Get(appID)
+	if !ok {
+		return
+	}
+
+	client.ChatService().Delete(
+		[]string{connection},
+		[]string{jti},
+		"",
+	)
+}
@@ -2,12 +2,23 @@ package message
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/template"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/mock"
+	"github.com/joinself/restful-client/pkg/worker"
+	"github.com/joinself/self-go-sdk/chat"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var errCRUD = errors.New("error crud")
@@ -20,7 +31,22 @@ func TestCreateMessageRequest_Validate(t *testing.T) {
 	}{
 		{"success", CreateMessageRequest{Body: "test"}, false},
 		{"required", CreateMessageRequest{Body: ""}, true},
-		{"too long", CreateMessageRequest{Body: "1234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890"}, true},
+		{"template", CreateMessageRequest{TemplateID: "template"}, false},
+		{"template with body", CreateMessageRequest{Body: "test", TemplateID: "template"}, true},
+		{"long form", CreateMessageRequest{Body: strings.Repeat("a", MAX_BODY_LENGTH)}, false},
+		{"too long", CreateMessageRequest{Body: strings.Repeat("a", MAX_BODY_LENGTH+1)}, true},
+		{"quick replies", CreateMessageRequest{Body: "test", Content: &MessageContent{Type: CONTENT_QUICK_REPLIES, QuickReplies: []QuickReply{{ID: "yes", Label: "Yes"}}}}, false},
+		{"quick replies required", CreateMessageRequest{Body: "test", Content: &MessageContent{Type: CONTENT_QUICK_REPLIES}}, true},
+		{"invalid quick reply", CreateMessageRequest{Body: "test", Content: &MessageContent{Type: CONTENT_QUICK_REPLIES, QuickReplies: []QuickReply{{ID: "yes"}}}}, true},
+		{"link", CreateMessageRequest{Body: "test", Content: &MessageContent{Type: CONTENT_LINK, Link: &LinkPreview{URL: "https://example.com"}}}, false},
+		{"content without body", CreateMessageRequest{Content: &MessageContent{Type: CONTENT_LINK, Link: &LinkPreview{URL: "https://example.com"}}}, false},
+		{"content with long body", CreateMessageRequest{Body: strings.Repeat("a", MAX_BODY_LENGTH+1), Content: &MessageContent{Type: CONTENT_LINK, Link: &LinkPreview{URL: "https://example.com"}}}, true},
+		{"invalid link", CreateMessageRequest{Body: "test", Content: &MessageContent{Type: CONTENT_LINK, Link: &LinkPreview{URL: "example"}}}, true},
+		{"unknown content", CreateMessageRequest{Body: "test", Content: &MessageContent{Type: "buttons"}}, true},
+		{"files", CreateMessageRequest{Body: "test", Files: []MessageFile{{Name: "photo.png", Mime: "image/png", Data: []byte("png")}, {Name: "doc.pdf", Mime: "application/pdf", Data: []byte("pdf")}}}, false},
+		{"file too big", CreateMessageRequest{Body: "test", Files: []MessageFile{{Name: "photo.png", Mime: "image/png", Data: make([]byte, MAX_FILE_SIZE+1)}}}, true},
+		{"file mime not allowed", CreateMessageRequest{Body: "test", Files: []MessageFile{{Name: "app.exe", Mime: "application/x-msdownload", Data: []byte("exe")}}}, true},
+		{"empty file", CreateMessageRequest{Body: "test", Files: []MessageFile{{Name: "photo.png", Mime: "image/png"}}}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -38,7 +64,7 @@ func TestUpdateMessageRequest_Validate(t *testing.T) {
 	}{
 		{"success", UpdateMessageRequest{Body: "test"}, false},
 		{"required", UpdateMessageRequest{Body: ""}, true},
-		{"too long", UpdateMessageRequest{Body: "1234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890"}, true},
+		{"too long", UpdateMessageRequest{Body: strings.Repeat("a", MAX_BODY_LENGTH+1)}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -51,13 +77,13 @@ func TestUpdateMessageRequest_Validate(t *testing.T) {
 func Test_service_CRUD(t *testing.T) {
 	logger, _ := log.NewForTest()
 	runner := mock.NewRunnerMock()
-	s := NewService(&mock.MessageRepositoryMock{}, runner, logger)
+	s := NewService(&mock.MessageRepositoryMock{}, runner, &mock.SchedulerMock{}, mockTemplateRenderer{}, logger)
 	ctx := context.Background()
 
 	connection := 1
 
 	// initial count
-	count, _ := s.Count(ctx, connection, 0)
+	count, _ := s.Count(ctx, connection, 0, "")
 	assert.Equal(t, 0, count)
 
 	// successful creation
@@ -67,11 +93,45 @@ func Test_service_CRUD(t *testing.T) {
 	assert.Equal(t, "test", message.Body)
 	assert.NotEmpty(t, message.CreatedAt)
 	assert.NotEmpty(t, message.UpdatedAt)
-	count, _ = s.Count(ctx, connection, 0)
+	count, _ = s.Count(ctx, connection, 0, "")
 	assert.Equal(t, 1, count)
 
 	_, _ = s.Create(ctx, "app", "connection", connection, CreateMessageRequest{Body: "test2"})
 
+	// reply
+	reply, err := s.Create(ctx, "app", "connection", connection, CreateMessageRequest{Body: "reply", RID: message.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, message.ID, reply.RID)
+	assert.Equal(t, message.CID, reply.CID)
+	_, err = s.Create(ctx, "app", "connection", connection, CreateMessageRequest{Body: "reply", RID: "unknown"})
+	assert.Equal(t, ErrRepliedMessageNotFound, err)
+
+	// template
+	templated, err := s.Create(ctx, "app", "connection", connection, CreateMessageRequest{TemplateID: "welcome", Variables: map[string]string{"amount": "10"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello connection, you owe 10", templated.Body)
+	_, err = s.Create(ctx, "app", "connection", connection, CreateMessageRequest{TemplateID: "unknown"})
+	assert.Equal(t, template.ErrTemplateNotFound, err)
+	_, err = s.Create(ctx, "app", "connection", connection, CreateMessageRequest{TemplateID: "welcome", Variables: map[string]string{"amount": strings.Repeat("9", MAX_BODY_LENGTH)}})
+	assert.Equal(t, ErrBodyTooLong, err)
+	err = s.Delete(ctx, "app", connection, "connection", templated.ID)
+	assert.Nil(t, err)
+
+	// structured content
+	content := &MessageContent{Type: CONTENT_QUICK_REPLIES, QuickReplies: []QuickReply{{ID: "yes", Label: "Yes"}}}
+	rich, err := s.Create(ctx, "app", "connection", connection, CreateMessageRequest{Body: "Continue?", Content: content})
+	assert.Nil(t, err)
+	assert.Equal(t, "Continue?", rich.Body)
+	assert.Equal(t, content, rich.Content)
+	err = s.Delete(ctx, "app", connection, "connection", rich.ID)
+	assert.Nil(t, err)
+
+	// thread
+	thread, _ := s.Query(ctx, connection, 0, message.CID, 0, 0)
+	assert.Equal(t, 2, len(thread))
+	count, _ = s.Count(ctx, connection, 0, message.CID)
+	assert.Equal(t, 2, count)
+
 	// update
 	message, err = s.Update(ctx, "app", connection, "connection", message.ID, UpdateMessageRequest{Body: "test updated"})
 	assert.Nil(t, err)
@@ -79,6 +139,14 @@ func Test_service_CRUD(t *testing.T) {
 	_, err = s.Update(ctx, "app", connection, "connection", "1", UpdateMessageRequest{Body: "test updated"})
 	assert.NotNil(t, err)
 
+	// revisions
+	revisions, err := s.Revisions(ctx, connection, message.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(revisions))
+	assert.Equal(t, "test", revisions[0].Body)
+	_, err = s.Revisions(ctx, connection, "1")
+	assert.NotNil(t, err)
+
 	// get
 	_, err = s.Get(ctx, connection, "1")
 	assert.NotNil(t, err)
@@ -88,14 +156,187 @@ func Test_service_CRUD(t *testing.T) {
 	assert.Equal(t, id, message.ID)
 
 	// query
-	messages, _ := s.Query(ctx, connection, 0, 0, 0)
-	assert.Equal(t, 2, len(messages))
+	messages, _ := s.Query(ctx, connection, 0, "", 0, 0)
+	assert.Equal(t, 3, len(messages))
 
 	// delete
-	err = s.Delete(ctx, connection, "non existing")
+	err = s.Delete(ctx, "app", connection, "connection", "non existing")
+	assert.NotNil(t, err)
+	err = s.Delete(ctx, "app", connection, "connection", message.ID)
+	assert.Nil(t, err)
+	count, _ = s.Count(ctx, connection, 0, "")
+	assert.Equal(t, 2, count)
+}
+
+func Test_service_GetAttachment(t *testing.T) {
+	logger, _ := log.NewForTest()
+	runner := mock.NewRunnerMock()
+	path := filepath.Join(t.TempDir(), "0")
+	assert.Nil(t, os.WriteFile(path, []byte("content"), 0600))
+
+	repo := &mock.MessageRepositoryMock{
+		Items: []entity.Message{{ID: 1, JTI: "jti"}},
+		AttachmentItems: []entity.MessageAttachment{
+			{MessageID: 1, Position: 0, Name: "file.txt", Path: path},
+			{MessageID: 1, Position: 1, Name: "public.txt", Public: true},
+			{MessageID: 1, Position: 2, Name: "remote.txt"},
+		},
+	}
+	s := NewService(repo, runner, &mock.SchedulerMock{}, mockTemplateRenderer{}, logger)
+	ctx := context.Background()
+
+	// stored attachment
+	a, content, err := s.GetAttachment(ctx, "app", 1, "jti", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "file.txt", a.Name)
+	assert.Equal(t, []byte("content"), content)
+
+	// public attachment
+	a, content, err = s.GetAttachment(ctx, "app", 1, "jti", 1)
+	assert.Nil(t, err)
+	assert.True(t, a.Public)
+	assert.Nil(t, content)
+
+	// remote attachment for a non running app
+	_, _, err = s.GetAttachment(ctx, "app", 1, "jti", 2)
 	assert.NotNil(t, err)
-	err = s.Delete(ctx, connection, message.ID)
+
+	// unknown position
+	_, _, err = s.GetAttachment(ctx, "app", 1, "jti", 3)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// unknown message
+	_, _, err = s.GetAttachment(ctx, "app", 1, "unknown", 0)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func Test_service_Search(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mock.MessageRepositoryMock{
+		Items: []entity.Message{
+			{ID: 1, JTI: "jti1", Body: "where is my parcel"},
+			{ID: 2, JTI: "jti2", Body: "thanks!"},
+		},
+	}
+	s := NewService(repo, mock.NewRunnerMock(), &mock.SchedulerMock{}, mockTemplateRenderer{}, logger)
+	ctx := context.Background()
+
+	count, err := s.CountSearch(ctx, "app", entity.MessageSearch{Query: "parcel"})
 	assert.Nil(t, err)
-	count, _ = s.Count(ctx, connection, 0)
 	assert.Equal(t, 1, count)
+
+	results, err := s.Search(ctx, "app", entity.MessageSearch{Query: "parcel"}, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "jti1", results[0].ID)
+	assert.Equal(t, "where is my parcel", results[0].Snippet)
+
+	_, err = s.Search(ctx, "app", entity.MessageSearch{Query: "error"}, 0, 10)
+	assert.NotNil(t, err)
+}
+
+func Test_service_Scheduled(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mock.MessageRepositoryMock{}
+	scheduler := &mock.SchedulerMock{}
+	s := NewService(repo, mock.NewRunnerMock(), scheduler, mockTemplateRenderer{}, logger)
+	ctx := context.Background()
+	connection := 1
+
+	// scheduled creation
+	sendAt := time.Now().Add(time.Hour)
+	message, err := s.Create(ctx, "app", "connection", connection, CreateMessageRequest{Body: "later", SendAt: &sendAt})
+	assert.Nil(t, err)
+	assert.Equal(t, entity.MESSAGE_SCHEDULED_STATUS, message.Status)
+	require.Equal(t, 1, len(scheduler.Tasks))
+	assert.Equal(t, TASK_SEND_MESSAGE, scheduler.Tasks[0].Type)
+	assert.Equal(t, "app", scheduler.Tasks[0].AppID)
+	assert.Equal(t, sendAt, scheduler.Times[0])
+
+	// edit and reschedule
+	sendAt = time.Now().Add(2 * time.Hour)
+	message, err = s.Update(ctx, "app", connection, "connection", message.ID, UpdateMessageRequest{Body: "even later", SendAt: &sendAt})
+	assert.Nil(t, err)
+	assert.Equal(t, "even later", message.Body)
+	require.Equal(t, 2, len(scheduler.Tasks))
+
+	// the first task is ignored as the message has been rescheduled
+	err = s.Dispatch(ctx, scheduler.Tasks[0])
+	assert.Nil(t, err)
+	stored, _ := s.Get(ctx, connection, message.ID)
+	assert.Equal(t, entity.MESSAGE_SCHEDULED_STATUS, stored.Status)
+
+	// due messages are retried while the app is not running
+	repo.Items[0].SendAt = nil
+	repo.Items[0].CreatedAt = time.Now()
+	err = s.Dispatch(ctx, scheduler.Tasks[1])
+	assert.NotNil(t, err)
+	stored, _ = s.Get(ctx, connection, message.ID)
+	assert.Equal(t, entity.MESSAGE_SCHEDULED_STATUS, stored.Status)
+
+	// and errored once the retry period is over
+	overdue := time.Now().Add(-worker.RetryPeriod)
+	repo.Items[0].SendAt = &overdue
+	err = s.Dispatch(ctx, scheduler.Tasks[1])
+	assert.Nil(t, err)
+	stored, _ = s.Get(ctx, connection, message.ID)
+	assert.Equal(t, entity.MESSAGE_ERRORED_STATUS, stored.Status)
+	repo.Items[0].Status = entity.MESSAGE_SCHEDULED_STATUS
+
+	// sent messages cannot be rescheduled
+	sent, _ := s.Create(ctx, "app", "connection", connection, CreateMessageRequest{Body: "now"})
+	assert.Equal(t, entity.MESSAGE_SENT_STATUS, sent.Status)
+	revisions := len(repo.RevisionItems)
+	_, err = s.Update(ctx, "app", connection, "connection", sent.ID, UpdateMessageRequest{Body: "later", SendAt: &sendAt})
+	assert.Equal(t, ErrMessageNotScheduled, err)
+	assert.Equal(t, revisions, len(repo.RevisionItems))
+
+	// cancellation
+	err = s.Delete(ctx, "app", connection, "connection", message.ID)
+	assert.Nil(t, err)
+	err = s.Dispatch(ctx, scheduler.Tasks[1])
+	assert.Nil(t, err)
+
+	// storage errors are retried
+	payload, _ := json.Marshal(scheduledMessage{ConnectionID: connection, Connection: "connection", JTI: "error"})
+	err = s.Dispatch(ctx, worker.ScheduledTask{AppID: "app", Type: TASK_SEND_MESSAGE, Payload: payload})
+	assert.NotNil(t, err)
+}
+
+func TestMessageContent_Text(t *testing.T) {
+	qr := MessageContent{Type: CONTENT_QUICK_REPLIES, QuickReplies: []QuickReply{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}}}
+	assert.Equal(t, "Continue?\n\n1. Yes\n2. No", qr.Text("Continue?"))
+
+	link := MessageContent{Type: CONTENT_LINK, Link: &LinkPreview{URL: "https://example.com", Title: "Example"}}
+	assert.Equal(t, "Example\nhttps://example.com", link.Text(""))
+}
+
+func TestMessageContent_Match(t *testing.T) {
+	c := MessageContent{Type: CONTENT_QUICK_REPLIES, QuickReplies: []QuickReply{{ID: "yes", Label: "Yes please"}, {ID: "no", Label: "No"}}}
+
+	tests := []struct {
+		answer string
+		want   string
+		ok     bool
+	}{
+		{"1", "yes", true},
+		{" 2 ", "no", true},
+		{"3", "", false},
+		{"yes please", "yes", true},
+		{"NO", "no", true},
+		{"maybe", "", false},
+	}
+	for _, tt := range tests {
+		reply, ok := c.Match(tt.answer)
+		assert.Equal(t, tt.ok, ok, tt.answer)
+		assert.Equal(t, tt.want, reply.ID, tt.answer)
+	}
+}
+
+func Test_sendOptions(t *testing.T) {
+	assert.Nil(t, sendOptions(chat.MessageOptions{}))
+	assert.Equal(t, []chat.MessageOptions{{RID: "rid"}}, sendOptions(chat.MessageOptions{RID: "rid"}))
+	assert.Equal(t, []chat.MessageOptions{{JTI: "jti"}}, sendOptions(chat.MessageOptions{JTI: "jti"}))
+	objects := []chat.MessageObject{{Name: "file.txt"}}
+	assert.Equal(t, []chat.MessageOptions{{Objects: objects}}, sendOptions(chat.MessageOptions{Objects: objects}))
 }
@@ -1,14 +1,47 @@
 package message
 
 import (
+	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/response"
 )
 
+const (
+	// MAX_BODY_LENGTH is the maximum length of a message body.
+	MAX_BODY_LENGTH = 4096
+	// MAX_FILES is the maximum number of files uploaded with a message.
+	MAX_FILES = 5
+	// MAX_FILE_SIZE is the maximum size in bytes of a file uploaded with a
+	// message.
+	MAX_FILE_SIZE = 10 << 20
+	// MAX_UPLOAD_SIZE is the maximum size in bytes of a message creation
+	// request, it leaves room for the message fields along with the files.
+	MAX_UPLOAD_SIZE = MAX_FILES*MAX_FILE_SIZE + 1<<20
+)
+
+// multipartFields are the message fields which can be sent in a multipart
+// request, the structured ones are only accepted in JSON requests.
+var multipartFields = []string{"body", "rid", "cid", "send_at"}
+
+// allowedMimes are the mime types of the files that can be uploaded with a
+// message, a trailing slash allows any subtype.
+var allowedMimes = []string{"image/", "video/", "audio/", "application/pdf", "text/plain"}
+
+const (
+	// CONTENT_QUICK_REPLIES content offering a set of replies to choose from.
+	CONTENT_QUICK_REPLIES = "quick_replies"
+	// CONTENT_LINK content previewing a link.
+	CONTENT_LINK = "link"
+)
+
 type ExtListResponse struct {
 	Page       int       `json:"page"`
 	PerPage    int       `json:"per_page"`
@@ -17,6 +50,14 @@ type ExtListResponse struct {
 	Items      []Message `json:"items"`
 }
 
+type ExtSearchResponse struct {
+	Page       int            `json:"page"`
+	PerPage    int            `json:"per_page"`
+	PageCount  int            `json:"page_count"`
+	TotalCount int            `json:"total_count"`
+	Items      []SearchResult `json:"items"`
+}
+
 type MessageObject struct {
 	Link    string `json:"link"`
 	Name    string `json:"name"`
@@ -36,19 +77,179 @@ func (m MessageObject) Validate() error {
 	)
 }
 
+// MessageFile is a file uploaded with a message, the Self object carrying it
+// is built when the message is sent.
+type MessageFile struct {
+	Name string
+	Mime string
+	Data []byte
+}
+
+func (f MessageFile) Validate() error {
+	return validation.ValidateStruct(&f,
+		validation.Field(&f.Name, validation.Required, validation.Length(1, 128)),
+		validation.Field(&f.Mime, validation.Required, validation.By(allowedMime)),
+		validation.Field(&f.Data,
+			validation.Required,
+			validation.Length(0, MAX_FILE_SIZE).Error(fmt.Sprintf("the file size must be no more than %d bytes", MAX_FILE_SIZE)),
+		),
+	)
+}
+
+// allowedMime checks the given mime type can be uploaded with a message.
+func allowedMime(value interface{}) error {
+	mime, _ := value.(string)
+	for _, allowed := range allowedMimes {
+		if mime == allowed || strings.HasSuffix(allowed, "/") && strings.HasPrefix(mime, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("mime type %s is not allowed", mime)
+}
+
 type MessageOptions struct {
 	Objects []MessageObject `json:"objects"`
 }
 
+// QuickReply is one of the replies offered by a quick replies content.
+type QuickReply struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+func (q QuickReply) Validate() error {
+	return validation.ValidateStruct(&q,
+		validation.Field(&q.ID, validation.Required, validation.Length(1, 64)),
+		validation.Field(&q.Label, validation.Required, validation.Length(1, 64)),
+	)
+}
+
+// LinkPreview describes the link previewed by a link content.
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (l LinkPreview) Validate() error {
+	return validation.ValidateStruct(&l,
+		validation.Field(&l.URL, validation.Required, is.URL),
+		validation.Field(&l.Title, validation.Length(0, 128)),
+		validation.Field(&l.Description, validation.Length(0, 512)),
+	)
+}
+
+// MessageContent is the structured content sent along with a message body.
+// The Self chat protocol only carries text, so the content is rendered as
+// text for the recipient, and the replies to quick replies are matched back
+// to the offered options.
+type MessageContent struct {
+	Type         string       `json:"type"`
+	QuickReplies []QuickReply `json:"quick_replies,omitempty"`
+	Link         *LinkPreview `json:"link,omitempty"`
+}
+
+func (c MessageContent) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Type, validation.Required, validation.In(CONTENT_QUICK_REPLIES, CONTENT_LINK)),
+		validation.Field(&c.QuickReplies, validation.When(c.Type == CONTENT_QUICK_REPLIES, validation.Required, validation.Length(1, 10))),
+		validation.Field(&c.Link, validation.When(c.Type == CONTENT_LINK, validation.Required)),
+	)
+}
+
+// Text returns the given body followed by the text representation of the
+// content.
+func (c MessageContent) Text(body string) string {
+	lines := []string{}
+	switch c.Type {
+	case CONTENT_QUICK_REPLIES:
+		for i, q := range c.QuickReplies {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, q.Label))
+		}
+	case CONTENT_LINK:
+		if c.Link.Title != "" {
+			lines = append(lines, c.Link.Title)
+		}
+		if c.Link.Description != "" {
+			lines = append(lines, c.Link.Description)
+		}
+		lines = append(lines, c.Link.URL)
+	}
+
+	if body == "" {
+		return strings.Join(lines, "\n")
+	}
+	return body + "\n\n" + strings.Join(lines, "\n")
+}
+
+// Match returns the quick reply selected by the given answer, which can be
+// either the number, the id or the label of the reply.
+func (c MessageContent) Match(answer string) (QuickReply, bool) {
+	answer = strings.TrimSpace(answer)
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n > 0 && n <= len(c.QuickReplies) {
+			return c.QuickReplies[n-1], true
+		}
+		return QuickReply{}, false
+	}
+
+	for _, q := range c.QuickReplies {
+		if strings.EqualFold(q.ID, answer) || strings.EqualFold(q.Label, answer) {
+			return q, true
+		}
+	}
+	return QuickReply{}, false
+}
+
+// QuickReplyEvent is the payload of the webhook sent when a connection
+// answers a quick replies message.
+type QuickReplyEvent struct {
+	// JTI is the id of the received answer.
+	JTI string `json:"jti"`
+	// RID is the id of the message offering the quick replies.
+	RID   string `json:"rid"`
+	CID   string `json:"cid"`
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Body  string `json:"body"`
+}
+
 type CreateMessageRequest struct {
 	Body    string         `json:"body"`
 	Options MessageOptions `json:"options,omitempty"`
+	// Content is the structured content sent along with the body.
+	Content *MessageContent `json:"content,omitempty"`
+	// Files are the files uploaded with a multipart request.
+	Files []MessageFile `json:"-"`
+	// RID is the JTI of the message this message replies to.
+	RID string `json:"rid,omitempty"`
+	// CID is the conversation this message belongs to, when empty the
+	// conversation of the replied message is used or a new one is started.
+	// Conversations are only tracked locally, the SDK doesn't send the cid to
+	// the connection, which can follow threads through the rid of replies.
+	CID string `json:"cid,omitempty"`
+	// SendAt schedules the message to be sent at the given time.
+	SendAt *time.Time `json:"send_at,omitempty"`
+	// TemplateID is the template used to build the body of the message.
+	TemplateID string `json:"template_id,omitempty"`
+	// Variables are the values of the variables declared by the template.
+	Variables map[string]string `json:"variables,omitempty"`
 }
 
 // Validate validates the CreateMessageRequest fields.
 func (m CreateMessageRequest) Validate() *response.Error {
 	err := validation.ValidateStruct(&m,
-		validation.Field(&m.Body, validation.Required, validation.Length(0, 128)),
+		validation.Field(&m.Body,
+			// Quick replies and links can be sent without a body.
+			validation.When(m.TemplateID == "" && m.Content == nil, validation.Required),
+			validation.When(m.TemplateID == "", validation.Length(0, MAX_BODY_LENGTH)),
+			validation.When(m.TemplateID != "", validation.In("").Error("must be blank when using a template")),
+		),
+		validation.Field(&m.TemplateID, validation.Length(0, 128)),
+		validation.Field(&m.RID, validation.Length(0, 128)),
+		validation.Field(&m.CID, validation.Length(0, 128)),
+		validation.Field(&m.Content),
+		validation.Field(&m.SendAt, validation.Min(time.Now()).Error("must be in the future")),
 	)
 	if err != nil {
 		return &response.Error{
@@ -59,6 +260,11 @@ func (m CreateMessageRequest) Validate() *response.Error {
 	}
 
 	err = validateObjects(m.Options.Objects)
+	if err == nil {
+		err = validation.Errors{
+			"files": validation.Validate(m.Files, validation.Length(0, MAX_FILES)),
+		}.Filter()
+	}
 	if err != nil {
 		return &response.Error{
 			Status:  http.StatusBadRequest,
@@ -67,18 +273,29 @@ func (m CreateMessageRequest) Validate() *response.Error {
 		}
 	}
 
+	if m.SendAt != nil && (len(m.Options.Objects) > 0 || len(m.Files) > 0) {
+		return &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: "options: scheduled messages cannot contain objects.",
+		}
+	}
+
 	return nil
 }
 
 // UpdateMessageRequest represents an message update request.
 type UpdateMessageRequest struct {
 	Body string `json:"body"`
+	// SendAt reschedules a scheduled message.
+	SendAt *time.Time `json:"send_at,omitempty"`
 }
 
 // Validate validates the CreateMessageRequest fields.
 func (m UpdateMessageRequest) Validate() *response.Error {
 	err := validation.ValidateStruct(&m,
-		validation.Field(&m.Body, validation.Required, validation.Length(0, 128)),
+		validation.Field(&m.Body, validation.Required, validation.Length(0, MAX_BODY_LENGTH)),
+		validation.Field(&m.SendAt, validation.Min(time.Now()).Error("must be in the future")),
 	)
 	if err == nil {
 		return nil
@@ -98,3 +315,30 @@ func validateObjects(objects []MessageObject) error {
 	}
 	return nil
 }
+
+// invalidParam builds the error returned for a query parameter that cannot
+// be parsed.
+func invalidParam(name, reason string) *response.Error {
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: name + ": " + reason + ".",
+	}
+}
+
+// validateSearchParams validates the message search params.
+func validateSearchParams(p entity.MessageSearch) *response.Error {
+	err := validation.ValidateStruct(&p,
+		validation.Field(&p.Query, validation.Required, validation.Length(1, 128)),
+		validation.Field(&p.Direction, validation.In(entity.MESSAGE_DIRECTION_INBOUND, entity.MESSAGE_DIRECTION_OUTBOUND)),
+	)
+	if err != nil {
+		return &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
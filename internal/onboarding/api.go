@@ -0,0 +1,102 @@
+package onboarding
+
+import (
+	"net/http"
+
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/response"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *echo.Group, service Service, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.GET("/:app_id/onboarding", res.get)
+	r.PUT("/:app_id/onboarding", res.update)
+	r.DELETE("/:app_id/onboarding", res.reset)
+}
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+// GetOnboarding godoc
+// @Summary         Retrieve the onboarding policy
+// @Description     Get the onboarding policy run when a new connection is established with the app. Apps without a policy use the default one, requesting the connection display name and notifying the app.
+// @Tags            onboarding
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Success         200     {object}  Policy          "Successful Response"
+// @Failure         500     {object}  response.Error  "Internal Server Error"
+// @Router          /apps/{app_id}/onboarding [get]
+func (r resource) get(c echo.Context) error {
+	ctx := c.Request().Context()
+	policy, err := r.service.Get(ctx, c.Param("app_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving onboarding policy: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdateOnboarding godoc
+// @Summary         Update the onboarding policy
+// @Description     Replaces the onboarding policy of the app: the welcome message sent, the facts requested and the tags applied to the new connections, and whether the app is notified about them.
+// @Tags            onboarding
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path      string                   true  "Application ID"
+// @Param           request  body      UpdateOnboardingRequest  true  "Onboarding policy"
+// @Success         200      {object}  Policy                   "Policy updated"
+// @Failure         400      {object}  response.Error           "Invalid input"
+// @Failure         500      {object}  response.Error           "Internal Server Error"
+// @Router          /apps/{app_id}/onboarding [put]
+func (r resource) update(c echo.Context) error {
+	ctx := c.Request().Context()
+	var input UpdateOnboardingRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("error invalid input: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+
+	if err := input.Validate(); err != nil {
+		r.logger.With(ctx).Infof("error invalid input: %s", err.Error)
+		return c.JSON(err.Status, err)
+	}
+
+	policy, err := r.service.Update(ctx, c.Param("app_id"), input)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error updating onboarding policy: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// ResetOnboarding godoc
+// @Summary         Reset the onboarding policy
+// @Description     Removes the onboarding policy of the app, so the default one is used.
+// @Tags            onboarding
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Success         200     {object}  Policy          "Default policy"
+// @Failure         500     {object}  response.Error  "Internal Server Error"
+// @Router          /apps/{app_id}/onboarding [delete]
+func (r resource) reset(c echo.Context) error {
+	ctx := c.Request().Context()
+	policy, err := r.service.Reset(ctx, c.Param("app_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error resetting onboarding policy: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
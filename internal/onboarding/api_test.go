@@ -0,0 +1,93 @@
+package onboarding
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/acl"
+	"github.com/joinself/restful-client/pkg/filter"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+func TestOnboardingAPIEndpointsAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"ANY /apps/app_id/onboarding", "ANY /apps/error/onboarding"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/onboarding",
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"welcome_message":"", "facts":[{"name":"display_name", "sources":["user_specified"]}], "description":"info", "tags":[], "notify":true, "default":true}`,
+		},
+		{
+			Name:       "get error",
+			Method:     "GET",
+			URL:        "/apps/error/onboarding",
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:         "update",
+			Method:       "PUT",
+			URL:          "/apps/app_id/onboarding",
+			Body:         `{"welcome_message":"welcome!","facts":[{"name":"email_address"}],"tags":["new"],"notify":false}`,
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"welcome_message":"welcome!", "facts":[{"name":"email_address"}], "description":"", "tags":["new"], "notify":false, "default":false}`,
+		},
+		{
+			Name:         "update invalid",
+			Method:       "PUT",
+			URL:          "/apps/app_id/onboarding",
+			Body:         `{"facts":[{"name":"a"}]}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"facts: (0: (name: the length must be between 3 and 128.).)."}`,
+		},
+		{
+			Name:       "update error",
+			Method:     "PUT",
+			URL:        "/apps/error/onboarding",
+			Body:       `{}`,
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:         "reset",
+			Method:       "DELETE",
+			URL:          "/apps/app_id/onboarding",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"default":true*`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestOnboardingAPIEndpointsAsPlainWithoutPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/onboarding",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
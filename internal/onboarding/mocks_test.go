@@ -0,0 +1,89 @@
+package onboarding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/internal/request"
+)
+
+type mockService struct{}
+
+func (m mockService) Get(ctx context.Context, appID string) (Policy, error) {
+	if appID == "error" {
+		return Policy{}, errors.New("error!")
+	}
+	return DefaultPolicy(), nil
+}
+
+func (m mockService) Update(ctx context.Context, appID string, input UpdateOnboardingRequest) (Policy, error) {
+	if appID == "error" {
+		return Policy{}, errors.New("error!")
+	}
+	return Policy{
+		WelcomeMessage: input.WelcomeMessage,
+		Facts:          input.Facts,
+		Description:    input.Description,
+		Tags:           input.Tags,
+		Notify:         input.Notify == nil || *input.Notify,
+	}, nil
+}
+
+func (m mockService) Reset(ctx context.Context, appID string) (Policy, error) {
+	if appID == "error" {
+		return Policy{}, errors.New("error!")
+	}
+	return DefaultPolicy(), nil
+}
+
+func (m mockService) Onboard(ctx context.Context, appID string, conn entity.Connection) bool {
+	return true
+}
+
+type mockRepository struct {
+	items map[string]entity.Onboarding
+}
+
+func (m *mockRepository) Get(ctx context.Context, appID string) (entity.Onboarding, error) {
+	if policy, ok := m.items[appID]; ok {
+		return policy, nil
+	}
+	return entity.Onboarding{}, sql.ErrNoRows
+}
+
+func (m *mockRepository) Save(ctx context.Context, policy entity.Onboarding) error {
+	if m.items == nil {
+		m.items = map[string]entity.Onboarding{}
+	}
+	m.items[policy.AppID] = policy
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, appID string) error {
+	if _, ok := m.items[appID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.items, appID)
+	return nil
+}
+
+type mockMessageSender struct {
+	sent []message.CreateMessageRequest
+}
+
+func (m *mockMessageSender) Create(ctx context.Context, appID, selfID string, connection int, req message.CreateMessageRequest) (message.Message, error) {
+	m.sent = append(m.sent, req)
+	return message.Message{Body: req.Body}, nil
+}
+
+type mockFactRequester struct {
+	requested []request.CreateRequest
+}
+
+func (m *mockFactRequester) Create(ctx context.Context, appID string, conn *entity.Connection, req request.CreateRequest) (request.ExtRequest, error) {
+	m.requested = append(m.requested, req)
+	return request.ExtRequest{}, nil
+}
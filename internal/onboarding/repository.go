@@ -0,0 +1,56 @@
+package onboarding
+
+import (
+	"context"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/dbcontext"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// Repository encapsulates the logic to access onboarding policies from the
+// data source.
+type Repository interface {
+	// Get returns the onboarding policy of an app.
+	Get(ctx context.Context, appID string) (entity.Onboarding, error)
+	// Save creates or updates the onboarding policy of an app.
+	Save(ctx context.Context, policy entity.Onboarding) error
+	// Delete removes the onboarding policy of an app.
+	Delete(ctx context.Context, appID string) error
+}
+
+// repository persists onboarding policies in database
+type repository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+// NewRepository creates a new onboarding repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+// Get reads the onboarding policy of an app from the database.
+func (r repository) Get(ctx context.Context, appID string) (entity.Onboarding, error) {
+	var policy entity.Onboarding
+	err := r.db.With(ctx).Select().Model(appID, &policy)
+	return policy, err
+}
+
+// Save creates or updates the onboarding policy of an app in the database.
+func (r repository) Save(ctx context.Context, policy entity.Onboarding) error {
+	_, err := r.Get(ctx, policy.AppID)
+	if err != nil {
+		return r.db.With(ctx).Model(&policy).Insert()
+	}
+	return r.db.With(ctx).Model(&policy).Update()
+}
+
+// Delete deletes the onboarding policy of an app from the database.
+func (r repository) Delete(ctx context.Context, appID string) error {
+	policy, err := r.Get(ctx, appID)
+	if err != nil {
+		return err
+	}
+	return r.db.With(ctx).Model(&policy).Delete()
+}
@@ -0,0 +1,60 @@
+package onboarding
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "onboarding")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	appID := "app_" + uuid.New().String()
+
+	// get
+	_, err := repo.Get(ctx, appID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// save
+	policy := entity.Onboarding{
+		AppID:          appID,
+		WelcomeMessage: "welcome!",
+		Facts:          []byte(`[{"name":"email_address"}]`),
+		Tags:           []byte(`["new"]`),
+		Notify:         true,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	err = repo.Save(ctx, policy)
+	assert.Nil(t, err)
+	stored, err := repo.Get(ctx, appID)
+	assert.Nil(t, err)
+	assert.Equal(t, "welcome!", stored.WelcomeMessage)
+	assert.Equal(t, `["new"]`, string(stored.Tags))
+	assert.True(t, stored.Notify)
+
+	stored.Notify = false
+	err = repo.Save(ctx, stored)
+	assert.Nil(t, err)
+	stored, _ = repo.Get(ctx, appID)
+	assert.False(t, stored.Notify)
+
+	// delete
+	err = repo.Delete(ctx, appID)
+	assert.Nil(t, err)
+	_, err = repo.Get(ctx, appID)
+	assert.Equal(t, sql.ErrNoRows, err)
+	err = repo.Delete(ctx, appID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,214 @@
+package onboarding
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/internal/request"
+	"github.com/joinself/restful-client/pkg/log"
+	selffact "github.com/joinself/self-go-sdk/fact"
+)
+
+// Service encapsulates usecase logic for onboarding policies.
+type Service interface {
+	Get(ctx context.Context, appID string) (Policy, error)
+	Update(ctx context.Context, appID string, input UpdateOnboardingRequest) (Policy, error)
+	Reset(ctx context.Context, appID string) (Policy, error)
+	Onboard(ctx context.Context, appID string, conn entity.Connection) bool
+}
+
+// MessageSender sends the welcome messages.
+type MessageSender interface {
+	Create(ctx context.Context, appID, selfID string, connection int, req message.CreateMessageRequest) (message.Message, error)
+}
+
+// FactRequester sends the initial fact requests.
+type FactRequester interface {
+	Create(ctx context.Context, appID string, conn *entity.Connection, req request.CreateRequest) (request.ExtRequest, error)
+}
+
+// Policy represents the onboarding policy of an app.
+type Policy struct {
+	WelcomeMessage string                `json:"welcome_message"`
+	Facts          []request.FactRequest `json:"facts"`
+	Description    string                `json:"description"`
+	Tags           []string              `json:"tags"`
+	Notify         bool                  `json:"notify"`
+	// Default is set when the app uses the default policy.
+	Default bool `json:"default"`
+}
+
+// DefaultPolicy returns the policy of the apps without an onboarding policy,
+// it requests the display name of the new connections and notifies the app.
+func DefaultPolicy() Policy {
+	return Policy{
+		Facts: []request.FactRequest{{
+			Name:    selffact.FactDisplayName,
+			Sources: []string{selffact.SourceUserSpecified},
+		}},
+		Description: "info",
+		Tags:        []string{},
+		Notify:      true,
+		Default:     true,
+	}
+}
+
+type service struct {
+	repo     Repository
+	messages MessageSender
+	requests FactRequester
+	cRepo    connection.Repository
+	logger   log.Logger
+}
+
+// NewService creates a new onboarding service.
+func NewService(repo Repository, messages MessageSender, requests FactRequester, cRepo connection.Repository, logger log.Logger) Service {
+	return service{repo, messages, requests, cRepo, logger}
+}
+
+// Get returns the onboarding policy of an app.
+func (s service) Get(ctx context.Context, appID string) (Policy, error) {
+	policy, err := s.repo.Get(ctx, appID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultPolicy(), nil
+	}
+	if err != nil {
+		return Policy{}, err
+	}
+	return newPolicyFromEntity(policy)
+}
+
+// Update replaces the onboarding policy of an app.
+func (s service) Update(ctx context.Context, appID string, req UpdateOnboardingRequest) (Policy, error) {
+	if req.Facts == nil {
+		req.Facts = []request.FactRequest{}
+	}
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	facts, err := json.Marshal(req.Facts)
+	if err != nil {
+		return Policy{}, err
+	}
+	tags, err := json.Marshal(req.Tags)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	now := time.Now()
+	policy, err := s.repo.Get(ctx, appID)
+	if err != nil {
+		policy = entity.Onboarding{AppID: appID, CreatedAt: now}
+	}
+	policy.WelcomeMessage = req.WelcomeMessage
+	policy.Facts = facts
+	policy.Description = req.Description
+	policy.Tags = tags
+	policy.Notify = req.Notify == nil || *req.Notify
+	policy.UpdatedAt = now
+
+	err = s.repo.Save(ctx, policy)
+	if err != nil {
+		return Policy{}, err
+	}
+	return newPolicyFromEntity(policy)
+}
+
+// Reset removes the onboarding policy of an app, so the default one is used.
+func (s service) Reset(ctx context.Context, appID string) (Policy, error) {
+	err := s.repo.Delete(ctx, appID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Policy{}, err
+	}
+	return DefaultPolicy(), nil
+}
+
+// Onboard runs the onboarding policy of an app for a new connection, it
+// returns whether the app must be notified about the connection.
+func (s service) Onboard(ctx context.Context, appID string, conn entity.Connection) bool {
+	logger := s.logger.With(ctx, "onboarding")
+
+	policy, err := s.Get(ctx, appID)
+	if err != nil {
+		logger.Infof("error loading the onboarding policy, using the default one: %v", err)
+		policy = DefaultPolicy()
+	}
+
+	if len(policy.Tags) > 0 {
+		err = s.cRepo.SetTags(ctx, conn.ID, mergeTags(conn.Tags, policy.Tags))
+		if err != nil {
+			logger.Infof("error tagging the connection: %v", err)
+		}
+	}
+
+	if len(policy.WelcomeMessage) > 0 {
+		_, err = s.messages.Create(ctx, appID, conn.SelfID, conn.ID, message.CreateMessageRequest{
+			Body: policy.WelcomeMessage,
+		})
+		if err != nil {
+			logger.Infof("error sending the welcome message: %v", err)
+		}
+	}
+
+	if len(policy.Facts) > 0 {
+		_, err = s.requests.Create(ctx, appID, &conn, request.CreateRequest{
+			Type:        "fact",
+			Facts:       policy.Facts,
+			Description: policy.Description,
+			SelfID:      conn.SelfID,
+		})
+		if err != nil {
+			logger.Infof("error requesting the initial facts: %v", err)
+		}
+	}
+
+	return policy.Notify
+}
+
+// mergeTags returns the union of the given tags sorted alphabetically.
+func mergeTags(tags, added []string) []string {
+	set := map[string]bool{}
+	for _, tag := range append(append([]string{}, tags...), added...) {
+		set[tag] = true
+	}
+
+	result := make([]string, 0, len(set))
+	for tag := range set {
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func newPolicyFromEntity(o entity.Onboarding) (Policy, error) {
+	facts := []request.FactRequest{}
+	if len(o.Facts) > 0 {
+		err := json.Unmarshal(o.Facts, &facts)
+		if err != nil {
+			return Policy{}, err
+		}
+	}
+
+	tags := []string{}
+	if len(o.Tags) > 0 {
+		err := json.Unmarshal(o.Tags, &tags)
+		if err != nil {
+			return Policy{}, err
+		}
+	}
+
+	return Policy{
+		WelcomeMessage: o.WelcomeMessage,
+		Facts:          facts,
+		Description:    o.Description,
+		Tags:           tags,
+		Notify:         o.Notify,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package onboarding
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/request"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateOnboardingRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     UpdateOnboardingRequest
+		wantError bool
+	}{
+		{"success", UpdateOnboardingRequest{WelcomeMessage: "welcome!", Facts: []request.FactRequest{{Name: "email_address"}}, Tags: []string{"new"}}, false},
+		{"empty", UpdateOnboardingRequest{}, false},
+		{"welcome message too long", UpdateOnboardingRequest{WelcomeMessage: strings.Repeat("a", 4097)}, true},
+		{"invalid fact", UpdateOnboardingRequest{Facts: []request.FactRequest{{Name: "a"}}}, true},
+		{"empty tag", UpdateOnboardingRequest{Tags: []string{""}}, true},
+		{"description too long", UpdateOnboardingRequest{Description: strings.Repeat("a", 129)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.Validate()
+			assert.Equal(t, tt.wantError, err != nil)
+		})
+	}
+}
+
+func Test_service_Policy(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(&mockRepository{}, &mockMessageSender{}, &mockFactRequester{}, &mock.ConnectionRepositoryMock{}, logger)
+	ctx := context.Background()
+
+	// default
+	policy, err := s.Get(ctx, "app")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultPolicy(), policy)
+
+	// update
+	notify := false
+	policy, err = s.Update(ctx, "app", UpdateOnboardingRequest{WelcomeMessage: "welcome!", Tags: []string{"new"}, Notify: &notify})
+	assert.Nil(t, err)
+	assert.False(t, policy.Default)
+	assert.False(t, policy.Notify)
+	assert.Equal(t, []request.FactRequest{}, policy.Facts)
+
+	policy, err = s.Get(ctx, "app")
+	assert.Nil(t, err)
+	assert.Equal(t, "welcome!", policy.WelcomeMessage)
+	assert.Equal(t, []string{"new"}, policy.Tags)
+
+	// reset
+	policy, err = s.Reset(ctx, "app")
+	assert.Nil(t, err)
+	assert.True(t, policy.Default)
+	policy, _ = s.Get(ctx, "app")
+	assert.True(t, policy.Default)
+	_, err = s.Reset(ctx, "app")
+	assert.Nil(t, err)
+}
+
+func Test_service_Onboard(t *testing.T) {
+	logger, _ := log.NewForTest()
+	messages := &mockMessageSender{}
+	requests := &mockFactRequester{}
+	cRepo := &mock.ConnectionRepositoryMock{Items: []entity.Connection{{ID: 1, AppID: "app", SelfID: "selfid", Tags: []string{"vip"}}}}
+	s := NewService(&mockRepository{}, messages, requests, cRepo, logger)
+	ctx := context.Background()
+
+	// default policy
+	notify := s.Onboard(ctx, "app", cRepo.Items[0])
+	assert.True(t, notify)
+	assert.Equal(t, 0, len(messages.sent))
+	assert.Equal(t, 1, len(requests.requested))
+	assert.Equal(t, "display_name", requests.requested[0].Facts[0].Name)
+	assert.Equal(t, []string{"user_specified"}, requests.requested[0].Facts[0].Sources)
+
+	// custom policy
+	disabled := false
+	_, err := s.Update(ctx, "app", UpdateOnboardingRequest{
+		WelcomeMessage: "welcome!",
+		Facts:          []request.FactRequest{{Name: "email_address"}},
+		Description:    "contact details",
+		Tags:           []string{"new", "vip"},
+		Notify:         &disabled,
+	})
+	assert.Nil(t, err)
+
+	notify = s.Onboard(ctx, "app", cRepo.Items[0])
+	assert.False(t, notify)
+	assert.Equal(t, []string{"new", "vip"}, cRepo.Items[0].Tags)
+	assert.Equal(t, 1, len(messages.sent))
+	assert.Equal(t, "welcome!", messages.sent[0].Body)
+	assert.Equal(t, 2, len(requests.requested))
+	assert.Equal(t, "fact", requests.requested[1].Type)
+	assert.Equal(t, "contact details", requests.requested[1].Description)
+	assert.Equal(t, "selfid", requests.requested[1].SelfID)
+
+	// nothing to do
+	_, err = s.Update(ctx, "app", UpdateOnboardingRequest{})
+	assert.Nil(t, err)
+	notify = s.Onboard(ctx, "app", cRepo.Items[0])
+	assert.True(t, notify)
+	assert.Equal(t, 1, len(messages.sent))
+	assert.Equal(t, 2, len(requests.requested))
+}
@@ -0,0 +1,46 @@
+package onboarding
+
+import (
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/internal/request"
+	"github.com/joinself/restful-client/pkg/response"
+)
+
+// MAX_FACTS is the maximum number of facts requested to new connections.
+const MAX_FACTS = 20
+
+// UpdateOnboardingRequest represents an onboarding policy update request.
+type UpdateOnboardingRequest struct {
+	// WelcomeMessage is sent to the new connections, none is sent when empty.
+	WelcomeMessage string `json:"welcome_message"`
+	// Facts are requested to the new connections, no request is sent when
+	// empty.
+	Facts       []request.FactRequest `json:"facts"`
+	Description string                `json:"description"`
+	// Tags are applied to the new connections.
+	Tags []string `json:"tags"`
+	// Notify sends the connection webhook to the app callback, defaults to
+	// true.
+	Notify *bool `json:"notify,omitempty"`
+}
+
+// Validate validates the UpdateOnboardingRequest fields.
+func (m UpdateOnboardingRequest) Validate() *response.Error {
+	err := validation.ValidateStruct(&m,
+		validation.Field(&m.WelcomeMessage, validation.Length(0, message.MAX_BODY_LENGTH)),
+		validation.Field(&m.Facts, validation.Length(0, MAX_FACTS)),
+		validation.Field(&m.Description, validation.Length(0, 128)),
+		validation.Field(&m.Tags, validation.Each(validation.Required, validation.Length(1, 64))),
+	)
+	if err != nil {
+		return &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
@@ -1,11 +1,15 @@
 package request
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/joinself/restful-client/internal/connection"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/pagination"
 	"github.com/joinself/restful-client/pkg/response"
 	"github.com/labstack/echo/v4"
 )
@@ -15,7 +19,11 @@ func RegisterHandlers(r *echo.Group, service Service, cService connection.Servic
 	res := resource{service, cService, logger}
 
 	r.GET("/:app_id/requests/:id", res.get)
+	r.GET("/:app_id/requests/:id/qr", res.qr)
+	r.GET("/:app_id/requests", res.query)
+	r.GET("/:app_id/connections/:connection_id/requests", res.queryByConnection)
 	r.POST("/:app_id/requests", res.create)
+	r.POST("/:app_id/requests/:id/cancel", res.cancel)
 }
 
 type resource struct {
@@ -48,9 +56,106 @@ func (r resource) get(c echo.Context) error {
 	return c.JSON(http.StatusOK, request)
 }
 
+// ListRequests godoc
+// @Summary         List requests
+// @Description     Retrieves the requests of an app, newest first. Requests can be filtered by status, type, connection, out of band and creation date.
+// @Tags            Requests
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id       path   string  true   "Application ID"
+// @Param           status       query  string  false  "Only list the requests with the given status" Enums(requested, responded, partial, mismatched, expired, cancelled, rejected, errored)
+// @Param           type         query  string  false  "Only list the requests of the given type" Enums(auth, fact)
+// @Param           connection   query  string  false  "Only list the requests sent to the given connection"
+// @Param           out_of_band  query  bool    false  "Only list the out of band requests, or the ones sent to a connection"
+// @Param           from         query  int     false  "Only list the requests created after the given Unix timestamp"
+// @Param           to           query  int     false  "Only list the requests created before the given Unix timestamp"
+// @Param           page         query  int     false  "Page number for results pagination"
+// @Param           per_page     query  int     false  "Number of results per page for pagination"
+// @Success         200  {object}  ExtListResponse  "Successfully retrieved the requests"
+// @Failure         400  {object}  response.Error   "Invalid input"
+// @Failure         500  {object}  response.Error   "Internal Server Error"
+// @Router          /apps/{app_id}/requests [get]
+func (r resource) query(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	filter := entity.RequestFilter{
+		Status:     c.QueryParam("status"),
+		Type:       c.QueryParam("type"),
+		Connection: c.QueryParam("connection"),
+	}
+	if c.Param("connection_id") != "" {
+		filter.Connection = c.Param("connection_id")
+	}
+	if p := c.QueryParam("out_of_band"); p != "" {
+		v, err := strconv.ParseBool(p)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, invalidParam("out_of_band", "must be a boolean"))
+		}
+		filter.OutOfBand = &v
+	}
+	if p := c.QueryParam("from"); p != "" {
+		v, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, invalidParam("from", "must be a Unix timestamp"))
+		}
+		filter.From = time.Unix(v, 0)
+	}
+	if p := c.QueryParam("to"); p != "" {
+		v, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, invalidParam("to", "must be a Unix timestamp"))
+		}
+		filter.To = time.Unix(v, 0)
+	}
+
+	if err := validateListParams(filter); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	count, err := r.service.Count(ctx, c.Param("app_id"), filter)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting requests: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	requests, err := r.service.Query(ctx, c.Param("app_id"), filter, pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving requests: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = requests
+	return c.JSON(http.StatusOK, pages)
+}
+
+// ListConnectionRequests godoc
+// @Summary         List connection requests
+// @Description     Retrieves the requests sent to a connection, newest first. Requests can be filtered by status, type and creation date.
+// @Tags            Requests
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id         path   string  true   "Application ID"
+// @Param           connection_id  path   string  true   "Connection ID"
+// @Param           status         query  string  false  "Only list the requests with the given status" Enums(requested, responded, partial, mismatched, expired, cancelled, rejected, errored)
+// @Param           type           query  string  false  "Only list the requests of the given type" Enums(auth, fact)
+// @Param           from           query  int     false  "Only list the requests created after the given Unix timestamp"
+// @Param           to             query  int     false  "Only list the requests created before the given Unix timestamp"
+// @Param           page           query  int     false  "Page number for results pagination"
+// @Param           per_page       query  int     false  "Number of results per page for pagination"
+// @Success         200  {object}  ExtListResponse  "Successfully retrieved the requests"
+// @Failure         400  {object}  response.Error   "Invalid input"
+// @Failure         500  {object}  response.Error   "Internal Server Error"
+// @Router          /apps/{app_id}/connections/{connection_id}/requests [get]
+func (r resource) queryByConnection(c echo.Context) error {
+	return r.query(c)
+}
+
 // CreateConnection godoc
 // @Summary         Send a fact or authentication request
-// @Description     This endpoint allows you to send a fact or authentication request to a specified self user.
+// @Description     This endpoint allows you to send a fact or authentication request to a specified self user. Out of band requests return a QR code and, when configured, the URL of the app logo in qr_logo; the logo is not drawn into the QR code, clients overlay it on its center.
 // @Tags            Requests
 // @Accept          json
 // @Produce         json
@@ -93,3 +198,95 @@ func (r resource) create(c echo.Context) error {
 	request.AppID = c.Param("app_id")
 	return c.JSON(http.StatusAccepted, request)
 }
+
+// CancelRequest godoc
+// @Summary         Cancel a request
+// @Description     Withdraws a pending request, the responses received afterwards, including the ones to its QR code or deep link, are ignored.
+// @Tags            Requests
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Param           id      path      string  true  "Request ID"
+// @Success         200     {object}  ExtRequest        "Request cancelled"
+// @Failure         400     {object}  response.Error    "Request not pending"
+// @Failure         404     {object}  response.Error    "Request Not Found"
+// @Router          /apps/{app_id}/requests/{id}/cancel [post]
+func (r resource) cancel(c echo.Context) error {
+	ctx := c.Request().Context()
+	request, err := r.service.Cancel(ctx, c.Param("app_id"), c.Param("id"))
+	if errors.Is(err, ErrRequestClosed) {
+		return c.JSON(http.StatusBadRequest, &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		})
+	}
+	if err != nil {
+		r.logger.With(ctx).Warnf("error cancelling request: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	request.AppID = c.Param("app_id")
+	return c.JSON(http.StatusOK, request)
+}
+
+// GetRequestQRCode godoc
+// @Summary         Retrieve the QR code of a request
+// @Description     Regenerates the QR code of a pending out of band request, the QR code expires with the request.
+// @Tags            Requests
+// @Produce         png
+// @Produce         image/svg+xml
+// @Security        BearerAuth
+// @Param           app_id  path   string  true   "Application ID"
+// @Param           id      path   string  true   "Request ID"
+// @Param           format  query  string  false  "Format of the QR code, png by default" Enums(png, svg)
+// @Param           size    query  int     false  "Size in pixels of the QR code, the app size by default"
+// @Success         200     {file}    file            "QR code image"
+// @Failure         400     {object}  response.Error  "Invalid input, or request not pending"
+// @Failure         404     {object}  response.Error  "Request Not Found"
+// @Failure         500     {object}  response.Error  "Internal Server Error"
+// @Router          /apps/{app_id}/requests/{id}/qr [get]
+func (r resource) qr(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	params := QRParams{Format: c.QueryParam("format")}
+	if params.Format == "" {
+		params.Format = QR_FORMAT_PNG
+	}
+	if c.QueryParam("size") != "" {
+		size, err := strconv.Atoi(c.QueryParam("size"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &response.Error{
+				Status:  http.StatusBadRequest,
+				Error:   "Invalid input",
+				Details: "size: must be an integer.",
+			})
+		}
+		params.Size = size
+	}
+	if err := params.Validate(); err != nil {
+		return c.JSON(err.Status, err)
+	}
+
+	qrdata, err := r.service.QRCode(ctx, c.Param("app_id"), c.Param("id"), params.Format, params.Size)
+	if errors.Is(err, ErrRequestClosed) || errors.Is(err, ErrNotOutOfBand) {
+		return c.JSON(http.StatusBadRequest, &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		})
+	}
+	if errors.Is(err, ErrAppNotRunning) {
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+	if err != nil {
+		r.logger.With(ctx).Warnf("error generating request qr code: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	if params.Format == QR_FORMAT_SVG {
+		return c.Blob(http.StatusOK, "image/svg+xml", qrdata)
+	}
+	return c.Blob(http.StatusOK, "image/png", qrdata)
+}
@@ -164,3 +164,192 @@ func TestCreateRequestAPIEndpoint(t *testing.T) {
 		test.Endpoint(t, router, tc)
 	}
 }
+
+func TestListRequestsAPIEndpointAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"GET /apps/app_id/*", "GET /apps/count_error/requests", "GET /apps/query_error/requests"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "list",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests?status=requested&type=fact&out_of_band=false&from=1700000000",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"status":"requested"*`,
+		},
+		{
+			Name:         "list by connection",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/selfid/requests",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"connection_id":"selfid"*`,
+		},
+		{
+			Name:         "invalid status",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests?status=unknown",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"status: must be a valid value."}`,
+		},
+		{
+			Name:       "list rejected",
+			Method:     "GET",
+			URL:        "/apps/app_id/requests?status=rejected",
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name:         "invalid out of band",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests?out_of_band=maybe",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"out_of_band: must be a boolean."}`,
+		},
+		{
+			Name:         "invalid from",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests?from=yesterday",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"from: must be a Unix timestamp."}`,
+		},
+		{
+			Name:         "invalid to",
+			Method:       "GET",
+			URL:          "/apps/app_id/connections/selfid/requests?to=2024-01-01",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"to: must be a Unix timestamp."}`,
+		},
+		{
+			Name:       "count error",
+			Method:     "GET",
+			URL:        "/apps/count_error/requests",
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:       "query error",
+			Method:     "GET",
+			URL:        "/apps/query_error/requests",
+			WantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestCancelRequestAPIEndpointAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"POST /apps/app_id/requests/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "cancel",
+			Method:       "POST",
+			URL:          "/apps/app_id/requests/request_id/cancel",
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"id":"request_id","app_id":"app_id","status":"cancelled"}`,
+		},
+		{
+			Name:         "not pending",
+			Method:       "POST",
+			URL:          "/apps/app_id/requests/responded_id/cancel",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"request is not pending"}`,
+		},
+		{
+			Name:       "not found",
+			Method:     "POST",
+			URL:        "/apps/app_id/requests/not_found_id/cancel",
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:       "without permissions",
+			Method:     "POST",
+			URL:        "/apps/other_app/requests/request_id/cancel",
+			WantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestGetRequestQRCodeAPIEndpointAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"GET /apps/app_id/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, mockConnectionService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "png",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests/request_id/qr",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*png*`,
+		},
+		{
+			Name:         "svg",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests/request_id/qr?format=svg&size=300",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*svg*`,
+		},
+		{
+			Name:         "invalid format",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests/request_id/qr?format=gif",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"format: must be a valid value."}`,
+		},
+		{
+			Name:         "invalid size",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests/request_id/qr?size=big",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"size: must be an integer."}`,
+		},
+		{
+			Name:         "size too small",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests/request_id/qr?size=10",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"size: must be no less than 100."}`,
+		},
+		{
+			Name:         "not pending",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests/responded_id/qr",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"request is not pending"}`,
+		},
+		{
+			Name:         "not out of band",
+			Method:       "GET",
+			URL:          "/apps/app_id/requests/connection_id/qr",
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"request is not out of band"}`,
+		},
+		{
+			Name:       "not found",
+			Method:     "GET",
+			URL:        "/apps/app_id/requests/not_found_id/qr",
+			WantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/joinself/restful-client/internal/connection"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/support"
+	"github.com/joinself/restful-client/pkg/webhook"
 	selffact "github.com/joinself/self-go-sdk/fact"
 )
 
@@ -20,6 +21,26 @@ func (m mockService) Get(ctx context.Context, appID, id string) (ExtRequest, err
 	return ExtRequest{}, nil
 }
 
+func (m mockService) Count(ctx context.Context, appID string, filter entity.RequestFilter) (int, error) {
+	if appID == "count_error" {
+		return 0, errors.New("error!")
+	}
+	return 1, nil
+}
+
+func (m mockService) Query(ctx context.Context, appID string, filter entity.RequestFilter, offset, limit int) ([]RequestSummary, error) {
+	if appID == "query_error" {
+		return nil, errors.New("error!")
+	}
+	return []RequestSummary{{
+		ID:           "id",
+		Type:         "fact",
+		Status:       filter.Status,
+		ConnectionID: filter.Connection,
+		Facts:        []FactRequest{},
+	}}, nil
+}
+
 func (m mockService) Create(ctx context.Context, appID string, conn *entity.Connection, input CreateRequest) (ExtRequest, error) {
 	if appID == "error" {
 		return ExtRequest{}, errors.New("error!")
@@ -27,6 +48,28 @@ func (m mockService) Create(ctx context.Context, appID string, conn *entity.Conn
 	return ExtRequest{}, nil
 }
 
+func (m mockService) Cancel(ctx context.Context, appID, id string) (ExtRequest, error) {
+	if id == "not_found_id" {
+		return ExtRequest{}, errors.New("not found")
+	}
+	if id == "responded_id" {
+		return ExtRequest{}, ErrRequestClosed
+	}
+	return ExtRequest{ID: id, Status: entity.REQUEST_CANCELLED_STATUS}, nil
+}
+
+func (m mockService) QRCode(ctx context.Context, appID, id, format string, size int) ([]byte, error) {
+	switch id {
+	case "not_found_id":
+		return nil, errors.New("not found")
+	case "responded_id":
+		return nil, ErrRequestClosed
+	case "connection_id":
+		return nil, ErrNotOutOfBand
+	}
+	return []byte(format), nil
+}
+
 func (m mockService) CreateFactsFromResponse(conn entity.Connection, req entity.Request, facts []selffact.Fact) []entity.Fact {
 	return []entity.Fact{}
 }
@@ -81,3 +124,12 @@ func (m mockConnectionService) Delete(ctx context.Context, appid, selfid string)
 	}
 	return connection.Connection{}, nil
 }
+
+type mockNotifier struct {
+	history []webhook.WebhookPayload
+}
+
+func (m *mockNotifier) Notify(appID string, payload webhook.WebhookPayload) error {
+	m.history = append(m.history, payload)
+	return nil
+}
@@ -0,0 +1,58 @@
+package request
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DATE_FORMAT is the format of the dates the relative expected values resolve
+// to.
+const DATE_FORMAT = "2006-01-02"
+
+// relativeDate matches the expected values relative to the current day, as
+// "today", "today-18y", "today+30d" or "today-6m".
+var relativeDate = regexp.MustCompile(`^today(?:\s*([+-])\s*(\d+)([dmy]))?$`)
+
+var errInvalidRelativeDate = errors.New("must be today optionally followed by an offset in days, months or years, as today-18y")
+
+// resolveExpectedValue replaces the expected values relative to the current
+// day with the date they refer to, other values are returned as they are.
+func resolveExpectedValue(value string, now time.Time) (string, error) {
+	if len(value) < 5 || value[:5] != "today" {
+		return value, nil
+	}
+
+	m := relativeDate.FindStringSubmatch(value)
+	if m == nil {
+		return "", errInvalidRelativeDate
+	}
+	if m[1] == "" {
+		return now.Format(DATE_FORMAT), nil
+	}
+
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", errInvalidRelativeDate
+	}
+	if m[1] == "-" {
+		n = -n
+	}
+
+	switch m[3] {
+	case "d":
+		now = now.AddDate(0, 0, n)
+	case "m":
+		now = now.AddDate(0, n, 0)
+	case "y":
+		now = now.AddDate(n, 0, 0)
+	}
+	return now.Format(DATE_FORMAT), nil
+}
+
+// validExpectedValue validates the relative dates of the expected values.
+func validExpectedValue(value interface{}) error {
+	_, err := resolveExpectedValue(value.(string), time.Now())
+	return err
+}
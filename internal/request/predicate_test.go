@@ -0,0 +1,33 @@
+package request
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_resolveExpectedValue(t *testing.T) {
+	now := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"GB", "GB", false},
+		{"today", "2024-02-29", false},
+		{"today-18y", "2006-03-01", false},
+		{"today + 30d", "2024-03-30", false},
+		{"today-6m", "2023-08-29", false},
+		{"today-18", "", true},
+		{"tomorrow", "tomorrow", false},
+		{"todayish", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := resolveExpectedValue(tt.value, now)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+
+	"github.com/joinself/restful-client/internal/entity"
+)
+
+// svgQRCode traces a QR code rendered with a pixel per module into an SVG
+// image of the configured size and colors.
+func svgQRCode(data []byte, config entity.OutOfBandConfig) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// The quiet zone around the code has the background color.
+	bounds := img.Bounds()
+	bgR, bgG, bgB, bgA := img.At(bounds.Min.X, bounds.Min.Y).RGBA()
+
+	var path strings.Builder
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		run := 0
+		for x := bounds.Min.X; x <= bounds.Max.X; x++ {
+			dark := false
+			if x < bounds.Max.X {
+				r, g, b, a := img.At(x, y).RGBA()
+				dark = r != bgR || g != bgG || b != bgB || a != bgA
+			}
+			if dark {
+				run++
+				continue
+			}
+			if run > 0 {
+				fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x-run-bounds.Min.X, y-bounds.Min.Y, run, run)
+				run = 0
+			}
+		}
+	}
+
+	width, height := bounds.Dx(), bounds.Dy()
+	var svg bytes.Buffer
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, config.QRSize, config.QRSize, width, height)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="%s"/>`, width, height, config.QRBackgroundColor)
+	fmt.Fprintf(&svg, `<path fill="%s" d="%s"/>`, config.QRForegroundColor, path.String())
+	svg.WriteString(`</svg>`)
+
+	return svg.Bytes(), nil
+}
@@ -0,0 +1,42 @@
+package request
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_svgQRCode(t *testing.T) {
+	// 4x3 modules, with a quiet zone pixel around them.
+	img := image.NewRGBA(image.Rect(0, 0, 6, 5))
+	for x := 0; x < 6; x++ {
+		for y := 0; y < 5; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	img.Set(1, 1, color.Black)
+	img.Set(2, 1, color.Black)
+	img.Set(4, 2, color.Black)
+	img.Set(1, 3, color.Black)
+	img.Set(2, 3, color.Black)
+	img.Set(3, 3, color.Black)
+	img.Set(4, 3, color.Black)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	svg, err := svgQRCode(buf.Bytes(), entity.OutOfBandConfig{QRSize: 300, QRForegroundColor: "#0E1C42", QRBackgroundColor: "#FFFFFF"})
+	require.NoError(t, err)
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 6 5" shape-rendering="crispEdges">`+
+		`<rect width="6" height="5" fill="#FFFFFF"/>`+
+		`<path fill="#0E1C42" d="M1 1h2v1h-2zM4 2h1v1h-1zM1 3h4v1h-4z"/></svg>`, string(svg))
+
+	_, err = svgQRCode([]byte("not a png"), defaultOutOfBandConfig)
+	assert.NotNil(t, err)
+}
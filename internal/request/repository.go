@@ -23,7 +23,18 @@ type Repository interface {
 	Delete(ctx context.Context, id string) error
 	// SetStatus updates the status of the given request.
 	SetStatus(ctx context.Context, id string, status string) error
+	// Transition moves the request with the given ID from one status to
+	// another, reporting whether it was still in the former status.
+	Transition(ctx context.Context, id, from, to string) (bool, error)
 	GetByID(ctx context.Context, id string) (entity.Request, error)
+	// Count returns the number of requests of an app matching the given filter.
+	Count(ctx context.Context, appID string, filter entity.RequestFilter) (int, error)
+	// Query returns the requests of an app matching the given filter, newest
+	// first, with the given offset and limit.
+	Query(ctx context.Context, appID string, filter entity.RequestFilter, offset, limit int) ([]entity.RequestSummary, error)
+	// Expired returns up to limit pending requests that expired before the
+	// given time.
+	Expired(ctx context.Context, before time.Time, limit int) ([]entity.Request, error)
 }
 
 // repository persists requests in database
@@ -84,8 +95,86 @@ func (r repository) SetStatus(ctx context.Context, id string, status string) err
 	return r.Update(ctx, request)
 }
 
+// Transition updates the status of the request only if it still has the
+// expected one, so concurrent updates can't overwrite each other.
+func (r repository) Transition(ctx context.Context, id, from, to string) (bool, error) {
+	res, err := r.db.With(ctx).Update(
+		"request",
+		dbx.Params{"status": to, "updated_at": time.Now()},
+		dbx.HashExp{"id": id, "status": from},
+	).Execute()
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 func (r repository) GetByID(ctx context.Context, id string) (entity.Request, error) {
 	var request entity.Request
 	err := r.db.With(ctx).Select().Model(id, &request)
 	return request, err
 }
+
+// Count returns the number of requests of an app matching the given filter.
+func (r repository) Count(ctx context.Context, appID string, filter entity.RequestFilter) (int, error) {
+	var count int
+	err := r.filterQuery(ctx, appID, filter, "COUNT(*)").Row(&count)
+	return count, err
+}
+
+// Query retrieves the requests of an app matching the given filter, newest
+// first.
+func (r repository) Query(ctx context.Context, appID string, filter entity.RequestFilter, offset, limit int) ([]entity.RequestSummary, error) {
+	var requests []entity.RequestSummary
+	err := r.filterQuery(ctx, appID, filter, "request.*", "COALESCE(connection.selfid, '') AS selfid").
+		OrderBy("request.created_at DESC", "request.id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&requests)
+	return requests, err
+}
+
+// Expired retrieves the pending requests which expired before the given time,
+// oldest first.
+func (r repository) Expired(ctx context.Context, before time.Time, limit int) ([]entity.Request, error) {
+	var requests []entity.Request
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"status": entity.REQUEST_REQUESTED_STATUS}).
+		AndWhere(dbx.NewExp("expires_at IS NOT NULL AND expires_at <= {:before}", dbx.Params{"before": before})).
+		OrderBy("expires_at").
+		Limit(int64(limit)).
+		All(&requests)
+	return requests, err
+}
+
+func (r repository) filterQuery(ctx context.Context, appID string, filter entity.RequestFilter, columns ...string) *dbx.SelectQuery {
+	q := r.db.With(ctx).
+		Select(columns...).
+		From("request").
+		LeftJoin("connection", dbx.NewExp("connection.id = request.connection_id")).
+		Where(dbx.HashExp{"request.app_id": appID})
+
+	if filter.Status != "" {
+		q = q.AndWhere(dbx.HashExp{"request.status": filter.Status})
+	}
+	if filter.Type != "" {
+		q = q.AndWhere(dbx.HashExp{"request.type": filter.Type})
+	}
+	if filter.Connection != "" {
+		q = q.AndWhere(dbx.HashExp{"connection.selfid": filter.Connection})
+	}
+	if filter.OutOfBand != nil {
+		q = q.AndWhere(dbx.HashExp{"request.out_of_band": *filter.OutOfBand})
+	}
+	if !filter.From.IsZero() {
+		q = q.AndWhere(dbx.NewExp("request.created_at >= {:from}", dbx.Params{"from": filter.From}))
+	}
+	if !filter.To.IsZero() {
+		q = q.AndWhere(dbx.NewExp("request.created_at <= {:to}", dbx.Params{"to": filter.To}))
+	}
+
+	return q
+}
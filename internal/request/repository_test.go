@@ -3,6 +3,7 @@ package request
 import (
 	"context"
 	"math/rand"
+	"strconv"
 	"testing"
 	"time"
 
@@ -46,3 +47,123 @@ func TestRepository(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, req.ID, "")
 }
+
+func TestRepositoryQuery(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "request")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	connection := rand.Intn(99999999)
+	err := test.CreateConnection(ctx, db, connection)
+	assert.Nil(t, err)
+	appID := "app_" + strconv.Itoa(connection)
+	selfID := "connection_" + strconv.Itoa(connection)
+
+	now := time.Now()
+	requests := []entity.Request{
+		{ID: entity.GenerateID(), AppID: appID, Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, ConnectionID: &connection, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: entity.GenerateID(), AppID: appID, Type: "auth", Status: entity.REQUEST_RESPONDED_STATUS, ConnectionID: &connection, CreatedAt: now.Add(-time.Hour)},
+		{ID: entity.GenerateID(), AppID: appID, Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, OutOfBand: true, CreatedAt: now},
+		{ID: entity.GenerateID(), AppID: "other", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, CreatedAt: now},
+	}
+	for _, r := range requests {
+		r.UpdatedAt = r.CreatedAt
+		err = repo.Create(ctx, r)
+		assert.Nil(t, err)
+	}
+
+	oob := true
+	tests := []struct {
+		name   string
+		filter entity.RequestFilter
+		want   []string
+	}{
+		{"all", entity.RequestFilter{}, []string{requests[2].ID, requests[1].ID, requests[0].ID}},
+		{"status", entity.RequestFilter{Status: entity.REQUEST_REQUESTED_STATUS}, []string{requests[2].ID, requests[0].ID}},
+		{"type", entity.RequestFilter{Type: "auth"}, []string{requests[1].ID}},
+		{"connection", entity.RequestFilter{Connection: selfID}, []string{requests[1].ID, requests[0].ID}},
+		{"out of band", entity.RequestFilter{OutOfBand: &oob}, []string{requests[2].ID}},
+		{"created range", entity.RequestFilter{From: now.Add(-90 * time.Minute), To: now.Add(-time.Minute)}, []string{requests[1].ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := repo.Count(ctx, appID, tt.filter)
+			assert.Nil(t, err)
+			assert.Equal(t, len(tt.want), count)
+
+			items, err := repo.Query(ctx, appID, tt.filter, 0, 10)
+			assert.Nil(t, err)
+			ids := []string{}
+			for _, item := range items {
+				ids = append(ids, item.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+
+	// the connection self id is listed
+	items, err := repo.Query(ctx, appID, entity.RequestFilter{}, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "", items[0].Connection)
+	assert.Equal(t, selfID, items[1].Connection)
+}
+
+func TestRepositoryExpired(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "request")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	requests := []entity.Request{
+		{ID: entity.GenerateID(), AppID: "app", Status: entity.REQUEST_REQUESTED_STATUS, ExpiresAt: &past},
+		{ID: entity.GenerateID(), AppID: "app", Status: entity.REQUEST_REQUESTED_STATUS, ExpiresAt: &future},
+		{ID: entity.GenerateID(), AppID: "app", Status: entity.REQUEST_RESPONDED_STATUS, ExpiresAt: &past},
+		{ID: entity.GenerateID(), AppID: "app", Status: entity.REQUEST_REQUESTED_STATUS},
+	}
+	for _, r := range requests {
+		r.CreatedAt = now.Add(-time.Hour)
+		r.UpdatedAt = r.CreatedAt
+		err := repo.Create(ctx, r)
+		assert.Nil(t, err)
+	}
+
+	expired, err := repo.Expired(ctx, now, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(expired))
+	assert.Equal(t, requests[0].ID, expired[0].ID)
+
+	expired, err = repo.Expired(ctx, future, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(expired))
+}
+
+func TestRepositoryTransition(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "request")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	id := entity.GenerateID()
+	err := repo.Create(ctx, entity.Request{ID: id, AppID: "app", Status: entity.REQUEST_REQUESTED_STATUS})
+	assert.Nil(t, err)
+
+	ok, err := repo.Transition(ctx, id, entity.REQUEST_REQUESTED_STATUS, entity.REQUEST_CANCELLED_STATUS)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// the request is no longer pending
+	ok, err = repo.Transition(ctx, id, entity.REQUEST_REQUESTED_STATUS, entity.REQUEST_EXPIRED_STATUS)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	req, err := repo.GetByID(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.REQUEST_CANCELLED_STATUS, req.Status)
+}
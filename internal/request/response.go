@@ -0,0 +1,89 @@
+package request
+
+import (
+	"encoding/json"
+
+	"github.com/joinself/restful-client/internal/entity"
+	selffact "github.com/joinself/self-go-sdk/fact"
+)
+
+// ValidateResponse compares the facts received on a response with the ones
+// requested, returning the status of the request and the outcome of each
+// fact.
+func ValidateResponse(req entity.Request, facts []selffact.Fact) (string, []entity.FactResult) {
+	var requested []entity.RequestFacts
+	if len(req.Facts) > 0 {
+		if err := json.Unmarshal(req.Facts, &requested); err != nil {
+			requested = nil
+		}
+	}
+
+	received := map[string]selffact.Fact{}
+	for _, f := range facts {
+		received[f.Fact] = f
+	}
+
+	results := []entity.FactResult{}
+	missing, mismatched := false, false
+	for _, r := range requested {
+		result := entity.FactResult{
+			Fact:    r.Name,
+			Sources: r.Sources,
+			Status:  entity.FACT_RESULT_SATISFIED,
+		}
+
+		f, ok := received[r.Name]
+		delete(received, r.Name)
+		if ok && len(f.Sources) > 0 {
+			result.Source = f.Sources[0]
+		}
+
+		if ok && r.IsPredicate() {
+			holds := f.Result()
+			result.Result = &holds
+		}
+
+		switch {
+		case !ok || len(f.Attestations) == 0:
+			result.Status = entity.FACT_RESULT_MISSING
+			missing = true
+		case result.Source != "" && len(r.Sources) > 0 && !contains(r.Sources, result.Source):
+			result.Status = entity.FACT_RESULT_MISMATCHED
+			mismatched = true
+		}
+		results = append(results, result)
+	}
+
+	// The facts left were not requested.
+	for _, f := range facts {
+		if _, ok := received[f.Fact]; !ok {
+			continue
+		}
+		result := entity.FactResult{
+			Fact:   f.Fact,
+			Status: entity.FACT_RESULT_UNEXPECTED,
+		}
+		if len(f.Sources) > 0 {
+			result.Source = f.Sources[0]
+		}
+		results = append(results, result)
+		mismatched = true
+	}
+
+	switch {
+	case mismatched:
+		return entity.REQUEST_MISMATCHED_STATUS, results
+	case missing:
+		return entity.REQUEST_PARTIAL_STATUS, results
+	}
+	return entity.REQUEST_RESPONDED_STATUS, results
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package request
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/entity"
+	selffact "github.com/joinself/self-go-sdk/fact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateResponse(t *testing.T) {
+	attestations := []json.RawMessage{[]byte(`"attestation"`)}
+	req := entity.Request{Facts: []byte(`[{"name":"email_address","sources":["user_specified"]},{"name":"display_name"}]`)}
+	email := selffact.Fact{Fact: "email_address", Sources: []string{"user_specified"}, Attestations: attestations}
+	name := selffact.Fact{Fact: "display_name", Sources: []string{"user_specified"}, Attestations: attestations}
+
+	tests := []struct {
+		name        string
+		req         entity.Request
+		facts       []selffact.Fact
+		wantStatus  string
+		wantResults []string
+	}{
+		{"satisfied", req, []selffact.Fact{email, name}, entity.REQUEST_RESPONDED_STATUS, []string{entity.FACT_RESULT_SATISFIED, entity.FACT_RESULT_SATISFIED}},
+		{"missing fact", req, []selffact.Fact{email}, entity.REQUEST_PARTIAL_STATUS, []string{entity.FACT_RESULT_SATISFIED, entity.FACT_RESULT_MISSING}},
+		{"no attestations", req, []selffact.Fact{email, {Fact: "display_name"}}, entity.REQUEST_PARTIAL_STATUS, []string{entity.FACT_RESULT_SATISFIED, entity.FACT_RESULT_MISSING}},
+		{"mismatched source", req, []selffact.Fact{{Fact: "email_address", Sources: []string{"passport"}, Attestations: attestations}, name}, entity.REQUEST_MISMATCHED_STATUS, []string{entity.FACT_RESULT_MISMATCHED, entity.FACT_RESULT_SATISFIED}},
+		{"unexpected fact", req, []selffact.Fact{email, name, {Fact: "phone_number", Attestations: attestations}}, entity.REQUEST_MISMATCHED_STATUS, []string{entity.FACT_RESULT_SATISFIED, entity.FACT_RESULT_SATISFIED, entity.FACT_RESULT_UNEXPECTED}},
+		{"no requested facts", entity.Request{Type: "auth"}, nil, entity.REQUEST_RESPONDED_STATUS, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, results := ValidateResponse(tt.req, tt.facts)
+			assert.Equal(t, tt.wantStatus, status)
+			got := []string{}
+			for _, r := range results {
+				got = append(got, r.Status)
+			}
+			assert.Equal(t, tt.wantResults, got)
+		})
+	}
+
+	_, results := ValidateResponse(req, []selffact.Fact{email})
+	assert.Equal(t, entity.FactResult{Fact: "email_address", Sources: []string{"user_specified"}, Source: "user_specified", Status: entity.FACT_RESULT_SATISFIED}, results[0])
+
+	// predicates disclose whether they hold
+	predicate := entity.Request{Facts: []byte(`[{"name":"nationality","operator":"==","expected_value":"GB"}]`)}
+	_, results = ValidateResponse(predicate, []selffact.Fact{{Fact: "nationality", Attestations: []json.RawMessage{[]byte(`{"payload":"eyJuYXRpb25hbGl0eSI6ZmFsc2V9","protected":"eyJhbGciOiJFZERTQSJ9","signature":"c2ln"}`)}}})
+	require.NotNil(t, results[0].Result)
+	assert.False(t, *results[0].Result)
+	assert.Equal(t, entity.FACT_RESULT_SATISFIED, results[0].Status)
+}
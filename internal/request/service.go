@@ -3,6 +3,7 @@ package request
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	b64 "encoding/base64"
@@ -19,11 +20,26 @@ import (
 // Service encapsulates usecase logic for requests.
 type Service interface {
 	Get(ctx context.Context, appID, id string) (ExtRequest, error)
+	Count(ctx context.Context, appID string, filter entity.RequestFilter) (int, error)
+	Query(ctx context.Context, appID string, filter entity.RequestFilter, offset, limit int) ([]RequestSummary, error)
 	Create(ctx context.Context, appID string, conn *entity.Connection, input CreateRequest) (ExtRequest, error)
+	Cancel(ctx context.Context, appID, id string) (ExtRequest, error)
+	QRCode(ctx context.Context, appID, id, format string, size int) ([]byte, error)
 	CreateFactsFromResponse(conn entity.Connection, req entity.Request, facts []selffact.Fact) []entity.Fact
 	SetRunner(runner support.SelfClientGetter)
 }
 
+// ErrRequestClosed is returned when cancelling a request which is not
+// waiting for a response anymore.
+var ErrRequestClosed = errors.New("request is not pending")
+
+// ErrNotOutOfBand is returned when generating the QR code of a request sent to
+// a connection.
+var ErrNotOutOfBand = errors.New("request is not out of band")
+
+// ErrAppNotRunning is returned when the Self client of the app is not running.
+var ErrAppNotRunning = errors.New("app is not running")
+
 // RequesterService service to manage sending and receiving request requests
 type RequesterService interface {
 	Request(*selffact.FactRequest) (*selffact.FactResponse, error)
@@ -31,20 +47,35 @@ type RequesterService interface {
 	GenerateDeepLink(req *selffact.DeepLinkFactRequest) (string, error)
 }
 
+// AppGetter retrieves the apps the requests are sent from.
+type AppGetter interface {
+	Get(ctx context.Context, id string) (entity.App, error)
+}
+
+// defaultOutOfBandConfig is the config used to build the QR codes of the apps
+// without out of band settings.
+var defaultOutOfBandConfig = entity.OutOfBandConfig{
+	QRSize:            400,
+	QRForegroundColor: "#000000",
+	QRBackgroundColor: "#FFFFFF",
+}
+
 type service struct {
 	repo   Repository
 	fRepo  fact.Repository
 	atRepo attestation.Repository
+	apps   AppGetter
 	runner support.SelfClientGetter
 	logger log.Logger
 }
 
 // NewService creates a new request service.
-func NewService(repo Repository, fRepo fact.Repository, atRepo attestation.Repository, logger log.Logger) Service {
+func NewService(repo Repository, fRepo fact.Repository, atRepo attestation.Repository, apps AppGetter, logger log.Logger) Service {
 	return &service{
 		repo:   repo,
 		fRepo:  fRepo,
 		atRepo: atRepo,
+		apps:   apps,
 		logger: logger,
 	}
 }
@@ -82,20 +113,70 @@ func (s service) Get(ctx context.Context, appID, id string) (ExtRequest, error)
 	return ExtRequest{
 		ID:        request.ID,
 		Status:    request.Status,
+		ExpiresAt: request.ExpiresAt,
 		Resources: resources,
 	}, nil
 }
 
+// Count returns the number of requests of an app matching the given filter.
+func (s service) Count(ctx context.Context, appID string, filter entity.RequestFilter) (int, error) {
+	return s.repo.Count(ctx, appID, filter)
+}
+
+// Query returns the requests of an app matching the given filter, newest
+// first.
+func (s service) Query(ctx context.Context, appID string, filter entity.RequestFilter, offset, limit int) ([]RequestSummary, error) {
+	items, err := s.repo.Query(ctx, appID, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []RequestSummary{}
+	for _, item := range items {
+		facts := []FactRequest{}
+		if len(item.Facts) > 0 {
+			err = json.Unmarshal(item.Facts, &facts)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result = append(result, RequestSummary{
+			ID:           item.ID,
+			Type:         item.Type,
+			Status:       item.Status,
+			ConnectionID: item.Connection,
+			Description:  item.Description,
+			Facts:        facts,
+			OutOfBand:    item.OutOfBand,
+			ExpiresAt:    item.ExpiresAt,
+			CreatedAt:    item.CreatedAt,
+			UpdatedAt:    item.UpdatedAt,
+		})
+	}
+	return result, nil
+}
+
 // Create creates a new request.
 func (s service) Create(ctx context.Context, appID string, connection *entity.Connection, req CreateRequest) (ExtRequest, error) {
 	id := entity.GenerateID()
 	now := time.Now()
+	expiresAt := now.Add(entity.DEFAULT_REQUEST_EXPIRY)
+	if req.Expiry > 0 {
+		expiresAt = now.Add(time.Duration(req.Expiry) * time.Second)
+	}
 
 	facts := make([]entity.RequestFacts, len(req.Facts))
 	for i, f := range req.Facts {
+		expected, err := resolveExpectedValue(f.ExpectedValue, now)
+		if err != nil {
+			return ExtRequest{}, err
+		}
 		facts[i] = entity.RequestFacts{
-			Sources: f.Sources,
-			Name:    f.Name,
+			Sources:       f.Sources,
+			Name:          f.Name,
+			Operator:      f.Operator,
+			ExpectedValue: expected,
 		}
 	}
 	factsBody, err := json.Marshal(facts)
@@ -108,11 +189,12 @@ func (s service) Create(ctx context.Context, appID string, connection *entity.Co
 		AppID:       appID,
 		Type:        req.Type,
 		Facts:       factsBody,
-		Status:      "requested",
+		Status:      entity.REQUEST_REQUESTED_STATUS,
 		Callback:    req.Callback,
 		Description: req.Description,
 		OutOfBand:   req.OutOfBand,
 		AllowedFor:  time.Duration(req.AllowedFor),
+		ExpiresAt:   &expiresAt,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -124,38 +206,60 @@ func (s service) Create(ctx context.Context, appID string, connection *entity.Co
 		f.Auth = true
 	}
 
+	var config entity.OutOfBandConfig
+	if req.OutOfBand {
+		config = s.outOfBandConfig(ctx, appID, req.OutOfBandConfig)
+		f.OutOfBandConfig, err = json.Marshal(config)
+		if err != nil {
+			return ExtRequest{}, err
+		}
+	}
+
 	err = s.repo.Create(ctx, f)
 	if err != nil {
 		return ExtRequest{}, err
 	}
 
 	if req.OutOfBand {
-		r, err := s.buildSelfFactQRRequest(f)
-		if err != nil {
-			s.logger.Debug("error building Self Fact Request")
-			return ExtRequest{}, err
-		}
-
 		client, ok := s.runner.Get(appID)
 		if !ok {
-			s.logger.Debug("client %s not found", appID)
-			return ExtRequest{}, err
+			s.logger.Debugf("client %s not found", appID)
+			return ExtRequest{}, ErrAppNotRunning
 		}
 
-		qrdata, err := client.FactService().GenerateQRCode(r)
-		if err != nil {
-			s.logger.Debug("error generating QR Code")
-			return ExtRequest{}, err
+		qrcode := ""
+		if !req.OmitQRCode {
+			r, err := s.buildSelfFactQRRequest(f, config)
+			if err != nil {
+				s.logger.Debug("error building Self Fact Request")
+				return ExtRequest{}, err
+			}
+
+			qrdata, err := client.FactService().GenerateQRCode(r)
+			if err != nil {
+				s.logger.Debug("error generating QR Code")
+				return ExtRequest{}, err
+			}
+			qrcode = b64.StdEncoding.EncodeToString(qrdata)
 		}
+
 		link := ""
-		dlr, err := s.buildSelfFactDLRequest(f, appID)
-		if err == nil {
-			link, err = client.FactService().GenerateDeepLink(dlr)
+		if config.DLCode != "" {
+			dlr, err := s.buildSelfFactDLRequest(f, config)
+			if err == nil {
+				link, err = client.FactService().GenerateDeepLink(dlr)
+			}
+			if err != nil {
+				s.logger.Warnf("error generating deep link: %v", err)
+			}
 		}
 
 		persisted, err := s.Get(ctx, appID, id)
-		persisted.QRCode = b64.StdEncoding.EncodeToString(qrdata)
+		persisted.QRCode = qrcode
 		persisted.DeepLink = link
+		// The logo is not drawn into the QR code, it is returned for the
+		// clients to overlay.
+		persisted.QRLogo = config.QRLogo
 
 		return persisted, err
 	}
@@ -168,6 +272,85 @@ func (s service) Create(ctx context.Context, appID string, connection *entity.Co
 	return s.Get(ctx, appID, id)
 }
 
+// Cancel withdraws a pending request. The Self protocol has no message to
+// withdraw a request, so the responses received afterwards, including the ones
+// to its QR code or deep link, are discarded.
+func (s service) Cancel(ctx context.Context, appID, id string) (ExtRequest, error) {
+	request, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return ExtRequest{}, err
+	}
+
+	if !request.IsPending() || request.IsExpired(time.Now()) {
+		return ExtRequest{}, ErrRequestClosed
+	}
+
+	ok, err := s.repo.Transition(ctx, request.ID, entity.REQUEST_REQUESTED_STATUS, entity.REQUEST_CANCELLED_STATUS)
+	if err != nil {
+		return ExtRequest{}, err
+	}
+	if !ok {
+		return ExtRequest{}, ErrRequestClosed
+	}
+
+	return s.Get(ctx, appID, id)
+}
+
+// QRCode regenerates the QR code of a pending out of band request in the given
+// format, it expires with the request.
+func (s service) QRCode(ctx context.Context, appID, id, format string, size int) ([]byte, error) {
+	request, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !request.IsOutOfBand() {
+		return nil, ErrNotOutOfBand
+	}
+	if !request.IsPending() || request.IsExpired(time.Now()) {
+		return nil, ErrRequestClosed
+	}
+
+	config := defaultOutOfBandConfig
+	if len(request.OutOfBandConfig) > 0 {
+		var stored entity.OutOfBandConfig
+		if err = json.Unmarshal(request.OutOfBandConfig, &stored); err != nil {
+			return nil, err
+		}
+		config = config.Override(stored)
+	}
+	if size > 0 {
+		config.QRSize = size
+	}
+
+	r, err := s.buildSelfFactQRRequest(request, config)
+	if err != nil {
+		return nil, err
+	}
+	if request.ExpiresAt != nil {
+		r.Expiry = time.Until(*request.ExpiresAt)
+	}
+	if format == QR_FORMAT_SVG {
+		// Render a module per pixel, to be traced into the SVG.
+		r.QRConfig.Size = -1
+	}
+
+	client, ok := s.runner.Get(appID)
+	if !ok {
+		return nil, ErrAppNotRunning
+	}
+
+	qrdata, err := client.FactService().GenerateQRCode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == QR_FORMAT_SVG {
+		return svgQRCode(qrdata, config)
+	}
+	return qrdata, nil
+}
+
 // sendRequest sends a request to the specified connection through Self Network.
 func (s service) sendRequest(req entity.Request, appid, selfID string) {
 	// Check if the self is initialized.
@@ -203,8 +386,10 @@ func (s service) buildSelfFactRequestAsync(selfID string, req entity.Request) (*
 	facts := make([]selffact.Fact, len(incomingFacts))
 	for i, f := range incomingFacts {
 		facts[i] = selffact.Fact{
-			Fact:    f.Name,
-			Sources: f.Sources,
+			Fact:          f.Name,
+			Sources:       f.Sources,
+			Operator:      f.Operator,
+			ExpectedValue: f.ExpectedValue,
 		}
 	}
 
@@ -213,7 +398,7 @@ func (s service) buildSelfFactRequestAsync(selfID string, req entity.Request) (*
 		SelfID:      selfID,
 		Description: req.Description,
 		Facts:       facts,
-		Expiry:      time.Minute * 5,
+		Expiry:      req.Expiry(),
 		AllowedFor:  req.AllowedFor,
 	}
 
@@ -224,8 +409,26 @@ func (s service) buildSelfFactRequestAsync(selfID string, req entity.Request) (*
 	return r, nil
 }
 
+// outOfBandConfig returns the settings used to build the QR code and deep link
+// of an out of band request, the ones given override the app settings.
+func (s service) outOfBandConfig(ctx context.Context, appID string, override *entity.OutOfBandConfig) entity.OutOfBandConfig {
+	config := defaultOutOfBandConfig
+	if s.apps != nil {
+		app, err := s.apps.Get(ctx, appID)
+		if err == nil {
+			config = config.Override(app.OutOfBandConfig)
+		} else {
+			s.logger.Debugf("using default out of band settings for app %s: %v", appID, err)
+		}
+	}
+	if override != nil {
+		config = config.Override(*override)
+	}
+	return config
+}
+
 // buildSelfFactQRRequest builds a fact request from a given entity.Request
-func (s service) buildSelfFactQRRequest(req entity.Request) (*selffact.QRFactRequest, error) {
+func (s service) buildSelfFactQRRequest(req entity.Request, config entity.OutOfBandConfig) (*selffact.QRFactRequest, error) {
 	var incomingFacts []entity.RequestFacts
 	err := json.Unmarshal(req.Facts, &incomingFacts)
 	if err != nil {
@@ -236,8 +439,10 @@ func (s service) buildSelfFactQRRequest(req entity.Request) (*selffact.QRFactReq
 	facts := make([]selffact.Fact, len(incomingFacts))
 	for i, f := range incomingFacts {
 		facts[i] = selffact.Fact{
-			Fact:    f.Name,
-			Sources: f.Sources,
+			Fact:          f.Name,
+			Sources:       f.Sources,
+			Operator:      f.Operator,
+			ExpectedValue: f.ExpectedValue,
 		}
 	}
 
@@ -245,11 +450,11 @@ func (s service) buildSelfFactQRRequest(req entity.Request) (*selffact.QRFactReq
 		ConversationID: req.ID,
 		Description:    req.Description,
 		Facts:          facts,
-		Expiry:         time.Minute * 5,
+		Expiry:         req.Expiry(),
 		QRConfig: selffact.QRConfig{
-			Size:            400,       // this is optional/defaulted
-			BackgroundColor: "#FFFFFF", // this is optional/defaulted
-			ForegroundColor: "#000000", // this is optional/defaulted
+			Size:            config.QRSize,
+			BackgroundColor: config.QRBackgroundColor,
+			ForegroundColor: config.QRForegroundColor,
 		},
 	}
 
@@ -260,11 +465,8 @@ func (s service) buildSelfFactQRRequest(req entity.Request) (*selffact.QRFactReq
 	return r, nil
 }
 
-// buildSelfFactQRRequest builds a fact request from a given entity.Request
-func (s service) buildSelfFactDLRequest(req entity.Request, appID string) (*selffact.DeepLinkFactRequest, error) {
-	// FIXME: dlCodes must be stored on the database, so consumed through the app_repository instead
-	dlCode := "default_non_working_dl_code"
-
+// buildSelfFactDLRequest builds a fact request from a given entity.Request
+func (s service) buildSelfFactDLRequest(req entity.Request, config entity.OutOfBandConfig) (*selffact.DeepLinkFactRequest, error) {
 	var incomingFacts []entity.RequestFacts
 	err := json.Unmarshal(req.Facts, &incomingFacts)
 	if err != nil {
@@ -275,8 +477,10 @@ func (s service) buildSelfFactDLRequest(req entity.Request, appID string) (*self
 	facts := make([]selffact.Fact, len(incomingFacts))
 	for i, f := range incomingFacts {
 		facts[i] = selffact.Fact{
-			Fact:    f.Name,
-			Sources: f.Sources,
+			Fact:          f.Name,
+			Sources:       f.Sources,
+			Operator:      f.Operator,
+			ExpectedValue: f.ExpectedValue,
 		}
 	}
 
@@ -284,8 +488,8 @@ func (s service) buildSelfFactDLRequest(req entity.Request, appID string) (*self
 		ConversationID: req.ID,
 		Description:    req.Description,
 		Facts:          facts,
-		Callback:       dlCode,
-		Expiry:         time.Minute * 5,
+		Callback:       config.DLCode,
+		Expiry:         req.Expiry(),
 	}
 
 	if req.Auth {
@@ -303,6 +507,9 @@ func (s service) markRequestAs(id, status string) {
 }
 
 func (s service) CreateFactsFromResponse(conn entity.Connection, req entity.Request, facts []selffact.Fact) []entity.Fact {
+	predicates := requestedPredicates(req)
+	histories := map[string][]byte{}
+
 	output := []entity.Fact{}
 	for _, receivedFact := range facts {
 		// Create the received fact.
@@ -327,6 +534,13 @@ func (s service) CreateFactsFromResponse(conn entity.Connection, req entity.Requ
 		if len(req.ID) > 0 {
 			f.RequestID = &req.ID
 		}
+		predicate, ok := predicates[receivedFact.Fact]
+		if ok {
+			result := receivedFact.Result()
+			f.Operator = predicate.Operator
+			f.ExpectedValue = predicate.ExpectedValue
+			f.Result = &result
+		}
 
 		err := s.fRepo.Create(context.Background(), f)
 		if err != nil {
@@ -334,27 +548,90 @@ func (s service) CreateFactsFromResponse(conn entity.Connection, req entity.Requ
 			continue
 		}
 
-		s.createAttestations(id, receivedFact)
+		s.createAttestations(conn.AppID, id, receivedFact, ok, histories)
 		output = append(output, f)
 	}
 	return output
 }
 
-func (s service) createAttestations(id string, fact selffact.Fact) {
+func (s service) createAttestations(appID, id string, fact selffact.Fact, predicate bool, histories map[string][]byte) {
 	// Create the relative attestations.
 	now := time.Now()
-	for _, v := range fact.AttestedValues() {
-		err := s.atRepo.Create(context.Background(), entity.Attestation{
+	values := fact.AttestedValues()
+	for i, body := range fact.Attestations {
+		a := entity.Attestation{
 			ID:        uuid.New().String(),
-			Body:      "TODO", // TODO: store body.
+			Body:      string(body),
 			FactID:    id,
-			Value:     v,
 			CreatedAt: now,
 			UpdatedAt: now,
-		})
+		}
+		err := attestation.Details(&a)
+		if err != nil {
+			s.logger.Warnf("failed parsing attestation: %v", err)
+		}
+		a.History = s.keyHistory(appID, a.Issuer, histories)
+
+		if i < len(values) {
+			a.Value = values[i]
+			if predicate {
+				// Predicate attestations hold whether the predicate holds
+				// instead of the fact value.
+				result := a.Value == "true"
+				a.Result = &result
+			}
+		}
+		err = s.atRepo.Create(context.Background(), a)
 		if err != nil {
 			s.logger.Errorf("failed creating attestation: %v", err)
 			continue
 		}
 	}
 }
+
+// keyHistory returns the public key history of the given issuer, so the
+// attestation signatures can be verified later on. Histories are cached
+// for the duration of a response.
+func (s service) keyHistory(appID, issuer string, histories map[string][]byte) []byte {
+	if issuer == "" {
+		return nil
+	}
+	if h, ok := histories[issuer]; ok {
+		return h
+	}
+
+	var history []byte
+	if s.runner != nil {
+		if client, ok := s.runner.Get(appID); ok && client != nil {
+			keys, err := client.IdentityService().GetHistory(issuer)
+			if err != nil {
+				s.logger.Warnf("failed retrieving public keys for %s: %v", issuer, err)
+			} else {
+				history, _ = json.Marshal(keys)
+			}
+		}
+	}
+	histories[issuer] = history
+
+	return history
+}
+
+// requestedPredicates returns the facts of a request requested as predicates
+// by name.
+func requestedPredicates(req entity.Request) map[string]entity.RequestFacts {
+	predicates := map[string]entity.RequestFacts{}
+	if len(req.Facts) == 0 {
+		return predicates
+	}
+
+	var requested []entity.RequestFacts
+	if err := json.Unmarshal(req.Facts, &requested); err != nil {
+		return predicates
+	}
+	for _, f := range requested {
+		if f.IsPredicate() {
+			predicates[f.Name] = f
+		}
+	}
+	return predicates
+}
@@ -1,9 +1,17 @@
 package request
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/mock"
+	selffact "github.com/joinself/self-go-sdk/fact"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateMessageRequest_Validate(t *testing.T) {
@@ -16,6 +24,17 @@ func TestCreateMessageRequest_Validate(t *testing.T) {
 		{"fact", CreateRequest{Type: "fact"}, false},
 		{"invalid", CreateRequest{Type: "invalid"}, true},
 		{"empty", CreateRequest{Type: ""}, true},
+		{"expiry", CreateRequest{Type: "fact", Expiry: 3600}, false},
+		{"negative expiry", CreateRequest{Type: "fact", Expiry: -1}, true},
+		{"expiry too long", CreateRequest{Type: "fact", Expiry: MAX_EXPIRY + 1}, true},
+		{"out of band config", CreateRequest{Type: "auth", OutOfBandConfig: &entity.OutOfBandConfig{QRSize: 300, QRBackgroundColor: "#FAFAFA", QRLogo: "https://example.com/logo.png"}}, false},
+		{"invalid qr color", CreateRequest{Type: "auth", OutOfBandConfig: &entity.OutOfBandConfig{QRBackgroundColor: "white"}}, true},
+		{"invalid qr size", CreateRequest{Type: "auth", OutOfBandConfig: &entity.OutOfBandConfig{QRSize: MAX_QR_SIZE + 1}}, true},
+		{"predicate", CreateRequest{Type: "fact", Facts: []FactRequest{{Name: "date_of_birth", Operator: "<=", ExpectedValue: "today-18y"}}}, false},
+		{"invalid operator", CreateRequest{Type: "fact", Facts: []FactRequest{{Name: "nationality", Operator: "~", ExpectedValue: "GB"}}}, true},
+		{"operator without value", CreateRequest{Type: "fact", Facts: []FactRequest{{Name: "nationality", Operator: "=="}}}, true},
+		{"value without operator", CreateRequest{Type: "fact", Facts: []FactRequest{{Name: "nationality", ExpectedValue: "GB"}}}, true},
+		{"invalid relative date", CreateRequest{Type: "fact", Facts: []FactRequest{{Name: "date_of_birth", Operator: "<=", ExpectedValue: "today-18 years"}}}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -24,3 +43,164 @@ func TestCreateMessageRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func Test_service_Query(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "1", AppID: "app", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, Description: "kyc", Facts: []byte(`[{"name":"email_address","sources":["user_specified"]}]`)},
+		{ID: "2", AppID: "app", Type: "auth", Status: entity.REQUEST_RESPONDED_STATUS},
+		{ID: "3", AppID: "other", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS},
+	}}
+	s := NewService(repo, nil, nil, nil, logger)
+	ctx := context.Background()
+
+	filter := entity.RequestFilter{Status: entity.REQUEST_REQUESTED_STATUS}
+	count, err := s.Count(ctx, "app", filter)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	requests, err := s.Query(ctx, "app", filter, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, "kyc", requests[0].Description)
+	assert.Equal(t, []FactRequest{{Name: "email_address", Sources: []string{"user_specified"}}}, requests[0].Facts)
+
+	requests, err = s.Query(ctx, "app", entity.RequestFilter{Type: "auth"}, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []FactRequest{}, requests[0].Facts)
+}
+
+func Test_service_Cancel(t *testing.T) {
+	logger, _ := log.NewForTest()
+	past := time.Now().Add(-time.Minute)
+	repo := &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "1", AppID: "app", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, Facts: []byte(`[]`)},
+		{ID: "2", AppID: "app", Type: "fact", Status: entity.REQUEST_RESPONDED_STATUS, Facts: []byte(`[]`)},
+		{ID: "3", AppID: "app", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, Facts: []byte(`[]`), ExpiresAt: &past},
+	}}
+	s := NewService(repo, nil, nil, nil, logger)
+	ctx := context.Background()
+
+	r, err := s.Cancel(ctx, "app", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, entity.REQUEST_CANCELLED_STATUS, r.Status)
+	assert.Equal(t, entity.REQUEST_CANCELLED_STATUS, repo.Items[0].Status)
+
+	_, err = s.Cancel(ctx, "app", "1")
+	assert.Equal(t, ErrRequestClosed, err)
+	_, err = s.Cancel(ctx, "app", "2")
+	assert.Equal(t, ErrRequestClosed, err)
+	_, err = s.Cancel(ctx, "app", "3")
+	assert.Equal(t, ErrRequestClosed, err)
+	_, err = s.Cancel(ctx, "app", "unknown")
+	assert.NotNil(t, err)
+}
+
+func Test_service_outOfBandConfig(t *testing.T) {
+	logger, _ := log.NewForTest()
+	apps := &mock.AppRepositoryMock{Items: []entity.App{
+		{ID: "app", OutOfBandConfig: entity.OutOfBandConfig{DLCode: "dl_code", QRForegroundColor: "#0E1C42"}},
+	}}
+	s := service{apps: apps, logger: logger}
+	ctx := context.Background()
+
+	assert.Equal(t, defaultOutOfBandConfig, s.outOfBandConfig(ctx, "unknown", nil))
+	assert.Equal(t, entity.OutOfBandConfig{
+		DLCode:            "dl_code",
+		QRSize:            400,
+		QRForegroundColor: "#0E1C42",
+		QRBackgroundColor: "#FFFFFF",
+	}, s.outOfBandConfig(ctx, "app", nil))
+	assert.Equal(t, entity.OutOfBandConfig{
+		DLCode:            "dl_code",
+		QRSize:            250,
+		QRForegroundColor: "#0E1C42",
+		QRBackgroundColor: "#FFFFFF",
+		QRLogo:            "https://example.com/logo.png",
+	}, s.outOfBandConfig(ctx, "app", &entity.OutOfBandConfig{QRSize: 250, QRLogo: "https://example.com/logo.png"}))
+
+	r, err := s.buildSelfFactDLRequest(entity.Request{ID: "id", Facts: []byte(`[]`)}, s.outOfBandConfig(ctx, "app", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "dl_code", r.Callback)
+}
+
+func Test_service_QRCode(t *testing.T) {
+	logger, _ := log.NewForTest()
+	past := time.Now().Add(-time.Minute)
+	repo := &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "1", AppID: "app", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, Facts: []byte(`[]`)},
+		{ID: "2", AppID: "app", Type: "fact", Status: entity.REQUEST_CANCELLED_STATUS, OutOfBand: true, Facts: []byte(`[]`)},
+		{ID: "3", AppID: "app", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, OutOfBand: true, Facts: []byte(`[]`), ExpiresAt: &past},
+		{ID: "4", AppID: "app", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, OutOfBand: true, Facts: []byte(`[]`)},
+	}}
+	s := NewService(repo, nil, nil, nil, logger)
+	s.SetRunner(mock.NewRunnerMock())
+	ctx := context.Background()
+
+	_, err := s.QRCode(ctx, "app", "1", QR_FORMAT_PNG, 0)
+	assert.Equal(t, ErrNotOutOfBand, err)
+	_, err = s.QRCode(ctx, "app", "2", QR_FORMAT_PNG, 0)
+	assert.Equal(t, ErrRequestClosed, err)
+	_, err = s.QRCode(ctx, "app", "3", QR_FORMAT_PNG, 0)
+	assert.Equal(t, ErrRequestClosed, err)
+	_, err = s.QRCode(ctx, "app", "4", QR_FORMAT_PNG, 0)
+	assert.Equal(t, ErrAppNotRunning, err)
+	_, err = s.QRCode(ctx, "app", "unknown", QR_FORMAT_PNG, 0)
+	assert.NotNil(t, err)
+}
+
+func Test_service_CreateFactsFromResponsePredicates(t *testing.T) {
+	logger, _ := log.NewForTest()
+	fRepo := &mock.FactRepositoryMock{}
+	atRepo := &mock.AttestationRepositoryMock{}
+	s := NewService(&mock.RequestRepositoryMock{}, fRepo, atRepo, nil, logger)
+
+	// an unverified JWS attesting the predicate holds.
+	attestation := []byte(`{"payload":"eyJuYXRpb25hbGl0eSI6dHJ1ZX0","protected":"eyJhbGciOiJFZERTQSJ9","signature":"c2ln"}`)
+	req := entity.Request{ID: "req", Facts: []byte(`[{"name":"nationality","operator":"==","expected_value":"GB"},{"name":"display_name"}]`)}
+	facts := s.CreateFactsFromResponse(entity.Connection{ID: 1, SelfID: "selfid"}, req, []selffact.Fact{
+		{Fact: "nationality", Attestations: []json.RawMessage{attestation}},
+		{Fact: "display_name"},
+	})
+
+	require.Equal(t, 2, len(facts))
+	assert.Equal(t, "==", facts[0].Operator)
+	assert.Equal(t, "GB", facts[0].ExpectedValue)
+	require.NotNil(t, facts[0].Result)
+	assert.True(t, *facts[0].Result)
+	assert.Nil(t, facts[1].Result)
+	assert.Equal(t, 2, len(fRepo.Items))
+}
+
+func Test_validateListParams(t *testing.T) {
+	assert.Nil(t, validateListParams(entity.RequestFilter{Status: "responded", Type: "fact"}))
+	assert.NotNil(t, validateListParams(entity.RequestFilter{Status: "unknown"}))
+	assert.NotNil(t, validateListParams(entity.RequestFilter{Type: "unknown"}))
+}
+
+func Test_service_CreateFactsFromResponseAttestations(t *testing.T) {
+	logger, _ := log.NewForTest()
+	atRepo := &mock.AttestationRepositoryMock{}
+	s := NewService(&mock.RequestRepositoryMock{}, &mock.FactRepositoryMock{}, atRepo, nil, logger)
+	s.SetRunner(mock.NewRunnerMock())
+
+	// {"iss":"issuer","source":"passport","iat":"2024-08-18T09:00:00Z","exp":"2024-08-19T09:00:00Z"} signed by kid "1".
+	body := `{"payload":"eyJpc3MiOiJpc3N1ZXIiLCJzb3VyY2UiOiJwYXNzcG9ydCIsImlhdCI6IjIwMjQtMDgtMThUMDk6MDA6MDBaIiwiZXhwIjoiMjAyNC0wOC0xOVQwOTowMDowMFoifQ","protected":"eyJhbGciOiJFZERTQSIsImtpZCI6IjEifQ","signature":"c2ln"}`
+	facts := s.CreateFactsFromResponse(entity.Connection{ID: 1, AppID: "app", SelfID: "selfid"}, entity.Request{}, []selffact.Fact{
+		{Fact: "document_number", Sources: []string{"passport"}, Attestations: []json.RawMessage{json.RawMessage(body)}},
+	})
+
+	require.Equal(t, 1, len(facts))
+	require.Equal(t, 1, len(atRepo.Items))
+	a := atRepo.Items[0]
+	assert.Equal(t, body, a.Body)
+	assert.Equal(t, "issuer", a.Issuer)
+	assert.Equal(t, "passport", a.Source)
+	assert.Equal(t, "1", a.KID)
+	require.NotNil(t, a.IssuedAt)
+	assert.Equal(t, "2024-08-18T09:00:00Z", a.IssuedAt.Format(time.RFC3339))
+	require.NotNil(t, a.ExpiresAt)
+	assert.Equal(t, "2024-08-19T09:00:00Z", a.ExpiresAt.Format(time.RFC3339))
+	// the app is not running, so the issuer keys could not be cached.
+	assert.Nil(t, a.History)
+}
@@ -2,34 +2,117 @@ package request
 
 import (
 	"net/http"
+	"regexp"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/response"
+	selffact "github.com/joinself/self-go-sdk/fact"
 )
 
+// MAX_EXPIRY is the maximum number of seconds a request accepts responses
+// for.
+const MAX_EXPIRY = int64(30 * 24 * 60 * 60)
+
+const (
+	// MIN_QR_SIZE is the minimum size in pixels of the generated QR codes.
+	MIN_QR_SIZE = 100
+	// MAX_QR_SIZE is the maximum size in pixels of the generated QR codes.
+	MAX_QR_SIZE = 2000
+)
+
+const (
+	// QR_FORMAT_PNG is the format of the PNG QR codes.
+	QR_FORMAT_PNG = "png"
+	// QR_FORMAT_SVG is the format of the SVG QR codes.
+	QR_FORMAT_SVG = "svg"
+)
+
+// operators are the comparison operators of the predicate fact requests.
+var operators = []interface{}{
+	selffact.OperatorEqual,
+	selffact.OperatorDifferent,
+	selffact.OperatorGreaterOrEqualThan,
+	selffact.OperatorLessOrEqualThan,
+	selffact.OperatorGreaterThan,
+	selffact.OperatorLessThan,
+}
+
+// hexColor matches the colors in #RRGGBB format.
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// statuses are the statuses requests can be filtered by.
+var statuses = []interface{}{
+	entity.REQUEST_REQUESTED_STATUS,
+	entity.REQUEST_RESPONDED_STATUS,
+	entity.REQUEST_PARTIAL_STATUS,
+	entity.REQUEST_MISMATCHED_STATUS,
+	entity.REQUEST_EXPIRED_STATUS,
+	entity.REQUEST_CANCELLED_STATUS,
+	entity.STATUS_REJECTED,
+	entity.STATUS_ERRORED,
+}
+
 type ExtResource struct {
 	ID           string `json:"id"`
 	ConnectionID string `json:"connection_id"`
 }
 
+// ExtRequest represents a request. The QRLogo of out of band requests is the
+// URL of the app logo, which is not drawn into the QR code and is meant to be
+// overlaid on its center by the clients.
 type ExtRequest struct {
 	ID        string        `json:"id"`
 	AppID     string        `json:"app_id"`
 	Status    string        `json:"status,omitempty"`
 	QRCode    string        `json:"qr_code,omitempty"`
 	DeepLink  string        `json:"deep_link,omitempty"`
+	QRLogo    string        `json:"qr_logo,omitempty"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
 	Resources []ExtResource `json:"resources,omitempty"`
 }
 
+// RequestSummary represents a listed request.
+type RequestSummary struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	// ConnectionID is the self id of the connection the request was sent to,
+	// empty for out of band requests.
+	ConnectionID string        `json:"connection_id,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Facts        []FactRequest `json:"facts"`
+	OutOfBand    bool          `json:"out_of_band"`
+	ExpiresAt    *time.Time    `json:"expires_at,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// ExtListResponse represents the json object returned when listing requests.
+type ExtListResponse struct {
+	Page       int              `json:"page"`
+	PerPage    int              `json:"per_page"`
+	PageCount  int              `json:"page_count"`
+	TotalCount int              `json:"total_count"`
+	Items      []RequestSummary `json:"items"`
+}
+
 type FactRequest struct {
 	Sources []string `json:"sources,omitempty"`
 	Name    string   `json:"name"`
+	// Operator and ExpectedValue request a predicate on the fact instead of
+	// its value, dates can be relative to the current day, as "today-18y".
+	Operator      string `json:"operator,omitempty"`
+	ExpectedValue string `json:"expected_value,omitempty"`
 }
 
 func (f FactRequest) Validate() error {
 	return validation.ValidateStruct(&f,
 		validation.Field(&f.Name, validation.Required, validation.Length(3, 128)),
+		validation.Field(&f.Operator, validation.When(f.ExpectedValue != "", validation.Required), validation.In(operators...)),
+		validation.Field(&f.ExpectedValue, validation.When(f.Operator != "", validation.Required), validation.Length(0, 255), validation.By(validExpectedValue)),
 	)
 }
 
@@ -42,6 +125,12 @@ type CreateRequest struct {
 	SelfID      string        `json:"connection_self_id"`
 	OutOfBand   bool          `json:"out_of_band,omitempty"`
 	AllowedFor  int64         `json:"allowed_for,omitempty"`
+	Expiry      int64         `json:"expiry,omitempty"`
+	// OutOfBandConfig overrides the app out of band settings for this request.
+	OutOfBandConfig *entity.OutOfBandConfig `json:"out_of_band_config,omitempty"`
+	// OmitQRCode skips the inline QR code of out of band requests, which can
+	// be retrieved later on the request QR code endpoint.
+	OmitQRCode bool `json:"omit_qr_code,omitempty"`
 }
 
 // Validate validates the CreateRequest fields.
@@ -51,6 +140,13 @@ func (m CreateRequest) Validate() *response.Error {
 		validation.Field(&m.Type, validation.In("auth", "fact")),
 		validation.Field(&m.Description, validation.Length(0, 128)),
 		validation.Field(&m.Callback, is.URL),
+		validation.Field(&m.Expiry, validation.Min(int64(0)), validation.Max(MAX_EXPIRY)),
+		validation.Field(&m.OutOfBandConfig, validation.By(func(value interface{}) error {
+			if m.OutOfBandConfig == nil {
+				return nil
+			}
+			return ValidateOutOfBandConfig(*m.OutOfBandConfig)
+		})),
 	)
 	if err != nil {
 		return &response.Error{
@@ -72,3 +168,66 @@ func (m CreateRequest) Validate() *response.Error {
 
 	return nil
 }
+
+// ValidateOutOfBandConfig validates the out of band settings of an app or a
+// request.
+func ValidateOutOfBandConfig(c entity.OutOfBandConfig) error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.DLCode, validation.Length(0, 255)),
+		validation.Field(&c.QRSize, validation.Min(MIN_QR_SIZE), validation.Max(MAX_QR_SIZE)),
+		validation.Field(&c.QRForegroundColor, validation.Match(hexColor)),
+		validation.Field(&c.QRBackgroundColor, validation.Match(hexColor)),
+		validation.Field(&c.QRLogo, validation.Length(0, 2048), is.URL),
+	)
+}
+
+// QRParams represents the parameters of a QR code regeneration.
+type QRParams struct {
+	Format string `json:"format"`
+	Size   int    `json:"size"`
+}
+
+// Validate validates the QRParams fields.
+func (m QRParams) Validate() *response.Error {
+	err := validation.ValidateStruct(&m,
+		validation.Field(&m.Format, validation.In(QR_FORMAT_PNG, QR_FORMAT_SVG)),
+		validation.Field(&m.Size, validation.Min(MIN_QR_SIZE), validation.Max(MAX_QR_SIZE)),
+	)
+	if err == nil {
+		return nil
+	}
+
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: err.Error(),
+	}
+}
+
+// invalidParam builds the error returned for a query parameter that cannot
+// be parsed.
+func invalidParam(name, reason string) *response.Error {
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: name + ": " + reason + ".",
+	}
+}
+
+// validateListParams validates the filters applied when listing requests.
+func validateListParams(f entity.RequestFilter) *response.Error {
+	err := validation.ValidateStruct(&f,
+		validation.Field(&f.Status, validation.In(statuses...)),
+		validation.Field(&f.Type, validation.In("auth", "fact")),
+		validation.Field(&f.Connection, validation.Length(0, 128)),
+	)
+	if err != nil {
+		return &response.Error{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid input",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/webhook"
+)
+
+const (
+	sweepInterval  = 30 * time.Second
+	sweepBatchSize = 100
+)
+
+// Notifier sends webhooks to the callback of an app.
+type Notifier interface {
+	Notify(appID string, payload webhook.WebhookPayload) error
+}
+
+// Sweeper periodically moves the pending requests past their expiry to the
+// expired status.
+type Sweeper struct {
+	repo     Repository
+	notifier Notifier
+	logger   log.Logger
+	interval time.Duration
+	quit     chan bool
+	wg       sync.WaitGroup
+}
+
+// NewSweeper creates a new expired requests sweeper.
+func NewSweeper(repo Repository, notifier Notifier, logger log.Logger) *Sweeper {
+	return &Sweeper{
+		repo:     repo,
+		notifier: notifier,
+		logger:   logger,
+		interval: sweepInterval,
+		quit:     make(chan bool),
+	}
+}
+
+// Start begins sweeping the expired requests.
+func (s *Sweeper) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+				s.Sweep(context.Background(), time.Now())
+			}
+		}
+	}()
+}
+
+// Stop signals the sweeper to stop
+func (s *Sweeper) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// Sweep expires the pending requests which expired before the given time,
+// returning the number of expired requests.
+func (s *Sweeper) Sweep(ctx context.Context, now time.Time) int {
+	expired := 0
+	for {
+		requests, err := s.repo.Expired(ctx, now, sweepBatchSize)
+		if err != nil {
+			s.logger.Errorf("error retrieving expired requests: %v", err)
+			return expired
+		}
+
+		for _, req := range requests {
+			if err := Expire(ctx, s.repo, s.notifier, req); err != nil {
+				s.logger.Errorf("error expiring request %s: %v", req.ID, err)
+				return expired
+			}
+			expired++
+		}
+
+		if len(requests) < sweepBatchSize {
+			return expired
+		}
+	}
+}
+
+// Expire marks the given pending request as expired and notifies the app
+// about it. Requests answered or cancelled meanwhile are left untouched.
+func Expire(ctx context.Context, repo Repository, notifier Notifier, req entity.Request) error {
+	ok, err := repo.Transition(ctx, req.ID, entity.REQUEST_REQUESTED_STATUS, entity.REQUEST_EXPIRED_STATUS)
+	if err != nil || !ok {
+		return err
+	}
+
+	req.Status = entity.REQUEST_EXPIRED_STATUS
+	return notifier.Notify(req.AppID, ExpiredPayload(req))
+}
+
+// ExpiredPayload builds the webhook sent when a request expires.
+func ExpiredPayload(req entity.Request) webhook.WebhookPayload {
+	return webhook.WebhookPayload{
+		Type: webhook.TYPE_REQUEST_EXPIRED,
+		URI:  fmt.Sprintf("/apps/%s/requests/%s", req.AppID, req.ID),
+		Data: ExtRequest{
+			ID:        req.ID,
+			AppID:     req.AppID,
+			Status:    req.Status,
+			ExpiresAt: req.ExpiresAt,
+		},
+	}
+}
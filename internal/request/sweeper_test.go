@@ -0,0 +1,38 @@
+package request
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/mock"
+	"github.com/joinself/restful-client/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweeper_Sweep(t *testing.T) {
+	logger, _ := log.NewForTest()
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	repo := &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "1", AppID: "app", Status: entity.REQUEST_REQUESTED_STATUS, ExpiresAt: &past},
+		{ID: "2", AppID: "app", Status: entity.REQUEST_REQUESTED_STATUS, ExpiresAt: &future},
+		{ID: "3", AppID: "app", Status: entity.REQUEST_RESPONDED_STATUS, ExpiresAt: &past},
+	}}
+	notifier := &mockNotifier{}
+	s := NewSweeper(repo, notifier, logger)
+
+	assert.Equal(t, 1, s.Sweep(context.Background(), now))
+	assert.Equal(t, entity.REQUEST_EXPIRED_STATUS, repo.Items[0].Status)
+	assert.Equal(t, entity.REQUEST_REQUESTED_STATUS, repo.Items[1].Status)
+	assert.Equal(t, 1, len(notifier.history))
+	assert.Equal(t, webhook.TYPE_REQUEST_EXPIRED, notifier.history[0].Type)
+	assert.Equal(t, "/apps/app/requests/1", notifier.history[0].URI)
+
+	// already expired requests are not notified again
+	assert.Equal(t, 0, s.Sweep(context.Background(), now))
+	assert.Equal(t, 1, len(notifier.history))
+}
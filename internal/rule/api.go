@@ -0,0 +1,214 @@
+package rule
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/pagination"
+	"github.com/joinself/restful-client/pkg/response"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *echo.Group, service Service, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.GET("/:app_id/rules/:id", res.get)
+	r.GET("/:app_id/rules", res.query)
+	r.POST("/:app_id/rules", res.create)
+	r.POST("/:app_id/rules/dry-run", res.dryRun)
+	r.PUT("/:app_id/rules/:id", res.update)
+	r.DELETE("/:app_id/rules/:id", res.delete)
+}
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+// GetRule godoc
+// @Summary         Retrieve a rule
+// @Description     Get the details of an inbound message rule of an app.
+// @Tags            rules
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Param           id      path      string  true  "Rule ID"
+// @Success         200     {object}  Rule              "Successful Response"
+// @Failure         404     {object}  response.Error    "Rule Not Found"
+// @Router          /apps/{app_id}/rules/{id} [get]
+func (r resource) get(c echo.Context) error {
+	rule, err := r.service.Get(c.Request().Context(), c.Param("app_id"), c.Param("id"))
+	if err != nil {
+		r.logger.With(c.Request().Context()).Warnf("error retrieving rule: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// ListRules godoc
+// @Summary         List rules
+// @Description     Retrieves the inbound message rules of an app in evaluation order.
+// @Tags            rules
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path   string  true  "Application ID"
+// @Param           page query int false "Page number for results pagination"
+// @Param           per_page query int false "Number of results per page for pagination"
+// @Success         200  {object}  ExtListResponse "Successfully retrieved the rules"
+// @Failure         500  {object}  response.Error "Internal server error"
+// @Router          /apps/{app_id}/rules [get]
+func (r resource) query(c echo.Context) error {
+	ctx := c.Request().Context()
+	count, err := r.service.Count(ctx, c.Param("app_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting rules: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	rules, err := r.service.Query(ctx, c.Param("app_id"), pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving rules: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = rules
+	return c.JSON(http.StatusOK, pages)
+}
+
+// CreateRule godoc
+// @Summary         Create a rule
+// @Description     Creates a rule evaluated on every message received by the app. When all its conditions match (keyword, regex, connection tag or time of day) its actions are run: replying, replying with a template, requesting facts, updating the connection tags or sending the message webhook to a specific endpoint instead of the app callback.
+// @Tags            rules
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path      string             true  "Application ID"
+// @Param           request  body      CreateRuleRequest  true  "Rule details"
+// @Success         201      {object}  Rule               "Rule created"
+// @Failure         400      {object}  response.Error     "Invalid input"
+// @Failure         500      {object}  response.Error     "Internal Server Error"
+// @Router          /apps/{app_id}/rules [post]
+func (r resource) create(c echo.Context) error {
+	ctx := c.Request().Context()
+	var input CreateRuleRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("error invalid input: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+
+	if err := input.Validate(); err != nil {
+		r.logger.With(ctx).Infof("error invalid input: %s", err.Error)
+		return c.JSON(err.Status, err)
+	}
+
+	rule, err := r.service.Create(ctx, c.Param("app_id"), input)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error creating rule: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule godoc
+// @Summary         Update a rule
+// @Description     Replaces the name, priority, conditions and actions of a rule, or enables and disables it.
+// @Tags            rules
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path      string             true  "Application ID"
+// @Param           id       path      string             true  "Rule ID"
+// @Param           request  body      UpdateRuleRequest  true  "Rule details"
+// @Success         200      {object}  Rule               "Rule updated"
+// @Failure         400      {object}  response.Error     "Invalid input"
+// @Failure         404      {object}  response.Error     "Rule Not Found"
+// @Router          /apps/{app_id}/rules/{id} [put]
+func (r resource) update(c echo.Context) error {
+	ctx := c.Request().Context()
+	var input UpdateRuleRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("error invalid input: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+
+	if err := input.Validate(); err != nil {
+		r.logger.With(ctx).Infof("error invalid input: %s", err.Error)
+		return c.JSON(err.Status, err)
+	}
+
+	rule, err := r.service.Update(ctx, c.Param("app_id"), c.Param("id"), input)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error updating rule: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule godoc
+// @Summary         Delete a rule
+// @Description     Deletes an inbound message rule.
+// @Tags            rules
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Param           id      path      string  true  "Rule ID"
+// @Success         200     {object}  Rule              "Rule deleted"
+// @Failure         404     {object}  response.Error    "Rule Not Found"
+// @Router          /apps/{app_id}/rules/{id} [delete]
+func (r resource) delete(c echo.Context) error {
+	rule, err := r.service.Delete(c.Request().Context(), c.Param("app_id"), c.Param("id"))
+	if err != nil {
+		r.logger.With(c.Request().Context()).Warnf("error deleting rule: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// DryRunRules godoc
+// @Summary         Dry run the rules
+// @Description     Evaluates the enabled rules of an app against a message without running their actions, and returns the matching rules in evaluation order. The connection tags are used when tags are not provided.
+// @Tags            rules
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path      string         true  "Application ID"
+// @Param           request  body      DryRunRequest  true  "Message details"
+// @Success         200      {array}   DryRunMatch    "Matching rules"
+// @Failure         400      {object}  response.Error "Invalid input"
+// @Failure         404      {object}  response.Error "Connection Not Found"
+// @Failure         500      {object}  response.Error "Internal Server Error"
+// @Router          /apps/{app_id}/rules/dry-run [post]
+func (r resource) dryRun(c echo.Context) error {
+	ctx := c.Request().Context()
+	var input DryRunRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("error invalid input: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+
+	if err := input.Validate(); err != nil {
+		r.logger.With(ctx).Infof("error invalid input: %s", err.Error)
+		return c.JSON(err.Status, err)
+	}
+
+	matches, err := r.service.DryRun(ctx, c.Param("app_id"), input)
+	if errors.Is(err, ErrConnectionNotFound) {
+		return c.JSON(response.DefaultNotFoundError())
+	}
+	if err != nil {
+		r.logger.With(ctx).Warnf("error evaluating rules: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, matches)
+}
@@ -0,0 +1,149 @@
+package rule
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/acl"
+	"github.com/joinself/restful-client/pkg/filter"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+func TestRuleAPIEndpointsAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"ANY /apps/app_id/rules", "ANY /apps/app_id/rules/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/rules/id",
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"id":"id", "name":"help", "enabled":true, "priority":0, "conditions":[], "actions":[], "created_at":"0001-01-01T00:00:00Z", "updated_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			Name:         "get not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/rules/not_found_id",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "list",
+			Method:       "GET",
+			URL:          "/apps/app_id/rules",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"total_count":1*`,
+		},
+		{
+			Name:         "create",
+			Method:       "POST",
+			URL:          "/apps/app_id/rules",
+			Body:         `{"name":"help","conditions":[{"type":"keyword","value":"help"}],"actions":[{"type":"reply","body":"hi"}]}`,
+			WantStatus:   http.StatusCreated,
+			WantResponse: `*"actions":[{"type":"reply","body":"hi"}]*`,
+		},
+		{
+			Name:         "create invalid condition",
+			Method:       "POST",
+			URL:          "/apps/app_id/rules",
+			Body:         `{"name":"help","conditions":[{"type":"regex","value":"("}],"actions":[{"type":"reply","body":"hi"}]}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"conditions: (0: (value: must be a valid regular expression.).)."}`,
+		},
+		{
+			Name:       "create error",
+			Method:     "POST",
+			URL:        "/apps/app_id/rules",
+			Body:       `{"name":"error","conditions":[{"type":"keyword","value":"help"}],"actions":[{"type":"reply","body":"hi"}]}`,
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:       "update",
+			Method:     "PUT",
+			URL:        "/apps/app_id/rules/id",
+			Body:       `{"name":"help","conditions":[{"type":"tag","value":"vip"}],"actions":[{"type":"webhook","url":"https://example.com/hook"}]}`,
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name:       "update invalid",
+			Method:     "PUT",
+			URL:        "/apps/app_id/rules/id",
+			Body:       `{"name":"help"}`,
+			WantStatus: http.StatusBadRequest,
+		},
+		{
+			Name:       "update not found",
+			Method:     "PUT",
+			URL:        "/apps/app_id/rules/not_found_id",
+			Body:       `{"name":"help","conditions":[{"type":"tag","value":"vip"}],"actions":[{"type":"reply","body":"hi"}]}`,
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:       "delete",
+			Method:     "DELETE",
+			URL:        "/apps/app_id/rules/id",
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name:       "delete not found",
+			Method:     "DELETE",
+			URL:        "/apps/app_id/rules/not_found_id",
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:         "dry run",
+			Method:       "POST",
+			URL:          "/apps/app_id/rules/dry-run",
+			Body:         `{"body":"help"}`,
+			WantStatus:   http.StatusOK,
+			WantResponse: `[{"rule_id":"id","name":"help","actions":[{"type":"reply","body":"hi"}]}]`,
+		},
+		{
+			Name:       "dry run invalid",
+			Method:     "POST",
+			URL:        "/apps/app_id/rules/dry-run",
+			Body:       `{}`,
+			WantStatus: http.StatusBadRequest,
+		},
+		{
+			Name:       "dry run connection not found",
+			Method:     "POST",
+			URL:        "/apps/app_id/rules/dry-run",
+			Body:       `{"body":"help","connection_id":"not_found"}`,
+			WantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestRuleAPIEndpointsAsPlainWithoutPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/rules/id",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
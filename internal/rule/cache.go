@@ -0,0 +1,54 @@
+package rule
+
+import (
+	"sync"
+
+	"github.com/joinself/restful-client/internal/entity"
+)
+
+// cache keeps the enabled rules of each app with their compiled conditions,
+// as the rules are loaded for every message received. Cached rules are
+// reused while they're not updated.
+type cache struct {
+	mu   sync.Mutex
+	apps map[string]map[string]Rule
+}
+
+func newCache() *cache {
+	return &cache{apps: map[string]map[string]Rule{}}
+}
+
+// rules returns the rules built from the given enabled rules of the app. The
+// cache of the app only keeps the given rules, so the ones disabled or
+// deleted are dropped.
+func (c *cache) rules(appID string, items []entity.Rule) ([]Rule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := c.apps[appID]
+	rules := map[string]Rule{}
+	result := []Rule{}
+	for _, item := range items {
+		r, ok := cached[item.ID]
+		if !ok || !r.UpdatedAt.Equal(item.UpdatedAt) {
+			var err error
+			r, err = newRuleFromEntity(item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		rules[item.ID] = r
+		result = append(result, r)
+	}
+	c.apps[appID] = rules
+
+	return result, nil
+}
+
+// forget removes the given rule of the app from the cache.
+func (c *cache) forget(appID, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.apps[appID], id)
+}
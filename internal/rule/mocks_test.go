@@ -0,0 +1,141 @@
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/internal/request"
+)
+
+type mockService struct{}
+
+func (m mockService) Get(ctx context.Context, appID, id string) (Rule, error) {
+	if id == "not_found_id" {
+		return Rule{}, sql.ErrNoRows
+	}
+	return Rule{ID: id, Name: "help", Enabled: true, Conditions: []Condition{}, Actions: []Action{}}, nil
+}
+
+func (m mockService) Query(ctx context.Context, appID string, offset, limit int) ([]Rule, error) {
+	if appID == "query_error" {
+		return nil, errors.New("error!")
+	}
+	return []Rule{{ID: "id", Name: "help", Enabled: true, Conditions: []Condition{}, Actions: []Action{}}}, nil
+}
+
+func (m mockService) Count(ctx context.Context, appID string) (int, error) {
+	if appID == "count_error" {
+		return 0, errors.New("error!")
+	}
+	return 1, nil
+}
+
+func (m mockService) Create(ctx context.Context, appID string, input CreateRuleRequest) (Rule, error) {
+	if input.Name == "error" {
+		return Rule{}, errors.New("error!")
+	}
+	return Rule{ID: "id", Name: input.Name, Enabled: true, Conditions: input.Conditions, Actions: input.Actions}, nil
+}
+
+func (m mockService) Update(ctx context.Context, appID, id string, input UpdateRuleRequest) (Rule, error) {
+	if id == "not_found_id" {
+		return Rule{}, sql.ErrNoRows
+	}
+	return Rule{ID: id, Name: input.Name, Enabled: true, Conditions: input.Conditions, Actions: input.Actions}, nil
+}
+
+func (m mockService) Delete(ctx context.Context, appID, id string) (Rule, error) {
+	if id == "not_found_id" {
+		return Rule{}, sql.ErrNoRows
+	}
+	return Rule{ID: id, Name: "help", Enabled: true, Conditions: []Condition{}, Actions: []Action{}}, nil
+}
+
+func (m mockService) DryRun(ctx context.Context, appID string, input DryRunRequest) ([]DryRunMatch, error) {
+	if input.ConnectionID == "not_found" {
+		return nil, ErrConnectionNotFound
+	}
+	return []DryRunMatch{{RuleID: "id", Name: "help", Actions: []Action{{Type: ACTION_REPLY, Body: "hi"}}}}, nil
+}
+
+func (m mockService) Evaluate(ctx context.Context, appID, selfID string, msg entity.Message) []string {
+	return nil
+}
+
+type mockRepository struct {
+	items []entity.Rule
+}
+
+func (m *mockRepository) Get(ctx context.Context, appID, id string) (entity.Rule, error) {
+	for _, item := range m.items {
+		if item.AppID == appID && item.ID == id {
+			return item, nil
+		}
+	}
+	return entity.Rule{}, sql.ErrNoRows
+}
+
+func (m *mockRepository) Count(ctx context.Context, appID string) (int, error) {
+	return len(m.items), nil
+}
+
+func (m *mockRepository) Query(ctx context.Context, appID string, offset, limit int) ([]entity.Rule, error) {
+	return m.items, nil
+}
+
+func (m *mockRepository) Enabled(ctx context.Context, appID string) ([]entity.Rule, error) {
+	rules := []entity.Rule{}
+	for _, item := range m.items {
+		if item.AppID == appID && item.Enabled {
+			rules = append(rules, item)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+	return rules, nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, rule entity.Rule) error {
+	m.items = append(m.items, rule)
+	return nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, rule entity.Rule) error {
+	for i, item := range m.items {
+		if item.ID == rule.ID {
+			m.items[i] = rule
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, appID, id string) error {
+	for i, item := range m.items {
+		if item.AppID == appID && item.ID == id {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+type mockMessageSender struct {
+	sent []message.CreateMessageRequest
+}
+
+func (m *mockMessageSender) Create(ctx context.Context, appID, selfID string, connection int, req message.CreateMessageRequest) (message.Message, error) {
+	m.sent = append(m.sent, req)
+	return message.Message{Body: req.Body}, nil
+}
+
+type mockFactRequester struct {
+	requested []request.CreateRequest
+}
+
+func (m *mockFactRequester) Create(ctx context.Context, appID string, conn *entity.Connection, req request.CreateRequest) (request.ExtRequest, error) {
+	m.requested = append(m.requested, req)
+	return request.ExtRequest{}, nil
+}
@@ -0,0 +1,103 @@
+package rule
+
+import (
+	"context"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/dbcontext"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// Repository encapsulates the logic to access rules from the data source.
+type Repository interface {
+	// Get returns the rule with the specified ID.
+	Get(ctx context.Context, appID, id string) (entity.Rule, error)
+	// Count returns the number of rules of an app.
+	Count(ctx context.Context, appID string) (int, error)
+	// Query returns the list of rules of an app with the given offset and limit.
+	Query(ctx context.Context, appID string, offset, limit int) ([]entity.Rule, error)
+	// Enabled returns the enabled rules of an app in evaluation order.
+	Enabled(ctx context.Context, appID string) ([]entity.Rule, error)
+	// Create saves a new rule in the storage.
+	Create(ctx context.Context, rule entity.Rule) error
+	// Update updates the rule with given ID in the storage.
+	Update(ctx context.Context, rule entity.Rule) error
+	// Delete removes the rule with given ID from the storage.
+	Delete(ctx context.Context, appID, id string) error
+}
+
+// repository persists rules in database
+type repository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+// NewRepository creates a new rule repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+// Get reads the rule with the specified ID from the database.
+func (r repository) Get(ctx context.Context, appID, id string) (entity.Rule, error) {
+	var rule entity.Rule
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"id": id, "app_id": appID}).
+		One(&rule)
+	return rule, err
+}
+
+// Count returns the number of rules of an app in the database.
+func (r repository) Count(ctx context.Context, appID string) (int, error) {
+	var count int
+	err := r.db.With(ctx).
+		Select("COUNT(*)").
+		From("rule").
+		Where(dbx.HashExp{"app_id": appID}).
+		Row(&count)
+	return count, err
+}
+
+// Query retrieves the rules of an app in evaluation order.
+func (r repository) Query(ctx context.Context, appID string, offset, limit int) ([]entity.Rule, error) {
+	var rules []entity.Rule
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"app_id": appID}).
+		OrderBy("priority", "created_at").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&rules)
+	return rules, err
+}
+
+// Enabled retrieves the enabled rules of an app in evaluation order.
+func (r repository) Enabled(ctx context.Context, appID string) ([]entity.Rule, error) {
+	var rules []entity.Rule
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"app_id": appID, "enabled": true}).
+		OrderBy("priority", "created_at").
+		All(&rules)
+	return rules, err
+}
+
+// Create saves a new rule record in the database.
+func (r repository) Create(ctx context.Context, rule entity.Rule) error {
+	return r.db.With(ctx).Model(&rule).Insert()
+}
+
+// Update saves the changes to a rule in the database.
+func (r repository) Update(ctx context.Context, rule entity.Rule) error {
+	return r.db.With(ctx).Model(&rule).Update()
+}
+
+// Delete deletes the rule with the specified ID from the database.
+func (r repository) Delete(ctx context.Context, appID, id string) error {
+	rule, err := r.Get(ctx, appID, id)
+	if err != nil {
+		return err
+	}
+	return r.db.With(ctx).Model(&rule).Delete()
+}
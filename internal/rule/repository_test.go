@@ -0,0 +1,76 @@
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "rule")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	appID := "app_" + uuid.New().String()
+
+	// create
+	rules := []entity.Rule{
+		{ID: uuid.New().String(), AppID: appID, Name: "late", Enabled: true, Priority: 2},
+		{ID: uuid.New().String(), AppID: appID, Name: "disabled", Enabled: false, Priority: 0},
+		{ID: uuid.New().String(), AppID: appID, Name: "early", Enabled: true, Priority: 1},
+	}
+	for _, r := range rules {
+		r.Conditions = []byte(`[{"type":"keyword","value":"help"}]`)
+		r.Actions = []byte(`[{"type":"reply","body":"hi"}]`)
+		r.CreatedAt = time.Now()
+		r.UpdatedAt = time.Now()
+		err := repo.Create(ctx, r)
+		assert.Nil(t, err)
+	}
+
+	// get
+	stored, err := repo.Get(ctx, appID, rules[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "late", stored.Name)
+	assert.Equal(t, `[{"type":"reply","body":"hi"}]`, string(stored.Actions))
+	_, err = repo.Get(ctx, "other", rules[0].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// update
+	stored.Enabled = false
+	err = repo.Update(ctx, stored)
+	assert.Nil(t, err)
+	stored, _ = repo.Get(ctx, appID, rules[0].ID)
+	assert.False(t, stored.Enabled)
+
+	// query
+	count, err := repo.Count(ctx, appID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	items, err := repo.Query(ctx, appID, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"disabled", "early", "late"}, []string{items[0].Name, items[1].Name, items[2].Name})
+
+	// enabled
+	items, err = repo.Enabled(ctx, appID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "early", items[0].Name)
+
+	// delete
+	err = repo.Delete(ctx, appID, rules[0].ID)
+	assert.Nil(t, err)
+	_, err = repo.Get(ctx, appID, rules[0].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+	err = repo.Delete(ctx, appID, rules[0].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,401 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
+	"github.com/joinself/restful-client/internal/request"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// ErrConnectionNotFound is returned when dry running the rules for a
+// connection that does not exist.
+var ErrConnectionNotFound = errors.New("connection not found")
+
+// Service encapsulates usecase logic for inbound message rules.
+type Service interface {
+	Get(ctx context.Context, appID, id string) (Rule, error)
+	Query(ctx context.Context, appID string, offset, limit int) ([]Rule, error)
+	Count(ctx context.Context, appID string) (int, error)
+	Create(ctx context.Context, appID string, input CreateRuleRequest) (Rule, error)
+	Update(ctx context.Context, appID, id string, input UpdateRuleRequest) (Rule, error)
+	Delete(ctx context.Context, appID, id string) (Rule, error)
+	DryRun(ctx context.Context, appID string, input DryRunRequest) ([]DryRunMatch, error)
+	Evaluate(ctx context.Context, appID, selfID string, msg entity.Message) []string
+}
+
+// MessageSender sends the replies of the rules.
+type MessageSender interface {
+	Create(ctx context.Context, appID, selfID string, connection int, req message.CreateMessageRequest) (message.Message, error)
+}
+
+// FactRequester sends the fact requests of the rules.
+type FactRequester interface {
+	Create(ctx context.Context, appID string, conn *entity.Connection, req request.CreateRequest) (request.ExtRequest, error)
+}
+
+// Rule represents the data about an inbound message rule.
+type Rule struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Enabled    bool        `json:"enabled"`
+	Priority   int         `json:"priority"`
+	Conditions []Condition `json:"conditions"`
+	Actions    []Action    `json:"actions"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+type service struct {
+	repo     Repository
+	messages MessageSender
+	requests FactRequester
+	cRepo    connection.Repository
+	cache    *cache
+	logger   log.Logger
+}
+
+// NewService creates a new rule service.
+func NewService(repo Repository, messages MessageSender, requests FactRequester, cRepo connection.Repository, logger log.Logger) Service {
+	return service{repo, messages, requests, cRepo, newCache(), logger}
+}
+
+// Get returns the rule with the specified ID.
+func (s service) Get(ctx context.Context, appID, id string) (Rule, error) {
+	rule, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return Rule{}, err
+	}
+	return newRuleFromEntity(rule)
+}
+
+// Query returns the rules of an app with the specified offset and limit.
+func (s service) Query(ctx context.Context, appID string, offset, limit int) ([]Rule, error) {
+	items, err := s.repo.Query(ctx, appID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newRulesFromEntities(items)
+}
+
+// Count returns the number of rules of an app.
+func (s service) Count(ctx context.Context, appID string) (int, error) {
+	return s.repo.Count(ctx, appID)
+}
+
+// Create creates a new rule.
+func (s service) Create(ctx context.Context, appID string, req CreateRuleRequest) (Rule, error) {
+	now := time.Now()
+	rule := entity.Rule{
+		ID:        uuid.New().String(),
+		AppID:     appID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := setRuleFields(&rule, UpdateRuleRequest(req))
+	if err != nil {
+		return Rule{}, err
+	}
+
+	err = s.repo.Create(ctx, rule)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	return s.Get(ctx, appID, rule.ID)
+}
+
+// Update updates the rule with the specified ID.
+func (s service) Update(ctx context.Context, appID, id string, req UpdateRuleRequest) (Rule, error) {
+	rule, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	err = setRuleFields(&rule, req)
+	if err != nil {
+		return Rule{}, err
+	}
+	rule.UpdatedAt = time.Now()
+
+	err = s.repo.Update(ctx, rule)
+	if err != nil {
+		return Rule{}, err
+	}
+	s.cache.forget(appID, id)
+
+	return newRuleFromEntity(rule)
+}
+
+// Delete deletes the rule with the specified ID.
+func (s service) Delete(ctx context.Context, appID, id string) (Rule, error) {
+	rule, err := s.Get(ctx, appID, id)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	err = s.repo.Delete(ctx, appID, id)
+	if err != nil {
+		return Rule{}, err
+	}
+	s.cache.forget(appID, id)
+	return rule, nil
+}
+
+// DryRun returns the enabled rules matching the given message, without
+// running their actions.
+func (s service) DryRun(ctx context.Context, appID string, req DryRunRequest) ([]DryRunMatch, error) {
+	tags := req.Tags
+	if tags == nil && len(req.ConnectionID) > 0 {
+		conn, err := s.cRepo.Get(ctx, appID, req.ConnectionID)
+		if err != nil {
+			return nil, ErrConnectionNotFound
+		}
+		tags = conn.Tags
+	}
+
+	at := time.Now()
+	if req.Time != nil {
+		at = *req.Time
+	}
+
+	rules, err := s.enabled(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []DryRunMatch{}
+	for _, r := range rules {
+		if r.Match(req.Body, tags, at) {
+			matches = append(matches, DryRunMatch{
+				RuleID:  r.ID,
+				Name:    r.Name,
+				Actions: r.Actions,
+			})
+		}
+	}
+	return matches, nil
+}
+
+// Evaluate runs the actions of the enabled rules matching the given message
+// received from a connection, in priority order. It returns the endpoints
+// the message webhook is routed to, if any.
+func (s service) Evaluate(ctx context.Context, appID, selfID string, msg entity.Message) []string {
+	logger := s.logger.With(ctx, "rule")
+
+	rules, err := s.enabled(ctx, appID)
+	if err != nil {
+		logger.Infof("error loading the rules: %v", err)
+		return nil
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	conn, err := s.cRepo.Get(ctx, appID, selfID)
+	if err != nil {
+		logger.Infof("error loading the connection: %v", err)
+		return nil
+	}
+
+	routes := []string{}
+	for _, r := range rules {
+		if !r.Match(msg.Body, conn.Tags, msg.CreatedAt) {
+			continue
+		}
+
+		for _, a := range r.Actions {
+			if a.Type == ACTION_WEBHOOK {
+				routes = append(routes, a.URL)
+				continue
+			}
+
+			err = s.run(ctx, appID, &conn, msg, a)
+			if err != nil {
+				logger.Infof("error running %s action of rule %s: %v", a.Type, r.ID, err)
+			}
+		}
+	}
+	return routes
+}
+
+// run runs the given action for a message received from a connection.
+func (s service) run(ctx context.Context, appID string, conn *entity.Connection, msg entity.Message, a Action) error {
+	switch a.Type {
+	case ACTION_REPLY:
+		_, err := s.messages.Create(ctx, appID, conn.SelfID, conn.ID, message.CreateMessageRequest{
+			Body: a.Body,
+			RID:  msg.JTI,
+		})
+		return err
+	case ACTION_TEMPLATE:
+		_, err := s.messages.Create(ctx, appID, conn.SelfID, conn.ID, message.CreateMessageRequest{
+			TemplateID: a.TemplateID,
+			Variables:  a.Variables,
+			RID:        msg.JTI,
+		})
+		return err
+	case ACTION_FACT_REQUEST:
+		facts := make([]request.FactRequest, len(a.Facts))
+		for i, name := range a.Facts {
+			facts[i] = request.FactRequest{Name: name}
+		}
+		_, err := s.requests.Create(ctx, appID, conn, request.CreateRequest{
+			Type:        "fact",
+			Facts:       facts,
+			Description: a.Description,
+			SelfID:      conn.SelfID,
+		})
+		return err
+	case ACTION_TAG:
+		tags := updateTags(conn.Tags, a.AddTags, a.RemoveTags)
+		err := s.cRepo.SetTags(ctx, conn.ID, tags)
+		if err != nil {
+			return err
+		}
+		// Later rules see the updated tags.
+		conn.Tags = tags
+	}
+	return nil
+}
+
+// enabled returns the enabled rules of an app in evaluation order.
+func (s service) enabled(ctx context.Context, appID string) ([]Rule, error) {
+	items, err := s.repo.Enabled(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	return s.cache.rules(appID, items)
+}
+
+// Match checks all the conditions of the rule match a message with the
+// given body, received at the given time from a connection with the given
+// tags.
+func (r Rule) Match(body string, tags []string, at time.Time) bool {
+	for _, c := range r.Conditions {
+		if !c.Match(body, tags, at) {
+			return false
+		}
+	}
+	return len(r.Conditions) > 0
+}
+
+// Match checks the condition matches a message with the given body,
+// received at the given time from a connection with the given tags.
+func (c Condition) Match(body string, tags []string, at time.Time) bool {
+	switch c.Type {
+	case CONDITION_KEYWORD, CONDITION_REGEX:
+		if c.re == nil && c.compile() != nil {
+			return false
+		}
+		return c.re.MatchString(body)
+	case CONDITION_TAG:
+		return contains(tags, c.Value)
+	case CONDITION_TIME:
+		loc, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return false
+		}
+		t := at.In(loc).Format(timeOfDay)
+		if c.From <= c.To {
+			return t >= c.From && t < c.To
+		}
+		return t >= c.From || t < c.To
+	}
+	return false
+}
+
+// updateTags returns the given tags with the added tags and without the
+// removed ones, sorted alphabetically.
+func updateTags(tags, add, remove []string) []string {
+	result := []string{}
+	for _, tag := range append(append([]string{}, tags...), add...) {
+		if !contains(result, tag) && !contains(remove, tag) {
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// setRuleFields sets the fields of a rule entity from the given request.
+func setRuleFields(rule *entity.Rule, req UpdateRuleRequest) error {
+	conditions, err := json.Marshal(req.Conditions)
+	if err != nil {
+		return err
+	}
+	actions, err := json.Marshal(req.Actions)
+	if err != nil {
+		return err
+	}
+
+	rule.Name = req.Name
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.Priority = req.Priority
+	rule.Conditions = conditions
+	rule.Actions = actions
+	return nil
+}
+
+func newRulesFromEntities(items []entity.Rule) ([]Rule, error) {
+	result := []Rule{}
+	for _, item := range items {
+		r, err := newRuleFromEntity(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func newRuleFromEntity(r entity.Rule) (Rule, error) {
+	conditions := []Condition{}
+	if len(r.Conditions) > 0 {
+		err := json.Unmarshal(r.Conditions, &conditions)
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+	for i := range conditions {
+		// Expressions are validated when the rule is saved, the ones failing
+		// to compile never match.
+		_ = conditions[i].compile()
+	}
+
+	actions := []Action{}
+	if len(r.Actions) > 0 {
+		err := json.Unmarshal(r.Actions, &actions)
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+
+	return Rule{
+		ID:         r.ID,
+		Name:       r.Name,
+		Enabled:    r.Enabled,
+		Priority:   r.Priority,
+		Conditions: conditions,
+		Actions:    actions,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package rule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRuleRequest_Validate(t *testing.T) {
+	reply := []Action{{Type: ACTION_REPLY, Body: "hi"}}
+	keyword := []Condition{{Type: CONDITION_KEYWORD, Value: "help"}}
+
+	tests := []struct {
+		name      string
+		model     CreateRuleRequest
+		wantError bool
+	}{
+		{"success", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: reply}, false},
+		{"name required", CreateRuleRequest{Conditions: keyword, Actions: reply}, true},
+		{"conditions required", CreateRuleRequest{Name: "help", Actions: reply}, true},
+		{"actions required", CreateRuleRequest{Name: "help", Conditions: keyword}, true},
+		{"unknown condition", CreateRuleRequest{Name: "help", Conditions: []Condition{{Type: "unknown", Value: "x"}}, Actions: reply}, true},
+		{"keyword required", CreateRuleRequest{Name: "help", Conditions: []Condition{{Type: CONDITION_KEYWORD}}, Actions: reply}, true},
+		{"regex", CreateRuleRequest{Name: "help", Conditions: []Condition{{Type: CONDITION_REGEX, Value: `^order \d+$`}}, Actions: reply}, false},
+		{"invalid regex", CreateRuleRequest{Name: "help", Conditions: []Condition{{Type: CONDITION_REGEX, Value: `(`}}, Actions: reply}, true},
+		{"time", CreateRuleRequest{Name: "help", Conditions: []Condition{{Type: CONDITION_TIME, From: "18:00", To: "09:00", Timezone: "UTC"}}, Actions: reply}, false},
+		{"invalid time", CreateRuleRequest{Name: "help", Conditions: []Condition{{Type: CONDITION_TIME, From: "6pm", To: "09:00"}}, Actions: reply}, true},
+		{"invalid timezone", CreateRuleRequest{Name: "help", Conditions: []Condition{{Type: CONDITION_TIME, From: "18:00", To: "09:00", Timezone: "Nowhere/Town"}}, Actions: reply}, true},
+		{"unknown action", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: "unknown"}}}, true},
+		{"reply body required", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: ACTION_REPLY}}}, true},
+		{"template required", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: ACTION_TEMPLATE}}}, true},
+		{"facts required", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: ACTION_FACT_REQUEST}}}, true},
+		{"tags required", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: ACTION_TAG}}}, true},
+		{"remove tags", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: ACTION_TAG, RemoveTags: []string{"new"}}}}, false},
+		{"webhook url required", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: ACTION_WEBHOOK}}}, true},
+		{"invalid webhook url", CreateRuleRequest{Name: "help", Conditions: keyword, Actions: []Action{{Type: ACTION_WEBHOOK, URL: "not a url"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.Validate()
+			assert.Equal(t, tt.wantError, err != nil)
+		})
+	}
+}
+
+func TestCondition_Match(t *testing.T) {
+	night := time.Date(2024, 8, 12, 23, 30, 0, 0, time.UTC)
+	day := time.Date(2024, 8, 12, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		condition Condition
+		body      string
+		tags      []string
+		at        time.Time
+		want      bool
+	}{
+		{"keyword", Condition{Type: CONDITION_KEYWORD, Value: "help"}, "I need HELP please", nil, day, true},
+		{"keyword within a word", Condition{Type: CONDITION_KEYWORD, Value: "help"}, "helpful", nil, day, false},
+		{"regex", Condition{Type: CONDITION_REGEX, Value: `order \d+`}, "where is order 42?", nil, day, true},
+		{"regex mismatch", Condition{Type: CONDITION_REGEX, Value: `order \d+`}, "where is my order?", nil, day, false},
+		{"tag", Condition{Type: CONDITION_TAG, Value: "vip"}, "hi", []string{"beta", "vip"}, day, true},
+		{"tag missing", Condition{Type: CONDITION_TAG, Value: "vip"}, "hi", []string{"beta"}, day, false},
+		{"time window", Condition{Type: CONDITION_TIME, From: "09:00", To: "18:00"}, "hi", nil, day, true},
+		{"time outside window", Condition{Type: CONDITION_TIME, From: "09:00", To: "18:00"}, "hi", nil, night, false},
+		{"time across midnight", Condition{Type: CONDITION_TIME, From: "18:00", To: "09:00"}, "hi", nil, night, true},
+		{"time outside window across midnight", Condition{Type: CONDITION_TIME, From: "18:00", To: "09:00"}, "hi", nil, day, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.condition.Match(tt.body, tt.tags, tt.at))
+		})
+	}
+}
+
+func Test_service_CRUD(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(&mockRepository{}, &mockMessageSender{}, &mockFactRequester{}, &mock.ConnectionRepositoryMock{}, logger)
+	ctx := context.Background()
+
+	// create
+	disabled := false
+	rule, err := s.Create(ctx, "app", CreateRuleRequest{
+		Name:       "help",
+		Conditions: []Condition{{Type: CONDITION_KEYWORD, Value: "help"}},
+		Actions:    []Action{{Type: ACTION_REPLY, Body: "hi"}},
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, rule.ID)
+	assert.True(t, rule.Enabled)
+	assert.Equal(t, "help", rule.Conditions[0].Value)
+
+	// update
+	rule, err = s.Update(ctx, "app", rule.ID, UpdateRuleRequest{
+		Name:       "help",
+		Enabled:    &disabled,
+		Priority:   3,
+		Conditions: []Condition{{Type: CONDITION_KEYWORD, Value: "support"}},
+		Actions:    []Action{{Type: ACTION_REPLY, Body: "hi"}},
+	})
+	assert.Nil(t, err)
+	assert.False(t, rule.Enabled)
+	assert.Equal(t, 3, rule.Priority)
+	_, err = s.Update(ctx, "other", rule.ID, UpdateRuleRequest{Name: "help"})
+	assert.NotNil(t, err)
+
+	// get
+	rule, err = s.Get(ctx, "app", rule.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "support", rule.Conditions[0].Value)
+
+	// query
+	count, _ := s.Count(ctx, "app")
+	assert.Equal(t, 1, count)
+	rules, err := s.Query(ctx, "app", 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rules))
+
+	// delete
+	_, err = s.Delete(ctx, "app", rule.ID)
+	assert.Nil(t, err)
+	_, err = s.Delete(ctx, "app", rule.ID)
+	assert.NotNil(t, err)
+}
+
+func Test_service_Evaluate(t *testing.T) {
+	logger, _ := log.NewForTest()
+	messages := &mockMessageSender{}
+	requests := &mockFactRequester{}
+	cRepo := &mock.ConnectionRepositoryMock{Items: []entity.Connection{{ID: 1, AppID: "app", SelfID: "selfid", Tags: []string{"new"}}}}
+	s := NewService(&mockRepository{}, messages, requests, cRepo, logger)
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, "app", CreateRuleRequest{
+		Name:       "onboarding",
+		Priority:   1,
+		Conditions: []Condition{{Type: CONDITION_TAG, Value: "new"}, {Type: CONDITION_KEYWORD, Value: "verify"}},
+		Actions: []Action{
+			{Type: ACTION_FACT_REQUEST, Facts: []string{"email_address"}},
+			{Type: ACTION_TAG, AddTags: []string{"verifying"}, RemoveTags: []string{"new"}},
+		},
+	})
+	assert.Nil(t, err)
+	_, err = s.Create(ctx, "app", CreateRuleRequest{
+		Name:       "support",
+		Priority:   2,
+		Conditions: []Condition{{Type: CONDITION_TAG, Value: "verifying"}},
+		Actions: []Action{
+			{Type: ACTION_TEMPLATE, TemplateID: "welcome", Variables: map[string]string{"name": "John"}},
+			{Type: ACTION_REPLY, Body: "an agent will contact you"},
+			{Type: ACTION_WEBHOOK, URL: "https://support.example.com/hook"},
+		},
+	})
+	assert.Nil(t, err)
+	_, err = s.Create(ctx, "app", CreateRuleRequest{
+		Name:       "unmatched",
+		Conditions: []Condition{{Type: CONDITION_KEYWORD, Value: "cancel"}},
+		Actions:    []Action{{Type: ACTION_WEBHOOK, URL: "https://cancel.example.com/hook"}},
+	})
+	assert.Nil(t, err)
+
+	msg := entity.Message{JTI: "jti", Body: "please verify me", CreatedAt: time.Now()}
+	routes := s.Evaluate(ctx, "app", "selfid", msg)
+	assert.Equal(t, []string{"https://support.example.com/hook"}, routes)
+
+	// the fact request is sent to the connection
+	assert.Equal(t, 1, len(requests.requested))
+	assert.Equal(t, "fact", requests.requested[0].Type)
+	assert.Equal(t, "selfid", requests.requested[0].SelfID)
+	assert.Equal(t, "email_address", requests.requested[0].Facts[0].Name)
+
+	// the tags are updated before evaluating the next rules
+	assert.Equal(t, []string{"verifying"}, cRepo.Items[0].Tags)
+
+	// the replies are threaded to the message
+	assert.Equal(t, 2, len(messages.sent))
+	assert.Equal(t, "welcome", messages.sent[0].TemplateID)
+	assert.Equal(t, "an agent will contact you", messages.sent[1].Body)
+	assert.Equal(t, "jti", messages.sent[1].RID)
+
+	// no rules
+	assert.Empty(t, s.Evaluate(ctx, "other", "selfid", msg))
+}
+
+func Test_service_DryRun(t *testing.T) {
+	logger, _ := log.NewForTest()
+	messages := &mockMessageSender{}
+	cRepo := &mock.ConnectionRepositoryMock{Items: []entity.Connection{{ID: 1, AppID: "app", SelfID: "selfid", Tags: []string{"vip"}}}}
+	s := NewService(&mockRepository{}, messages, &mockFactRequester{}, cRepo, logger)
+	ctx := context.Background()
+
+	rule, err := s.Create(ctx, "app", CreateRuleRequest{
+		Name:       "after hours",
+		Conditions: []Condition{{Type: CONDITION_TAG, Value: "vip"}, {Type: CONDITION_TIME, From: "18:00", To: "09:00"}},
+		Actions:    []Action{{Type: ACTION_REPLY, Body: "we are closed"}},
+	})
+	assert.Nil(t, err)
+
+	night := time.Date(2024, 8, 12, 23, 30, 0, 0, time.UTC)
+	matches, err := s.DryRun(ctx, "app", DryRunRequest{Body: "hi", ConnectionID: "selfid", Time: &night})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+	assert.Equal(t, rule.ID, matches[0].RuleID)
+
+	// the given tags replace the connection ones
+	matches, err = s.DryRun(ctx, "app", DryRunRequest{Body: "hi", ConnectionID: "selfid", Tags: []string{}, Time: &night})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(matches))
+
+	_, err = s.DryRun(ctx, "app", DryRunRequest{Body: "hi", ConnectionID: "unknown"})
+	assert.Equal(t, ErrConnectionNotFound, err)
+
+	// actions are not run
+	assert.Equal(t, 0, len(messages.sent))
+}
+
+func Test_service_Cache(t *testing.T) {
+	logger, _ := log.NewForTest()
+	cRepo := &mock.ConnectionRepositoryMock{Items: []entity.Connection{{ID: 1, AppID: "app", SelfID: "selfid"}}}
+	s := NewService(&mockRepository{}, &mockMessageSender{}, &mockFactRequester{}, cRepo, logger)
+	ctx := context.Background()
+	cache := s.(service).cache
+
+	rule, err := s.Create(ctx, "app", CreateRuleRequest{
+		Name:       "greeting",
+		Conditions: []Condition{{Type: CONDITION_KEYWORD, Value: "hello"}},
+		Actions:    []Action{{Type: ACTION_REPLY, Body: "hi"}},
+	})
+	assert.Nil(t, err)
+
+	matches, err := s.DryRun(ctx, "app", DryRunRequest{Body: "hello there", Tags: []string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+	re := cache.apps["app"][rule.ID].Conditions[0].re
+
+	// cached rules are reused
+	_, err = s.DryRun(ctx, "app", DryRunRequest{Body: "hello there", Tags: []string{}})
+	assert.Nil(t, err)
+	assert.Same(t, re, cache.apps["app"][rule.ID].Conditions[0].re)
+
+	// updated rules are compiled again
+	_, err = s.Update(ctx, "app", rule.ID, UpdateRuleRequest{
+		Name:       "farewell",
+		Conditions: []Condition{{Type: CONDITION_KEYWORD, Value: "bye"}},
+		Actions:    []Action{{Type: ACTION_REPLY, Body: "see you"}},
+	})
+	assert.Nil(t, err)
+	matches, err = s.DryRun(ctx, "app", DryRunRequest{Body: "hello there", Tags: []string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(matches))
+	matches, err = s.DryRun(ctx, "app", DryRunRequest{Body: "bye now", Tags: []string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+
+	// deleted rules are dropped
+	_, err = s.Delete(ctx, "app", rule.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, cache.apps["app"])
+}
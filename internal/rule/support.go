@@ -0,0 +1,228 @@
+package rule
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/joinself/restful-client/pkg/response"
+)
+
+const (
+	// CONDITION_KEYWORD matches messages containing a keyword, ignoring case.
+	CONDITION_KEYWORD = "keyword"
+	// CONDITION_REGEX matches messages matching a regular expression.
+	CONDITION_REGEX = "regex"
+	// CONDITION_TAG matches messages from connections with a tag.
+	CONDITION_TAG = "tag"
+	// CONDITION_TIME matches messages received within a time of day window.
+	CONDITION_TIME = "time"
+)
+
+const (
+	// ACTION_REPLY replies to the message with a fixed body.
+	ACTION_REPLY = "reply"
+	// ACTION_TEMPLATE replies to the message with a message template.
+	ACTION_TEMPLATE = "template"
+	// ACTION_FACT_REQUEST requests facts to the connection.
+	ACTION_FACT_REQUEST = "fact_request"
+	// ACTION_TAG adds or removes tags of the connection.
+	ACTION_TAG = "tag"
+	// ACTION_WEBHOOK sends the message webhook to a specific endpoint instead
+	// of the app callback.
+	ACTION_WEBHOOK = "webhook"
+)
+
+// timeOfDay is the format of the bounds of time conditions.
+const timeOfDay = "15:04"
+
+// ExtListResponse represents the json object returned when listing rules.
+type ExtListResponse struct {
+	Page       int    `json:"page"`
+	PerPage    int    `json:"per_page"`
+	PageCount  int    `json:"page_count"`
+	TotalCount int    `json:"total_count"`
+	Items      []Rule `json:"items"`
+}
+
+// Condition is matched against the inbound messages.
+type Condition struct {
+	Type string `json:"type"`
+	// Value is the keyword, the regular expression or the tag matched.
+	Value string `json:"value,omitempty"`
+	// From and To delimit the time of day window as HH:MM, windows crossing
+	// midnight are allowed.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Timezone is the IANA time zone of the window, UTC by default.
+	Timezone string `json:"timezone,omitempty"`
+
+	// re is the compiled expression of keyword and regex conditions.
+	re *regexp.Regexp
+}
+
+// compile compiles the expression matched by keyword and regex conditions.
+func (c *Condition) compile() error {
+	var pattern string
+	switch c.Type {
+	case CONDITION_KEYWORD:
+		pattern = `(?i)\b` + regexp.QuoteMeta(c.Value) + `\b`
+	case CONDITION_REGEX:
+		pattern = c.Value
+	default:
+		return nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	c.re = re
+	return nil
+}
+
+func (c Condition) Validate() error {
+	valued := c.Type == CONDITION_KEYWORD || c.Type == CONDITION_REGEX || c.Type == CONDITION_TAG
+	timed := c.Type == CONDITION_TIME
+
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Type, validation.Required, validation.In(CONDITION_KEYWORD, CONDITION_REGEX, CONDITION_TAG, CONDITION_TIME)),
+		validation.Field(&c.Value,
+			validation.When(valued, validation.Required, validation.Length(1, 256)),
+			validation.When(c.Type == CONDITION_REGEX, validation.By(validRegex)),
+		),
+		validation.Field(&c.From, validation.When(timed, validation.Required, validation.By(validTimeOfDay))),
+		validation.Field(&c.To, validation.When(timed, validation.Required, validation.By(validTimeOfDay))),
+		validation.Field(&c.Timezone, validation.By(validTimezone)),
+	)
+}
+
+// Action is run when a rule matches an inbound message.
+type Action struct {
+	Type string `json:"type"`
+	// Body is the body of the reply.
+	Body string `json:"body,omitempty"`
+	// TemplateID and Variables build the body of a template reply.
+	TemplateID string            `json:"template_id,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	// Facts are the names of the requested facts.
+	Facts       []string `json:"facts,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// AddTags and RemoveTags update the tags of the connection.
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	// URL is the endpoint the message webhook is sent to.
+	URL string `json:"url,omitempty"`
+}
+
+func (a Action) Validate() error {
+	tags := a.Type == ACTION_TAG && len(a.AddTags) == 0 && len(a.RemoveTags) == 0
+
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Type, validation.Required, validation.In(ACTION_REPLY, ACTION_TEMPLATE, ACTION_FACT_REQUEST, ACTION_TAG, ACTION_WEBHOOK)),
+		validation.Field(&a.Body, validation.When(a.Type == ACTION_REPLY, validation.Required, validation.Length(1, 4096))),
+		validation.Field(&a.TemplateID, validation.When(a.Type == ACTION_TEMPLATE, validation.Required, validation.Length(1, 128))),
+		validation.Field(&a.Facts, validation.When(a.Type == ACTION_FACT_REQUEST, validation.Required), validation.Each(validation.Length(3, 128))),
+		validation.Field(&a.Description, validation.Length(0, 128)),
+		validation.Field(&a.AddTags, validation.When(tags, validation.Required), validation.Each(validation.Required, validation.Length(1, 64))),
+		validation.Field(&a.RemoveTags, validation.Each(validation.Required, validation.Length(1, 64))),
+		validation.Field(&a.URL, validation.When(a.Type == ACTION_WEBHOOK, validation.Required), is.URL),
+	)
+}
+
+// CreateRuleRequest represents a rule creation request.
+type CreateRuleRequest struct {
+	Name string `json:"name"`
+	// Enabled rules are evaluated on every inbound message, defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Priority sets the evaluation order of the rules, lowest first.
+	Priority int `json:"priority"`
+	// Conditions must all match for the rule to run its actions.
+	Conditions []Condition `json:"conditions"`
+	Actions    []Action    `json:"actions"`
+}
+
+// Validate validates the CreateRuleRequest fields.
+func (m CreateRuleRequest) Validate() *response.Error {
+	return validateRule(m.Name, m.Conditions, m.Actions)
+}
+
+// UpdateRuleRequest represents a rule update request.
+type UpdateRuleRequest CreateRuleRequest
+
+// Validate validates the UpdateRuleRequest fields.
+func (m UpdateRuleRequest) Validate() *response.Error {
+	return validateRule(m.Name, m.Conditions, m.Actions)
+}
+
+// DryRunRequest represents an inbound message the rules are evaluated
+// against without running their actions.
+type DryRunRequest struct {
+	Body string `json:"body"`
+	// ConnectionID is the selfID of the connection sending the message, its
+	// tags are used when Tags are not provided.
+	ConnectionID string   `json:"connection_id,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	// Time is when the message is received, now by default.
+	Time *time.Time `json:"time,omitempty"`
+}
+
+// Validate validates the DryRunRequest fields.
+func (m DryRunRequest) Validate() *response.Error {
+	err := validation.ValidateStruct(&m,
+		validation.Field(&m.Body, validation.Required, validation.Length(1, 4096)),
+		validation.Field(&m.ConnectionID, validation.Length(0, 128)),
+	)
+	return invalidInput(err)
+}
+
+// DryRunMatch is a rule matching a dry run message.
+type DryRunMatch struct {
+	RuleID  string   `json:"rule_id"`
+	Name    string   `json:"name"`
+	Actions []Action `json:"actions"`
+}
+
+func validateRule(name string, conditions []Condition, actions []Action) *response.Error {
+	err := validation.Errors{
+		"name":       validation.Validate(name, validation.Required, validation.Length(1, 128)),
+		"conditions": validation.Validate(conditions, validation.Required, validation.Length(1, 10)),
+		"actions":    validation.Validate(actions, validation.Required, validation.Length(1, 10)),
+	}.Filter()
+	return invalidInput(err)
+}
+
+func invalidInput(err error) *response.Error {
+	if err == nil {
+		return nil
+	}
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: err.Error(),
+	}
+}
+
+func validRegex(value interface{}) error {
+	if _, err := regexp.Compile(value.(string)); err != nil {
+		return errors.New("must be a valid regular expression")
+	}
+	return nil
+}
+
+func validTimeOfDay(value interface{}) error {
+	if _, err := time.Parse(timeOfDay, value.(string)); err != nil {
+		return errors.New("must be a time of day as HH:MM")
+	}
+	return nil
+}
+
+func validTimezone(value interface{}) error {
+	if _, err := time.LoadLocation(value.(string)); err != nil {
+		return errors.New("must be a valid time zone")
+	}
+	return nil
+}
@@ -26,6 +26,22 @@ func (m RequestServiceMock) Get(ctx context.Context, appID, id string) (request.
 	return request.ExtRequest{}, sql.ErrNoRows
 }
 
+func (m RequestServiceMock) Count(ctx context.Context, appID string, filter entity.RequestFilter) (int, error) {
+	return len(m.Items), nil
+}
+
+func (m RequestServiceMock) Query(ctx context.Context, appID string, filter entity.RequestFilter, offset, limit int) ([]request.RequestSummary, error) {
+	return []request.RequestSummary{}, nil
+}
+
+func (m *RequestServiceMock) Cancel(ctx context.Context, appID, id string) (request.ExtRequest, error) {
+	return request.ExtRequest{ID: id, Status: entity.REQUEST_CANCELLED_STATUS}, nil
+}
+
+func (m *RequestServiceMock) QRCode(ctx context.Context, appID, id, format string, size int) ([]byte, error) {
+	return []byte{}, nil
+}
+
 func (m *RequestServiceMock) Create(ctx context.Context, appID string, connection *entity.Connection, input request.CreateRequest) (request.ExtRequest, error) {
 	r := request.ExtRequest{}
 	m.Items = append(m.Items, r)
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/conversation"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/internal/fact"
 	"github.com/joinself/restful-client/internal/message"
@@ -28,6 +29,9 @@ type Runner interface {
 	StopAll()
 	Get(id string) (*selfsdk.Client, bool)
 	Poster(id string) (webhook.Poster, bool)
+	Notify(id string, payload webhook.WebhookPayload) error
+	SetRules(rules support.RuleEvaluator)
+	SetOnboarding(onboarding support.Onboarder)
 }
 
 type appStatusSetter interface {
@@ -39,7 +43,9 @@ type runner struct {
 	runners    map[string]Service
 	cRepo      connection.Repository
 	fRepo      fact.Repository
+	ifRepo     fact.IssuedRepository
 	mRepo      message.Repository
+	convRepo   conversation.Repository
 	rRepo      request.Repository
 	aRepo      appStatusSetter
 	metRepo    metric.Repository
@@ -47,25 +53,33 @@ type runner struct {
 	sRepo      signature.Repository
 	logger     log.Logger
 	rService   request.Service
+	rules      support.RuleEvaluator
+	onboarding support.Onboarder
 	storageKey string
 	storageDir string
+	attDir     string
 	wp         *worker.CallbackWorkerPool
 }
 
 type RunnerConfig struct {
 	ConnectionRepo connection.Repository
 	FactRepo       fact.Repository
+	// IssuedFactRepo correlates the delivery metrics of the issued facts.
+	IssuedFactRepo fact.IssuedRepository
 	MessageRepo    message.Repository
-	RequestRepo    request.Repository
-	AppRepo        appStatusSetter
-	MetricRepo     metric.Repository
-	VoiceRepo      voice.Repository
-	SignatureRepo  signature.Repository
-	Logger         log.Logger
-	RequestService request.Service
-	StorageKey     string
-	StorageDir     string
-	Queue          *goqite.Queue
+	// ConversationRepo reopens the conversations receiving new messages.
+	ConversationRepo conversation.Repository
+	RequestRepo      request.Repository
+	AppRepo          appStatusSetter
+	MetricRepo       metric.Repository
+	VoiceRepo        voice.Repository
+	SignatureRepo    signature.Repository
+	Logger           log.Logger
+	RequestService   request.Service
+	StorageKey       string
+	StorageDir       string
+	AttachmentsDir   string
+	Queue            *goqite.Queue
 }
 
 func NewRunner(config RunnerConfig) Runner {
@@ -73,7 +87,9 @@ func NewRunner(config RunnerConfig) Runner {
 		runners:    map[string]Service{},
 		cRepo:      config.ConnectionRepo,
 		fRepo:      config.FactRepo,
+		ifRepo:     config.IssuedFactRepo,
 		mRepo:      config.MessageRepo,
+		convRepo:   config.ConversationRepo,
 		rRepo:      config.RequestRepo,
 		aRepo:      config.AppRepo,
 		metRepo:    config.MetricRepo,
@@ -83,6 +99,7 @@ func NewRunner(config RunnerConfig) Runner {
 		rService:   config.RequestService,
 		storageKey: config.StorageKey,
 		storageDir: config.StorageDir,
+		attDir:     config.AttachmentsDir,
 	}
 
 	wp := worker.NewCallbackWorkerPool(config.Queue, config.Logger, &r, 3)
@@ -118,7 +135,9 @@ func (r *runner) Run(app entity.App) error {
 	r.runners[app.ID] = NewService(Config{
 		ConnectionRepo:     r.cRepo,
 		FactRepo:           r.fRepo,
+		IssuedFactRepo:     r.ifRepo,
 		MessageRepo:        r.mRepo,
+		ConversationRepo:   r.convRepo,
 		RequestRepo:        r.rRepo,
 		Logger:             r.logger,
 		RequestService:     r.rService,
@@ -129,6 +148,9 @@ func (r *runner) Run(app entity.App) error {
 		Poster:             webhook.NewWebhook(),
 		App:                app,
 		CallbackWorkerPool: r.wp,
+		AttachmentsDir:     r.attDir,
+		Rules:              r.rules,
+		Onboarding:         r.onboarding,
 	})
 	r.logger.Infof("trying to start %s", app.ID)
 	err = r.runners[app.ID].Run()
@@ -156,11 +178,32 @@ func (r *runner) SetApp(app entity.App) error {
 	return nil
 }
 
-func (r *runner) SendCallback(appID string, payload webhook.WebhookPayload) error {
+func (r *runner) SendCallback(appID, url string, payload webhook.WebhookPayload) error {
 	if _, ok := r.runners[appID]; !ok {
 		return errors.New("runner not found")
 	}
-	return r.runners[appID].SendCallback(payload)
+	return r.runners[appID].SendCallback(url, payload)
+}
+
+// Notify queues a webhook for the given app, it's skipped when the app is not
+// running.
+func (r *runner) Notify(appID string, payload webhook.WebhookPayload) error {
+	if _, ok := r.runners[appID]; !ok {
+		return nil
+	}
+	return r.runners[appID].Notify(payload)
+}
+
+// SetRules sets the rules evaluated on the messages received by the apps
+// started afterwards.
+func (r *runner) SetRules(rules support.RuleEvaluator) {
+	r.rules = rules
+}
+
+// SetOnboarding sets the onboarding policies run for the new connections of
+// the apps started afterwards.
+func (r *runner) SetOnboarding(onboarding support.Onboarder) {
+	r.onboarding = onboarding
 }
 
 // StopAll stops all runners.
@@ -6,11 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/conversation"
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/internal/fact"
 	"github.com/joinself/restful-client/internal/message"
@@ -38,13 +42,16 @@ type Service interface {
 	Get() *selfsdk.Client
 	Poster() webhook.Poster
 	SetApp(app entity.App)
-	SendCallback(webhook.WebhookPayload) error
+	SendCallback(url string, p webhook.WebhookPayload) error
+	Notify(webhook.WebhookPayload) error
 	processFactsQueryResp(body []byte, payload map[string]interface{}) error
 	processChatMessage(payload map[string]interface{}) error
 	processConnectionResp(payload map[string]interface{}) error
 	processIncomingMessage(m *messaging.Message)
 	processChatMessageRead(payload map[string]interface{}) error
 	processChatMessageDelivered(payload map[string]interface{}) error
+	processChatMessageEdit(payload map[string]interface{}) error
+	processChatMessageDelete(payload map[string]interface{}) error
 }
 
 // WebhookPayload represents a the payload that will be resent to the
@@ -66,7 +73,9 @@ type Config struct {
 	SelfClient         support.SelfClient
 	ConnectionRepo     connection.Repository
 	FactRepo           fact.Repository
+	IssuedFactRepo     fact.IssuedRepository
 	MessageRepo        message.Repository
+	ConversationRepo   conversation.Repository
 	RequestRepo        request.Repository
 	MetricRepo         metric.Repository
 	VoiceRepo          voice.Repository
@@ -76,41 +85,60 @@ type Config struct {
 	RequestService     request.Service
 	App                entity.App
 	CallbackWorkerPool Callbacker
+	// AttachmentsDir is the directory where the inbound message attachments
+	// are downloaded to, leave it empty to disable the background download.
+	AttachmentsDir string
+	// Rules are evaluated on the received messages, leave it empty to
+	// disable them.
+	Rules support.RuleEvaluator
+	// Onboarding runs the app onboarding policy for the new connections,
+	// leave it empty to request their display name and notify the app.
+	Onboarding support.Onboarder
 }
 type service struct {
-	client    support.SelfClient
-	cRepo     connection.Repository
-	fRepo     fact.Repository
-	mRepo     message.Repository
-	rRepo     request.Repository
-	metRepo   metric.Repository
-	voiceRepo voice.Repository
-	signRepo  signature.Repository
-	logger    log.Logger
-	selfID    string
-	w         webhook.Poster
-	rService  request.Service
-	app       entity.App
-	wp        Callbacker
+	client     support.SelfClient
+	cRepo      connection.Repository
+	fRepo      fact.Repository
+	ifRepo     fact.IssuedRepository
+	mRepo      message.Repository
+	convRepo   conversation.Repository
+	rRepo      request.Repository
+	metRepo    metric.Repository
+	voiceRepo  voice.Repository
+	signRepo   signature.Repository
+	logger     log.Logger
+	selfID     string
+	w          webhook.Poster
+	rService   request.Service
+	app        entity.App
+	wp         Callbacker
+	attDir     string
+	rules      support.RuleEvaluator
+	onboarding support.Onboarder
 }
 
 // NewService creates a new fact service.
 func NewService(c Config) Service {
 	s := service{
-		client:    c.SelfClient,
-		cRepo:     c.ConnectionRepo,
-		fRepo:     c.FactRepo,
-		mRepo:     c.MessageRepo,
-		rRepo:     c.RequestRepo,
-		metRepo:   c.MetricRepo,
-		voiceRepo: c.VoiceRepo,
-		signRepo:  c.SignRepo,
-		logger:    c.Logger,
-		selfID:    c.SelfClient.SelfAppID(),
-		rService:  c.RequestService,
-		w:         c.Poster,
-		app:       c.App,
-		wp:        c.CallbackWorkerPool,
+		client:     c.SelfClient,
+		cRepo:      c.ConnectionRepo,
+		fRepo:      c.FactRepo,
+		ifRepo:     c.IssuedFactRepo,
+		mRepo:      c.MessageRepo,
+		convRepo:   c.ConversationRepo,
+		rRepo:      c.RequestRepo,
+		metRepo:    c.MetricRepo,
+		voiceRepo:  c.VoiceRepo,
+		signRepo:   c.SignRepo,
+		logger:     c.Logger,
+		selfID:     c.SelfClient.SelfAppID(),
+		rService:   c.RequestService,
+		w:          c.Poster,
+		app:        c.App,
+		wp:         c.CallbackWorkerPool,
+		attDir:     c.AttachmentsDir,
+		rules:      c.Rules,
+		onboarding: c.Onboarding,
 	}
 	s.SetupHooks()
 
@@ -191,6 +219,12 @@ func (s *service) processIncomingMessage(m *messaging.Message) {
 	case "chat.message.read":
 		_ = s.processChatMessageRead(payload)
 
+	case "chat.message.edit":
+		_ = s.processChatMessageEdit(payload)
+
+	case "chat.message.delete":
+		_ = s.processChatMessageDelete(payload)
+
 	case "chat.voice.setup":
 		_ = s.processChatVoiceSetup(payload)
 
@@ -259,6 +293,16 @@ func (s *service) processIssuedFacts(body []byte, payload map[string]interface{}
 	for _, m := range metrics {
 		m.AppID = s.selfID
 		s.metRepo.Upsert(context.Background(), m)
+
+		// Correlate the transactions issuing facts with the facts issued to
+		// the recipient.
+		if s.ifRepo == nil || m.Recipient == "" || !m.HasAction(entity.ISSUED_FACT_ACTION) {
+			continue
+		}
+		err := s.ifRepo.Deliver(context.Background(), s.selfID, helper.FlattenSelfID(m.Recipient), m.UUID, m.CreatedAt)
+		if err != nil {
+			s.logger.Warnf("failed correlating issued facts: %v", err)
+		}
 	}
 
 	return nil
@@ -268,7 +312,7 @@ func (s *service) processFactsQueryResp(body []byte, payload map[string]interfac
 	iss := payload["iss"].(string)
 	sub := payload["sub"].(string)
 
-	conn, err := s.getOrCreateConnection(iss, "-")
+	conn, err := s.connection(iss)
 	if err != nil {
 		s.logger.With(context.Background(), "self").Info("error creating connection " + err.Error())
 		return err
@@ -290,25 +334,48 @@ func (s *service) processFactsQueryResp(body []byte, payload map[string]interfac
 		}
 	}
 
+	var results []entity.FactResult
 	req, err := s.rRepo.GetByID(context.Background(), payload["cid"].(string))
 	if err != nil {
 		req = entity.Request{
 			ConnectionID: &conn.ID,
 		}
+	} else if req.IsCancelled() {
+		s.logger.With(context.Background(), "self").Infof("discarding response for cancelled request %s", req.ID)
+		return nil
+	} else if req.Status == entity.REQUEST_EXPIRED_STATUS {
+		s.logger.With(context.Background(), "self").Infof("discarding response for expired request %s", req.ID)
+		return nil
+	} else if req.IsPending() && req.IsExpired(time.Now()) {
+		// Late responses are discarded, the app is notified unless the
+		// request was swept or answered meanwhile.
+		s.logger.With(context.Background(), "self").Infof("discarding response for expired request %s", req.ID)
+		ok, err := s.rRepo.Transition(context.Background(), req.ID, entity.REQUEST_REQUESTED_STATUS, entity.REQUEST_EXPIRED_STATUS)
+		if err != nil {
+			s.logger.With(context.Background(), "self").Info("error updating request " + err.Error())
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		req.Status = entity.REQUEST_EXPIRED_STATUS
+		return s.post(request.ExpiredPayload(req))
 	} else {
+		from := req.Status
 		if payload["status"].(string) == "rejected" {
 			req.Status = entity.STATUS_REJECTED
-		} else if len(facts) != 1 && req.Type == "fact" {
-			req.Status = entity.STATUS_REJECTED
 		} else {
-			req.Status = "responded"
+			req.Status, results = request.ValidateResponse(req, facts)
 		}
-		req.UpdatedAt = time.Now()
-		err = s.rRepo.Update(context.Background(), req)
+		ok, err := s.rRepo.Transition(context.Background(), req.ID, from, req.Status)
 		if err != nil {
 			s.logger.With(context.Background(), "self").Info("error updating request " + err.Error())
 			return err
 		}
+		if !ok {
+			s.logger.With(context.Background(), "self").Infof("discarding response for request %s, updated meanwhile", req.ID)
+			return nil
+		}
 	}
 	createdFacts := s.rService.CreateFactsFromResponse(conn, req, facts)
 
@@ -322,7 +389,9 @@ func (s *service) processFactsQueryResp(body []byte, payload map[string]interfac
 		Type: webhook.TYPE_FACT_RESPONSE,
 		URI:  "",
 		Data: entity.Response{
-			Facts: createdFacts,
+			Facts:   createdFacts,
+			Status:  req.Status,
+			Results: results,
 		},
 		Payload: payload,
 	})
@@ -341,16 +410,39 @@ func (s *service) processConnectionResp(payload map[string]interface{}) error {
 		name = data["name"]
 	}
 
-	conn, err := s.getOrCreateConnection(iss, name)
+	conn, created, err := s.getOrCreateConnection(iss, name)
 	if err != nil {
 		s.logger.With(context.Background(), "self").Info("error creating connection " + err.Error())
 		return err
 	}
 
-	// request public info
-	err = s.client.FactService().RequestAsync(&selfsdkfact.FactRequestAsync{
+	// Connections which reconnect have already been onboarded.
+	if created && !s.onboard(conn) {
+		return nil
+	}
+
+	return s.post(webhook.WebhookPayload{
+		Type: webhook.TYPE_CONNECTION,
+		URI:  fmt.Sprintf("/apps/%s/connections/%s", s.selfID, conn.SelfID),
+		Data: conn})
+}
+
+// onboard runs the onboarding policy for a new connection, and returns
+// whether the app must be notified about it. Apps without onboarding only
+// request the connection display name.
+func (s *service) onboard(conn entity.Connection) bool {
+	if s.onboarding != nil {
+		return s.onboarding.Onboard(context.Background(), s.selfID, conn)
+	}
+	s.requestPublicInfo(conn.SelfID)
+	return true
+}
+
+// requestPublicInfo requests the display name of the given connection.
+func (s *service) requestPublicInfo(selfID string) {
+	err := s.client.FactService().RequestAsync(&selfsdkfact.FactRequestAsync{
 		CID:         uuid.New().String(),
-		SelfID:      iss,
+		SelfID:      selfID,
 		Description: "info",
 		Facts:       []selfsdkfact.Fact{{Fact: selfsdkfact.FactDisplayName, Sources: []string{selfsdkfact.SourceUserSpecified}}},
 		Expiry:      time.Minute * 5,
@@ -358,11 +450,6 @@ func (s *service) processConnectionResp(payload map[string]interface{}) error {
 	if err != nil {
 		s.logger.Warnf("failed to request public info: %v", err)
 	}
-
-	return s.post(webhook.WebhookPayload{
-		Type: webhook.TYPE_CONNECTION,
-		URI:  fmt.Sprintf("/apps/%s/connections/%s", s.selfID, conn.SelfID),
-		Data: conn})
 }
 
 func (s *service) processChatMessage(payload map[string]interface{}) error {
@@ -370,10 +457,19 @@ func (s *service) processChatMessage(payload map[string]interface{}) error {
 	if scs := s.client.ChatService(); scs != nil {
 		cs = scs.(*chat.Service)
 	}
-	cm := chat.NewMessage(cs, []string{payload["aud"].(string)}, payload)
+
+	// Objects are parsed into attachments below, so they're not needed to
+	// build the chat message.
+	mp := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		if k != "objects" {
+			mp[k] = v
+		}
+	}
+	cm := chat.NewMessage(cs, []string{payload["aud"].(string)}, mp)
 
 	// Get connection or create one.
-	c, err := s.getOrCreateConnection(cm.ISS, "-")
+	c, err := s.connection(cm.ISS)
 	if err != nil {
 		s.logger.With(context.Background(), "self").Info("error creating connection " + err.Error())
 		return err
@@ -383,6 +479,8 @@ func (s *service) processChatMessage(payload map[string]interface{}) error {
 	msg := entity.Message{
 		ConnectionID: c.ID,
 		ISS:          cm.ISS,
+		CID:          stringValue(payload, "cid"),
+		RID:          stringValue(payload, "rid"),
 		JTI:          cm.JTI,
 		Body:         cm.Body,
 		IAT:          time.Now(),
@@ -390,16 +488,211 @@ func (s *service) processChatMessage(payload map[string]interface{}) error {
 		UpdatedAt:    time.Now(),
 	}
 
+	// Replies without a conversation id continue the replied message one.
+	if msg.CID == "" && msg.RID != "" {
+		if replied, err := s.mRepo.Get(context.Background(), c.ID, msg.RID); err == nil {
+			msg.CID = replied.CID
+		}
+	}
+
 	err = s.mRepo.Create(context.Background(), &msg)
 	if err != nil {
 		s.logger.With(context.Background(), "self").Info("error creating message " + err.Error())
 		return err
 	}
 
-	return s.post(webhook.WebhookPayload{
+	// Store the attachments metadata.
+	for _, a := range parseMessageAttachments(payload) {
+		a.MessageID = msg.ID
+		err = s.mRepo.CreateAttachment(context.Background(), &a)
+		if err != nil {
+			s.logger.With(context.Background(), "self").Info("error creating message attachment " + err.Error())
+			continue
+		}
+		a.URL = a.URI(s.selfID, c.SelfID, msg.JTI)
+		msg.Attachments = append(msg.Attachments, a)
+	}
+
+	if len(msg.Attachments) > 0 && len(s.attDir) > 0 {
+		go s.downloadAttachments(msg)
+	}
+
+	s.reopenConversation(c)
+
+	hook := webhook.WebhookPayload{
 		Type: webhook.TYPE_MESSAGE,
 		URI:  fmt.Sprintf("/apps/%s/connections/%s/messages/%s", s.selfID, c.SelfID, msg.JTI),
-		Data: msg})
+		Data: msg}
+
+	// The prompt is resolved before the rules run, as their auto replies
+	// would be taken as the last message sent to the connection.
+	prompt := s.quickReplyPrompt(c, msg)
+
+	// Messages routed by the rules are sent to the rule endpoints instead of
+	// the app callback.
+	routes := s.evaluateRules(c, msg)
+	if len(routes) > 0 {
+		s.route(routes, hook)
+	} else {
+		err = s.post(hook)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.processQuickReply(c, msg, prompt)
+}
+
+// evaluateRules runs the app rules on a message received from the given
+// connection, it returns the endpoints the message is routed to.
+func (s *service) evaluateRules(c entity.Connection, msg entity.Message) []string {
+	if s.rules == nil {
+		return nil
+	}
+	return s.rules.Evaluate(context.Background(), s.selfID, c.SelfID, msg)
+}
+
+// route queues the given webhook to be sent to each of the given endpoints,
+// which are retried like the app callback.
+func (s *service) route(routes []string, payload webhook.WebhookPayload) {
+	for _, url := range routes {
+		err := s.wp.Send(worker.CallbackTask{
+			AppID:          s.selfID,
+			URL:            url,
+			WebhookPayload: payload,
+		})
+		if err != nil {
+			s.logger.With(context.Background(), "self").Info("error routing message to " + url + ": " + err.Error())
+		}
+	}
+}
+
+// reopenConversation reopens the pending or closed conversation with the
+// given connection when a new message is received.
+func (s *service) reopenConversation(c entity.Connection) {
+	if s.convRepo == nil {
+		return
+	}
+
+	state, err := s.convRepo.State(context.Background(), c.ID)
+	if err != nil || state.Status == entity.CONVERSATION_OPEN_STATUS {
+		return
+	}
+
+	previous := state
+	state.Status = entity.CONVERSATION_OPEN_STATUS
+	state.UpdatedAt = time.Now()
+	err = s.convRepo.SaveState(context.Background(), state)
+	if err != nil {
+		s.logger.With(context.Background(), "self").Info("error reopening conversation " + err.Error())
+		return
+	}
+
+	err = s.post(conversation.NewStateEvent(s.selfID, c.SelfID, previous, state))
+	if err != nil {
+		s.logger.With(context.Background(), "self").Info("error posting conversation reopening " + err.Error())
+	}
+}
+
+// quickReplyPrompt returns the message the given message may answer, the
+// replied message or the last message sent to the connection.
+func (s *service) quickReplyPrompt(c entity.Connection, msg entity.Message) entity.Message {
+	if msg.RID != "" {
+		m, _ := s.mRepo.Get(context.Background(), c.ID, msg.RID)
+		return m
+	}
+
+	messages, err := s.mRepo.Query(context.Background(), c.ID, 0, "", 0, 10)
+	if err != nil {
+		return entity.Message{}
+	}
+	for _, m := range messages {
+		if m.ISS == "me" {
+			return m
+		}
+	}
+	return entity.Message{}
+}
+
+// processQuickReply posts a quick reply event when the given message answers
+// the quick replies offered by the given prompt.
+func (s *service) processQuickReply(c entity.Connection, msg, prompt entity.Message) error {
+	if prompt.ISS != "me" || len(prompt.Content) == 0 {
+		return nil
+	}
+
+	var content message.MessageContent
+	if err := json.Unmarshal(prompt.Content, &content); err != nil || content.Type != message.CONTENT_QUICK_REPLIES {
+		return nil
+	}
+
+	reply, ok := content.Match(msg.Body)
+	if !ok {
+		return nil
+	}
+
+	return s.post(webhook.WebhookPayload{
+		Type: webhook.TYPE_MESSAGE_QUICK_REPLY,
+		URI:  fmt.Sprintf("/apps/%s/connections/%s/messages/%s", s.selfID, c.SelfID, msg.JTI),
+		Data: message.QuickReplyEvent{
+			JTI:   msg.JTI,
+			RID:   prompt.JTI,
+			CID:   msg.CID,
+			ID:    reply.ID,
+			Label: reply.Label,
+			Body:  msg.Body,
+		}})
+}
+
+// downloadAttachments downloads and decrypts the given message attachments
+// into the local storage.
+func (s *service) downloadAttachments(msg entity.Message) {
+	dir, err := s.attachmentsDir(msg)
+	if err != nil {
+		s.logger.With(context.Background(), "self").Info("error building attachments directory " + err.Error())
+		return
+	}
+	if err := os.MkdirAll(dir, 0744); err != nil {
+		s.logger.With(context.Background(), "self").Info("error creating attachments directory " + err.Error())
+		return
+	}
+
+	for _, a := range msg.Attachments {
+		if a.Public {
+			continue
+		}
+		a.UpdatedAt = time.Now()
+
+		content, err := s.client.DownloadObject(a.Link, a.Key, a.Digest)
+		if err == nil {
+			path := filepath.Join(dir, strconv.Itoa(a.Position))
+			if err = os.WriteFile(path, content, 0644); err == nil {
+				a.Status = entity.ATTACHMENT_DOWNLOADED_STATUS
+				a.Path = path
+				a.Size = len(content)
+			}
+		}
+		if err != nil {
+			s.logger.With(context.Background(), "self").Info("error downloading message attachment " + err.Error())
+			a.Status = entity.ATTACHMENT_FAILED_STATUS
+		}
+
+		if err = s.mRepo.UpdateAttachment(context.Background(), a); err != nil {
+			s.logger.With(context.Background(), "self").Info("error updating message attachment " + err.Error())
+		}
+	}
+}
+
+// attachmentsDir returns the directory to store the message attachments in.
+// It's keyed on the local message id, as the message jti is chosen by the
+// sender.
+func (s *service) attachmentsDir(msg entity.Message) (string, error) {
+	root := filepath.Clean(s.attDir)
+	dir := filepath.Join(root, s.selfID, helper.FlattenSelfID(msg.ISS), strconv.Itoa(msg.ID))
+	if !strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		return "", errors.New("attachments directory is outside the storage")
+	}
+	return dir, nil
 }
 
 func (s *service) processChatMessageRead(payload map[string]interface{}) error {
@@ -409,7 +702,7 @@ func (s *service) processChatMessageRead(payload map[string]interface{}) error {
 	}
 
 	// Get connection or create one.
-	c, err := s.getOrCreateConnection(payload["iss"].(string), "-")
+	c, err := s.connection(payload["iss"].(string))
 	if err != nil {
 		s.logger.With(context.Background(), "self").Info("error creating connection " + err.Error())
 		return err
@@ -420,6 +713,89 @@ func (s *service) processChatMessageRead(payload map[string]interface{}) error {
 	return s.mRepo.Update(context.Background(), m)
 }
 
+func (s *service) processChatMessageEdit(payload map[string]interface{}) error {
+	jti, ok := payload["cid"].(string)
+	if !ok || jti == "" {
+		return errors.New("invalid cid received")
+	}
+
+	c, err := s.connection(payload["iss"].(string))
+	if err != nil {
+		s.logger.With(context.Background(), "self").Info("error creating connection " + err.Error())
+		return err
+	}
+
+	m, err := s.mRepo.Get(context.Background(), c.ID, jti)
+	if err != nil {
+		return err
+	}
+
+	// Only the author of a message can edit it.
+	if m.ISS != c.SelfID {
+		return errors.New("message edited by a non author")
+	}
+
+	revision := entity.MessageRevision{
+		MessageID: m.ID,
+		ISS:       m.ISS,
+		Body:      m.Body,
+		CreatedAt: time.Now(),
+	}
+
+	m.Body = stringValue(payload, "msg")
+	m.UpdatedAt = time.Now()
+	err = s.mRepo.Edit(context.Background(), m, &revision)
+	if err != nil {
+		return err
+	}
+
+	return s.post(webhook.WebhookPayload{
+		Type: webhook.TYPE_MESSAGE_EDIT,
+		URI:  fmt.Sprintf("/apps/%s/connections/%s/messages/%s", s.selfID, c.SelfID, m.JTI),
+		Data: m})
+}
+
+func (s *service) processChatMessageDelete(payload map[string]interface{}) error {
+	cids, ok := payload["cids"].([]interface{})
+	if !ok || len(cids) == 0 {
+		return errors.New("invalid cids received")
+	}
+
+	c, err := s.connection(payload["iss"].(string))
+	if err != nil {
+		s.logger.With(context.Background(), "self").Info("error creating connection " + err.Error())
+		return err
+	}
+
+	for _, cid := range cids {
+		jti, ok := cid.(string)
+		if !ok {
+			continue
+		}
+
+		m, err := s.mRepo.Get(context.Background(), c.ID, jti)
+		if err != nil || m.ISS != c.SelfID {
+			continue
+		}
+
+		err = s.mRepo.Delete(context.Background(), c.ID, jti)
+		if err != nil {
+			s.logger.With(context.Background(), "self").Info("error deleting message " + err.Error())
+			continue
+		}
+
+		err = s.post(webhook.WebhookPayload{
+			Type: webhook.TYPE_MESSAGE_DELETE,
+			URI:  fmt.Sprintf("/apps/%s/connections/%s/messages/%s", s.selfID, c.SelfID, m.JTI),
+			Data: m})
+		if err != nil {
+			s.logger.With(context.Background(), "self").Info("error posting message deletion " + err.Error())
+		}
+	}
+
+	return nil
+}
+
 func (s *service) processChatMessageDelivered(payload map[string]interface{}) error {
 	cids := payload["cids"].([]interface{})
 	if len(cids) == 0 {
@@ -427,7 +803,7 @@ func (s *service) processChatMessageDelivered(payload map[string]interface{}) er
 	}
 
 	// Get connection or create one.
-	c, err := s.getOrCreateConnection(payload["iss"].(string), "-")
+	c, err := s.connection(payload["iss"].(string))
 	if err != nil {
 		s.logger.With(context.Background(), "self").Info("error creating connection " + err.Error())
 		return err
@@ -535,14 +911,27 @@ func (s *service) processChatVoiceBusy(payload map[string]interface{}) error {
 	})
 }
 
-func (s *service) getOrCreateConnection(selfID, name string) (entity.Connection, error) {
+// connection returns the connection with the given self id, connections
+// created implicitly, when they first message the app, are also onboarded.
+func (s *service) connection(selfID string) (entity.Connection, error) {
+	c, created, err := s.getOrCreateConnection(selfID, "-")
+	if err == nil && created {
+		s.onboard(c)
+	}
+	return c, err
+}
+
+// getOrCreateConnection returns the connection with the given self id,
+// creating it if it does not exist, and whether it was created.
+func (s *service) getOrCreateConnection(selfID, name string) (entity.Connection, bool, error) {
 	selfID = helper.FlattenSelfID(selfID)
 	c, err := s.cRepo.Get(context.Background(), s.selfID, selfID)
 	if err == nil {
-		return c, nil
+		return c, false, nil
 	}
 
-	return s.createConnection(selfID, name)
+	c, err = s.createConnection(selfID, name)
+	return c, err == nil, err
 }
 
 func (s *service) createConnection(selfID, name string) (entity.Connection, error) {
@@ -575,6 +964,16 @@ func (s *service) post(p webhook.WebhookPayload) error {
 	})
 }
 
-func (s *service) SendCallback(p webhook.WebhookPayload) error {
-	return s.w.Post(s.app.Callback, s.app.CallbackSecret, p)
+// Notify queues the given webhook to be sent to the app callback.
+func (s *service) Notify(p webhook.WebhookPayload) error {
+	return s.post(p)
+}
+
+// SendCallback sends the given webhook to the given url, or to the app
+// callback when the url is empty.
+func (s *service) SendCallback(url string, p webhook.WebhookPayload) error {
+	if url == "" {
+		url = s.app.Callback
+	}
+	return s.w.Post(url, s.app.CallbackSecret, p)
 }
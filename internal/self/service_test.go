@@ -1,12 +1,18 @@
 package self
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/joinself/restful-client/internal/conversation"
 	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/message"
 	"github.com/joinself/restful-client/pkg/log"
 	"github.com/joinself/restful-client/pkg/mock"
+	"github.com/joinself/restful-client/pkg/support"
 	"github.com/joinself/restful-client/pkg/webhook"
 	"github.com/joinself/self-go-sdk/messaging"
 	"github.com/stretchr/testify/assert"
@@ -15,13 +21,18 @@ import (
 
 type config struct {
 	mRepo  *mock.MessageRepositoryMock
+	vRepo  *mock.ConversationRepositoryMock
 	cRepo  *mock.ConnectionRepositoryMock
 	fRepo  *mock.FactRepositoryMock
+	ifRepo *mock.IssuedFactRepositoryMock
+	mtRepo *mock.MetricRepositoryMock
 	wMock  *mock.PosterMock
 	sMock  *mock.SelfMock
 	rRepo  *mock.RequestRepositoryMock
 	rsMock *RequestServiceMock
 	cwMock *mock.CallbackWorkerPoolMock
+	rules  support.RuleEvaluator
+	oMock  support.Onboarder
 }
 
 func buildService(c *config) Service {
@@ -32,9 +43,18 @@ func buildService(c *config) Service {
 	if c.cRepo == nil {
 		c.cRepo = &mock.ConnectionRepositoryMock{}
 	}
+	if c.vRepo == nil {
+		c.vRepo = &mock.ConversationRepositoryMock{}
+	}
 	if c.fRepo == nil {
 		c.fRepo = &mock.FactRepositoryMock{}
 	}
+	if c.ifRepo == nil {
+		c.ifRepo = &mock.IssuedFactRepositoryMock{}
+	}
+	if c.mtRepo == nil {
+		c.mtRepo = &mock.MetricRepositoryMock{}
+	}
 	if c.rRepo == nil {
 		c.rRepo = &mock.RequestRepositoryMock{}
 	}
@@ -55,12 +75,17 @@ func buildService(c *config) Service {
 		SelfClient:         c.sMock,
 		ConnectionRepo:     c.cRepo,
 		FactRepo:           c.fRepo,
+		IssuedFactRepo:     c.ifRepo,
+		MetricRepo:         c.mtRepo,
 		MessageRepo:        c.mRepo,
+		ConversationRepo:   c.vRepo,
 		RequestRepo:        c.rRepo,
 		Logger:             logger,
 		Poster:             c.wMock,
 		RequestService:     c.rsMock,
 		CallbackWorkerPool: c.cwMock,
+		Rules:              c.rules,
+		Onboarding:         c.oMock,
 	})
 
 }
@@ -90,6 +115,156 @@ func TestProcessFactsQueryResp(t *testing.T) {
 	assert.Equal(t, 0, len(resp.Facts))
 }
 
+func TestProcessFactsQueryRespValidatesFacts(t *testing.T) {
+	c := config{rRepo: &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "CID", AppID: "id", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, Facts: []byte(`[{"name":"display_name"}]`)},
+	}}}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss":    "ISS",
+		"sub":    "SUB",
+		"cid":    "CID",
+		"status": "accepted",
+	}
+	var ExportProcessQueryResp = (Service).processFactsQueryResp
+	err := ExportProcessQueryResp(s, []byte(`{"facts":[]}`), payload)
+	require.NoError(t, err)
+
+	// the mocked display name comes without attestations.
+	assert.Equal(t, entity.REQUEST_PARTIAL_STATUS, c.rRepo.Items[0].Status)
+	last := c.cwMock.History[len(c.cwMock.History)-1]
+	assert.Equal(t, webhook.TYPE_FACT_RESPONSE, last.Type)
+	resp := last.Data.(entity.Response)
+	assert.Equal(t, entity.REQUEST_PARTIAL_STATUS, resp.Status)
+	assert.Equal(t, []entity.FactResult{{Fact: "display_name", Status: entity.FACT_RESULT_MISSING}}, resp.Results)
+}
+
+func TestProcessFactsQueryRespExpired(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Minute)
+	c := config{rRepo: &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "CID", AppID: "id", Type: "fact", Status: entity.REQUEST_REQUESTED_STATUS, ExpiresAt: &expiresAt},
+	}}}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	body := []byte(`{"facts":[]}`)
+	payload := map[string]interface{}{
+		"iss":    "ISS",
+		"sub":    "SUB",
+		"cid":    "CID",
+		"status": "accepted",
+	}
+	var ExportProcessQueryResp = (Service).processFactsQueryResp
+	err := ExportProcessQueryResp(s, body, payload)
+	require.NoError(t, err)
+
+	assert.Equal(t, entity.REQUEST_EXPIRED_STATUS, c.rRepo.Items[0].Status)
+	require.Equal(t, 1, len(c.cwMock.History))
+	assert.Equal(t, webhook.TYPE_REQUEST_EXPIRED, c.cwMock.History[0].Type)
+
+	// responses for swept requests are discarded silently
+	err = ExportProcessQueryResp(s, body, payload)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(c.cwMock.History))
+}
+
+func TestProcessFactsQueryRespExpiredResponded(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Minute)
+	c := config{rRepo: &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "CID", AppID: "id", Type: "fact", Status: entity.REQUEST_RESPONDED_STATUS, ExpiresAt: &expiresAt},
+	}}}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss":    "ISS",
+		"sub":    "SUB",
+		"cid":    "CID",
+		"status": "rejected",
+	}
+	var ExportProcessQueryResp = (Service).processFactsQueryResp
+	err := ExportProcessQueryResp(s, []byte(`{"facts":[]}`), payload)
+	require.NoError(t, err)
+
+	// answered requests are not expired by a late response
+	assert.Equal(t, entity.STATUS_REJECTED, c.rRepo.Items[0].Status)
+	for _, h := range c.cwMock.History {
+		assert.NotEqual(t, webhook.TYPE_REQUEST_EXPIRED, h.Type)
+	}
+}
+
+func TestProcessFactsQueryRespCancelled(t *testing.T) {
+	c := config{rRepo: &mock.RequestRepositoryMock{Items: []entity.Request{
+		{ID: "CID", AppID: "id", Type: "fact", Status: entity.REQUEST_CANCELLED_STATUS},
+	}}}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	body := []byte(`{"facts":[]}`)
+	payload := map[string]interface{}{
+		"iss":    "ISS",
+		"sub":    "SUB",
+		"cid":    "CID",
+		"status": "accepted",
+	}
+	var ExportProcessQueryResp = (Service).processFactsQueryResp
+	err := ExportProcessQueryResp(s, body, payload)
+	require.NoError(t, err)
+
+	assert.Equal(t, entity.REQUEST_CANCELLED_STATUS, c.rRepo.Items[0].Status)
+	assert.Equal(t, 0, len(c.cwMock.History))
+}
+
+func TestProcessIssuedFacts(t *testing.T) {
+	c := config{
+		ifRepo: &mock.IssuedFactRepositoryMock{Items: []entity.IssuedFact{
+			{ID: "f1", AppID: "test", SelfID: "recipient", Status: entity.ISSUED_FACT_SENT_STATUS},
+			{ID: "f2", AppID: "test", SelfID: "other", Status: entity.ISSUED_FACT_SENT_STATUS},
+		}},
+	}
+	s := buildService(&c)
+
+	transactions, _ := json.Marshal(map[string]interface{}{
+		"transactions": []map[string]interface{}{
+			{"uuid": 41, "recipient": "other:1", "actions": []string{"chat.message"}, "date": 1723971600},
+			{"uuid": 42, "recipient": "recipient:1", "actions": []string{"identities.facts.issue"}, "date": 1723971600},
+		},
+	})
+	attestation, _ := json.Marshal(map[string]interface{}{
+		"facts": []map[string]string{{"key": "transactions", "value": string(transactions)}},
+	})
+	payload := map[string]interface{}{
+		"attestations": []interface{}{
+			map[string]interface{}{"payload": base64.RawURLEncoding.EncodeToString(attestation)},
+		},
+	}
+
+	err := s.(*service).processIssuedFacts(nil, payload)
+	require.NoError(t, err)
+
+	require.Len(t, c.mtRepo.Items, 2)
+	assert.Equal(t, 42, c.mtRepo.Items[1].UUID)
+	assert.Equal(t, entity.ISSUED_FACT_DELIVERED_STATUS, c.ifRepo.Items[0].Status)
+	require.NotNil(t, c.ifRepo.Items[0].MetricUUID)
+	assert.Equal(t, 42, *c.ifRepo.Items[0].MetricUUID)
+	assert.Equal(t, time.Unix(1723971600, 0), *c.ifRepo.Items[0].DeliveredAt)
+	assert.Equal(t, entity.ISSUED_FACT_SENT_STATUS, c.ifRepo.Items[1].Status)
+}
+
 func TestProcessChatMessage(t *testing.T) {
 	c := config{}
 	s := buildService(&c)
@@ -137,6 +312,349 @@ func TestProcessChatMessage(t *testing.T) {
 	assert.Equal(t, "MSG", lastMsg.Body)
 }
 
+func TestProcessChatMessageReply(t *testing.T) {
+	c := config{
+		mRepo: &mock.MessageRepositoryMock{
+			Items: []entity.Message{{ID: 1, ISS: "me", CID: "CID", JTI: "ORIGINAL"}},
+		},
+	}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "MSG",
+		"jti": "JTI",
+		"aud": "AUD",
+		"rid": "ORIGINAL",
+	}
+	var ExportProcessChatMessage = (Service).processChatMessage
+	ExportProcessChatMessage(s, payload)
+
+	lastMsg := c.mRepo.Items[len(c.mRepo.Items)-1]
+	assert.Equal(t, "JTI", lastMsg.JTI)
+	assert.Equal(t, "ORIGINAL", lastMsg.RID)
+	assert.Equal(t, "CID", lastMsg.CID)
+
+	// Explicit conversation ids are kept
+	payload["jti"] = "JTI2"
+	payload["cid"] = "OTHER"
+	ExportProcessChatMessage(s, payload)
+
+	lastMsg = c.mRepo.Items[len(c.mRepo.Items)-1]
+	assert.Equal(t, "JTI2", lastMsg.JTI)
+	assert.Equal(t, "OTHER", lastMsg.CID)
+}
+
+func TestProcessChatMessageReopensConversation(t *testing.T) {
+	c := config{
+		vRepo: &mock.ConversationRepositoryMock{
+			States: map[int]entity.ConversationState{0: {Assignee: "agent", Status: entity.CONVERSATION_CLOSED_STATUS}},
+		},
+	}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "MSG",
+		"jti": "JTI",
+		"aud": "AUD",
+	}
+	var ExportProcessChatMessage = (Service).processChatMessage
+	ExportProcessChatMessage(s, payload)
+
+	assert.Equal(t, entity.CONVERSATION_OPEN_STATUS, c.vRepo.States[0].Status)
+	assert.Equal(t, "agent", c.vRepo.States[0].Assignee)
+
+	var reopened *webhook.WebhookPayload
+	for i, p := range c.cwMock.History {
+		if p.Type == webhook.TYPE_CONVERSATION {
+			reopened = &c.cwMock.History[i]
+		}
+	}
+	require.NotNil(t, reopened)
+	event := reopened.Data.(conversation.StateEvent)
+	assert.Equal(t, "ISS", event.ConnectionID)
+	assert.Equal(t, entity.CONVERSATION_CLOSED_STATUS, event.PreviousStatus)
+	assert.Equal(t, entity.CONVERSATION_OPEN_STATUS, event.Status)
+
+	// Open conversations are left untouched
+	history := len(c.cwMock.History)
+	payload["jti"] = "JTI2"
+	ExportProcessChatMessage(s, payload)
+	assert.Equal(t, history+1, len(c.cwMock.History))
+}
+
+type rulesMock struct {
+	evaluated []entity.Message
+	routes    []string
+	// reply is run when evaluating the rules, like their auto replies.
+	reply func()
+}
+
+func (m *rulesMock) Evaluate(ctx context.Context, appID, selfID string, msg entity.Message) []string {
+	m.evaluated = append(m.evaluated, msg)
+	if m.reply != nil {
+		m.reply()
+	}
+	return m.routes
+}
+
+func TestProcessChatMessageRules(t *testing.T) {
+	rules := &rulesMock{}
+	c := config{rules: rules}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "MSG",
+		"jti": "JTI",
+		"aud": "AUD",
+	}
+	var ExportProcessChatMessage = (Service).processChatMessage
+	err := ExportProcessChatMessage(s, payload)
+	assert.Nil(t, err)
+
+	// Unrouted messages are sent to the app callback
+	require.Equal(t, 1, len(rules.evaluated))
+	assert.Equal(t, "MSG", rules.evaluated[0].Body)
+	assert.Equal(t, 1, len(c.cwMock.History))
+	assert.Equal(t, 0, len(c.wMock.History))
+
+	// Routed messages are queued for the rule endpoints instead
+	rules.routes = []string{"http://support", "http://sales"}
+	payload["jti"] = "JTI2"
+	err = ExportProcessChatMessage(s, payload)
+	assert.Nil(t, err)
+	require.Equal(t, 3, len(c.cwMock.History))
+	assert.Equal(t, []string{"", "http://support", "http://sales"}, c.cwMock.URLs)
+	assert.Equal(t, webhook.TYPE_MESSAGE, c.cwMock.History[1].Type)
+	assert.Equal(t, 0, len(c.wMock.History))
+}
+
+func TestProcessChatMessageQuickReply(t *testing.T) {
+	c := config{
+		mRepo: &mock.MessageRepositoryMock{
+			Items: []entity.Message{{
+				ID:      1,
+				ISS:     "me",
+				CID:     "CID",
+				JTI:     "PROMPT",
+				Body:    "Continue?",
+				Content: []byte(`{"type":"quick_replies","quick_replies":[{"id":"yes","label":"Yes"},{"id":"no","label":"No"}]}`),
+			}},
+		},
+	}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "2",
+		"jti": "JTI",
+		"aud": "AUD",
+	}
+	var ExportProcessChatMessage = (Service).processChatMessage
+	ExportProcessChatMessage(s, payload)
+
+	last := c.cwMock.History[len(c.cwMock.History)-1]
+	assert.Equal(t, webhook.TYPE_MESSAGE_QUICK_REPLY, last.Type)
+	event := last.Data.(message.QuickReplyEvent)
+	assert.Equal(t, "no", event.ID)
+	assert.Equal(t, "PROMPT", event.RID)
+	assert.Equal(t, "JTI", event.JTI)
+
+	// Answers not matching any option are plain messages
+	payload["jti"] = "JTI2"
+	payload["msg"] = "maybe"
+	ExportProcessChatMessage(s, payload)
+
+	last = c.cwMock.History[len(c.cwMock.History)-1]
+	assert.Equal(t, webhook.TYPE_MESSAGE, last.Type)
+}
+
+func TestProcessChatMessageQuickReplyAutoReply(t *testing.T) {
+	repo := &mock.MessageRepositoryMock{
+		Items: []entity.Message{{
+			ID:      1,
+			ISS:     "me",
+			JTI:     "PROMPT",
+			Body:    "Continue?",
+			Content: []byte(`{"type":"quick_replies","quick_replies":[{"id":"yes","label":"Yes"},{"id":"no","label":"No"}]}`),
+		}},
+	}
+	// The rules auto reply before the quick reply is processed, the reply
+	// becomes the latest message sent to the connection.
+	rules := &rulesMock{reply: func() {
+		repo.Items = append([]entity.Message{{ID: 2, ISS: "me", JTI: "AUTO", Body: "Thanks!"}}, repo.Items...)
+	}}
+	c := config{mRepo: repo, rules: rules}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "yes",
+		"jti": "JTI",
+		"aud": "AUD",
+	}
+	var ExportProcessChatMessage = (Service).processChatMessage
+	err := ExportProcessChatMessage(s, payload)
+	assert.Nil(t, err)
+
+	last := c.cwMock.History[len(c.cwMock.History)-1]
+	require.Equal(t, webhook.TYPE_MESSAGE_QUICK_REPLY, last.Type)
+	assert.Equal(t, "PROMPT", last.Data.(message.QuickReplyEvent).RID)
+}
+
+func TestProcessChatMessageEdit(t *testing.T) {
+	c := config{
+		mRepo: &mock.MessageRepositoryMock{
+			Items: []entity.Message{
+				{ID: 1, ISS: "ISS", JTI: "JTI", Body: "original"},
+				{ID: 2, ISS: "me", JTI: "MINE", Body: "mine"},
+			},
+		},
+	}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	var ExportProcessChatMessageEdit = (Service).processChatMessageEdit
+	err := ExportProcessChatMessageEdit(s, map[string]interface{}{
+		"iss": "ISS",
+		"cid": "JTI",
+		"msg": "edited",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "edited", c.mRepo.Items[0].Body)
+	require.Equal(t, 1, len(c.mRepo.RevisionItems))
+	assert.Equal(t, "original", c.mRepo.RevisionItems[0].Body)
+
+	last := c.cwMock.History[len(c.cwMock.History)-1]
+	assert.Equal(t, webhook.TYPE_MESSAGE_EDIT, last.Type)
+
+	// Messages can only be edited by their author
+	err = ExportProcessChatMessageEdit(s, map[string]interface{}{
+		"iss": "ISS",
+		"cid": "MINE",
+		"msg": "edited",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "mine", c.mRepo.Items[1].Body)
+}
+
+func TestProcessChatMessageDelete(t *testing.T) {
+	c := config{
+		mRepo: &mock.MessageRepositoryMock{
+			Items: []entity.Message{
+				{ID: 1, ISS: "ISS", JTI: "JTI", Body: "original"},
+				{ID: 2, ISS: "me", JTI: "MINE", Body: "mine"},
+			},
+		},
+	}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	var ExportProcessChatMessageDelete = (Service).processChatMessageDelete
+	err := ExportProcessChatMessageDelete(s, map[string]interface{}{
+		"iss":  "ISS",
+		"cids": []interface{}{"JTI", "MINE"},
+	})
+	require.NoError(t, err)
+
+	// Only the messages authored by the sender are deleted
+	require.Equal(t, 1, len(c.mRepo.Items))
+	assert.Equal(t, "MINE", c.mRepo.Items[0].JTI)
+
+	last := c.cwMock.History[len(c.cwMock.History)-1]
+	assert.Equal(t, webhook.TYPE_MESSAGE_DELETE, last.Type)
+	assert.Equal(t, "JTI", last.Data.(entity.Message).JTI)
+}
+
+func TestProcessChatMessageWithAttachments(t *testing.T) {
+	c := config{}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "MSG",
+		"jti": "JTI",
+		"aud": "AUD",
+		"objects": []interface{}{
+			map[string]interface{}{
+				"name":        "file.txt",
+				"link":        "https://example.com/file",
+				"mime":        "text/plain",
+				"key":         "secret",
+				"object_hash": "hash",
+				"expires":     float64(1700000000),
+			},
+		},
+	}
+	var ExportProcessChatMessage = (Service).processChatMessage
+	ExportProcessChatMessage(s, payload)
+
+	// Check the attachment has been stored
+	require.Equal(t, 1, len(c.mRepo.AttachmentItems))
+	stored := c.mRepo.AttachmentItems[0]
+	assert.Equal(t, "file.txt", stored.Name)
+	assert.Equal(t, "https://example.com/file", stored.Link)
+	assert.Equal(t, "secret", stored.Key)
+	assert.Equal(t, "hash", stored.Digest)
+	assert.Equal(t, int64(1700000000), stored.Expires)
+	assert.Equal(t, entity.ATTACHMENT_PENDING_STATUS, stored.Status)
+
+	// Check the webhook exposes the attachment without its secrets
+	last := c.cwMock.History[len(c.cwMock.History)-1]
+	data := last.Data.(entity.Message)
+	require.Equal(t, 1, len(data.Attachments))
+	assert.Equal(t, "/v1/apps/test/connections/ISS/messages/JTI/attachments/0", data.Attachments[0].URL)
+
+	body, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "secret")
+	assert.NotContains(t, string(body), "https://example.com/file")
+}
+
+func TestAttachmentsDir(t *testing.T) {
+	s := &service{attDir: "/storage/attachments", selfID: "app"}
+
+	dir, err := s.attachmentsDir(entity.Message{ID: 7, ISS: "ISS:1", JTI: "../../../.."})
+	require.NoError(t, err)
+	assert.Equal(t, "/storage/attachments/app/ISS/7", dir)
+
+	_, err = s.attachmentsDir(entity.Message{ID: 7, ISS: "../../.."})
+	assert.Error(t, err)
+}
+
 func TestProcessConnectionResp(t *testing.T) {
 	c := config{}
 	s := buildService(&c)
@@ -210,6 +728,87 @@ func TestProcessConnectionRespWithName(t *testing.T) {
 	assert.Equal(t, "NAME", lastConnection.Name)
 }
 
+type onboarderMock struct {
+	onboarded []entity.Connection
+	notify    bool
+}
+
+func (m *onboarderMock) Onboard(ctx context.Context, appID string, conn entity.Connection) bool {
+	m.onboarded = append(m.onboarded, conn)
+	return m.notify
+}
+
+func TestProcessConnectionRespOnboarding(t *testing.T) {
+	onboarder := &onboarderMock{}
+	c := config{oMock: onboarder}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "MSG",
+		"jti": "JTI",
+		"aud": "AUD",
+	}
+	var ExportProcessConnectionResp = (Service).processConnectionResp
+	err := ExportProcessConnectionResp(s, payload)
+	assert.Nil(t, err)
+
+	// The app is not notified when disabled by its policy
+	require.Equal(t, 1, len(onboarder.onboarded))
+	assert.Equal(t, "ISS", onboarder.onboarded[0].SelfID)
+	assert.Equal(t, 0, len(c.cwMock.History))
+
+	// Connections which reconnect are not onboarded again
+	err = ExportProcessConnectionResp(s, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(onboarder.onboarded))
+	require.Equal(t, 1, len(c.cwMock.History))
+	assert.Equal(t, webhook.TYPE_CONNECTION, c.cwMock.History[0].Type)
+
+	onboarder.notify = true
+	payload["iss"] = "NEW"
+	err = ExportProcessConnectionResp(s, payload)
+	assert.Nil(t, err)
+	require.Equal(t, 2, len(onboarder.onboarded))
+	assert.Equal(t, 2, len(c.cwMock.History))
+}
+
+func TestProcessChatMessageOnboarding(t *testing.T) {
+	onboarder := &onboarderMock{}
+	c := config{oMock: onboarder}
+	s := buildService(&c)
+	s.SetApp(entity.App{
+		ID:       "id",
+		Callback: "http://localhost",
+	})
+
+	payload := map[string]interface{}{
+		"iss": "ISS",
+		"msg": "MSG",
+		"jti": "JTI",
+		"aud": "AUD",
+		"cid": "CID",
+		"gid": "",
+		"sub": "SUB",
+	}
+
+	// Connections created when they first message the app are onboarded
+	var ExportProcessChatMessage = (Service).processChatMessage
+	err := ExportProcessChatMessage(s, payload)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(onboarder.onboarded))
+	assert.Equal(t, "ISS", onboarder.onboarded[0].SelfID)
+
+	payload["jti"] = "JTI2"
+	err = ExportProcessChatMessage(s, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(onboarder.onboarded))
+}
+
 func TestProcessIncomingMessage(t *testing.T) {
 	c := config{}
 	s := buildService(&c)
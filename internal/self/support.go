@@ -3,6 +3,7 @@ package self
 import (
 	"encoding/base64"
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/joinself/restful-client/internal/entity"
@@ -66,3 +67,59 @@ func parseIncomingMetrics(payload map[string]interface{}) ([]*entity.Metric, err
 	}
 	return metrics, nil
 }
+
+// parseMessageAttachments builds the attachment descriptors for the objects
+// received on a chat message payload.
+func parseMessageAttachments(payload map[string]interface{}) []entity.MessageAttachment {
+	attachments := []entity.MessageAttachment{}
+	objects, ok := payload["objects"].([]interface{})
+	if !ok {
+		return attachments
+	}
+
+	now := time.Now()
+	for i, oo := range objects {
+		o, ok := oo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		a := entity.MessageAttachment{
+			Position:  i,
+			Name:      stringValue(o, "name"),
+			Link:      stringValue(o, "link"),
+			Mime:      stringValue(o, "mime"),
+			Key:       stringValue(o, "key"),
+			Digest:    stringValue(o, "object_hash"),
+			Status:    entity.ATTACHMENT_PENDING_STATUS,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if a.Digest == "" {
+			a.Digest = stringValue(o, "image_hash")
+		}
+		if public, ok := o["public"].(bool); ok {
+			a.Public = public
+		}
+
+		switch v := o["expires"].(type) {
+		case string:
+			if expires, err := strconv.ParseInt(v, 10, 64); err == nil {
+				a.Expires = expires
+			}
+		case float64:
+			a.Expires = int64(v)
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments
+}
+
+func stringValue(m map[string]interface{}, key string) string {
+	if v, ok := m[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+package template
+
+import (
+	"net/http"
+
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/pagination"
+	"github.com/joinself/restful-client/pkg/response"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *echo.Group, service Service, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.GET("/:app_id/templates/:id", res.get)
+	r.GET("/:app_id/templates", res.query)
+	r.POST("/:app_id/templates", res.create)
+	r.PUT("/:app_id/templates/:id", res.update)
+	r.DELETE("/:app_id/templates/:id", res.delete)
+}
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+// GetTemplate godoc
+// @Summary         Retrieve a message template
+// @Description     Get the details of a message template of an app.
+// @Tags            templates
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Param           id      path      string  true  "Template ID"
+// @Success         200     {object}  Template          "Successful Response"
+// @Failure         404     {object}  response.Error    "Template Not Found"
+// @Router          /apps/{app_id}/templates/{id} [get]
+func (r resource) get(c echo.Context) error {
+	template, err := r.service.Get(c.Request().Context(), c.Param("app_id"), c.Param("id"))
+	if err != nil {
+		r.logger.With(c.Request().Context()).Warnf("error retrieving template: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// ListTemplates godoc
+// @Summary         List message templates
+// @Description     Retrieves the message templates of an app sorted by name.
+// @Tags            templates
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path   string  true  "Application ID"
+// @Param           page query int false "Page number for results pagination"
+// @Param           per_page query int false "Number of results per page for pagination"
+// @Success         200  {object}  ExtListResponse "Successfully retrieved the templates"
+// @Failure         500  {object}  response.Error "Internal server error"
+// @Router          /apps/{app_id}/templates [get]
+func (r resource) query(c echo.Context) error {
+	ctx := c.Request().Context()
+	count, err := r.service.Count(ctx, c.Param("app_id"))
+	if err != nil {
+		r.logger.With(ctx).Warnf("error counting templates: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages := pagination.NewFromRequest(c.Request(), count)
+	templates, err := r.service.Query(ctx, c.Param("app_id"), pages.Offset(), pages.Limit())
+	if err != nil {
+		r.logger.With(ctx).Warnf("error retrieving templates: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	pages.Items = templates
+	return c.JSON(http.StatusOK, pages)
+}
+
+// CreateTemplate godoc
+// @Summary         Create a message template
+// @Description     Creates a message template, variables are included on its body as {{variable}} placeholders and must be declared. The connection.id and connection.name variables are filled with the details of the connection the message is sent to.
+// @Tags            templates
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path      string                 true  "Application ID"
+// @Param           request  body      CreateTemplateRequest  true  "Template details"
+// @Success         201      {object}  Template               "Template created"
+// @Failure         400      {object}  response.Error         "Invalid input"
+// @Failure         500      {object}  response.Error         "Internal Server Error"
+// @Router          /apps/{app_id}/templates [post]
+func (r resource) create(c echo.Context) error {
+	ctx := c.Request().Context()
+	var input CreateTemplateRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("error invalid input: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+
+	if err := input.Validate(); err != nil {
+		r.logger.With(ctx).Infof("error invalid input: %s", err.Error)
+		return c.JSON(err.Status, err)
+	}
+
+	template, err := r.service.Create(ctx, c.Param("app_id"), input)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error creating template: %s", err.Error())
+		return c.JSON(response.DefaultInternalError(c, r.logger, err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTemplate godoc
+// @Summary         Update a message template
+// @Description     Replaces the name, body and variables of a message template.
+// @Tags            templates
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id   path      string                 true  "Application ID"
+// @Param           id       path      string                 true  "Template ID"
+// @Param           request  body      UpdateTemplateRequest  true  "Template details"
+// @Success         200      {object}  Template               "Template updated"
+// @Failure         400      {object}  response.Error         "Invalid input"
+// @Failure         404      {object}  response.Error         "Template Not Found"
+// @Router          /apps/{app_id}/templates/{id} [put]
+func (r resource) update(c echo.Context) error {
+	ctx := c.Request().Context()
+	var input UpdateTemplateRequest
+	if err := c.Bind(&input); err != nil {
+		r.logger.With(ctx).Warnf("error invalid input: %s", err.Error())
+		return c.JSON(response.DefaultBadRequestError())
+	}
+
+	if err := input.Validate(); err != nil {
+		r.logger.With(ctx).Infof("error invalid input: %s", err.Error)
+		return c.JSON(err.Status, err)
+	}
+
+	template, err := r.service.Update(ctx, c.Param("app_id"), c.Param("id"), input)
+	if err != nil {
+		r.logger.With(ctx).Warnf("error updating template: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate godoc
+// @Summary         Delete a message template
+// @Description     Deletes a message template, messages already sent with it are not affected.
+// @Tags            templates
+// @Accept          json
+// @Produce         json
+// @Security        BearerAuth
+// @Param           app_id  path      string  true  "Application ID"
+// @Param           id      path      string  true  "Template ID"
+// @Success         200     {object}  Template          "Template deleted"
+// @Failure         404     {object}  response.Error    "Template Not Found"
+// @Router          /apps/{app_id}/templates/{id} [delete]
+func (r resource) delete(c echo.Context) error {
+	template, err := r.service.Delete(c.Request().Context(), c.Param("app_id"), c.Param("id"))
+	if err != nil {
+		r.logger.With(c.Request().Context()).Warnf("error deleting template: %s", err.Error())
+		return c.JSON(response.DefaultNotFoundError())
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
@@ -0,0 +1,128 @@
+package template
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/acl"
+	"github.com/joinself/restful-client/pkg/filter"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+func TestTemplateAPIEndpointsAsPlainWithPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{"ANY /apps/app_id/templates", "ANY /apps/app_id/templates/*"}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/templates/id",
+			WantStatus:   http.StatusOK,
+			WantResponse: `{"id":"id", "name":"welcome", "body":"hello {{connection.name}}", "variables":[], "created_at":"0001-01-01T00:00:00Z", "updated_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			Name:         "get not found",
+			Method:       "GET",
+			URL:          "/apps/app_id/templates/not_found_id",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+		{
+			Name:         "list",
+			Method:       "GET",
+			URL:          "/apps/app_id/templates",
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"total_count":1*`,
+		},
+		{
+			Name:         "create",
+			Method:       "POST",
+			URL:          "/apps/app_id/templates",
+			Body:         `{"name":"invoice","body":"you owe {{amount}}","variables":["amount"]}`,
+			WantStatus:   http.StatusCreated,
+			WantResponse: `*"variables":["amount"]*`,
+		},
+		{
+			Name:         "create undeclared variable",
+			Method:       "POST",
+			URL:          "/apps/app_id/templates",
+			Body:         `{"name":"invoice","body":"you owe {{amount}}"}`,
+			WantStatus:   http.StatusBadRequest,
+			WantResponse: `{"status":400,"error":"Invalid input","details":"body: undeclared variables amount."}`,
+		},
+		{
+			Name:       "create error",
+			Method:     "POST",
+			URL:        "/apps/app_id/templates",
+			Body:       `{"name":"error","body":"hello"}`,
+			WantStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:         "update",
+			Method:       "PUT",
+			URL:          "/apps/app_id/templates/id",
+			Body:         `{"name":"invoice","body":"hello"}`,
+			WantStatus:   http.StatusOK,
+			WantResponse: `*"body":"hello"*`,
+		},
+		{
+			Name:       "update invalid",
+			Method:     "PUT",
+			URL:        "/apps/app_id/templates/id",
+			Body:       `{"name":"invoice"}`,
+			WantStatus: http.StatusBadRequest,
+		},
+		{
+			Name:       "update not found",
+			Method:     "PUT",
+			URL:        "/apps/app_id/templates/not_found_id",
+			Body:       `{"name":"invoice","body":"hello"}`,
+			WantStatus: http.StatusNotFound,
+		},
+		{
+			Name:       "delete",
+			Method:     "DELETE",
+			URL:        "/apps/app_id/templates/id",
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name:       "delete not found",
+			Method:     "DELETE",
+			URL:        "/apps/app_id/templates/not_found_id",
+			WantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestTemplateAPIEndpointsAsPlainWithoutPermissions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+
+	rg := router.Group("/apps")
+	rg.Use(acl.AuthAsPlainMiddleware([]string{}))
+	rg.Use(acl.NewMiddleware(filter.NewChecker()).TokenAndAccessCheckMiddleware)
+	RegisterHandlers(rg, mockService{}, logger)
+
+	tests := []test.APITestCase{
+		{
+			Name:         "get",
+			Method:       "GET",
+			URL:          "/apps/app_id/templates/id",
+			WantStatus:   http.StatusNotFound,
+			WantResponse: `{"status":404,"error":"Not found","details":"The requested resource does not exist, or you don't have permissions to access it"}`,
+		},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
@@ -0,0 +1,106 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/mock"
+)
+
+type mockService struct{}
+
+func (m mockService) Get(ctx context.Context, appID, id string) (Template, error) {
+	if id == "not_found_id" {
+		return Template{}, sql.ErrNoRows
+	}
+	return Template{ID: id, Name: "welcome", Body: "hello {{connection.name}}", Variables: []string{}}, nil
+}
+
+func (m mockService) Query(ctx context.Context, appID string, offset, limit int) ([]Template, error) {
+	if appID == "query_error" {
+		return nil, errors.New("error!")
+	}
+	return []Template{{ID: "id", Name: "welcome", Body: "hello", Variables: []string{}}}, nil
+}
+
+func (m mockService) Count(ctx context.Context, appID string) (int, error) {
+	if appID == "count_error" {
+		return 0, errors.New("error!")
+	}
+	return 1, nil
+}
+
+func (m mockService) Create(ctx context.Context, appID string, input CreateTemplateRequest) (Template, error) {
+	if input.Name == "error" {
+		return Template{}, errors.New("error!")
+	}
+	return Template{ID: "id", Name: input.Name, Body: input.Body, Variables: input.Variables}, nil
+}
+
+func (m mockService) Update(ctx context.Context, appID, id string, input UpdateTemplateRequest) (Template, error) {
+	if id == "not_found_id" {
+		return Template{}, sql.ErrNoRows
+	}
+	return Template{ID: id, Name: input.Name, Body: input.Body, Variables: input.Variables}, nil
+}
+
+func (m mockService) Delete(ctx context.Context, appID, id string) (Template, error) {
+	if id == "not_found_id" {
+		return Template{}, sql.ErrNoRows
+	}
+	return Template{ID: id, Name: "welcome", Body: "hello", Variables: []string{}}, nil
+}
+
+func (m mockService) Render(ctx context.Context, appID, id, selfID string, variables map[string]string) (string, error) {
+	return "hello", nil
+}
+
+type mockRepository struct {
+	items []entity.Template
+}
+
+func (m *mockRepository) Get(ctx context.Context, appID, id string) (entity.Template, error) {
+	if appID == "error" {
+		return entity.Template{}, mock.ErrCRUD
+	}
+	for _, item := range m.items {
+		if item.AppID == appID && item.ID == id {
+			return item, nil
+		}
+	}
+	return entity.Template{}, sql.ErrNoRows
+}
+
+func (m *mockRepository) Count(ctx context.Context, appID string) (int, error) {
+	return len(m.items), nil
+}
+
+func (m *mockRepository) Query(ctx context.Context, appID string, offset, limit int) ([]entity.Template, error) {
+	return m.items, nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, template entity.Template) error {
+	m.items = append(m.items, template)
+	return nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, template entity.Template) error {
+	for i, item := range m.items {
+		if item.ID == template.ID {
+			m.items[i] = template
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, appID, id string) error {
+	for i, item := range m.items {
+		if item.AppID == appID && item.ID == id {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
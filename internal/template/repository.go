@@ -0,0 +1,90 @@
+package template
+
+import (
+	"context"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/dbcontext"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// Repository encapsulates the logic to access templates from the data source.
+type Repository interface {
+	// Get returns the template with the specified ID.
+	Get(ctx context.Context, appID, id string) (entity.Template, error)
+	// Count returns the number of templates of an app.
+	Count(ctx context.Context, appID string) (int, error)
+	// Query returns the list of templates of an app with the given offset and limit.
+	Query(ctx context.Context, appID string, offset, limit int) ([]entity.Template, error)
+	// Create saves a new template in the storage.
+	Create(ctx context.Context, template entity.Template) error
+	// Update updates the template with given ID in the storage.
+	Update(ctx context.Context, template entity.Template) error
+	// Delete removes the template with given ID from the storage.
+	Delete(ctx context.Context, appID, id string) error
+}
+
+// repository persists templates in database
+type repository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+// NewRepository creates a new template repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+// Get reads the template with the specified ID from the database.
+func (r repository) Get(ctx context.Context, appID, id string) (entity.Template, error) {
+	var template entity.Template
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"id": id, "app_id": appID}).
+		One(&template)
+	return template, err
+}
+
+// Count returns the number of templates of an app in the database.
+func (r repository) Count(ctx context.Context, appID string) (int, error) {
+	var count int
+	err := r.db.With(ctx).
+		Select("COUNT(*)").
+		From("template").
+		Where(dbx.HashExp{"app_id": appID}).
+		Row(&count)
+	return count, err
+}
+
+// Query retrieves the templates of an app sorted by name.
+func (r repository) Query(ctx context.Context, appID string, offset, limit int) ([]entity.Template, error) {
+	var templates []entity.Template
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"app_id": appID}).
+		OrderBy("name").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&templates)
+	return templates, err
+}
+
+// Create saves a new template record in the database.
+func (r repository) Create(ctx context.Context, template entity.Template) error {
+	return r.db.With(ctx).Model(&template).Insert()
+}
+
+// Update saves the changes to a template in the database.
+func (r repository) Update(ctx context.Context, template entity.Template) error {
+	return r.db.With(ctx).Model(&template).Update()
+}
+
+// Delete deletes the template with the specified ID from the database.
+func (r repository) Delete(ctx context.Context, appID, id string) error {
+	template, err := r.Get(ctx, appID, id)
+	if err != nil {
+		return err
+	}
+	return r.db.With(ctx).Model(&template).Delete()
+}
@@ -0,0 +1,68 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/internal/test"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := test.DB(t)
+	test.ResetTables(t, db, "template")
+	repo := NewRepository(db, logger)
+
+	ctx := context.Background()
+	appID := "app_" + uuid.New().String()
+
+	// create
+	template := entity.Template{
+		ID:        uuid.New().String(),
+		AppID:     appID,
+		Name:      "welcome",
+		Body:      "hello {{name}}",
+		Variables: []byte(`["name"]`),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	err := repo.Create(ctx, template)
+	assert.Nil(t, err)
+
+	// get
+	stored, err := repo.Get(ctx, appID, template.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello {{name}}", stored.Body)
+	assert.Equal(t, `["name"]`, string(stored.Variables))
+	_, err = repo.Get(ctx, "other", template.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// update
+	stored.Body = "hi {{name}}"
+	err = repo.Update(ctx, stored)
+	assert.Nil(t, err)
+	stored, _ = repo.Get(ctx, appID, template.ID)
+	assert.Equal(t, "hi {{name}}", stored.Body)
+
+	// query
+	count, err := repo.Count(ctx, appID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	templates, err := repo.Query(ctx, appID, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(templates))
+
+	// delete
+	err = repo.Delete(ctx, appID, template.ID)
+	assert.Nil(t, err)
+	_, err = repo.Get(ctx, appID, template.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+	err = repo.Delete(ctx, appID, template.ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
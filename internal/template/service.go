@@ -0,0 +1,252 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joinself/restful-client/internal/connection"
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+)
+
+// ErrTemplateNotFound is returned when rendering a template that does not
+// exist.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrInvalidVariables is returned when the variables used to render a
+// template don't match the variables it declares.
+var ErrInvalidVariables = errors.New("invalid template variables")
+
+// placeholder matches the {{variable}} placeholders of a template body.
+var placeholder = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.]+)\s*\}\}`)
+
+// builtins are the variables filled with the connection fields.
+var builtins = map[string]func(c entity.Connection) string{
+	"connection.id":   func(c entity.Connection) string { return c.SelfID },
+	"connection.name": func(c entity.Connection) string { return c.Name },
+}
+
+// Service encapsulates usecase logic for message templates.
+type Service interface {
+	Get(ctx context.Context, appID, id string) (Template, error)
+	Query(ctx context.Context, appID string, offset, limit int) ([]Template, error)
+	Count(ctx context.Context, appID string) (int, error)
+	Create(ctx context.Context, appID string, input CreateTemplateRequest) (Template, error)
+	Update(ctx context.Context, appID, id string, input UpdateTemplateRequest) (Template, error)
+	Delete(ctx context.Context, appID, id string) (Template, error)
+	Render(ctx context.Context, appID, id, selfID string, variables map[string]string) (string, error)
+}
+
+// Template represents the data about a message template.
+type Template struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Body      string    `json:"body"`
+	Variables []string  `json:"variables"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type service struct {
+	repo   Repository
+	cRepo  connection.Repository
+	logger log.Logger
+}
+
+// NewService creates a new template service.
+func NewService(repo Repository, cRepo connection.Repository, logger log.Logger) Service {
+	return service{repo, cRepo, logger}
+}
+
+// Get returns the template with the specified ID.
+func (s service) Get(ctx context.Context, appID, id string) (Template, error) {
+	template, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return Template{}, err
+	}
+	return newTemplateFromEntity(template)
+}
+
+// Query returns the templates of an app with the specified offset and limit.
+func (s service) Query(ctx context.Context, appID string, offset, limit int) ([]Template, error) {
+	items, err := s.repo.Query(ctx, appID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Template{}
+	for _, item := range items {
+		t, err := newTemplateFromEntity(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// Count returns the number of templates of an app.
+func (s service) Count(ctx context.Context, appID string) (int, error) {
+	return s.repo.Count(ctx, appID)
+}
+
+// Create creates a new template.
+func (s service) Create(ctx context.Context, appID string, req CreateTemplateRequest) (Template, error) {
+	variables, err := json.Marshal(req.Variables)
+	if err != nil {
+		return Template{}, err
+	}
+
+	now := time.Now()
+	template := entity.Template{
+		ID:        uuid.New().String(),
+		AppID:     appID,
+		Name:      req.Name,
+		Body:      req.Body,
+		Variables: variables,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = s.repo.Create(ctx, template)
+	if err != nil {
+		return Template{}, err
+	}
+
+	return s.Get(ctx, appID, template.ID)
+}
+
+// Update updates the template with the specified ID.
+func (s service) Update(ctx context.Context, appID, id string, req UpdateTemplateRequest) (Template, error) {
+	template, err := s.repo.Get(ctx, appID, id)
+	if err != nil {
+		return Template{}, err
+	}
+
+	variables, err := json.Marshal(req.Variables)
+	if err != nil {
+		return Template{}, err
+	}
+
+	template.Name = req.Name
+	template.Body = req.Body
+	template.Variables = variables
+	template.UpdatedAt = time.Now()
+
+	err = s.repo.Update(ctx, template)
+	if err != nil {
+		return Template{}, err
+	}
+
+	return newTemplateFromEntity(template)
+}
+
+// Delete deletes the template with the specified ID.
+func (s service) Delete(ctx context.Context, appID, id string) (Template, error) {
+	template, err := s.Get(ctx, appID, id)
+	if err != nil {
+		return Template{}, err
+	}
+
+	err = s.repo.Delete(ctx, appID, id)
+	if err != nil {
+		return Template{}, err
+	}
+	return template, nil
+}
+
+// Render returns the body of the given template with its placeholders
+// replaced by the given variables and the fields of the connection.
+func (s service) Render(ctx context.Context, appID, id, selfID string, variables map[string]string) (string, error) {
+	template, err := s.Get(ctx, appID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTemplateNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = checkVariables(template.Variables, variables)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := s.cRepo.Get(ctx, appID, selfID)
+	if err != nil {
+		return "", err
+	}
+
+	return placeholder.ReplaceAllStringFunc(template.Body, func(p string) string {
+		name := placeholder.FindStringSubmatch(p)[1]
+		if builtin, ok := builtins[name]; ok {
+			return builtin(conn)
+		}
+		return variables[name]
+	}), nil
+}
+
+// checkVariables checks the given values are provided for all the declared
+// variables and nothing else.
+func checkVariables(declared []string, values map[string]string) error {
+	missing := []string{}
+	for _, name := range declared {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrInvalidVariables, strings.Join(missing, ", "))
+	}
+
+	unknown := []string{}
+	for name := range values {
+		if !contains(declared, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: unknown %s", ErrInvalidVariables, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+func newTemplateFromEntity(t entity.Template) (Template, error) {
+	var variables []string
+	if len(t.Variables) > 0 {
+		err := json.Unmarshal(t.Variables, &variables)
+		if err != nil {
+			return Template{}, err
+		}
+	}
+	if variables == nil {
+		variables = []string{}
+	}
+
+	return Template{
+		ID:        t.ID,
+		Name:      t.Name,
+		Body:      t.Body,
+		Variables: variables,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/joinself/restful-client/pkg/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTemplateRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     CreateTemplateRequest
+		wantError bool
+	}{
+		{"success", CreateTemplateRequest{Name: "welcome", Body: "hello"}, false},
+		{"variables", CreateTemplateRequest{Name: "welcome", Body: "hello {{ name }}, you owe {{amount}}", Variables: []string{"name", "amount"}}, false},
+		{"built-in variables", CreateTemplateRequest{Name: "welcome", Body: "hello {{connection.name}}"}, false},
+		{"name required", CreateTemplateRequest{Body: "hello"}, true},
+		{"body required", CreateTemplateRequest{Name: "welcome"}, true},
+		{"undeclared variable", CreateTemplateRequest{Name: "welcome", Body: "hello {{name}}"}, true},
+		{"invalid variable name", CreateTemplateRequest{Name: "welcome", Body: "hello {{connection.name}}", Variables: []string{"connection.name"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.Validate()
+			assert.Equal(t, tt.wantError, err != nil)
+		})
+	}
+}
+
+func Test_service_CRUD(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(&mockRepository{}, &mock.ConnectionRepositoryMock{}, logger)
+	ctx := context.Background()
+
+	// create
+	template, err := s.Create(ctx, "app", CreateTemplateRequest{Name: "welcome", Body: "hello {{name}}", Variables: []string{"name"}})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, template.ID)
+	assert.Equal(t, []string{"name"}, template.Variables)
+
+	// update
+	template, err = s.Update(ctx, "app", template.ID, UpdateTemplateRequest{Name: "welcome", Body: "hi", Variables: nil})
+	assert.Nil(t, err)
+	assert.Equal(t, "hi", template.Body)
+	assert.Equal(t, []string{}, template.Variables)
+	_, err = s.Update(ctx, "other", template.ID, UpdateTemplateRequest{Name: "welcome", Body: "hi"})
+	assert.NotNil(t, err)
+
+	// get
+	template, err = s.Get(ctx, "app", template.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "hi", template.Body)
+
+	// query
+	count, _ := s.Count(ctx, "app")
+	assert.Equal(t, 1, count)
+	templates, err := s.Query(ctx, "app", 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(templates))
+
+	// delete
+	_, err = s.Delete(ctx, "app", template.ID)
+	assert.Nil(t, err)
+	_, err = s.Delete(ctx, "app", template.ID)
+	assert.NotNil(t, err)
+}
+
+func Test_service_Render(t *testing.T) {
+	logger, _ := log.NewForTest()
+	cRepo := &mock.ConnectionRepositoryMock{Items: []entity.Connection{{AppID: "app", SelfID: "selfid", Name: "John"}}}
+	s := NewService(&mockRepository{}, cRepo, logger)
+	ctx := context.Background()
+
+	template, err := s.Create(ctx, "app", CreateTemplateRequest{
+		Name:      "invoice",
+		Body:      "Hi {{connection.name}}, you owe {{ amount }} by {{date}}.",
+		Variables: []string{"amount", "date"},
+	})
+	assert.Nil(t, err)
+
+	body, err := s.Render(ctx, "app", template.ID, "selfid", map[string]string{"amount": "10€", "date": "Monday"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hi John, you owe 10€ by Monday.", body)
+
+	// missing variables
+	_, err = s.Render(ctx, "app", template.ID, "selfid", map[string]string{"amount": "10€"})
+	assert.True(t, errors.Is(err, ErrInvalidVariables))
+	assert.Equal(t, "invalid template variables: missing date", err.Error())
+
+	// unknown variables
+	_, err = s.Render(ctx, "app", template.ID, "selfid", map[string]string{"amount": "10€", "date": "Monday", "name": "Jane"})
+	assert.True(t, errors.Is(err, ErrInvalidVariables))
+	assert.Equal(t, "invalid template variables: unknown name", err.Error())
+
+	// unknown template
+	_, err = s.Render(ctx, "other", template.ID, "selfid", nil)
+	assert.Equal(t, ErrTemplateNotFound, err)
+
+	// storage errors are returned as they are
+	_, err = s.Render(ctx, "error", template.ID, "selfid", nil)
+	assert.Equal(t, mock.ErrCRUD, err)
+
+	// unknown connection
+	_, err = s.Render(ctx, "app", template.ID, "unknown", map[string]string{"amount": "10€", "date": "Monday"})
+	assert.NotNil(t, err)
+}
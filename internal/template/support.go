@@ -0,0 +1,86 @@
+package template
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/joinself/restful-client/pkg/response"
+)
+
+// variableName matches the names of the variables a template can declare.
+var variableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ExtListResponse represents the json object returned when listing templates.
+type ExtListResponse struct {
+	Page       int        `json:"page"`
+	PerPage    int        `json:"per_page"`
+	PageCount  int        `json:"page_count"`
+	TotalCount int        `json:"total_count"`
+	Items      []Template `json:"items"`
+}
+
+// CreateTemplateRequest represents a template creation request.
+type CreateTemplateRequest struct {
+	Name string `json:"name"`
+	// Body is the text of the template, variables are included as
+	// {{variable}} placeholders.
+	Body string `json:"body"`
+	// Variables are the names of the variables used on the body, besides the
+	// connection.id and connection.name built-in variables.
+	Variables []string `json:"variables"`
+}
+
+// Validate validates the CreateTemplateRequest fields.
+func (m CreateTemplateRequest) Validate() *response.Error {
+	return validateTemplate(m.Name, m.Body, m.Variables)
+}
+
+// UpdateTemplateRequest represents a template update request.
+type UpdateTemplateRequest struct {
+	Name      string   `json:"name"`
+	Body      string   `json:"body"`
+	Variables []string `json:"variables"`
+}
+
+// Validate validates the UpdateTemplateRequest fields.
+func (m UpdateTemplateRequest) Validate() *response.Error {
+	return validateTemplate(m.Name, m.Body, m.Variables)
+}
+
+func validateTemplate(name, body string, variables []string) *response.Error {
+	err := validation.Errors{
+		"name":      validation.Validate(name, validation.Required, validation.Length(1, 128)),
+		"body":      validation.Validate(body, validation.Required, validation.Length(1, 1024), validation.By(placeholdersDeclared(variables))),
+		"variables": validation.Validate(variables, validation.Each(validation.Match(variableName))),
+	}.Filter()
+	if err == nil {
+		return nil
+	}
+	return &response.Error{
+		Status:  http.StatusBadRequest,
+		Error:   "Invalid input",
+		Details: err.Error(),
+	}
+}
+
+// placeholdersDeclared checks every placeholder of a template body is a
+// declared or built-in variable.
+func placeholdersDeclared(variables []string) validation.RuleFunc {
+	return func(value interface{}) error {
+		undeclared := []string{}
+		for _, m := range placeholder.FindAllStringSubmatch(value.(string), -1) {
+			name := m[1]
+			if _, ok := builtins[name]; ok || contains(variables, name) || contains(undeclared, name) {
+				continue
+			}
+			undeclared = append(undeclared, name)
+		}
+		if len(undeclared) > 0 {
+			return fmt.Errorf("undeclared variables %s", strings.Join(undeclared, ", "))
+		}
+		return nil
+	}
+}
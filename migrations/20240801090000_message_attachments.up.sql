@@ -0,0 +1,22 @@
+CREATE TABLE message_attachment
+(
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id      INTEGER NOT NULL,
+    position        INTEGER NOT NULL DEFAULT 0,
+    name            VARCHAR NOT NULL DEFAULT '',
+    link            VARCHAR NOT NULL DEFAULT '',
+    mime            VARCHAR NOT NULL DEFAULT '',
+    key             VARCHAR NOT NULL DEFAULT '',
+    digest          VARCHAR NOT NULL DEFAULT '',
+    expires         INTEGER NOT NULL DEFAULT 0,
+    public          INTEGER NOT NULL DEFAULT 0,
+    status          VARCHAR NOT NULL DEFAULT 'pending',
+    path            VARCHAR NOT NULL DEFAULT '',
+    size            INTEGER NOT NULL DEFAULT 0,
+    created_at      TIMESTAMP NOT NULL,
+    updated_at      TIMESTAMP NOT NULL,
+    UNIQUE(message_id, position),
+    CONSTRAINT fk_message
+      FOREIGN KEY(message_id)
+      REFERENCES message(id)
+);
@@ -0,0 +1 @@
+DROP INDEX message_cid_idx;
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX message_cid_idx ON message (connection_id, cid);
\ No newline at end of file
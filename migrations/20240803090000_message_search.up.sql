@@ -0,0 +1,2 @@
+CREATE VIRTUAL TABLE message_fts USING fts5(body);
+INSERT INTO message_fts(rowid, body) SELECT id, body FROM message;
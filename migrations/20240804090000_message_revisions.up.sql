@@ -0,0 +1,12 @@
+CREATE TABLE message_revision
+(
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id      INTEGER NOT NULL,
+    iss             VARCHAR NOT NULL DEFAULT '',
+    body            VARCHAR NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+    CONSTRAINT fk_message
+      FOREIGN KEY(message_id)
+      REFERENCES message(id)
+);
+CREATE INDEX message_revision_message_idx ON message_revision (message_id);
@@ -0,0 +1,4 @@
+ALTER TABLE message
+DROP COLUMN send_at;
+ALTER TABLE message
+DROP COLUMN status;
@@ -0,0 +1,4 @@
+ALTER TABLE message
+ADD COLUMN status VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE message
+ADD COLUMN send_at TIMESTAMP;
@@ -0,0 +1,2 @@
+DROP INDEX connection_tag_tag_idx;
+DROP TABLE connection_tag;
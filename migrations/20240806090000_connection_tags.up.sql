@@ -0,0 +1,7 @@
+CREATE TABLE connection_tag (
+    connection_id INTEGER NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (connection_id, tag),
+    FOREIGN KEY(connection_id) REFERENCES connection(id)
+);
+CREATE INDEX connection_tag_tag_idx ON connection_tag (tag);
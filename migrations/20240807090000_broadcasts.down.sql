@@ -0,0 +1,4 @@
+DROP INDEX broadcast_recipient_status_idx;
+DROP TABLE broadcast_recipient;
+DROP INDEX broadcast_app_idx;
+DROP TABLE broadcast;
@@ -0,0 +1,25 @@
+CREATE TABLE broadcast (
+    id TEXT NOT NULL PRIMARY KEY,
+    app_id VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    target VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE INDEX broadcast_app_idx ON broadcast (app_id);
+
+CREATE TABLE broadcast_recipient (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    broadcast_id TEXT NOT NULL,
+    connection_id INTEGER NOT NULL,
+    selfid VARCHAR(255) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    jti VARCHAR(255) DEFAULT '' NOT NULL,
+    error TEXT DEFAULT '' NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY(broadcast_id) REFERENCES broadcast(id),
+    FOREIGN KEY(connection_id) REFERENCES connection(id)
+);
+CREATE INDEX broadcast_recipient_status_idx ON broadcast_recipient (broadcast_id, status);
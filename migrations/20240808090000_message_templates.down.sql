@@ -0,0 +1,2 @@
+DROP INDEX template_app_idx;
+DROP TABLE template;
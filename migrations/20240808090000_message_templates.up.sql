@@ -0,0 +1,10 @@
+CREATE TABLE template (
+    id TEXT NOT NULL PRIMARY KEY,
+    app_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    variables TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE INDEX template_app_idx ON template (app_id);
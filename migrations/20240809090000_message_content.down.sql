@@ -0,0 +1,2 @@
+ALTER TABLE message
+DROP COLUMN content;
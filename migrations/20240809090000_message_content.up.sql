@@ -0,0 +1,2 @@
+ALTER TABLE message
+ADD COLUMN content TEXT;
@@ -0,0 +1 @@
+DROP INDEX message_connection_created_idx;
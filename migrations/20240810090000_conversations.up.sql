@@ -0,0 +1 @@
+CREATE INDEX message_connection_created_idx ON message (connection_id, created_at);
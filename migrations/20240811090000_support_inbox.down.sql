@@ -0,0 +1,3 @@
+DROP INDEX conversation_note_connection_idx;
+DROP TABLE conversation_note;
+DROP TABLE conversation_state;
@@ -0,0 +1,16 @@
+CREATE TABLE conversation_state (
+    connection_id INTEGER NOT NULL PRIMARY KEY,
+    assignee VARCHAR(255) DEFAULT '' NOT NULL,
+    status VARCHAR(32) DEFAULT 'open' NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE conversation_note (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    connection_id INTEGER NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX conversation_note_connection_idx ON conversation_note (connection_id);
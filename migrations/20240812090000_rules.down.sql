@@ -0,0 +1,2 @@
+DROP INDEX rule_app_idx;
+DROP TABLE rule;
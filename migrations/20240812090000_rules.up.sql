@@ -0,0 +1,12 @@
+CREATE TABLE rule (
+    id TEXT NOT NULL PRIMARY KEY,
+    app_id VARCHAR(255) NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    enabled BOOLEAN DEFAULT TRUE NOT NULL,
+    priority INTEGER DEFAULT 0 NOT NULL,
+    conditions TEXT DEFAULT '[]' NOT NULL,
+    actions TEXT DEFAULT '[]' NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE INDEX rule_app_idx ON rule (app_id, priority);
@@ -0,0 +1,10 @@
+CREATE TABLE onboarding (
+    app_id VARCHAR(255) NOT NULL PRIMARY KEY,
+    welcome_message TEXT DEFAULT '' NOT NULL,
+    facts TEXT DEFAULT '[]' NOT NULL,
+    description VARCHAR(128) DEFAULT '' NOT NULL,
+    tags TEXT DEFAULT '[]' NOT NULL,
+    notify BOOLEAN DEFAULT TRUE NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
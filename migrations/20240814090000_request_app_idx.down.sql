@@ -0,0 +1 @@
+DROP INDEX request_app_created_idx;
@@ -0,0 +1 @@
+CREATE INDEX request_app_created_idx ON request (app_id, created_at);
@@ -0,0 +1,2 @@
+DROP INDEX request_status_expires_idx;
+ALTER TABLE request DROP COLUMN expires_at;
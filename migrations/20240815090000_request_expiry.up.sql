@@ -0,0 +1,2 @@
+ALTER TABLE request ADD COLUMN expires_at DATETIME;
+CREATE INDEX request_status_expires_idx ON request (status, expires_at);
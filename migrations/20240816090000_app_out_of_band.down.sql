@@ -0,0 +1,6 @@
+ALTER TABLE request DROP COLUMN out_of_band_config;
+ALTER TABLE app DROP COLUMN qr_logo;
+ALTER TABLE app DROP COLUMN qr_background_color;
+ALTER TABLE app DROP COLUMN qr_foreground_color;
+ALTER TABLE app DROP COLUMN qr_size;
+ALTER TABLE app DROP COLUMN dl_code;
@@ -0,0 +1,6 @@
+ALTER TABLE app ADD COLUMN dl_code VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE app ADD COLUMN qr_size INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE app ADD COLUMN qr_foreground_color VARCHAR(7) DEFAULT '' NOT NULL;
+ALTER TABLE app ADD COLUMN qr_background_color VARCHAR(7) DEFAULT '' NOT NULL;
+ALTER TABLE app ADD COLUMN qr_logo VARCHAR(2048) DEFAULT '' NOT NULL;
+ALTER TABLE request ADD COLUMN out_of_band_config TEXT;
@@ -0,0 +1,4 @@
+ALTER TABLE attestation DROP COLUMN result;
+ALTER TABLE fact DROP COLUMN result;
+ALTER TABLE fact DROP COLUMN expected_value;
+ALTER TABLE fact DROP COLUMN operator;
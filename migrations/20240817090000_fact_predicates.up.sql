@@ -0,0 +1,4 @@
+ALTER TABLE fact ADD COLUMN operator VARCHAR(2) DEFAULT '' NOT NULL;
+ALTER TABLE fact ADD COLUMN expected_value VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE fact ADD COLUMN result BOOLEAN;
+ALTER TABLE attestation ADD COLUMN result BOOLEAN;
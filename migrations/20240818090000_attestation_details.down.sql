@@ -0,0 +1,6 @@
+ALTER TABLE attestation DROP COLUMN history;
+ALTER TABLE attestation DROP COLUMN expires_at;
+ALTER TABLE attestation DROP COLUMN issued_at;
+ALTER TABLE attestation DROP COLUMN kid;
+ALTER TABLE attestation DROP COLUMN source;
+ALTER TABLE attestation DROP COLUMN issuer;
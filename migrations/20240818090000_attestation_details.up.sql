@@ -0,0 +1,6 @@
+ALTER TABLE attestation ADD COLUMN issuer VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE attestation ADD COLUMN source VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE attestation ADD COLUMN kid VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE attestation ADD COLUMN issued_at DATETIME;
+ALTER TABLE attestation ADD COLUMN expires_at DATETIME;
+ALTER TABLE attestation ADD COLUMN history TEXT;
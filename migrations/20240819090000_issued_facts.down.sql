@@ -0,0 +1,3 @@
+DROP INDEX issued_fact_recipient_idx;
+DROP INDEX issued_fact_connection_idx;
+DROP TABLE issued_fact;
@@ -0,0 +1,23 @@
+CREATE TABLE issued_fact (
+    id TEXT NOT NULL PRIMARY KEY,
+    issue_id TEXT NOT NULL,
+    app_id VARCHAR(255) NOT NULL,
+    connection_id INTEGER NOT NULL,
+    self_id VARCHAR(255) NOT NULL,
+    fact_key VARCHAR(128) NOT NULL,
+    value_hash VARCHAR(64) NOT NULL,
+    source VARCHAR(128) DEFAULT '' NOT NULL,
+    group_name VARCHAR(128) DEFAULT '' NOT NULL,
+    group_icon VARCHAR(128) DEFAULT '' NOT NULL,
+    type VARCHAR(32) DEFAULT '' NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    error TEXT DEFAULT '' NOT NULL,
+    metric_uuid INTEGER,
+    expires_at DATETIME,
+    delivered_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY(connection_id) REFERENCES connection(id)
+);
+CREATE INDEX issued_fact_connection_idx ON issued_fact (connection_id, created_at);
+CREATE INDEX issued_fact_recipient_idx ON issued_fact (app_id, self_id, status);
@@ -0,0 +1 @@
+DROP INDEX fact_connection_fact_idx;
@@ -0,0 +1 @@
+CREATE INDEX fact_connection_fact_idx ON fact (connection_id, fact, created_at);
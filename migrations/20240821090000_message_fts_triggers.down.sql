@@ -0,0 +1,3 @@
+DROP TRIGGER message_fts_delete;
+DROP TRIGGER message_fts_update;
+DROP TRIGGER message_fts_insert;
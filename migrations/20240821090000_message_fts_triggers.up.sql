@@ -0,0 +1,10 @@
+DELETE FROM message_fts WHERE rowid NOT IN (SELECT id FROM message);
+CREATE TRIGGER message_fts_insert AFTER INSERT ON message BEGIN
+  INSERT INTO message_fts(rowid, body) VALUES (new.id, new.body);
+END;
+CREATE TRIGGER message_fts_update AFTER UPDATE OF body ON message BEGIN
+  UPDATE message_fts SET body = new.body WHERE rowid = old.id;
+END;
+CREATE TRIGGER message_fts_delete AFTER DELETE ON message BEGIN
+  DELETE FROM message_fts WHERE rowid = old.id;
+END;
@@ -0,0 +1,238 @@
+// Package bundle builds and verifies the fact export bundles, which hold the
+// facts received from a connection along with everything needed to verify
+// them without access to the restful client.
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joinself/restful-client/pkg/jws"
+)
+
+// VERSION is the version of the bundles format.
+const VERSION = "1.0.0"
+
+var (
+	// ErrInvalidBundle is returned when the bundle can't be decoded.
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrInvalidManifest is returned when the manifest is not signed by the app.
+	ErrInvalidManifest = errors.New("invalid bundle manifest")
+	// ErrFactsMismatch is returned when the bundle facts don't match the ones
+	// listed on the manifest.
+	ErrFactsMismatch = errors.New("bundle facts don't match the manifest")
+	// ErrInvalidAttestation is returned when an attestation is not signed by
+	// its issuer, or doesn't attest the fact value of the bundle connection.
+	ErrInvalidAttestation = errors.New("invalid attestation")
+
+	errSubjectMismatch = errors.New("attestation subject doesn't match the bundle connection")
+	errValueMismatch   = errors.New("attestation value doesn't match the signed value")
+)
+
+// KeyResolver returns the trusted public key history of the given Self
+// identity, usually retrieved from Self.
+type KeyResolver func(selfID string) ([]json.RawMessage, error)
+
+// Bundle is a verifiable export of the facts received from a connection.
+type Bundle struct {
+	Version string `json:"version"`
+	Facts   []Fact `json:"facts"`
+	// AppKeys is the public key history of the app which signed the manifest
+	// when the bundle was generated. It's informative only, Verify checks the
+	// manifest against the keys returned by its KeyResolver.
+	AppKeys []json.RawMessage `json:"app_keys"`
+	// Manifest is the JWS of the bundle Manifest signed by the app.
+	Manifest json.RawMessage `json:"manifest"`
+}
+
+// Manifest lists the digests of the bundle facts.
+type Manifest struct {
+	Version     string    `json:"version"`
+	AppID       string    `json:"app_id"`
+	SelfID      string    `json:"self_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Facts       []Digest  `json:"facts"`
+}
+
+// Digest is the SHA-256 digest of a fact.
+type Digest struct {
+	ID     string `json:"id"`
+	SHA256 string `json:"sha256"`
+}
+
+// Fact is a fact received from the connection.
+type Fact struct {
+	ID            string        `json:"id"`
+	ISS           string        `json:"iss"`
+	Fact          string        `json:"fact"`
+	Source        string        `json:"source"`
+	Status        string        `json:"status"`
+	Operator      string        `json:"operator,omitempty"`
+	ExpectedValue string        `json:"expected_value,omitempty"`
+	Result        *bool         `json:"result,omitempty"`
+	Request       *Request      `json:"request,omitempty"`
+	Attestations  []Attestation `json:"attestations"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// Request is the request the fact was received for.
+type Request struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"typ"`
+	Description string          `json:"description"`
+	Facts       json.RawMessage `json:"facts,omitempty"`
+	Status      string          `json:"status"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Attestation is a signed attestation of a fact.
+type Attestation struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+	// Body is the JWS signed by the issuer.
+	Body string `json:"body"`
+	// IssuerKeys is the public key history of the issuer when the attestation
+	// was received. Like the app keys, it's informative only.
+	IssuerKeys []json.RawMessage `json:"issuer_keys"`
+}
+
+// Digest returns the SHA-256 digest of the fact.
+func (f Fact) Digest() (string, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sign lists the bundle facts on a manifest signed by the app.
+func (b *Bundle) Sign(appID, selfID string, at time.Time, kid string, sk ed25519.PrivateKey) error {
+	m := Manifest{
+		Version:     VERSION,
+		AppID:       appID,
+		SelfID:      selfID,
+		GeneratedAt: at,
+		Facts:       []Digest{},
+	}
+	for _, f := range b.Facts {
+		digest, err := f.Digest()
+		if err != nil {
+			return err
+		}
+		m.Facts = append(m.Facts, Digest{ID: f.ID, SHA256: digest})
+	}
+
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	b.Version = VERSION
+	b.Manifest, err = jws.Sign(payload, kid, sk)
+	return err
+}
+
+// Verify checks the bundle manifest is signed by its app, the facts match
+// the manifest, and every attestation is signed by its issuer. The key
+// histories of the app and the issuers are taken from the given resolver, as
+// the ones included in the bundle can't be trusted. It returns the verified
+// manifest.
+func Verify(data []byte, keys KeyResolver) (Manifest, error) {
+	var b Bundle
+	err := json.Unmarshal(data, &b)
+	if err != nil {
+		return Manifest{}, ErrInvalidBundle
+	}
+
+	// Check the manifest was signed by the app.
+	signed, err := jws.Parse(b.Manifest)
+	if err != nil {
+		return Manifest{}, ErrInvalidManifest
+	}
+
+	var m Manifest
+	err = signed.DecodePayload(&m)
+	if err != nil || m.AppID == "" {
+		return Manifest{}, ErrInvalidManifest
+	}
+
+	appKeys, err := keys(m.AppID)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%w: %s", ErrInvalidManifest, err.Error())
+	}
+
+	err = signed.Verify(appKeys, m.GeneratedAt)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%w: %s", ErrInvalidManifest, err.Error())
+	}
+
+	// Check the facts are the ones listed on the manifest.
+	if len(m.Facts) != len(b.Facts) {
+		return Manifest{}, ErrFactsMismatch
+	}
+	for i, f := range b.Facts {
+		digest, err := f.Digest()
+		if err != nil || m.Facts[i].ID != f.ID || m.Facts[i].SHA256 != digest {
+			return Manifest{}, fmt.Errorf("%w: fact %s", ErrFactsMismatch, f.ID)
+		}
+
+		for _, a := range f.Attestations {
+			err = verifyAttestation(a, f.Fact, m.SelfID, keys)
+			if err != nil {
+				return Manifest{}, fmt.Errorf("%w %s: %s", ErrInvalidAttestation, a.ID, err.Error())
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// verifyAttestation checks the attestation is signed by the issuer it names,
+// and that it attests the given fact of the given subject with the value
+// stated on the bundle.
+func verifyAttestation(a Attestation, fact, subject string, keys KeyResolver) error {
+	signed, err := jws.Parse([]byte(a.Body))
+	if err != nil {
+		return err
+	}
+
+	var p struct {
+		Issuer   string    `json:"iss"`
+		Subject  string    `json:"sub"`
+		IssuedAt time.Time `json:"iat"`
+	}
+	err = signed.DecodePayload(&p)
+	if err != nil {
+		return err
+	}
+	if p.Issuer == "" {
+		return jws.ErrNoKeyHistory
+	}
+	if p.Subject != subject {
+		return errSubjectMismatch
+	}
+
+	// The attested value is keyed by the fact name.
+	var claims map[string]interface{}
+	err = signed.DecodePayload(&claims)
+	if err != nil {
+		return err
+	}
+	if value, ok := claims[fact].(string); !ok || value != a.Value {
+		return errValueMismatch
+	}
+
+	history, err := keys(p.Issuer)
+	if err != nil {
+		return err
+	}
+
+	return signed.Verify(history, p.IssuedAt)
+}
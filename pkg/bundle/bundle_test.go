@@ -0,0 +1,160 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joinself/restful-client/pkg/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// identity is a Self identity with a single device key.
+type identity struct {
+	id      string
+	kid     string
+	sk      ed25519.PrivateKey
+	history []json.RawMessage
+}
+
+func newIdentity(t *testing.T, id string, createdAt time.Time) identity {
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	rpk, _, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	enc := base64.RawURLEncoding
+	op, err := json.Marshal(map[string]interface{}{
+		"sequence":  0,
+		"version":   "1.0.0",
+		"timestamp": createdAt.Unix(),
+		"actions": []map[string]interface{}{
+			{"kid": "1", "did": "1", "type": "device.key", "action": "key.add", "from": createdAt.Unix(), "key": enc.EncodeToString(sk.Public().(ed25519.PublicKey))},
+			{"kid": "2", "type": "recovery.key", "action": "key.add", "from": createdAt.Unix(), "key": enc.EncodeToString(rpk)},
+		},
+	})
+	require.Nil(t, err)
+	signed, err := jws.Sign(op, "1", sk)
+	require.Nil(t, err)
+
+	return identity{id, "1", sk, []json.RawMessage{signed}}
+}
+
+func (i identity) attest(t *testing.T, sub, fact, value string, at time.Time) Attestation {
+	payload, err := json.Marshal(map[string]string{
+		"iss":    i.id,
+		"sub":    sub,
+		"iat":    at.Format(time.RFC3339),
+		"source": "user_specified",
+		fact:     value,
+	})
+	require.Nil(t, err)
+	body, err := jws.Sign(payload, i.kid, i.sk)
+	require.Nil(t, err)
+
+	return Attestation{ID: "attestation_" + fact, Value: value, Body: string(body), IssuerKeys: i.history}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Now().UTC()
+	app := newIdentity(t, "app", now.Add(-time.Hour))
+	issuer := newIdentity(t, "connection", now.Add(-time.Hour))
+
+	// keys resolves the trusted key histories, as retrieved from Self.
+	keys := func(selfID string) ([]json.RawMessage, error) {
+		switch selfID {
+		case app.id:
+			return app.history, nil
+		case issuer.id:
+			return issuer.history, nil
+		}
+		return nil, errors.New("unknown identity")
+	}
+
+	newBundle := func() Bundle {
+		b := Bundle{
+			AppKeys: app.history,
+			Facts: []Fact{{
+				ID:           "fact_1",
+				ISS:          "connection",
+				Fact:         "email_address",
+				Source:       "user_specified",
+				Status:       "accepted",
+				Request:      &Request{ID: "request_1", Type: "fact", Facts: json.RawMessage(`[{"name":"email_address"}]`), CreatedAt: now},
+				Attestations: []Attestation{issuer.attest(t, "connection", "email_address", "test@example.com", now)},
+				CreatedAt:    now,
+			}},
+		}
+		require.Nil(t, b.Sign("app", "connection", now, app.kid, app.sk))
+		return b
+	}
+
+	data, err := json.Marshal(newBundle())
+	require.Nil(t, err)
+	m, err := Verify(data, keys)
+	require.Nil(t, err)
+	assert.Equal(t, "app", m.AppID)
+	assert.Equal(t, "connection", m.SelfID)
+	assert.Len(t, m.Facts, 1)
+
+	// An attacker can generate identities claiming to be the app and the
+	// issuer, and embed their keys on the bundle.
+	fakeApp := newIdentity(t, "app", now.Add(-time.Hour))
+	fakeIssuer := newIdentity(t, "connection", now.Add(-time.Hour))
+
+	tests := []struct {
+		name   string
+		tamper func(b *Bundle)
+		want   error
+	}{
+		{"tampered fact", func(b *Bundle) { b.Facts[0].Attestations[0].Value = "other@example.com" }, ErrFactsMismatch},
+		{"removed fact", func(b *Bundle) { b.Facts = []Fact{} }, ErrFactsMismatch},
+		{"unknown app", func(b *Bundle) {
+			require.Nil(t, b.Sign("other", "connection", now, app.kid, app.sk))
+		}, ErrInvalidManifest},
+		{"self-signed bundle", func(b *Bundle) {
+			b.Facts[0].Attestations[0] = fakeIssuer.attest(t, "connection", "email_address", "other@example.com", now)
+			b.AppKeys = fakeApp.history
+			require.Nil(t, b.Sign("app", "connection", now, fakeApp.kid, fakeApp.sk))
+		}, ErrInvalidManifest},
+		{"forged attestation", func(b *Bundle) {
+			b.Facts[0].Attestations[0] = fakeIssuer.attest(t, "connection", "email_address", "other@example.com", now)
+			require.Nil(t, b.Sign("app", "connection", now, app.kid, app.sk))
+		}, ErrInvalidAttestation},
+		{"altered value", func(b *Bundle) {
+			b.Facts[0].Attestations[0].Value = "other@example.com"
+			require.Nil(t, b.Sign("app", "connection", now, app.kid, app.sk))
+		}, ErrInvalidAttestation},
+		{"other fact", func(b *Bundle) {
+			b.Facts[0].Attestations[0] = issuer.attest(t, "connection", "phone_number", "test@example.com", now)
+			require.Nil(t, b.Sign("app", "connection", now, app.kid, app.sk))
+		}, ErrInvalidAttestation},
+		{"other subject", func(b *Bundle) {
+			b.Facts[0].Attestations[0] = issuer.attest(t, "someone_else", "email_address", "test@example.com", now)
+			require.Nil(t, b.Sign("app", "connection", now, app.kid, app.sk))
+		}, ErrInvalidAttestation},
+		{"unknown issuer", func(b *Bundle) {
+			stranger := newIdentity(t, "stranger", now.Add(-time.Hour))
+			b.Facts[0].Attestations[0] = stranger.attest(t, "connection", "email_address", "other@example.com", now)
+			require.Nil(t, b.Sign("app", "connection", now, app.kid, app.sk))
+		}, ErrInvalidAttestation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBundle()
+			tt.tamper(&b)
+			data, err := json.Marshal(b)
+			require.Nil(t, err)
+			_, err = Verify(data, keys)
+			assert.True(t, errors.Is(err, tt.want), "got %v", err)
+		})
+	}
+
+	_, err = Verify([]byte("invalid"), keys)
+	assert.Equal(t, ErrInvalidBundle, err)
+}
@@ -1,6 +1,14 @@
 package helper
 
-import "strings"
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidDeviceSecret is returned when a device secret can't be decoded.
+var ErrInvalidDeviceSecret = errors.New("the device secret key provided is not valid")
 
 // FlattenSelfID removes anything after the colon, removing any references
 // to a device on the self identifier..
@@ -12,3 +20,21 @@ func FlattenSelfID(selfID string) string {
 
 	return selfID
 }
+
+// DeviceKey decodes the key identifier and the signing key from an app
+// device secret, as provided by the developer portal.
+func DeviceKey(secret string) (string, ed25519.PrivateKey, error) {
+	secret = strings.TrimPrefix(secret, "sk_")
+
+	parts := strings.Split(secret, ":")
+	if len(parts) < 2 {
+		return "", nil, ErrInvalidDeviceSecret
+	}
+
+	seed, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return "", nil, ErrInvalidDeviceSecret
+	}
+
+	return parts[0], ed25519.NewKeyFromSeed(seed), nil
+}
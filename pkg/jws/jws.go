@@ -0,0 +1,135 @@
+// Package jws signs and verifies the json serialized JWS objects exchanged
+// by Self identities, using the public key history of the signer.
+package jws
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/joinself/self-go-sdk/pkg/siggraph"
+)
+
+var (
+	// ErrInvalidJWS is returned when the given data is not a json serialized JWS.
+	ErrInvalidJWS = errors.New("invalid jws")
+	// ErrNoKeyHistory is returned when the signer public key history is
+	// missing or invalid.
+	ErrNoKeyHistory = errors.New("issuer public keys are not available")
+	// ErrInvalidKey is returned when the signing key was not valid at the
+	// time the object was signed.
+	ErrInvalidKey = errors.New("signing key was not valid when the jws was signed")
+	// ErrInvalidSignature is returned when the signature does not match the
+	// signer public key.
+	ErrInvalidSignature = errors.New("jws signature is not valid")
+)
+
+var enc = base64.RawURLEncoding
+
+// JWS is the json serialization of a signed object.
+type JWS struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Signature string `json:"signature"`
+}
+
+// Header is the protected header of a JWS.
+type Header struct {
+	Algorithm string `json:"alg"`
+	KID       string `json:"kid"`
+}
+
+// Parse parses a json serialized JWS.
+func Parse(data []byte) (JWS, error) {
+	var j JWS
+	err := json.Unmarshal(data, &j)
+	if err != nil || j.Payload == "" || j.Protected == "" {
+		return JWS{}, ErrInvalidJWS
+	}
+	return j, nil
+}
+
+// Sign signs the given payload with the given key, and returns its json
+// serialization.
+func Sign(payload []byte, kid string, sk ed25519.PrivateKey) ([]byte, error) {
+	hdr, err := json.Marshal(Header{Algorithm: "EdDSA", KID: kid})
+	if err != nil {
+		return nil, err
+	}
+
+	j := JWS{
+		Payload:   enc.EncodeToString(payload),
+		Protected: enc.EncodeToString(hdr),
+	}
+	j.Signature = enc.EncodeToString(ed25519.Sign(sk, j.signingInput()))
+
+	return json.Marshal(j)
+}
+
+// Header decodes the protected header.
+func (j JWS) Header() (Header, error) {
+	var hdr Header
+	return hdr, decode(j.Protected, &hdr)
+}
+
+// DecodePayload decodes the json payload into v.
+func (j JWS) DecodePayload(v interface{}) error {
+	return decode(j.Payload, v)
+}
+
+// Verify checks the signature was made at the given time by a valid key of
+// the given public key history.
+func (j JWS) Verify(history []json.RawMessage, at time.Time) error {
+	if len(history) == 0 {
+		return ErrNoKeyHistory
+	}
+
+	sg, err := siggraph.New(history)
+	if err != nil {
+		return ErrNoKeyHistory
+	}
+
+	hdr, err := j.Header()
+	if err != nil {
+		return err
+	}
+
+	if !sg.IsKeyValid(hdr.KID, at) {
+		return ErrInvalidKey
+	}
+
+	pk, err := sg.Key(hdr.KID)
+	if err != nil {
+		return ErrInvalidKey
+	}
+
+	sig, err := enc.DecodeString(j.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !ed25519.Verify(pk, j.signingInput(), sig) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (j JWS) signingInput() []byte {
+	return []byte(j.Protected + "." + j.Payload)
+}
+
+func decode(segment string, v interface{}) error {
+	data, err := enc.DecodeString(segment)
+	if err != nil {
+		return ErrInvalidJWS
+	}
+
+	if json.Unmarshal(data, v) != nil {
+		return ErrInvalidJWS
+	}
+
+	return nil
+}
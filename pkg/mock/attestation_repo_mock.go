@@ -28,6 +28,18 @@ func (m AttestationRepositoryMock) Query(ctx context.Context, connection string,
 	return m.Items, nil
 }
 
+func (m AttestationRepositoryMock) QueryByFacts(ctx context.Context, factIDs []string) ([]entity.Attestation, error) {
+	attestations := []entity.Attestation{}
+	for _, item := range m.Items {
+		for _, id := range factIDs {
+			if item.FactID == id {
+				attestations = append(attestations, item)
+			}
+		}
+	}
+	return attestations, nil
+}
+
 func (m *AttestationRepositoryMock) Create(ctx context.Context, attestation entity.Attestation) error {
 	m.Items = append(m.Items, attestation)
 	return nil
@@ -7,9 +7,11 @@ import (
 
 type CallbackWorkerPoolMock struct {
 	History []webhook.WebhookPayload
+	URLs    []string
 }
 
 func (p *CallbackWorkerPoolMock) Send(qm worker.CallbackTask) error {
 	p.History = append(p.History, qm.WebhookPayload)
+	p.URLs = append(p.URLs, qm.URL)
 	return nil
 }
@@ -62,3 +62,13 @@ func (m *ConnectionRepositoryMock) Delete(ctx context.Context, id int) error {
 	}
 	return nil
 }
+
+func (m *ConnectionRepositoryMock) SetTags(ctx context.Context, id int, tags []string) error {
+	for i, item := range m.Items {
+		if item.ID == id {
+			m.Items[i].Tags = tags
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/joinself/restful-client/internal/entity"
+)
+
+type ConversationRepositoryMock struct {
+	States map[int]entity.ConversationState
+}
+
+func (m *ConversationRepositoryMock) Get(ctx context.Context, appID, selfID string) (entity.Conversation, error) {
+	return entity.Conversation{}, sql.ErrNoRows
+}
+
+func (m *ConversationRepositoryMock) Count(ctx context.Context, appID string, filter entity.ConversationFilter) (int, error) {
+	return 0, nil
+}
+
+func (m *ConversationRepositoryMock) Query(ctx context.Context, appID string, filter entity.ConversationFilter, ascending bool, offset, limit int) ([]entity.Conversation, error) {
+	return []entity.Conversation{}, nil
+}
+
+func (m *ConversationRepositoryMock) Messages(ctx context.Context, ids []int) ([]entity.Message, error) {
+	return []entity.Message{}, nil
+}
+
+func (m *ConversationRepositoryMock) State(ctx context.Context, connectionID int) (entity.ConversationState, error) {
+	if state, ok := m.States[connectionID]; ok {
+		return state, nil
+	}
+	return entity.ConversationState{}, sql.ErrNoRows
+}
+
+func (m *ConversationRepositoryMock) SaveState(ctx context.Context, state entity.ConversationState) error {
+	if m.States == nil {
+		m.States = map[int]entity.ConversationState{}
+	}
+	m.States[state.ConnectionID] = state
+	return nil
+}
+
+func (m *ConversationRepositoryMock) CountNotes(ctx context.Context, connectionID int) (int, error) {
+	return 0, nil
+}
+
+func (m *ConversationRepositoryMock) Notes(ctx context.Context, connectionID int, offset, limit int) ([]entity.ConversationNote, error) {
+	return []entity.ConversationNote{}, nil
+}
+
+func (m *ConversationRepositoryMock) CreateNote(ctx context.Context, note *entity.ConversationNote) error {
+	return nil
+}
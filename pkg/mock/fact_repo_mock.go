@@ -3,6 +3,7 @@ package mock
 import (
 	"context"
 	"database/sql"
+	"sort"
 
 	"github.com/joinself/restful-client/internal/entity"
 )
@@ -20,6 +21,15 @@ func (m FactRepositoryMock) Get(ctx context.Context, connID int, id string) (ent
 	return entity.Fact{}, sql.ErrNoRows
 }
 
+func (m FactRepositoryMock) GetByApp(ctx context.Context, appID, id string) (entity.Fact, error) {
+	for _, item := range m.Items {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return entity.Fact{}, sql.ErrNoRows
+}
+
 func (m FactRepositoryMock) Count(ctx context.Context, conn int, source, fact string) (int, error) {
 	return len(m.Items), nil
 }
@@ -28,6 +38,68 @@ func (m FactRepositoryMock) Query(ctx context.Context, conn int, source, fact st
 	return m.Items, nil
 }
 
+func (m FactRepositoryMock) CountByApp(ctx context.Context, appID string, filter entity.FactFilter) (int, error) {
+	facts, _ := m.QueryByApp(ctx, appID, filter, 0, len(m.Items))
+	return len(facts), nil
+}
+
+func (m FactRepositoryMock) QueryByApp(ctx context.Context, appID string, filter entity.FactFilter, offset, limit int) ([]entity.FactSummary, error) {
+	facts := []entity.FactSummary{}
+	for _, item := range m.Items {
+		if filter.Fact != "" && item.Fact != filter.Fact {
+			continue
+		}
+		facts = append(facts, entity.FactSummary{Fact: item, Connection: item.ISS})
+	}
+	if offset >= len(facts) {
+		return []entity.FactSummary{}, nil
+	}
+	if offset+limit < len(facts) {
+		facts = facts[offset : offset+limit]
+	} else {
+		facts = facts[offset:]
+	}
+	return facts, nil
+}
+
+func (m FactRepositoryMock) Latest(ctx context.Context, conn int) ([]entity.Fact, error) {
+	latest := map[string]entity.Fact{}
+	keys := []string{}
+	for _, item := range m.Items {
+		if item.Status != entity.STATUS_ACCEPTED || item.Operator != "" {
+			continue
+		}
+		current, ok := latest[item.Fact]
+		if !ok {
+			keys = append(keys, item.Fact)
+		}
+		if !ok || item.CreatedAt.After(current.CreatedAt) {
+			latest[item.Fact] = item
+		}
+	}
+	facts := []entity.Fact{}
+	for _, k := range keys {
+		facts = append(facts, latest[k])
+	}
+	return facts, nil
+}
+
+func (m FactRepositoryMock) History(ctx context.Context, conn int) ([]entity.Fact, error) {
+	facts := []entity.Fact{}
+	for _, item := range m.Items {
+		if item.Status == entity.STATUS_ACCEPTED && item.Operator == "" {
+			facts = append(facts, item)
+		}
+	}
+	sort.SliceStable(facts, func(i, j int) bool {
+		if facts[i].Fact != facts[j].Fact {
+			return facts[i].Fact < facts[j].Fact
+		}
+		return facts[i].CreatedAt.After(facts[j].CreatedAt)
+	})
+	return facts, nil
+}
+
 func (m *FactRepositoryMock) Create(ctx context.Context, fact entity.Fact) error {
 	if fact.Fact == "error" {
 		return ErrCRUD
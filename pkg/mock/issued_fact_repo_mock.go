@@ -0,0 +1,41 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/joinself/restful-client/internal/entity"
+)
+
+type IssuedFactRepositoryMock struct {
+	Items []entity.IssuedFact
+}
+
+func (m IssuedFactRepositoryMock) Count(ctx context.Context, conn int) (int, error) {
+	return len(m.Items), nil
+}
+
+func (m IssuedFactRepositoryMock) Query(ctx context.Context, conn int, offset, limit int) ([]entity.IssuedFact, error) {
+	return m.Items, nil
+}
+
+func (m *IssuedFactRepositoryMock) Create(ctx context.Context, facts []entity.IssuedFact) error {
+	for _, f := range facts {
+		if f.Key == "error" {
+			return ErrCRUD
+		}
+	}
+	m.Items = append(m.Items, facts...)
+	return nil
+}
+
+func (m *IssuedFactRepositoryMock) Deliver(ctx context.Context, appID, selfID string, uuid int, at time.Time) error {
+	for i, item := range m.Items {
+		if item.AppID == appID && item.SelfID == selfID && item.Status == entity.ISSUED_FACT_SENT_STATUS && !item.CreatedAt.After(at) {
+			m.Items[i].Status = entity.ISSUED_FACT_DELIVERED_STATUS
+			m.Items[i].MetricUUID = &uuid
+			m.Items[i].DeliveredAt = &at
+		}
+	}
+	return nil
+}
@@ -4,15 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 
 	"github.com/joinself/restful-client/internal/entity"
 )
 
 type MessageRepositoryMock struct {
-	Items []entity.Message
+	Items           []entity.Message
+	AttachmentItems []entity.MessageAttachment
+	RevisionItems   []entity.MessageRevision
 }
 
 func (m MessageRepositoryMock) Get(ctx context.Context, connectionID int, id string) (entity.Message, error) {
+	if id == "error" {
+		return entity.Message{}, errors.New("error")
+	}
 	for _, item := range m.Items {
 		if item.JTI == id {
 			return item, nil
@@ -21,12 +27,23 @@ func (m MessageRepositoryMock) Get(ctx context.Context, connectionID int, id str
 	return entity.Message{}, sql.ErrNoRows
 }
 
-func (m MessageRepositoryMock) Count(ctx context.Context, connection, messagesSince int) (int, error) {
-	return len(m.Items), nil
+func (m MessageRepositoryMock) Count(ctx context.Context, connection, messagesSince int, thread string) (int, error) {
+	items, _ := m.Query(ctx, connection, messagesSince, thread, 0, 0)
+	return len(items), nil
 }
 
-func (m MessageRepositoryMock) Query(ctx context.Context, connection, lasMessageID, offset, limit int) ([]entity.Message, error) {
-	return m.Items, nil
+func (m MessageRepositoryMock) Query(ctx context.Context, connection, lasMessageID int, thread string, offset, limit int) ([]entity.Message, error) {
+	if thread == "" {
+		return m.Items, nil
+	}
+
+	items := []entity.Message{}
+	for _, item := range m.Items {
+		if item.CID == thread {
+			items = append(items, item)
+		}
+	}
+	return items, nil
 }
 
 func (m *MessageRepositoryMock) Create(ctx context.Context, message *entity.Message) error {
@@ -60,3 +77,71 @@ func (m *MessageRepositoryMock) Delete(ctx context.Context, connectioniD int, id
 	}
 	return errors.New("not found")
 }
+
+func (m *MessageRepositoryMock) CreateAttachment(ctx context.Context, attachment *entity.MessageAttachment) error {
+	if attachment.Name == "error" {
+		return ErrCRUD
+	}
+	m.AttachmentItems = append(m.AttachmentItems, *attachment)
+	return nil
+}
+
+func (m *MessageRepositoryMock) UpdateAttachment(ctx context.Context, attachment entity.MessageAttachment) error {
+	for i, item := range m.AttachmentItems {
+		if item.MessageID == attachment.MessageID && item.Position == attachment.Position {
+			m.AttachmentItems[i] = attachment
+			break
+		}
+	}
+	return nil
+}
+
+func (m MessageRepositoryMock) Attachments(ctx context.Context, messageID int) ([]entity.MessageAttachment, error) {
+	attachments := []entity.MessageAttachment{}
+	for _, item := range m.AttachmentItems {
+		if item.MessageID == messageID {
+			attachments = append(attachments, item)
+		}
+	}
+	return attachments, nil
+}
+
+func (m MessageRepositoryMock) Search(ctx context.Context, appID string, params entity.MessageSearch, offset, limit int) ([]entity.MessageMatch, error) {
+	if params.Query == "error" {
+		return nil, ErrCRUD
+	}
+
+	matches := []entity.MessageMatch{}
+	for _, item := range m.Items {
+		if strings.Contains(item.Body, params.Query) {
+			matches = append(matches, entity.MessageMatch{Message: item, Snippet: item.Body})
+		}
+	}
+	return matches, nil
+}
+
+func (m MessageRepositoryMock) CountSearch(ctx context.Context, appID string, params entity.MessageSearch) (int, error) {
+	matches, err := m.Search(ctx, appID, params, 0, 0)
+	return len(matches), err
+}
+
+func (m *MessageRepositoryMock) Edit(ctx context.Context, message entity.Message, revision *entity.MessageRevision) error {
+	if revision.Body == "error" {
+		return ErrCRUD
+	}
+	if err := m.Update(ctx, message); err != nil {
+		return err
+	}
+	m.RevisionItems = append(m.RevisionItems, *revision)
+	return nil
+}
+
+func (m MessageRepositoryMock) Revisions(ctx context.Context, messageID int) ([]entity.MessageRevision, error) {
+	revisions := []entity.MessageRevision{}
+	for _, item := range m.RevisionItems {
+		if item.MessageID == messageID {
+			revisions = append(revisions, item)
+		}
+	}
+	return revisions, nil
+}
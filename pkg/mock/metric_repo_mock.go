@@ -0,0 +1,24 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/joinself/restful-client/internal/entity"
+)
+
+type MetricRepositoryMock struct {
+	Items []entity.Metric
+}
+
+func (m MetricRepositoryMock) Count(ctx context.Context, appID string, from, to int64) (int, error) {
+	return len(m.Items), nil
+}
+
+func (m MetricRepositoryMock) Query(ctx context.Context, appid string, offset, limit int, from, to int64) ([]entity.Metric, error) {
+	return m.Items, nil
+}
+
+func (m *MetricRepositoryMock) Upsert(ctx context.Context, metric *entity.Metric) error {
+	m.Items = append(m.Items, *metric)
+	return nil
+}
@@ -3,6 +3,7 @@ package mock
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/support"
@@ -69,3 +70,41 @@ func (m *RequestRepositoryMock) Delete(ctx context.Context, id string) error {
 func (m *RequestRepositoryMock) SetStatus(ctx context.Context, id string, status string) error {
 	return nil
 }
+
+func (m *RequestRepositoryMock) Transition(ctx context.Context, id, from, to string) (bool, error) {
+	for i, item := range m.Items {
+		if item.ID == id && item.Status == from {
+			m.Items[i].Status = to
+			m.Items[i].UpdatedAt = time.Now()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *RequestRepositoryMock) Count(ctx context.Context, appID string, filter entity.RequestFilter) (int, error) {
+	items, _ := m.Query(ctx, appID, filter, 0, len(m.Items))
+	return len(items), nil
+}
+
+func (m *RequestRepositoryMock) Query(ctx context.Context, appID string, filter entity.RequestFilter, offset, limit int) ([]entity.RequestSummary, error) {
+	items := []entity.RequestSummary{}
+	for _, item := range m.Items {
+		if item.AppID != appID || (filter.Status != "" && item.Status != filter.Status) || (filter.Type != "" && item.Type != filter.Type) {
+			continue
+		}
+		items = append(items, entity.RequestSummary{Request: item})
+	}
+	return items, nil
+}
+
+func (m *RequestRepositoryMock) Expired(ctx context.Context, before time.Time, limit int) ([]entity.Request, error) {
+	items := []entity.Request{}
+	for _, item := range m.Items {
+		if item.Status != entity.REQUEST_REQUESTED_STATUS || item.ExpiresAt == nil || item.ExpiresAt.After(before) {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"github.com/joinself/restful-client/internal/entity"
+	"github.com/joinself/restful-client/pkg/support"
 	"github.com/joinself/restful-client/pkg/webhook"
 	selfsdk "github.com/joinself/self-go-sdk"
 )
@@ -39,6 +40,16 @@ func (m RunnerMock) Poster(id string) (webhook.Poster, bool) {
 	return nil, false
 }
 
+func (m RunnerMock) Notify(id string, payload webhook.WebhookPayload) error {
+	return nil
+}
+
+func (m RunnerMock) SetRules(rules support.RuleEvaluator) {
+}
+
+func (m RunnerMock) SetOnboarding(onboarding support.Onboarder) {
+}
+
 func (m RunnerMock) SetApp(app entity.App) error {
 	return nil
 }
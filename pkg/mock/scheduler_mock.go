@@ -0,0 +1,18 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/joinself/restful-client/pkg/worker"
+)
+
+type SchedulerMock struct {
+	Tasks []worker.ScheduledTask
+	Times []time.Time
+}
+
+func (s *SchedulerMock) Schedule(task worker.ScheduledTask, at time.Time) error {
+	s.Tasks = append(s.Tasks, task)
+	s.Times = append(s.Times, at)
+	return nil
+}
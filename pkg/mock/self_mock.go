@@ -34,6 +34,13 @@ func (m *SelfMock) FactService() support.FactService {
 	return &FactServiceMock{}
 }
 
+func (m *SelfMock) DownloadObject(link, key, digest string) ([]byte, error) {
+	if link == "error" {
+		return nil, ErrCRUD
+	}
+	return []byte("content"), nil
+}
+
 func (s *SelfMock) Stop() {
 }
 
@@ -3,10 +3,12 @@ package support
 import (
 	"context"
 
+	"github.com/joinself/restful-client/internal/entity"
 	"github.com/joinself/restful-client/pkg/webhook"
 	selfsdk "github.com/joinself/self-go-sdk"
 	"github.com/joinself/self-go-sdk/fact"
 	"github.com/joinself/self-go-sdk/messaging"
+	"github.com/joinself/self-go-sdk/pkg/object"
 	"github.com/maragudk/goqite"
 )
 
@@ -16,6 +18,7 @@ type SelfClient interface {
 	MessagingService() MessagingService
 	ChatService() ChatService
 	FactService() FactService
+	DownloadObject(link, key, digest string) ([]byte, error)
 	Stop()
 	Get() *selfsdk.Client
 }
@@ -39,6 +42,9 @@ func (s *selfClient) ChatService() ChatService {
 func (s *selfClient) FactService() FactService {
 	return s.client.FactService()
 }
+func (s *selfClient) DownloadObject(link, key, digest string) ([]byte, error) {
+	return DownloadObject(s.client, link, key, digest)
+}
 func (s *selfClient) Stop() {
 	s.client.Close()
 }
@@ -50,6 +56,21 @@ func NewSelfClient(client *selfsdk.Client) SelfClient {
 	return &selfClient{client}
 }
 
+// DownloadObject downloads and decrypts a remote Self object, checking its
+// content against the given digest when provided.
+func DownloadObject(client *selfsdk.Client, link, key, digest string) ([]byte, error) {
+	fi := object.NewRemoteFileInteractor(client.Rest())
+	if digest == "" {
+		return fi.GetObject(link, key)
+	}
+
+	o := object.New(fi)
+	o.Link = link
+	o.Key = key
+	o.Digest = digest
+	return o.GetContent()
+}
+
 type MessagingService interface {
 	Subscribe(messageType string, h func(m *messaging.Message))
 }
@@ -65,6 +86,18 @@ type SelfClientGetter interface {
 	Poster(id string) (webhook.Poster, bool)
 }
 
+// RuleEvaluator runs the rules of an app on the messages it receives, and
+// returns the endpoints the message webhook is routed to.
+type RuleEvaluator interface {
+	Evaluate(ctx context.Context, appID, selfID string, msg entity.Message) []string
+}
+
+// Onboarder runs the onboarding policy of an app for its new connections,
+// and returns whether the app must be notified about them.
+type Onboarder interface {
+	Onboard(ctx context.Context, appID string, conn entity.Connection) bool
+}
+
 type QueueSender interface {
 	Send(context.Context, goqite.Message) error
 }
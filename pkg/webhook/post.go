@@ -13,19 +13,31 @@ import (
 const (
 	// TYPE_MESSAGE webhook type used when a message is received
 	TYPE_MESSAGE = "message"
+	// TYPE_MESSAGE_EDIT webhook type used when a received message is edited
+	TYPE_MESSAGE_EDIT = "message_edit"
+	// TYPE_MESSAGE_DELETE webhook type used when a received message is deleted
+	TYPE_MESSAGE_DELETE = "message_delete"
+	// TYPE_MESSAGE_QUICK_REPLY webhook type used when a received message answers a quick replies message
+	TYPE_MESSAGE_QUICK_REPLY = "message_quick_reply"
+	// TYPE_CONVERSATION webhook type used when the assignee or the status of a conversation changes
+	TYPE_CONVERSATION = "conversation"
+	// TYPE_CONVERSATION_NOTE webhook type used when a note is added to a conversation
+	TYPE_CONVERSATION_NOTE = "conversation_note"
 	// TYPE_FACT_RESPONSE webhook type used when an untracked fact response is received
 	TYPE_FACT_RESPONSE = "fact_response"
 	// TYPE_CONNECTION webhook type used when a connection is received
 	TYPE_CONNECTION = "connection"
 	// TYPE_REQUEST webhook type used when a request response is received
-	TYPE_REQUEST      = "request"
-	TYPE_RAW          = "raw"
-	TYPE_VOICE_START  = "voice_start"
-	TYPE_VOICE_BUSY   = "voice_busy"
-	TYPE_VOICE_STOP   = "voice_stop"
-	TYPE_VOICE_ACCEPT = "voice_accept"
-	TYPE_VOICE_SETUP  = "voice_setup"
-	TYPE_SIGNATURE    = "signature"
+	TYPE_REQUEST = "request"
+	// TYPE_REQUEST_EXPIRED webhook type used when a request expires without a response
+	TYPE_REQUEST_EXPIRED = "request_expired"
+	TYPE_RAW             = "raw"
+	TYPE_VOICE_START     = "voice_start"
+	TYPE_VOICE_BUSY      = "voice_busy"
+	TYPE_VOICE_STOP      = "voice_stop"
+	TYPE_VOICE_ACCEPT    = "voice_accept"
+	TYPE_VOICE_SETUP     = "voice_setup"
+	TYPE_SIGNATURE       = "signature"
 )
 
 // WebhookPayload represents a the payload that will be resent to the
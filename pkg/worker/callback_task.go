@@ -6,12 +6,15 @@ import (
 
 // CallbackTask is the task to be queued.
 type CallbackTask struct {
-	AppID          string                 `json:"app_id"`
+	AppID string `json:"app_id"`
+	// URL is the endpoint the webhook is sent to, the app callback url is
+	// used when empty.
+	URL            string                 `json:"url,omitempty"`
 	WebhookPayload webhook.WebhookPayload `json:"webhook"`
 }
 
 // Send executes the send operation, so a webhook is sent to
 // the configured callback url.
 func (ct *CallbackTask) Send(s CallbackSender) error {
-	return s.SendCallback(ct.AppID, ct.WebhookPayload)
+	return s.SendCallback(ct.AppID, ct.URL, ct.WebhookPayload)
 }
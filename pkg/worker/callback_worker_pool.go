@@ -11,7 +11,7 @@ import (
 )
 
 type CallbackSender interface {
-	SendCallback(appID string, payload webhook.WebhookPayload) error
+	SendCallback(appID, url string, payload webhook.WebhookPayload) error
 }
 
 // CallbackWorkerPool manages the task queue and worker pool
@@ -49,7 +49,7 @@ type MockCallbackSender struct {
 	Error error
 }
 
-func (m *MockCallbackSender) SendCallback(appID string, payload webhook.WebhookPayload) error {
+func (m *MockCallbackSender) SendCallback(appID, url string, payload webhook.WebhookPayload) error {
 	return m.Error
 }
 
@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/maragudk/goqite"
+)
+
+const (
+	retryTimeout = 1 * time.Minute
+	// MaxReceive is the number of times the queued tasks are received before
+	// the queue gives up on them.
+	MaxReceive = 100
+	// RetryPeriod is a period failing tasks are always retried for before the
+	// queue gives up on them, so handlers can fail them on their own.
+	RetryPeriod = (MaxReceive - 10) * retryTimeout
+)
+
+// ScheduledTask is a task to be run at a given time.
+type ScheduledTask struct {
+	// Type is used to find the handler for the task.
+	Type    string          `json:"type"`
+	AppID   string          `json:"app_id"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// TaskHandler runs a scheduled task, returning an error retries the task
+// later.
+type TaskHandler func(ctx context.Context, task ScheduledTask) error
+
+// Scheduler runs tasks when they are due, using the delayed visibility of
+// the queue messages.
+type Scheduler struct {
+	queue    QueueManager
+	logger   log.Logger
+	handlers map[string]TaskHandler
+	mu       sync.RWMutex
+	quit     chan bool
+	wg       sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler.
+func NewScheduler(queue QueueManager, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		queue:    queue,
+		logger:   logger,
+		handlers: map[string]TaskHandler{},
+		quit:     make(chan bool),
+	}
+}
+
+// Register sets the handler for the tasks of the given type.
+func (s *Scheduler) Register(typ string, handler TaskHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[typ] = handler
+}
+
+// Schedule queues the given task to be run at the given time.
+func (s *Scheduler) Schedule(task ScheduledTask, at time.Time) error {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	delay := time.Until(at)
+	if delay < 0 {
+		delay = 0
+	}
+
+	return s.queue.Send(context.Background(), goqite.Message{
+		Body:  body,
+		Delay: delay,
+	})
+}
+
+// Start begins processing the due tasks.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case <-s.quit:
+				return
+			default:
+				if item, err := s.queue.Receive(context.Background()); err == nil && item != nil {
+					s.processTask(item)
+				} else {
+					time.Sleep(500 * time.Millisecond) // Avoid busy waiting
+				}
+			}
+		}
+	}()
+}
+
+// Stop signals the scheduler to stop
+func (s *Scheduler) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) processTask(m *goqite.Message) error {
+	var t ScheduledTask
+	err := json.Unmarshal(m.Body, &t)
+	if err != nil {
+		s.logger.Error("error unmarshalling scheduled task, deleting message")
+		return s.queue.Delete(context.Background(), m.ID)
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[t.Type]
+	s.mu.RUnlock()
+	if !ok {
+		s.logger.Errorf("no handler for scheduled task %s of type %s, deleting message", m.ID, t.Type)
+		return s.queue.Delete(context.Background(), m.ID)
+	}
+
+	if err = handler(context.Background(), t); err != nil {
+		s.logger.Infof("retrying scheduled task %s : %s", m.ID, err.Error())
+		if err := s.queue.Extend(context.Background(), m.ID, retryTimeout); err != nil {
+			s.logger.Error("error extending scheduled task timeout")
+		}
+		return err
+	}
+
+	return s.queue.Delete(context.Background(), m.ID)
+}
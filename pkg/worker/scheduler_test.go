@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joinself/restful-client/pkg/log"
+	"github.com/maragudk/goqite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScheduler_RunsDueTasks(t *testing.T) {
+	mockQueue := new(MockQueueManager)
+	mockQueue.On("Receive", mock.Anything).Return(&goqite.Message{
+		ID:   "msg1",
+		Body: []byte(`{"type":"test","app_id":"appID","payload":{"id":1}}`),
+	}, nil).Once()
+	mockQueue.On("Receive", mock.Anything).Return(nil, nil)
+	mockQueue.On("Delete", context.Background(), goqite.ID("msg1")).Return(nil)
+	mockLogger, _ := log.NewForTest()
+
+	handled := make(chan ScheduledTask, 1)
+	s := NewScheduler(mockQueue, mockLogger)
+	s.Register("test", func(ctx context.Context, task ScheduledTask) error {
+		handled <- task
+		return nil
+	})
+	s.Start()
+
+	select {
+	case task := <-handled:
+		assert.Equal(t, "appID", task.AppID)
+		assert.JSONEq(t, `{"id":1}`, string(task.Payload))
+	case <-time.After(time.Second):
+		t.Error("task was not handled")
+	}
+
+	s.Stop()
+	mockQueue.AssertExpectations(t)
+}
+
+func TestScheduler_ExtendOnHandlerError(t *testing.T) {
+	mockQueue := new(MockQueueManager)
+	mockQueue.On("Receive", mock.Anything).Return(&goqite.Message{
+		ID:   "msg1",
+		Body: []byte(`{"type":"test","app_id":"appID","payload":{}}`),
+	}, nil).Once()
+	mockQueue.On("Receive", mock.Anything).Return(nil, nil)
+	mockQueue.On("Extend", context.Background(), goqite.ID("msg1"), retryTimeout).Return(nil)
+	mockLogger, _ := log.NewForTest()
+
+	s := NewScheduler(mockQueue, mockLogger)
+	s.Register("test", func(ctx context.Context, task ScheduledTask) error {
+		return errors.New("not ready")
+	})
+	s.Start()
+
+	time.Sleep(100 * time.Millisecond)
+	s.Stop()
+	mockQueue.AssertExpectations(t)
+}
+
+func TestScheduler_DeleteUnknownTasks(t *testing.T) {
+	mockQueue := new(MockQueueManager)
+	mockQueue.On("Receive", mock.Anything).Return(&goqite.Message{
+		ID:   "msg1",
+		Body: []byte(`{"type":"unknown","app_id":"appID","payload":{}}`),
+	}, nil).Once()
+	mockQueue.On("Receive", mock.Anything).Return(nil, nil)
+	mockQueue.On("Delete", context.Background(), goqite.ID("msg1")).Return(nil)
+	mockLogger, _ := log.NewForTest()
+
+	s := NewScheduler(mockQueue, mockLogger)
+	s.Start()
+
+	time.Sleep(100 * time.Millisecond)
+	s.Stop()
+	mockQueue.AssertExpectations(t)
+}
+
+func TestScheduler_Schedule(t *testing.T) {
+	mockQueue := new(MockQueueManager)
+	mockLogger, _ := log.NewForTest()
+	s := NewScheduler(mockQueue, mockLogger)
+
+	task := ScheduledTask{Type: "test", AppID: "appID", Payload: json.RawMessage(`{}`)}
+	body, _ := json.Marshal(task)
+
+	mockQueue.On("Send", context.Background(), mock.MatchedBy(func(m goqite.Message) bool {
+		return string(m.Body) == string(body) && m.Delay > 50*time.Minute && m.Delay <= time.Hour
+	})).Return(nil).Once()
+	mockQueue.On("Send", context.Background(), goqite.Message{Body: body}).Return(nil).Once()
+
+	assert.NoError(t, s.Schedule(task, time.Now().Add(time.Hour)))
+	// Past dates are run straight away.
+	assert.NoError(t, s.Schedule(task, time.Now().Add(-time.Hour)))
+	mockQueue.AssertExpectations(t)
+}